@@ -2,11 +2,14 @@ package config
 
 import (
 	"encoding/json"
+	"strings"
 )
 
 type ClaudeSettings struct {
 	DefaultMaxTokens       map[string]int
 	BudgetTokensPercentage float64
+	// 这些模型的 Claude 请求可以通过任意对话渠道转换后提供，支持 * 结尾的前缀匹配
+	CompatibleModels []string
 }
 
 var ClaudeSettingsInstance = ClaudeSettings{
@@ -25,6 +28,40 @@ func init() {
 		ClaudeSettingsInstance.SetDefaultMaxTokens(value)
 		return nil
 	}, "")
+
+	GlobalOption.RegisterCustom("ClaudeCompatibleModels", func() string {
+		return strings.Join(ClaudeSettingsInstance.CompatibleModels, ",")
+	}, func(value string) error {
+		ClaudeSettingsInstance.SetCompatibleModels(value)
+		return nil
+	}, "")
+}
+
+func (c *ClaudeSettings) SetCompatibleModels(data string) {
+	models := make([]string, 0)
+	for _, model := range strings.Split(data, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	c.CompatibleModels = models
+}
+
+// IsCompatibleModel 模型是否允许通过对话渠道提供 Claude Messages API
+func (c *ClaudeSettings) IsCompatibleModel(model string) bool {
+	for _, compatibleModel := range c.CompatibleModels {
+		if prefix, ok := strings.CutSuffix(compatibleModel, "*"); ok {
+			if strings.HasPrefix(model, prefix) {
+				return true
+			}
+			continue
+		}
+		if compatibleModel == model {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (c *ClaudeSettings) SetDefaultMaxTokens(data string) {
@@ -33,6 +33,8 @@ func defaultConfig() {
 	viper.SetDefault("port", "3000")
 	viper.SetDefault("gin_mode", "release")
 	viper.SetDefault("log_dir", "./logs")
+	viper.SetDefault("storage.local.path", "./archives")
+	viper.SetDefault("embedding_cache_dir", "./embedding_cache")
 	viper.SetDefault("sqlite_path", "one-api.db")
 	viper.SetDefault("sqlite_busy_timeout", 3000)
 	viper.SetDefault("sync_frequency", 600)
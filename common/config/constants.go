@@ -52,6 +52,21 @@ var EnableSafe = false
 // 默认使用系统自带关键词审查工具
 var SafeToolName = "Keyword"
 
+// 是否审查模型输出，分组也可以单独开启
+var SafeCheckOutput = false
+
+// 流式输出按窗口审查，每个窗口的字符数
+var SafeStreamWindowSize = 200
+
+// OpenAIModeration 审查工具通过该渠道调用 /v1/moderations
+var SafeModerationChannelId = 0
+var SafeModerationModel = "omni-moderation-latest"
+
+// Webhook 审查工具
+var SafeWebhookURL = ""
+var SafeWebhookToken = ""
+var SafeWebhookTimeout = 5 // 秒
+
 // 系统自带关键词审查默认字典
 var SafeKeyWords = []string{
 	"fuck",
@@ -214,6 +229,79 @@ var GeminiAPIEnabled = true
 // Claude
 var ClaudeAPIEnabled = true
 
+// 对话缓存，需要令牌单独开启
+var ChatCacheEnabled = false
+var ChatCacheExpireMinute = 60
+var ChatCacheBillingRatio = 0.0 // 命中缓存时按原价的倍率计费，0 为不计费
+var ChatCacheMaxEntries = 10000 // 未开启Redis时内存缓存的最大条数
+
+// 渠道熔断，按 渠道:模型 统计
+var CircuitBreakerEnabled = false
+var CircuitBreakerWindowSeconds = 60    // 失败率统计窗口
+var CircuitBreakerMinRequests = 10      // 窗口内最少请求数，达到后才会计算失败率
+var CircuitBreakerFailureRate = 0.5     // 失败率达到该值时熔断
+var CircuitBreakerOpenSeconds = 30      // 熔断持续时间，之后进入半开状态
+var CircuitBreakerHalfOpenRatio = 0.1   // 半开状态下放行的流量比例
+var CircuitBreakerHalfOpenSuccesses = 3 // 半开状态下连续成功多少次后恢复
+
+// 对冲请求，仅流式对话，需要令牌单独开启
+var HedgeEnabled = false
+var HedgeDelayMilliseconds = 2000 // 超过该时间仍未收到首字时，并发请求另一个渠道
+
+// 请求归档，需要分组或令牌单独开启
+var ArchiveStorage = "Local"  // 归档使用的存储：Local / S3 / AliOSS
+var ArchiveRetentionDays = 30 // 归档保留天数，0 为永久保留
+var ArchiveMaxBodySize = 1024 // 请求和响应各自最多归档的大小，单位 KB
+var ArchiveRedactKeys = "api_key,apikey,authorization,password,secret,access_token,refresh_token"
+var ArchiveRedactPatterns = []string{
+	`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`, // 邮箱
+	`\b1[3-9]\d{9}\b`,           // 手机号
+	`\b\d{17}[\dXx]\b`,          // 身份证号
+	`\bsk-[A-Za-z0-9_-]{16,}\b`, // API Key
+}
+
+// 本地批处理，/v1/files 和 /v1/batches 未指定渠道时由网关执行
+var BatchStorage = "Local"      // 批处理文件使用的存储：Local / S3 / AliOSS
+var BatchDiscount = 0.5         // 批处理请求的计费倍率
+var BatchConcurrency = 4        // 同时执行的批处理请求数，修改后重启生效
+var BatchMaxRequests = 50000    // 单个批处理最多的请求数
+var BatchMaxFileSize = 100      // 上传文件的大小上限，单位 MB
+var BatchFileRetentionDays = 30 // 批处理文件保留天数，0 为永久保留
+
+// Responses API 兼容模式下由网关保存的响应，用于 previous_response_id
+var ResponsesRetentionDays = 30 // 响应保留天数，0 为永久保留
+var ResponsesMaxHistory = 100   // previous_response_id 最多向前追溯的响应数
+
+// 向量缓存，按分组、模型和输入文本缓存 embeddings 结果，只对未命中的输入计费
+var EmbeddingCacheEnabled = false
+var EmbeddingCacheStore = "Disk"    // 缓存存储：Redis / Disk，未开启 Redis 时使用 Disk
+var EmbeddingCacheExpireHours = 720 // 缓存有效期，0 为不过期
+var EmbeddingCacheMaxSize = 1024    // 磁盘缓存的大小上限，超出后按最近最少使用淘汰，单位 MB，0 为不限制
+
+// Realtime 会话限制，令牌未单独设置时使用，0 为不限制
+var RealtimeMaxSessions = 0        // 每个令牌同时进行的会话数
+var RealtimeMaxSessionMinutes = 60 // 单个会话的最长时长，单位分钟
+
+// 上下文窗口管理，令牌开启后对超出模型上下文的对话请求生效
+var ContextSummaryMaxTokens = 1024 // 摘要的最大长度，会从可用的上下文中预留
+
+// 异步任务结束时的客户端回调
+var TaskWebhookMaxAttempts = 6    // 最多投递次数，失败后按指数退避重试
+var TaskWebhookRetryInterval = 30 // 首次重试的间隔，之后每次翻倍，单位秒
+var TaskWebhookTimeout = 10       // 单次投递的超时时间，单位秒
+
+// 统一异步任务接口 /v1/tasks
+var VideoTaskRehost = false      // 任务成功后将结果视频转存到 storage，上游链接通常有有效期
+var VideoTaskMaxSeconds = 60     // 单个视频允许请求的最长秒数
+var VideoTaskMaxDownloadMB = 200 // 转存时单个文件的大小上限，单位 MB
+
+// 异步任务退款策略，上游失败和用户取消时全额退还
+var TaskTimeoutMinutes = 60      // 提交后超过该时长仍未完成视为超时，0 为不检查
+var TaskTimeoutRefundRatio = 1.0 // 超时的退还比例，0 到 1
+
+// 订阅套餐
+var SubscriptionRenewGraceHours = 24 // 自动续费的订阅在周期结束后等待扣款通知的时长，超时未支付则过期
+
 const (
 	RoleGuestUser     = 0
 	RoleCommonUser    = 1
@@ -326,6 +414,7 @@ const (
 	RelayModeChatRealtime
 	RelayModeKling
 	RelayModeResponses
+	RelayModeVideo
 )
 
 type ContextKey string
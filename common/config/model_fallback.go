@@ -0,0 +1,154 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 触发模型回退的错误类型
+const (
+	FallbackOnRateLimit     = "429"
+	FallbackOnServerError   = "5xx"
+	FallbackOnContextLength = "context_length_exceeded"
+)
+
+// ModelFallback 模型在所有渠道都失败后依次尝试的回退模型
+// Groups 为空时对所有分组生效，On 为空时所有错误类型都会触发回退
+type ModelFallback struct {
+	Model     string   `json:"model"`
+	Groups    []string `json:"groups,omitempty"`
+	Fallbacks []string `json:"fallbacks"`
+	On        []string `json:"on,omitempty"`
+}
+
+// Triggers 错误类型是否触发回退，class 为空表示不属于任何可回退的错误
+func (f *ModelFallback) Triggers(class string) bool {
+	if class == "" {
+		return false
+	}
+	if len(f.On) == 0 {
+		return true
+	}
+	for _, on := range f.On {
+		if on == class {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ModelFallback) matchGroup(group string) bool {
+	for _, g := range f.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+type ModelFallbackSettings struct {
+	sync.RWMutex
+	rules []ModelFallback
+}
+
+var ModelFallbackInstance = &ModelFallbackSettings{}
+
+func init() {
+	GlobalOption.RegisterCustom("ModelFallbacks", func() string {
+		return ModelFallbackInstance.GetJSONString()
+	}, func(value string) error {
+		return ModelFallbackInstance.SetRules(value)
+	}, "")
+}
+
+// ParseModelFallbacks 解析并校验回退配置，空字符串表示不配置
+func ParseModelFallbacks(data string) ([]ModelFallback, error) {
+	rules := make([]ModelFallback, 0)
+	if strings.TrimSpace(data) == "" {
+		return rules, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		return nil, fmt.Errorf("模型回退配置格式错误: %s", err.Error())
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Model == "" {
+			return nil, fmt.Errorf("第 %d 条回退配置缺少 model", i+1)
+		}
+		if len(rule.Fallbacks) == 0 {
+			return nil, fmt.Errorf("模型 %s 的回退配置缺少 fallbacks", rule.Model)
+		}
+		for _, fallback := range rule.Fallbacks {
+			if fallback == "" || fallback == rule.Model {
+				return nil, fmt.Errorf("模型 %s 的回退模型无效", rule.Model)
+			}
+		}
+		for _, on := range rule.On {
+			switch on {
+			case FallbackOnRateLimit, FallbackOnServerError, FallbackOnContextLength:
+			default:
+				return nil, errors.New("不支持的回退条件: " + on)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+func (s *ModelFallbackSettings) SetRules(data string) error {
+	rules, err := ParseModelFallbacks(data)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.rules = rules
+
+	return nil
+}
+
+func (s *ModelFallbackSettings) GetJSONString() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	if len(s.rules) == 0 {
+		return ""
+	}
+
+	data, err := json.Marshal(s.rules)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Get 返回模型在分组下的回退配置，指定了分组的配置优先于通用配置
+func (s *ModelFallbackSettings) Get(model, group string) *ModelFallback {
+	s.RLock()
+	defer s.RUnlock()
+
+	var generic *ModelFallback
+	for i := range s.rules {
+		rule := &s.rules[i]
+		if rule.Model != model {
+			continue
+		}
+		if len(rule.Groups) == 0 {
+			if generic == nil {
+				generic = rule
+			}
+			continue
+		}
+		if rule.matchGroup(group) {
+			return rule
+		}
+	}
+
+	return generic
+}
@@ -0,0 +1,41 @@
+package config_test
+
+import (
+	"one-api/common/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelFallbackGet(t *testing.T) {
+	settings := &config.ModelFallbackSettings{}
+	err := settings.SetRules(`[
+		{"model":"gpt-4o","fallbacks":["gpt-4o-mini"]},
+		{"model":"gpt-4o","groups":["vip"],"fallbacks":["claude-3-5-sonnet"],"on":["429","5xx"]}
+	]`)
+	assert.Nil(t, err)
+
+	fallback := settings.Get("gpt-4o", "vip")
+	assert.Equal(t, []string{"claude-3-5-sonnet"}, fallback.Fallbacks)
+	assert.True(t, fallback.Triggers(config.FallbackOnRateLimit))
+	assert.False(t, fallback.Triggers(config.FallbackOnContextLength))
+
+	fallback = settings.Get("gpt-4o", "default")
+	assert.Equal(t, []string{"gpt-4o-mini"}, fallback.Fallbacks)
+	assert.True(t, fallback.Triggers(config.FallbackOnContextLength))
+	assert.False(t, fallback.Triggers(""))
+
+	assert.Nil(t, settings.Get("gpt-4o-mini", "default"))
+}
+
+func TestParseModelFallbacksInvalid(t *testing.T) {
+	_, err := config.ParseModelFallbacks(`[{"model":"gpt-4o","fallbacks":["gpt-4o"]}]`)
+	assert.NotNil(t, err)
+
+	_, err = config.ParseModelFallbacks(`[{"model":"gpt-4o","fallbacks":["gpt-4o-mini"],"on":["400"]}]`)
+	assert.NotNil(t, err)
+
+	rules, err := config.ParseModelFallbacks("")
+	assert.Nil(t, err)
+	assert.Empty(t, rules)
+}
@@ -3,3 +3,6 @@ package config
 var PaymentUSDRate = 7.3
 var PaymentMinAmount = 1
 var RechargeDiscount = ""
+
+// 退款和拒付时扣回充值额度的策略：negative 全额扣回，余额可以为负；block 最多扣到零，不足时禁用用户
+var PaymentRefundQuotaPolicy = "negative"
@@ -0,0 +1,102 @@
+package limit
+
+import (
+	"context"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"sync"
+	"time"
+)
+
+const realtimeSessionsKey = "realtime-sessions:%d"
+
+// 进程异常退出时未释放的计数在过期后清除
+const realtimeSessionsExpiration = 24 * time.Hour
+
+var (
+	realtimeSessions   = make(map[int]int)
+	realtimeSessionsMu sync.Mutex
+
+	acquireRealtimeSessionScript = redis.NewScript(`
+		local key = KEYS[1]
+		local limit = tonumber(ARGV[1])
+		local expiration = tonumber(ARGV[2])
+
+		local current = tonumber(redis.call("GET", key) or "0")
+		if current >= limit then
+			return 0
+		end
+
+		redis.call("INCR", key)
+		redis.call("EXPIRE", key, expiration)
+		return 1
+	`)
+
+	releaseRealtimeSessionScript = redis.NewScript(`
+		local key = KEYS[1]
+		local current = tonumber(redis.call("GET", key) or "0")
+		if current <= 1 then
+			redis.call("DEL", key)
+			return 0
+		end
+
+		return redis.call("DECR", key)
+	`)
+)
+
+// AcquireRealtimeSession 占用令牌的一个 Realtime 会话名额，limit 为 0 时不限制
+// 成功时返回释放名额的函数，会话结束时必须调用
+func AcquireRealtimeSession(tokenId int, limit int) (func(), bool) {
+	if limit <= 0 {
+		return func() {}, true
+	}
+
+	if config.RedisEnabled {
+		return acquireRedisRealtimeSession(tokenId, limit)
+	}
+
+	realtimeSessionsMu.Lock()
+	defer realtimeSessionsMu.Unlock()
+
+	if realtimeSessions[tokenId] >= limit {
+		return nil, false
+	}
+	realtimeSessions[tokenId]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			realtimeSessionsMu.Lock()
+			defer realtimeSessionsMu.Unlock()
+
+			realtimeSessions[tokenId]--
+			if realtimeSessions[tokenId] <= 0 {
+				delete(realtimeSessions, tokenId)
+			}
+		})
+	}, true
+}
+
+func acquireRedisRealtimeSession(tokenId int, limit int) (func(), bool) {
+	key := fmt.Sprintf(realtimeSessionsKey, tokenId)
+	ok, err := acquireRealtimeSessionScript.Run(context.Background(), redis.GetRedisClient(), []string{key}, limit, int(realtimeSessionsExpiration.Seconds())).Int()
+	if err != nil {
+		// Redis 出错时不阻断会话
+		logger.SysError("acquire realtime session error: " + err.Error())
+		return func() {}, true
+	}
+	if ok == 0 {
+		return nil, false
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if err := releaseRealtimeSessionScript.Run(context.Background(), redis.GetRedisClient(), []string{key}).Err(); err != nil {
+				logger.SysError("release realtime session error: " + err.Error())
+			}
+		})
+	}, true
+}
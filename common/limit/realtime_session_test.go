@@ -0,0 +1,33 @@
+package limit_test
+
+import (
+	"one-api/common/limit"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireRealtimeSession(t *testing.T) {
+	release1, ok := limit.AcquireRealtimeSession(2001, 2)
+	assert.True(t, ok)
+	release2, ok := limit.AcquireRealtimeSession(2001, 2)
+	assert.True(t, ok)
+
+	_, ok = limit.AcquireRealtimeSession(2001, 2)
+	assert.False(t, ok)
+
+	// 重复释放只归还一个名额
+	release1()
+	release1()
+	release3, ok := limit.AcquireRealtimeSession(2001, 2)
+	assert.True(t, ok)
+	_, ok = limit.AcquireRealtimeSession(2001, 2)
+	assert.False(t, ok)
+
+	release2()
+	release3()
+
+	// 0 表示不限制
+	_, ok = limit.AcquireRealtimeSession(2001, 0)
+	assert.True(t, ok)
+}
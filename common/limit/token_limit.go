@@ -0,0 +1,101 @@
+package limit
+
+import (
+	"fmt"
+	"math"
+	"one-api/common/config"
+	"sync"
+	"time"
+)
+
+const (
+	tokenRequestsKey = "token-rpm:%d:%d"
+	tokenTokensKey   = "token-tpm:%d:%d"
+)
+
+var (
+	fixedWindowLimiters   = make(map[int]RateLimiter)
+	fixedWindowLimitersMu sync.Mutex
+)
+
+// getFixedWindowLimiter 按速率复用一分钟的固定窗口限流器
+func getFixedWindowLimiter(rate int) RateLimiter {
+	fixedWindowLimitersMu.Lock()
+	defer fixedWindowLimitersMu.Unlock()
+
+	if limiter, ok := fixedWindowLimiters[rate]; ok {
+		return limiter
+	}
+
+	var limiter RateLimiter
+	if config.RedisEnabled {
+		limiter = NewCountLimiter(rate, rate, window)
+	} else {
+		limiter = NewMemoryLimiter(rate, rate, window, false)
+	}
+	fixedWindowLimiters[rate] = limiter
+
+	return limiter
+}
+
+// TokenRateLimitStatus 令牌在当前分钟内的 RPM/TPM 使用情况，Limit 为 0 表示不限制
+type TokenRateLimitStatus struct {
+	LimitRequests     int
+	RemainingRequests int
+	LimitTokens       int
+	RemainingTokens   int
+	Reset             time.Duration
+}
+
+// 窗口按自然分钟对齐，便于计算重置时间
+func tokenLimitMinute(now time.Time) (int64, time.Duration) {
+	minute := now.Unix() / 60
+	return minute, time.Unix((minute+1)*60, 0).Sub(now)
+}
+
+// AllowTokenRequest 检查令牌的 RPM 和 TPM，TPM 按之前请求完成后的实际用量计算
+func AllowTokenRequest(tokenId int, rpm int, tpm int) (*TokenRateLimitStatus, bool) {
+	minute, reset := tokenLimitMinute(time.Now())
+	status := &TokenRateLimitStatus{
+		LimitRequests: rpm,
+		LimitTokens:   tpm,
+		Reset:         reset,
+	}
+
+	if tpm > 0 {
+		used, err := getFixedWindowLimiter(math.MaxInt32).GetCurrentRate(fmt.Sprintf(tokenTokensKey, tokenId, minute))
+		if err != nil {
+			used = 0
+		}
+		status.RemainingTokens = max(tpm-used, 0)
+		if status.RemainingTokens == 0 {
+			return status, false
+		}
+	}
+
+	if rpm > 0 {
+		key := fmt.Sprintf(tokenRequestsKey, tokenId, minute)
+		limiter := getFixedWindowLimiter(rpm)
+		if !limiter.Allow(key) {
+			return status, false
+		}
+		used, err := limiter.GetCurrentRate(key)
+		if err != nil {
+			used = 0
+		}
+		status.RemainingRequests = max(rpm-used, 0)
+	}
+
+	return status, true
+}
+
+// RecordTokenUsage 请求完成后累计令牌当前分钟的 token 用量
+func RecordTokenUsage(tokenId int, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+
+	minute, _ := tokenLimitMinute(time.Now())
+	// 只记录用量，是否超限在下一次请求时判断
+	getFixedWindowLimiter(math.MaxInt32).AllowN(fmt.Sprintf(tokenTokensKey, tokenId, minute), tokens)
+}
@@ -0,0 +1,38 @@
+package limit_test
+
+import (
+	"one-api/common/limit"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowTokenRequestRPM(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		status, ok := limit.AllowTokenRequest(1001, 3, 0)
+		assert.True(t, ok)
+		assert.Equal(t, 2-i, status.RemainingRequests)
+	}
+
+	status, ok := limit.AllowTokenRequest(1001, 3, 0)
+	assert.False(t, ok)
+	assert.Equal(t, 3, status.LimitRequests)
+	assert.Greater(t, status.Reset.Seconds(), 0.0)
+}
+
+func TestAllowTokenRequestTPM(t *testing.T) {
+	status, ok := limit.AllowTokenRequest(1002, 0, 100)
+	assert.True(t, ok)
+	assert.Equal(t, 100, status.RemainingTokens)
+
+	limit.RecordTokenUsage(1002, 60)
+	status, ok = limit.AllowTokenRequest(1002, 0, 100)
+	assert.True(t, ok)
+	assert.Equal(t, 40, status.RemainingTokens)
+
+	// 用量按实际值累计，允许超过限制
+	limit.RecordTokenUsage(1002, 60)
+	status, ok = limit.AllowTokenRequest(1002, 0, 100)
+	assert.False(t, ok)
+	assert.Equal(t, 0, status.RemainingTokens)
+}
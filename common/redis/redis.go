@@ -69,6 +69,12 @@ func RedisGet(key string) (string, error) {
 	return RDB.Get(ctx, key).Result()
 }
 
+// RedisMGet 批量读取，不存在的 key 对应位置为 nil
+func RedisMGet(keys ...string) ([]interface{}, error) {
+	ctx := context.Background()
+	return RDB.MGet(ctx, keys...).Result()
+}
+
 func RedisDel(key string) error {
 	ctx := context.Background()
 	return RDB.Del(ctx, key).Err()
@@ -106,3 +112,29 @@ func RedisSIsMember(key string, member interface{}) (bool, error) {
 	ctx := context.Background()
 	return RDB.SIsMember(ctx, key, member).Result()
 }
+
+// RedisDelByPattern 通过 SCAN 删除匹配的 key，返回删除的数量
+func RedisDelByPattern(pattern string) (int, error) {
+	ctx := context.Background()
+	var cursor uint64
+	deleted := 0
+	for {
+		keys, nextCursor, err := RDB.Scan(ctx, cursor, pattern, 500).Result()
+		if err != nil {
+			return deleted, err
+		}
+
+		if len(keys) > 0 {
+			n, err := RDB.Del(ctx, keys...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += int(n)
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
@@ -26,6 +26,8 @@ type HTTPRequester struct {
 	proxyAddr         string
 	Context           context.Context
 	IsOpenAI          bool
+	// 渠道和分组配置的改写规则，为 nil 时不处理
+	Transformer BodyTransformer
 }
 
 // NewHTTPRequester 创建一个新的 HTTPRequester 实例。
@@ -64,6 +66,13 @@ func (r *HTTPRequester) NewRequest(method, url string, setters ...requestOption)
 	for _, setter := range setters {
 		setter(args)
 	}
+	if r.Transformer != nil {
+		body, err := transformRequestBody(r.Transformer, args.body, args.header)
+		if err != nil {
+			return nil, err
+		}
+		args.body = body
+	}
 	req, err := utils.RequestBuilder(r.setProxy(), method, url, args.body, args.header)
 	if err != nil {
 		return nil, err
@@ -88,6 +97,8 @@ func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp
 		return nil, HandleErrorResp(resp, r.ErrorHandler, r.IsOpenAI)
 	}
 
+	r.transformResponse(resp)
+
 	// 解析响应
 	if response == nil {
 		return resp, nil
@@ -124,6 +135,8 @@ func (r *HTTPRequester) SendRequestRaw(req *http.Request) (*http.Response, *type
 		return nil, HandleErrorResp(resp, r.ErrorHandler, r.IsOpenAI)
 	}
 
+	r.transformResponse(resp)
+
 	return resp, nil
 }
 
@@ -0,0 +1,118 @@
+package requester
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// BodyTransformer 改写发往上游的请求和上游返回的响应
+type BodyTransformer interface {
+	TransformRequest(body []byte, header http.Header) []byte
+	TransformResponse(body []byte) []byte
+	HasResponseActions() bool
+}
+
+// transformRequestBody 表单等流式请求体只改写请求头
+func transformRequestBody(transformer BodyTransformer, body any, header http.Header) (any, error) {
+	switch v := body.(type) {
+	case nil, io.Reader:
+		transformer.TransformRequest(nil, header)
+		return body, nil
+	case []byte:
+		return transformer.TransformRequest(v, header), nil
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return transformer.TransformRequest(data, header), nil
+}
+
+type transformReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// transformResponse JSON 响应整体改写，SSE 响应按行改写
+func (r *HTTPRequester) transformResponse(resp *http.Response) {
+	if r.Transformer == nil || !r.Transformer.HasResponseActions() {
+		return
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	switch {
+	case strings.Contains(contentType, "text/event-stream"):
+		resp.Body = &transformReadCloser{
+			Reader: &sseTransformReader{reader: bufio.NewReader(resp.Body), transformer: r.Transformer},
+			Closer: resp.Body,
+		}
+	case strings.Contains(contentType, "json"):
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			// 读取失败时交给后续解析报错
+			resp.Body = &transformReadCloser{Reader: io.MultiReader(bytes.NewReader(body), errorReader{err}), Closer: resp.Body}
+			return
+		}
+		body = r.Transformer.TransformResponse(body)
+		resp.Body = &transformReadCloser{Reader: bytes.NewReader(body), Closer: resp.Body}
+		resp.ContentLength = int64(len(body))
+		resp.Header.Del("Content-Length")
+	}
+}
+
+type errorReader struct {
+	err error
+}
+
+func (e errorReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
+
+type sseTransformReader struct {
+	reader      *bufio.Reader
+	transformer BodyTransformer
+	pending     []byte
+	err         error
+}
+
+func (s *sseTransformReader) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+
+		line, err := s.reader.ReadBytes('\n')
+		s.err = err
+		s.pending = transformSSELine(line, s.transformer)
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// transformSSELine 只改写内容为 JSON 对象的 data 行，保留原有的换行符
+func transformSSELine(line []byte, transformer BodyTransformer) []byte {
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return line
+	}
+
+	content := bytes.TrimRight(line, "\r\n")
+	ending := line[len(content):]
+	payload := bytes.TrimSpace(content[len("data:"):])
+	if len(payload) == 0 || payload[0] != '{' {
+		return line
+	}
+
+	transformed := transformer.TransformResponse(payload)
+	result := make([]byte, 0, len(transformed)+len(ending)+6)
+	result = append(result, "data: "...)
+	result = append(result, transformed...)
+	return append(result, ending...)
+}
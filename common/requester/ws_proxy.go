@@ -2,6 +2,7 @@ package requester
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -19,6 +20,11 @@ type WSProxy struct {
 	done           chan struct{}
 	userClosed     chan struct{}
 	supplierClosed chan struct{}
+
+	// 同一连接不能并发写，Shutdown 可能与转发同时发生
+	userWriteMu     sync.Mutex
+	supplierWriteMu sync.Mutex
+	shutdownOnce    sync.Once
 }
 
 type MessageSource int
@@ -38,7 +44,7 @@ func NewWSProxy(userConn, supplierConn *websocket.Conn, timeout time.Duration, h
 		timeout:        timeout,
 		handler:        handler,
 		usageHandler:   usageHandler,
-		done:           make(chan struct{}),
+		done:           make(chan struct{}, 2),
 		userClosed:     make(chan struct{}),
 		supplierClosed: make(chan struct{}),
 	}
@@ -58,6 +64,33 @@ func (p *WSProxy) Close() {
 	p.supplierConn.Close()
 }
 
+// Shutdown 向用户发送错误事件和关闭帧后关闭两端连接，多次调用只生效一次
+func (p *WSProxy) Shutdown(code int, reason string, event []byte) {
+	p.shutdownOnce.Do(func() {
+		if event != nil {
+			p.writeMessage(p.userConn, websocket.TextMessage, event)
+		}
+
+		p.userWriteMu.Lock()
+		p.userConn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+		p.userWriteMu.Unlock()
+
+		p.Close()
+	})
+}
+
+func (p *WSProxy) writeMessage(conn *websocket.Conn, messageType int, message []byte) error {
+	mu := &p.supplierWriteMu
+	if conn == p.userConn {
+		mu = &p.userWriteMu
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	return conn.WriteMessage(messageType, message)
+}
+
 func (p *WSProxy) UserClosed() <-chan struct{} {
 	return p.userClosed
 }
@@ -85,7 +118,7 @@ func (p *WSProxy) transfer(src, dst *websocket.Conn, source MessageSource, close
 			shouldContinue, usage, newMessage, err := p.handler(source, messageType, message)
 			if err != nil {
 				errMsg := []byte(err.Error())
-				dst.WriteMessage(websocket.TextMessage, errMsg)
+				p.writeMessage(dst, websocket.TextMessage, errMsg)
 				logger.SysError(fmt.Sprintf("source: %d, handler error: %s", source, err.Error()))
 				return
 			}
@@ -101,16 +134,16 @@ func (p *WSProxy) transfer(src, dst *websocket.Conn, source MessageSource, close
 			if usage != nil && p.usageHandler != nil {
 				err := p.usageHandler(usage)
 				if err != nil {
-					dst.WriteMessage(websocket.TextMessage, message)
-					errMsg := []byte(err.Error())
-					dst.WriteMessage(websocket.TextMessage, errMsg)
+					// 产生用量的消息照常转发，随后告知用户原因并关闭会话
+					p.writeMessage(dst, messageType, message)
+					p.Shutdown(websocket.ClosePolicyViolation, "usage limit exceeded", []byte(err.Error()))
 					logger.SysError(fmt.Sprintf("source: %d, usageHandler error: %s", source, err.Error()))
 					return
 				}
 			}
 		}
 
-		err = dst.WriteMessage(messageType, message)
+		err = p.writeMessage(dst, messageType, message)
 		if err != nil {
 			logger.SysError(fmt.Sprintf("source: %d, WriteMessage error: %s", source, err.Error()))
 			return
@@ -177,6 +177,29 @@ func SendQuotaWarningCodeEmail(userName, email string, quota int, noMoreQuota bo
 	return stmp.Render(email, subject, content)
 }
 
+func SendTokenBudgetWarningEmail(userName, email, tokenName string, monthly bool, used, limit int) error {
+	stmp, err := GetSystemStmp()
+
+	if err != nil {
+		return err
+	}
+
+	contentTemp := `<p style="font-size: 30px">Hi <strong>%s,</strong></p>
+		<p>
+			您的令牌「%s」%s已使用额度 %d，预算为 %d，超出预算后该令牌的请求将被拒绝，直到预算重置。
+		</p>`
+
+	window := "今日"
+	if monthly {
+		window = "本月"
+	}
+
+	subject := fmt.Sprintf("您的令牌「%s」%s预算即将用尽", tokenName, window)
+	content := fmt.Sprintf(contentTemp, userName, tokenName, window, used, limit)
+
+	return stmp.Render(email, subject, content)
+}
+
 func DialAndSend(c *mail.Client, messages ...*mail.Msg) error {
 	ctx := context.Background()
 	if err := c.DialWithContext(ctx); err != nil {
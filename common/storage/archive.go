@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"fmt"
+	"one-api/common/storage/drives"
+
+	"github.com/spf13/viper"
+)
+
+// ArchiveDrive 可以按 key 读写和删除的存储，用于保存请求归档
+type ArchiveDrive interface {
+	Name() string
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+var archiveDrives = make(map[string]ArchiveDrive)
+
+func AddArchiveDrive(drive ArchiveDrive) {
+	if drive == nil {
+		return
+	}
+	archiveDrives[drive.Name()] = drive
+}
+
+func InitLocalStorage() {
+	path := viper.GetString("storage.local.path")
+	if path == "" {
+		return
+	}
+
+	AddArchiveDrive(drives.NewLocalStorage(path))
+}
+
+func getArchiveDrive(driveName string) (ArchiveDrive, error) {
+	drive, ok := archiveDrives[driveName]
+	if !ok {
+		return nil, fmt.Errorf("archive storage %s not configured", driveName)
+	}
+
+	return drive, nil
+}
+
+func PutArchive(driveName, key string, data []byte) error {
+	drive, err := getArchiveDrive(driveName)
+	if err != nil {
+		return err
+	}
+
+	return drive.Put(key, data)
+}
+
+func GetArchive(driveName, key string) ([]byte, error) {
+	drive, err := getArchiveDrive(driveName)
+	if err != nil {
+		return nil, err
+	}
+
+	return drive.Get(key)
+}
+
+func DeleteArchive(driveName, key string) error {
+	drive, err := getArchiveDrive(driveName)
+	if err != nil {
+		return err
+	}
+
+	return drive.Delete(key)
+}
@@ -3,6 +3,7 @@ package drives
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
 
@@ -26,17 +27,26 @@ func (a *AliOSSUpload) Name() string {
 	return "AliOSS"
 }
 
-func (a *AliOSSUpload) Upload(data []byte, fileName string) (string, error) {
+func (a *AliOSSUpload) getBucket() (*oss.Bucket, error) {
 	// Create OSS Client
 	client, err := oss.New(a.Endpoint, a.AccessKeyId, a.AccessKeySecret)
 	if err != nil {
-		return "", fmt.Errorf("creating OSS client: %w", err)
+		return nil, fmt.Errorf("creating OSS client: %w", err)
 	}
 
 	// Create Bucket
 	bucket, err := client.Bucket(a.BucketName)
 	if err != nil {
-		return "", fmt.Errorf("getting bucket: %w", err)
+		return nil, fmt.Errorf("getting bucket: %w", err)
+	}
+
+	return bucket, nil
+}
+
+func (a *AliOSSUpload) Upload(data []byte, fileName string) (string, error) {
+	bucket, err := a.getBucket()
+	if err != nil {
+		return "", err
 	}
 
 	// Upload File
@@ -54,3 +64,45 @@ func (a *AliOSSUpload) Upload(data []byte, fileName string) (string, error) {
 
 	return objectURL, nil
 }
+
+// Put 按指定的 key 保存对象
+func (a *AliOSSUpload) Put(key string, data []byte) error {
+	bucket, err := a.getBucket()
+	if err != nil {
+		return err
+	}
+
+	if err := bucket.PutObject(key, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("uploading file: %w", err)
+	}
+
+	return nil
+}
+
+func (a *AliOSSUpload) Get(key string) ([]byte, error) {
+	bucket, err := a.getBucket()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := bucket.GetObject(key)
+	if err != nil {
+		return nil, fmt.Errorf("getting file: %w", err)
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+func (a *AliOSSUpload) Delete(key string) error {
+	bucket, err := a.getBucket()
+	if err != nil {
+		return err
+	}
+
+	if err := bucket.DeleteObject(key); err != nil {
+		return fmt.Errorf("deleting file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package drives
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage 保存到本地磁盘，仅用于请求归档，不提供访问 URL
+type LocalStorage struct {
+	Dir string
+}
+
+func NewLocalStorage(dir string) *LocalStorage {
+	return &LocalStorage{
+		Dir: dir,
+	}
+}
+
+func (l *LocalStorage) Name() string {
+	return "Local"
+}
+
+// path 将 key 限制在存储目录内
+func (l *LocalStorage) path(key string) string {
+	return filepath.Join(l.Dir, filepath.Clean("/"+key))
+}
+
+func (l *LocalStorage) Put(key string, data []byte) error {
+	path := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+
+	return nil
+}
+
+func (l *LocalStorage) Get(key string) ([]byte, error) {
+	return os.ReadFile(l.path(key))
+}
+
+func (l *LocalStorage) Delete(key string) error {
+	err := os.Remove(l.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package drives
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -39,8 +40,7 @@ func (a *S3Upload) Name() string {
 	return "S3"
 }
 
-func (a *S3Upload) Upload(data []byte, s3Key string) (string, error) {
-
+func (a *S3Upload) newClient() (*s3.S3, error) {
 	// 创建 S3 会话
 	sess, err := session.NewSession(&aws.Config{
 		Credentials: credentials.NewStaticCredentials(
@@ -53,10 +53,17 @@ func (a *S3Upload) Upload(data []byte, s3Key string) (string, error) {
 		S3ForcePathStyle: aws.Bool(true),
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %v", err)
+		return nil, fmt.Errorf("failed to create session: %v", err)
 	}
 
-	svc := s3.New(sess)
+	return s3.New(sess), nil
+}
+
+func (a *S3Upload) Upload(data []byte, s3Key string) (string, error) {
+	svc, err := a.newClient()
+	if err != nil {
+		return "", err
+	}
 
 	// 获取当前日期作为文件名前缀
 	now := time.Now()
@@ -100,3 +107,57 @@ func (a *S3Upload) Upload(data []byte, s3Key string) (string, error) {
 
 	return fmt.Sprintf("%s/%s", a.CustomDomain, datedKey), nil
 }
+
+// Put 按指定的 key 保存对象，不添加日期前缀
+func (a *S3Upload) Put(key string, data []byte) error {
+	svc, err := a.newClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(a.BucketName),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put object to S3: %v", err)
+	}
+
+	return nil
+}
+
+func (a *S3Upload) Get(key string) ([]byte, error) {
+	svc, err := a.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(a.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object from S3: %v", err)
+	}
+	defer output.Body.Close()
+
+	return io.ReadAll(output.Body)
+}
+
+func (a *S3Upload) Delete(key string) error {
+	svc, err := a.newClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(a.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object from S3: %v", err)
+	}
+
+	return nil
+}
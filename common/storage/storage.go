@@ -15,6 +15,7 @@ func InitStorage() {
 	InitSMStorage()
 	InitALIOSSStorage()
 	InitS3Storage()
+	InitLocalStorage()
 }
 
 func InitALIOSSStorage() {
@@ -38,6 +39,7 @@ func InitALIOSSStorage() {
 
 	aliUpload := drives.NewAliOSSUpload(endpoint, accessKeyId, accessKeySecret, bucketName)
 	AddStorageDrive(aliUpload)
+	AddArchiveDrive(aliUpload)
 }
 
 func InitSMStorage() {
@@ -86,4 +88,5 @@ func InitS3Storage() {
 
 	s3Upload := drives.NewS3Upload(endpoint, accessKeyId, accessKeySecret, bucketName, cdnurl, expirationDays)
 	AddStorageDrive(s3Upload)
+	AddArchiveDrive(s3Upload)
 }
@@ -0,0 +1,151 @@
+package transform
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// updater 接收字段的当前值，返回新值，keep 为 false 时删除字段
+type updater func(value any, exists bool) (newValue any, keep bool)
+
+func splitPath(path string) []string {
+	return strings.Split(path, ".")
+}
+
+// update 按路径修改 JSON 节点并返回修改后的节点
+// create 为 false 时路径不存在直接返回，类型不符的路径同样忽略
+func update(node any, segs []string, create bool, fn updater) any {
+	seg := segs[0]
+
+	switch n := node.(type) {
+	case map[string]any:
+		value, exists := n[seg]
+		if len(segs) == 1 {
+			if !exists && !create {
+				return n
+			}
+			if newValue, keep := fn(value, exists); keep {
+				n[seg] = newValue
+			} else {
+				delete(n, seg)
+			}
+			return n
+		}
+
+		if !exists || value == nil {
+			if !create {
+				return n
+			}
+			value = make(map[string]any)
+		}
+		n[seg] = update(value, segs[1:], create, fn)
+		return n
+
+	case []any:
+		index, err := strconv.Atoi(seg)
+		if err != nil {
+			return n
+		}
+		if index < 0 {
+			index += len(n)
+		}
+		if index < 0 || index >= len(n) {
+			return n
+		}
+
+		if len(segs) == 1 {
+			newValue, keep := fn(n[index], true)
+			if !keep {
+				return append(n[:index:index], n[index+1:]...)
+			}
+			n[index] = newValue
+			return n
+		}
+
+		n[index] = update(n[index], segs[1:], create, fn)
+		return n
+	}
+
+	return node
+}
+
+func get(node any, segs []string) (any, bool) {
+	for _, seg := range segs {
+		switch n := node.(type) {
+		case map[string]any:
+			value, ok := n[seg]
+			if !ok {
+				return nil, false
+			}
+			node = value
+		case []any:
+			index, err := strconv.Atoi(seg)
+			if err != nil {
+				return nil, false
+			}
+			if index < 0 {
+				index += len(n)
+			}
+			if index < 0 || index >= len(n) {
+				return nil, false
+			}
+			node = n[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return node, true
+}
+
+// cloneValue 规则中的值在多个请求间共享，写入请求前复制一份
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case nil, string, bool, float64, json.Number:
+		return v
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var cloned any
+	if err := json.Unmarshal(data, &cloned); err != nil {
+		return value
+	}
+	return cloned
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// clampNumber 结果为整数时保持整数形式，避免 max_tokens 之类的字段变成小数
+func clampNumber(value any, min, max *float64) any {
+	number, ok := toFloat(value)
+	if !ok {
+		return value
+	}
+
+	clamped := number
+	if min != nil && clamped < *min {
+		clamped = *min
+	}
+	if max != nil && clamped > *max {
+		clamped = *max
+	}
+	if clamped == number {
+		return value
+	}
+
+	return json.Number(strconv.FormatFloat(clamped, 'f', -1, 64))
+}
@@ -0,0 +1,189 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Pipeline 一次请求匹配到的规则，按规则和动作的顺序执行
+// 改写失败或内容不是 JSON 对象时保持原样，不影响请求
+type Pipeline struct {
+	rules []Rule
+}
+
+// NewPipeline 依次从多组规则中筛选匹配的规则，没有匹配时返回 nil
+func NewPipeline(req *Request, ruleSets ...[]Rule) *Pipeline {
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	matched := make([]Rule, 0)
+	for _, rules := range ruleSets {
+		for _, rule := range rules {
+			if rule.Match.Matches(req) {
+				matched = append(matched, rule)
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil
+	}
+
+	return &Pipeline{rules: matched}
+}
+
+// RuleNames 匹配到的规则名称，未命名的规则按序号显示
+func (p *Pipeline) RuleNames() []string {
+	names := make([]string, 0, len(p.rules))
+	for i, rule := range p.rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func (p *Pipeline) hasActions(target string) bool {
+	for _, rule := range p.rules {
+		for i := range rule.Actions {
+			if rule.Actions[i].target() == target {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Pipeline) HasResponseActions() bool {
+	return p.hasActions(TargetResponse)
+}
+
+// TransformRequest 改写请求头和请求体，body 为空时只处理请求头
+func (p *Pipeline) TransformRequest(body []byte, header http.Header) []byte {
+	if header != nil {
+		for _, rule := range p.rules {
+			for i := range rule.Actions {
+				if rule.Actions[i].target() == TargetHeader {
+					applyHeaderAction(&rule.Actions[i], header)
+				}
+			}
+		}
+	}
+
+	if len(body) == 0 || !p.hasActions(TargetBody) {
+		return body
+	}
+
+	return p.transformBody(body, TargetBody)
+}
+
+func (p *Pipeline) TransformResponse(body []byte) []byte {
+	if len(body) == 0 || !p.HasResponseActions() {
+		return body
+	}
+
+	return p.transformBody(body, TargetResponse)
+}
+
+func (p *Pipeline) transformBody(body []byte, target string) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// 保留原始数字，避免大整数精度丢失
+	decoder.UseNumber()
+
+	var root map[string]any
+	if err := decoder.Decode(&root); err != nil || root == nil {
+		return body
+	}
+
+	for _, rule := range p.rules {
+		for i := range rule.Actions {
+			if rule.Actions[i].target() == target {
+				applyBodyAction(&rule.Actions[i], root)
+			}
+		}
+	}
+
+	data, err := json.Marshal(root)
+	if err != nil {
+		return body
+	}
+
+	return data
+}
+
+func applyBodyAction(action *Action, root map[string]any) {
+	segs := splitPath(action.Path)
+
+	switch action.Op {
+	case OpSet:
+		update(root, segs, true, func(any, bool) (any, bool) {
+			return cloneValue(action.Value), true
+		})
+	case OpDefault:
+		update(root, segs, true, func(value any, exists bool) (any, bool) {
+			if exists && value != nil {
+				return value, true
+			}
+			return cloneValue(action.Value), true
+		})
+	case OpDelete:
+		update(root, segs, false, func(any, bool) (any, bool) {
+			return nil, false
+		})
+	case OpRename:
+		value, ok := get(root, segs)
+		if !ok {
+			return
+		}
+		update(root, segs, false, func(any, bool) (any, bool) {
+			return nil, false
+		})
+		update(root, splitPath(action.To), true, func(any, bool) (any, bool) {
+			return value, true
+		})
+	case OpClamp:
+		update(root, segs, false, func(value any, _ bool) (any, bool) {
+			return clampNumber(value, action.Min, action.Max), true
+		})
+	case OpPrepend, OpAppend:
+		update(root, segs, true, func(value any, exists bool) (any, bool) {
+			items, ok := value.([]any)
+			if exists && value != nil && !ok {
+				return value, true
+			}
+
+			item := cloneValue(action.Value)
+			if action.Op == OpPrepend {
+				return append([]any{item}, items...), true
+			}
+			return append(items, item), true
+		})
+	}
+}
+
+func applyHeaderAction(action *Action, header http.Header) {
+	switch action.Op {
+	case OpSet:
+		header.Set(action.Path, fmt.Sprint(action.Value))
+	case OpDefault:
+		if header.Get(action.Path) == "" {
+			header.Set(action.Path, fmt.Sprint(action.Value))
+		}
+	case OpDelete:
+		header.Del(action.Path)
+	case OpRename:
+		values := header.Values(action.Path)
+		if len(values) == 0 {
+			return
+		}
+		header.Del(action.Path)
+		for _, value := range values {
+			header.Add(action.To, value)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package transform_test
+
+import (
+	"net/http"
+	"one-api/common/transform"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestPipelineTransformRequest(t *testing.T) {
+	rules := []transform.Rule{
+		{
+			Name:  "strip",
+			Match: transform.Match{Models: []string{"deepseek-*"}},
+			Actions: []transform.Action{
+				{Op: transform.OpDelete, Path: "reasoning_effort"},
+				{Op: transform.OpClamp, Path: "max_tokens", Max: float(8192)},
+				{Op: transform.OpRename, Path: "user", To: "metadata.user_id"},
+				{Target: transform.TargetHeader, Op: transform.OpSet, Path: "X-Vendor", Value: "ds"},
+			},
+		},
+		{
+			Name:  "system",
+			Match: transform.Match{Groups: []string{"vip"}, Paths: []string{"/v1/chat/*"}},
+			Actions: []transform.Action{
+				{Op: transform.OpPrepend, Path: "messages", Value: map[string]any{"role": "system", "content": "hi"}},
+			},
+		},
+		{
+			Name:    "other",
+			Match:   transform.Match{Models: []string{"gpt-*"}},
+			Actions: []transform.Action{{Op: transform.OpSet, Path: "seed", Value: 1}},
+		},
+	}
+	assert.Nil(t, transform.ValidateRules(rules))
+
+	pipeline := transform.NewPipeline(&transform.Request{
+		Model: "deepseek-chat",
+		Path:  "/v1/chat/completions",
+		Group: "vip",
+	}, rules)
+	assert.Equal(t, []string{"strip", "system"}, pipeline.RuleNames())
+
+	header := make(http.Header)
+	body := pipeline.TransformRequest([]byte(`{"model":"deepseek-chat","reasoning_effort":"high","max_tokens":100000,"user":"u1","messages":[{"role":"user","content":"q"}],"seed":12345678901234567}`), header)
+	assert.JSONEq(t, `{"model":"deepseek-chat","max_tokens":8192,"metadata":{"user_id":"u1"},"messages":[{"role":"system","content":"hi"},{"role":"user","content":"q"}],"seed":12345678901234567}`, string(body))
+	assert.Contains(t, string(body), `"seed":12345678901234567`)
+	assert.Equal(t, "ds", header.Get("X-Vendor"))
+
+	// 非 JSON 对象原样返回
+	assert.Equal(t, "[1]", string(pipeline.TransformRequest([]byte("[1]"), nil)))
+
+	assert.Nil(t, transform.NewPipeline(&transform.Request{Model: "claude-3"}, rules))
+}
+
+func TestPipelineTransformResponse(t *testing.T) {
+	pipeline := transform.NewPipeline(&transform.Request{}, []transform.Rule{{
+		Actions: []transform.Action{
+			{Target: transform.TargetResponse, Op: transform.OpDelete, Path: "choices.-1.delta.reasoning"},
+			{Target: transform.TargetResponse, Op: transform.OpDefault, Path: "system_fingerprint", Value: "fp"},
+		},
+	}})
+	assert.True(t, pipeline.HasResponseActions())
+
+	body := pipeline.TransformResponse([]byte(`{"choices":[{"delta":{"content":"a","reasoning":"r"}}],"system_fingerprint":null}`))
+	assert.JSONEq(t, `{"choices":[{"delta":{"content":"a"}}],"system_fingerprint":"fp"}`, string(body))
+}
+
+func TestValidateRules(t *testing.T) {
+	assert.NotNil(t, transform.ValidateRules([]transform.Rule{{Actions: []transform.Action{{Op: "replace", Path: "a"}}}}))
+	assert.NotNil(t, transform.ValidateRules([]transform.Rule{{Actions: []transform.Action{{Op: transform.OpRename, Path: "a"}}}}))
+	assert.NotNil(t, transform.ValidateRules([]transform.Rule{{Actions: []transform.Action{{Target: transform.TargetHeader, Op: transform.OpClamp, Path: "a", Max: float(1)}}}}))
+	assert.NotNil(t, transform.ValidateRules([]transform.Rule{{Name: "empty"}}))
+}
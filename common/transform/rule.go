@@ -0,0 +1,190 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// 动作作用的对象
+const (
+	TargetBody     = "body"     // 请求体，默认值
+	TargetHeader   = "header"   // 请求头
+	TargetResponse = "response" // 响应体，流式响应按每条 data 处理
+)
+
+const (
+	OpSet     = "set"     // 设置字段，中间层级不存在时自动创建
+	OpDefault = "default" // 字段不存在或为 null 时设置
+	OpDelete  = "delete"  // 删除字段
+	OpRename  = "rename"  // 移动字段到 to
+	OpClamp   = "clamp"   // 数值限制在 min 和 max 之间
+	OpPrepend = "prepend" // 在数组开头插入 value，数组不存在时创建
+	OpAppend  = "append"  // 在数组末尾追加 value，数组不存在时创建
+)
+
+// Rule 一条改写规则，匹配条件全部满足时按顺序执行动作
+type Rule struct {
+	Name    string   `json:"name"`
+	Match   Match    `json:"match"`
+	Actions []Action `json:"actions"`
+}
+
+// Match 匹配条件，未设置的条件视为匹配
+// 模型、路径和请求头的值支持 * 通配，模型匹配的是用户请求的模型名
+type Match struct {
+	Models  []string          `json:"models,omitempty"`
+	Paths   []string          `json:"paths,omitempty"`
+	Groups  []string          `json:"groups,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"` // 值为空时只要求请求头存在
+}
+
+// Action 改写动作，path 为点分隔的 JSON 路径，数组下标可以为负数，请求头动作的 path 为请求头名称
+type Action struct {
+	Target string   `json:"target,omitempty"`
+	Op     string   `json:"op"`
+	Path   string   `json:"path"`
+	To     string   `json:"to,omitempty"`
+	Value  any      `json:"value,omitempty"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+}
+
+// Request 用于匹配规则的请求信息
+type Request struct {
+	Model  string
+	Path   string
+	Group  string
+	Header http.Header
+}
+
+var (
+	bodyOps   = []string{OpSet, OpDefault, OpDelete, OpRename, OpClamp, OpPrepend, OpAppend}
+	headerOps = []string{OpSet, OpDefault, OpDelete, OpRename}
+)
+
+func (a *Action) target() string {
+	if a.Target == "" {
+		return TargetBody
+	}
+	return a.Target
+}
+
+func (a *Action) Validate() error {
+	if a.Path == "" {
+		return errors.New("path is required")
+	}
+
+	switch a.target() {
+	case TargetBody, TargetResponse:
+		if !slices.Contains(bodyOps, a.Op) {
+			return fmt.Errorf("unsupported op %q", a.Op)
+		}
+	case TargetHeader:
+		if !slices.Contains(headerOps, a.Op) {
+			return fmt.Errorf("unsupported header op %q", a.Op)
+		}
+	default:
+		return fmt.Errorf("unsupported target %q", a.Target)
+	}
+
+	if a.Op == OpRename && a.To == "" {
+		return errors.New("rename requires to")
+	}
+	if a.Op == OpClamp && a.Min == nil && a.Max == nil {
+		return errors.New("clamp requires min or max")
+	}
+
+	return nil
+}
+
+func (r *Rule) Validate() error {
+	if len(r.Actions) == 0 {
+		return errors.New("actions is required")
+	}
+
+	for i := range r.Actions {
+		if err := r.Actions[i].Validate(); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateRules 保存渠道或分组前校验规则
+func ValidateRules(rules []Rule) error {
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			name := rules[i].Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return fmt.Errorf("transform rule %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *Match) Matches(req *Request) bool {
+	if len(m.Models) > 0 && !matchAny(m.Models, req.Model) {
+		return false
+	}
+	if len(m.Paths) > 0 && !matchAny(m.Paths, req.Path) {
+		return false
+	}
+	if len(m.Groups) > 0 && !slices.Contains(m.Groups, req.Group) {
+		return false
+	}
+
+	for name, pattern := range m.Headers {
+		values := req.Header.Values(name)
+		if len(values) == 0 {
+			return false
+		}
+		if pattern != "" && !matchAny([]string{pattern}, strings.Join(values, ",")) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if wildcardMatch(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch 只支持 *，可以匹配任意字符（包括 /）
+func wildcardMatch(pattern, value string) bool {
+	p, v := 0, 0
+	star, match := -1, 0
+	for v < len(value) {
+		if p < len(pattern) && pattern[p] == '*' {
+			star, match = p, v
+			p++
+		} else if p < len(pattern) && pattern[p] == value[v] {
+			p++
+			v++
+		} else if star >= 0 {
+			p = star + 1
+			match++
+			v = match
+		} else {
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
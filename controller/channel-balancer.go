@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChannelAdaptiveWeights 查看自适应负载均衡下各渠道的有效权重
+func GetChannelAdaptiveWeights(c *gin.Context) {
+	group := c.Query("group")
+	modelName := c.Query("model")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.ChannelGroup.GetAdaptiveWeights(group, modelName),
+	})
+}
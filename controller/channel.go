@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"one-api/common"
+	"one-api/common/transform"
 	"one-api/common/utils"
 	"one-api/model"
 	"strconv"
@@ -24,6 +25,11 @@ func GetChannelsList(c *gin.Context) {
 		common.APIRespondWithError(c, http.StatusOK, err)
 		return
 	}
+
+	for _, channel := range *channels.Data {
+		channel.CircuitBreakers = model.ChannelGroup.GetCircuitBreakerStatus(channel.Id)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -58,6 +64,9 @@ func GetChannel(c *gin.Context) {
 func AddChannel(c *gin.Context) {
 	channel := model.Channel{}
 	err := c.ShouldBindJSON(&channel)
+	if err == nil {
+		err = transform.ValidateRules(channel.GetTransformRules())
+	}
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -157,6 +166,9 @@ func DeleteDisabledChannel(c *gin.Context) {
 func UpdateChannel(c *gin.Context) {
 	channel := model.Channel{}
 	err := c.ShouldBindJSON(&channel)
+	if err == nil {
+		err = transform.ValidateRules(channel.GetTransformRules())
+	}
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
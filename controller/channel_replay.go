@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/controller/check_channel"
+	"one-api/model"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type channelReplayRequest struct {
+	ChannelId int    `json:"channel_id" form:"channel_id"`
+	Model     string `json:"model" form:"model"`
+	LogIds    []int  `json:"log_ids" form:"-"`
+}
+
+// CreateChannelReplay 将日志关联的请求归档或上传的 JSONL 文件重放到指定渠道，任务在后台执行
+func CreateChannelReplay(c *gin.Context) {
+	var params channelReplayRequest
+	var cases []*check_channel.ReplayCase
+	var source string
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		if err := c.ShouldBind(&params); err != nil {
+			common.APIRespondWithError(c, http.StatusOK, err)
+			return
+		}
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			common.APIRespondWithError(c, http.StatusOK, errors.New("请上传 JSONL 文件"))
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			common.APIRespondWithError(c, http.StatusOK, err)
+			return
+		}
+		defer file.Close()
+
+		cases, err = check_channel.ParseReplayCasesFromJSONL(file)
+		if err != nil {
+			common.APIRespondWithError(c, http.StatusOK, err)
+			return
+		}
+		source = model.ChannelReplaySourceFile
+	} else {
+		if err := c.ShouldBindJSON(&params); err != nil {
+			common.APIRespondWithError(c, http.StatusOK, err)
+			return
+		}
+
+		var err error
+		cases, err = check_channel.LoadReplayCasesFromLogs(params.LogIds)
+		if err != nil {
+			common.APIRespondWithError(c, http.StatusOK, err)
+			return
+		}
+		source = model.ChannelReplaySourceLogs
+	}
+
+	replayer, err := check_channel.CreateChannelReplayer(params.ChannelId, strings.TrimSpace(params.Model), source, cases)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	// 后台任务会修改 Replay，响应使用启动前的副本
+	replay := *replayer.Replay
+	common.SafeGoroutine(replayer.Run)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    replay,
+	})
+}
+
+func GetChannelReplayList(c *gin.Context) {
+	var params model.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	replays, err := model.GetChannelReplayList(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    replays,
+	})
+}
+
+func GetChannelReplay(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	replay, err := model.GetChannelReplayById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    replay,
+	})
+}
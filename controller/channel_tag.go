@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"one-api/common"
 	"one-api/common/config"
+	"one-api/common/transform"
 	"one-api/model"
 
 	"github.com/gin-gonic/gin"
@@ -68,6 +69,9 @@ func UpdateChannelsTag(c *gin.Context) {
 	}
 	channel := model.Channel{}
 	err := c.ShouldBindJSON(&channel)
+	if err == nil {
+		err = transform.ValidateRules(channel.GetTransformRules())
+	}
 	if err != nil {
 		common.APIRespondWithError(c, http.StatusOK, err)
 		return
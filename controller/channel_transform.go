@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"one-api/common"
+	"one-api/common/transform"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type channelTransformPreviewRequest struct {
+	ChannelId int               `json:"channel_id"`
+	Group     string            `json:"group"`
+	Model     string            `json:"model" binding:"required"`
+	Path      string            `json:"path"`
+	Headers   map[string]string `json:"headers"`
+	Body      json.RawMessage   `json:"body"`
+	Response  json.RawMessage   `json:"response"`
+	// 预览尚未保存的渠道规则，为空时使用渠道已保存的规则
+	Rules *[]transform.Rule `json:"rules"`
+}
+
+type channelTransformPreviewResponse struct {
+	Rules    []string          `json:"rules"`
+	Headers  map[string]string `json:"headers"`
+	Body     json.RawMessage   `json:"body,omitempty"`
+	Response json.RawMessage   `json:"response,omitempty"`
+}
+
+// PreviewChannelTransform 不发送请求，返回分组和渠道规则改写后的请求体、请求头和响应体
+func PreviewChannelTransform(c *gin.Context) {
+	var params channelTransformPreviewRequest
+	if err := c.ShouldBindJSON(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	var channelRules []transform.Rule
+	if params.Rules != nil {
+		channelRules = *params.Rules
+	} else if params.ChannelId > 0 {
+		channel, err := model.GetChannelById(params.ChannelId)
+		if err != nil {
+			common.APIRespondWithError(c, http.StatusOK, err)
+			return
+		}
+		channelRules = channel.GetTransformRules()
+	}
+
+	if err := transform.ValidateRules(channelRules); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if params.Path == "" {
+		params.Path = "/v1/chat/completions"
+	}
+
+	header := make(http.Header)
+	for key, value := range params.Headers {
+		header.Set(key, value)
+	}
+
+	result := &channelTransformPreviewResponse{
+		Rules:    make([]string, 0),
+		Headers:  make(map[string]string),
+		Body:     params.Body,
+		Response: params.Response,
+	}
+
+	pipeline := transform.NewPipeline(&transform.Request{
+		Model:  params.Model,
+		Path:   params.Path,
+		Group:  params.Group,
+		Header: header.Clone(),
+	}, model.GlobalUserGroupRatio.GetTransformRules(params.Group), channelRules)
+
+	if pipeline != nil {
+		result.Rules = pipeline.RuleNames()
+		result.Body = pipeline.TransformRequest(params.Body, header)
+		result.Response = pipeline.TransformResponse(params.Response)
+	}
+
+	for key := range header {
+		result.Headers[key] = header.Get(key)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    result,
+	})
+}
@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/relay/relay_util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PurgeChatCache 清除对话缓存，可按分组和模型过滤
+func PurgeChatCache(c *gin.Context) {
+	group := c.Query("group")
+	modelName := c.Query("model")
+
+	deleted, err := relay_util.PurgeChatCache(group, modelName)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    deleted,
+	})
+}
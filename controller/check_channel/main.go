@@ -60,6 +60,20 @@ func CreateCheckChannel(channelId int, models string) (*CheckChannel, error) {
 		return nil, err
 	}
 
+	chatInterface, err := getChatInterface(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CheckChannel{
+		Models:        modelsList,
+		Channel:       channel,
+		ChatInterface: chatInterface,
+	}, nil
+}
+
+// getChatInterface 创建渠道的对话接口，每次调用都使用新的用量
+func getChatInterface(channel *model.Channel) (providers_base.ChatInterface, error) {
 	req, err := http.NewRequest("POST", "/v1/chat/completions", nil)
 	if err != nil {
 		return nil, err
@@ -86,11 +100,7 @@ func CreateCheckChannel(channelId int, models string) (*CheckChannel, error) {
 		return nil, errors.New("channel not implemented")
 	}
 
-	return &CheckChannel{
-		Models:        modelsList,
-		Channel:       channel,
-		ChatInterface: chatInterface,
-	}, nil
+	return chatInterface, nil
 }
 
 func (c *CheckChannel) Run() ([]*ModelResult, error) {
@@ -0,0 +1,366 @@
+package check_channel
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/types"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 单次重放的最大请求数
+const MaxReplayCases = 500
+
+// 计算相似度时最多比较的字符数
+const replayDiffMaxRunes = 4000
+
+// ReplayCase 一个待重放的请求，Original 为空表示没有可比较的原始响应
+type ReplayCase struct {
+	LogId     int
+	ArchiveId string
+	Request   *types.ChatCompletionRequest
+	Original  *ReplayOutput
+}
+
+// ReplayOutput 原始或重放的响应中用于比较的部分
+type ReplayOutput struct {
+	StatusCode       int
+	Latency          int
+	PromptTokens     int
+	CompletionTokens int
+	Content          string
+	FinishReason     string
+	ToolCalls        []string
+}
+
+type replayArchivePayload struct {
+	Path       string `json:"path"`
+	StatusCode int    `json:"status_code"`
+	Request    string `json:"request"`
+	Response   string `json:"response"`
+}
+
+// LoadReplayCasesFromLogs 从日志关联的请求归档中读取请求，只支持对话接口
+func LoadReplayCasesFromLogs(logIds []int) ([]*ReplayCase, error) {
+	if len(logIds) == 0 {
+		return nil, errors.New("log_ids is empty")
+	}
+	if len(logIds) > MaxReplayCases {
+		return nil, fmt.Errorf("最多重放 %d 个请求", MaxReplayCases)
+	}
+
+	logs, err := model.GetLogsByIds(logIds)
+	if err != nil {
+		return nil, err
+	}
+
+	cases := make([]*ReplayCase, 0, len(logs))
+	for _, log := range logs {
+		archiveId, _ := log.Metadata.Data()["archive_id"].(string)
+		if archiveId == "" {
+			return nil, fmt.Errorf("日志 #%d 没有请求归档", log.Id)
+		}
+
+		replayCase, err := loadReplayCaseFromArchive(archiveId)
+		if err != nil {
+			return nil, fmt.Errorf("日志 #%d: %w", log.Id, err)
+		}
+
+		replayCase.LogId = log.Id
+		replayCase.Original.Latency = log.RequestTime
+		replayCase.Original.PromptTokens = log.PromptTokens
+		replayCase.Original.CompletionTokens = log.CompletionTokens
+		cases = append(cases, replayCase)
+	}
+
+	return cases, nil
+}
+
+func loadReplayCaseFromArchive(archiveId string) (*ReplayCase, error) {
+	archive, err := model.GetPayloadArchiveById(archiveId)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := archive.GetContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var payload replayArchivePayload
+	if err := json.Unmarshal(content, &payload); err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(payload.Path, "/chat/completions") {
+		return nil, fmt.Errorf("不支持重放 %s 请求", payload.Path)
+	}
+
+	var request types.ChatCompletionRequest
+	if err := json.Unmarshal([]byte(payload.Request), &request); err != nil {
+		return nil, fmt.Errorf("请求内容不完整: %w", err)
+	}
+
+	original := parseReplayResponse(payload.Response)
+	original.StatusCode = payload.StatusCode
+
+	return &ReplayCase{
+		ArchiveId: archiveId,
+		Request:   &request,
+		Original:  original,
+	}, nil
+}
+
+// ParseReplayCasesFromJSONL 每行为一个对话请求，或 {"request": {...}, "response": {...}} 格式的请求和原始响应
+func ParseReplayCasesFromJSONL(reader io.Reader) ([]*ReplayCase, error) {
+	cases := make([]*ReplayCase, 0)
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		if len(cases) >= MaxReplayCases {
+			return nil, fmt.Errorf("最多重放 %d 个请求", MaxReplayCases)
+		}
+
+		var item struct {
+			Request  *types.ChatCompletionRequest  `json:"request"`
+			Response *types.ChatCompletionResponse `json:"response"`
+		}
+		if err := json.Unmarshal([]byte(text), &item); err != nil {
+			return nil, fmt.Errorf("第 %d 行格式错误: %w", line, err)
+		}
+
+		replayCase := &ReplayCase{Request: item.Request}
+		if item.Request == nil {
+			// 整行就是请求
+			replayCase.Request = &types.ChatCompletionRequest{}
+			if err := json.Unmarshal([]byte(text), replayCase.Request); err != nil {
+				return nil, fmt.Errorf("第 %d 行格式错误: %w", line, err)
+			}
+		}
+		if len(replayCase.Request.Messages) == 0 {
+			return nil, fmt.Errorf("第 %d 行缺少 messages", line)
+		}
+
+		if item.Response != nil {
+			replayCase.Original = getResponseOutput(item.Response)
+			replayCase.Original.StatusCode = http.StatusOK
+		}
+
+		cases = append(cases, replayCase)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("文件中没有请求")
+	}
+
+	return cases, nil
+}
+
+// parseReplayResponse 解析归档的响应，支持普通 JSON 和流式 SSE
+func parseReplayResponse(response string) *ReplayOutput {
+	var chatResponse types.ChatCompletionResponse
+	if err := json.Unmarshal([]byte(response), &chatResponse); err == nil {
+		return getResponseOutput(&chatResponse)
+	}
+
+	output := &ReplayOutput{}
+	var content strings.Builder
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var chunk types.ChatCompletionStreamResponse
+		if json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &chunk) != nil {
+			continue
+		}
+
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+			if reason, ok := choice.FinishReason.(string); ok && reason != "" {
+				output.FinishReason = reason
+			}
+			for _, toolCall := range choice.Delta.ToolCalls {
+				if toolCall.Function != nil && toolCall.Function.Name != "" {
+					output.ToolCalls = append(output.ToolCalls, toolCall.Function.Name)
+				}
+			}
+		}
+	}
+	output.Content = content.String()
+
+	return output
+}
+
+func getResponseOutput(response *types.ChatCompletionResponse) *ReplayOutput {
+	output := &ReplayOutput{
+		Content: response.GetContent(),
+	}
+
+	for _, choice := range response.Choices {
+		if choice.FinishReason != "" {
+			output.FinishReason = choice.FinishReason
+		}
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function != nil {
+				output.ToolCalls = append(output.ToolCalls, toolCall.Function.Name)
+			}
+		}
+	}
+
+	if response.Usage != nil {
+		output.PromptTokens = response.Usage.PromptTokens
+		output.CompletionTokens = response.Usage.CompletionTokens
+	}
+
+	return output
+}
+
+// ChannelReplayer 依次将请求重放到渠道，并保存结果
+type ChannelReplayer struct {
+	Replay  *model.ChannelReplay
+	Channel *model.Channel
+	Cases   []*ReplayCase
+}
+
+func CreateChannelReplayer(channelId int, modelName string, source string, cases []*ReplayCase) (*ChannelReplayer, error) {
+	channel, err := model.GetChannelById(channelId)
+	if err != nil {
+		return nil, err
+	}
+
+	// 提前检查渠道是否支持对话
+	if _, err := getChatInterface(channel); err != nil {
+		return nil, err
+	}
+
+	replay := &model.ChannelReplay{
+		ChannelId: channelId,
+		ModelName: modelName,
+		Source:    source,
+		Total:     len(cases),
+	}
+	if err := model.CreateChannelReplay(replay); err != nil {
+		return nil, err
+	}
+
+	return &ChannelReplayer{
+		Replay:  replay,
+		Channel: channel,
+		Cases:   cases,
+	}, nil
+}
+
+func (r *ChannelReplayer) Run() {
+	// 重放过程中 panic 时标记为失败，避免任务一直处于运行中
+	defer func() {
+		if err := recover(); err != nil {
+			r.finish(model.ChannelReplayStatusFailed, fmt.Sprintf("replay panic: %v", err))
+			panic(err)
+		}
+	}()
+
+	for i, replayCase := range r.Cases {
+		result := r.replayCase(replayCase)
+		result.ReplayId = r.Replay.Id
+		result.Seq = i + 1
+		if err := model.InsertChannelReplayResult(result); err != nil {
+			r.finish(model.ChannelReplayStatusFailed, err.Error())
+			return
+		}
+
+		r.Replay.Finished++
+		if result.Error == "" {
+			r.Replay.Succeeded++
+		}
+		if err := r.Replay.UpdateProgress(); err != nil {
+			logger.SysError(fmt.Sprintf("failed to update channel replay #%d: %s", r.Replay.Id, err.Error()))
+		}
+	}
+
+	r.finish(model.ChannelReplayStatusCompleted, "")
+}
+
+func (r *ChannelReplayer) finish(status int, message string) {
+	if err := r.Replay.Finish(status, message); err != nil {
+		logger.SysError(fmt.Sprintf("failed to finish channel replay #%d: %s", r.Replay.Id, err.Error()))
+	}
+}
+
+func (r *ChannelReplayer) replayCase(replayCase *ReplayCase) *model.ChannelReplayResult {
+	request := *replayCase.Request
+	if r.Replay.ModelName != "" {
+		request.Model = r.Replay.ModelName
+	}
+	// 重放统一使用非流式请求
+	request.Stream = false
+	request.StreamOptions = nil
+
+	result := &model.ChannelReplayResult{
+		LogId:     replayCase.LogId,
+		ArchiveId: replayCase.ArchiveId,
+		ModelName: request.Model,
+	}
+	original := replayCase.Original
+	if original != nil {
+		result.OriginalStatusCode = original.StatusCode
+		result.OriginalLatency = original.Latency
+		result.OriginalPromptTokens = original.PromptTokens
+		result.OriginalCompletionTokens = original.CompletionTokens
+	}
+
+	chatInterface, err := getChatInterface(r.Channel)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if modelName, err := chatInterface.ModelMappingHandler(request.Model); err == nil {
+		request.Model = strings.TrimPrefix(modelName, "+")
+	}
+
+	startTime := time.Now()
+	response, errWithCode := chatInterface.CreateChatCompletion(&request)
+	result.Latency = int(time.Since(startTime).Milliseconds())
+
+	output := &ReplayOutput{StatusCode: http.StatusOK}
+	if errWithCode != nil {
+		output.StatusCode = errWithCode.StatusCode
+		result.Error = errWithCode.Message
+	} else {
+		output = getResponseOutput(response)
+		output.StatusCode = http.StatusOK
+		if output.PromptTokens == 0 && output.CompletionTokens == 0 {
+			usage := chatInterface.GetUsage()
+			output.PromptTokens = usage.PromptTokens
+			output.CompletionTokens = usage.CompletionTokens
+		}
+	}
+
+	result.StatusCode = output.StatusCode
+	result.PromptTokens = output.PromptTokens
+	result.CompletionTokens = output.CompletionTokens
+	result.Diff = datatypes.NewJSONType(DiffReplayOutput(original, output))
+
+	return result
+}
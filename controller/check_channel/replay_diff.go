@@ -0,0 +1,92 @@
+package check_channel
+
+import (
+	"one-api/model"
+	"sort"
+	"strings"
+)
+
+// DiffReplayOutput 比较重放响应与原始响应
+func DiffReplayOutput(original, output *ReplayOutput) model.ChannelReplayDiff {
+	diff := model.ChannelReplayDiff{
+		Content:      output.Content,
+		FinishReason: output.FinishReason,
+		ToolCalls:    output.ToolCalls,
+	}
+	if original == nil {
+		return diff
+	}
+
+	diff.HasOriginal = true
+	diff.OriginalContent = original.Content
+	diff.OriginalFinishReason = original.FinishReason
+	diff.OriginalToolCalls = original.ToolCalls
+
+	diff.StatusEqual = original.StatusCode == output.StatusCode
+	diff.ContentEqual = strings.TrimSpace(original.Content) == strings.TrimSpace(output.Content)
+	diff.Similarity = textSimilarity(original.Content, output.Content)
+	diff.FinishReasonEqual = original.FinishReason == output.FinishReason
+	diff.ToolCallsEqual = sameToolCalls(original.ToolCalls, output.ToolCalls)
+
+	return diff
+}
+
+// sameToolCalls 只比较调用的工具名称，不比较参数和顺序
+func sameToolCalls(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// textSimilarity 按字符编辑距离计算相似度，过长的文本只比较开头部分
+func textSimilarity(a, b string) float64 {
+	ra := []rune(strings.TrimSpace(a))
+	rb := []rune(strings.TrimSpace(b))
+	if len(ra) > replayDiffMaxRunes {
+		ra = ra[:replayDiffMaxRunes]
+	}
+	if len(rb) > replayDiffMaxRunes {
+		rb = rb[:replayDiffMaxRunes]
+	}
+
+	maxLen := max(len(ra), len(rb))
+	if maxLen == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(maxLen)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,82 @@
+package check_channel_test
+
+import (
+	"strings"
+	"testing"
+
+	"one-api/controller/check_channel"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffReplayOutputWithoutOriginal(t *testing.T) {
+	output := &check_channel.ReplayOutput{StatusCode: 200, Content: "hello", FinishReason: "stop"}
+	diff := check_channel.DiffReplayOutput(nil, output)
+
+	assert.False(t, diff.HasOriginal)
+	assert.Equal(t, "hello", diff.Content)
+	assert.Equal(t, "stop", diff.FinishReason)
+	assert.Zero(t, diff.Similarity)
+}
+
+func TestDiffReplayOutput(t *testing.T) {
+	original := &check_channel.ReplayOutput{
+		StatusCode:   200,
+		Content:      "hello world",
+		FinishReason: "stop",
+		ToolCalls:    []string{"search", "weather"},
+	}
+	output := &check_channel.ReplayOutput{
+		StatusCode:   200,
+		Content:      " hello world\n",
+		FinishReason: "stop",
+		ToolCalls:    []string{"weather", "search"},
+	}
+
+	diff := check_channel.DiffReplayOutput(original, output)
+	assert.True(t, diff.HasOriginal)
+	assert.True(t, diff.StatusEqual)
+	assert.True(t, diff.ContentEqual)
+	assert.Equal(t, 1.0, diff.Similarity)
+	assert.True(t, diff.FinishReasonEqual)
+	assert.True(t, diff.ToolCallsEqual)
+	// 比较工具调用时不能改变原有顺序
+	assert.Equal(t, []string{"weather", "search"}, diff.ToolCalls)
+
+	output = &check_channel.ReplayOutput{
+		StatusCode:   500,
+		Content:      "hello word",
+		FinishReason: "length",
+		ToolCalls:    []string{"search"},
+	}
+	diff = check_channel.DiffReplayOutput(original, output)
+	assert.False(t, diff.StatusEqual)
+	assert.False(t, diff.ContentEqual)
+	assert.InDelta(t, 1-1.0/11, diff.Similarity, 1e-9)
+	assert.False(t, diff.FinishReasonEqual)
+	assert.False(t, diff.ToolCallsEqual)
+}
+
+func TestDiffReplayOutputSimilarity(t *testing.T) {
+	tests := []struct {
+		original string
+		output   string
+		expected float64
+	}{
+		{"", "", 1},
+		{"abc", "", 0},
+		{"kitten", "sitting", 1 - 3.0/7},
+		{"你好世界", "你好", 0.5},
+		{"abc", "xyz", 0},
+	}
+
+	for _, tt := range tests {
+		diff := check_channel.DiffReplayOutput(&check_channel.ReplayOutput{Content: tt.original}, &check_channel.ReplayOutput{Content: tt.output})
+		assert.InDelta(t, tt.expected, diff.Similarity, 1e-9, "%q vs %q", tt.original, tt.output)
+	}
+
+	// 过长的文本只比较开头部分
+	long := strings.Repeat("a", 5000)
+	diff := check_channel.DiffReplayOutput(&check_channel.ReplayOutput{Content: long}, &check_channel.ReplayOutput{Content: long + "b"})
+	assert.Equal(t, 1.0, diff.Similarity)
+}
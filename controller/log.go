@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"encoding/json"
 	"net/http"
 	"one-api/common"
 	"one-api/model"
@@ -112,3 +113,33 @@ func DeleteHistoryLogs(c *gin.Context) {
 		"data":    count,
 	})
 }
+
+// GetLogArchive 获取日志关联的请求归档内容
+func GetLogArchive(c *gin.Context) {
+	archive, err := model.GetPayloadArchiveById(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	content, err := archive.GetContent()
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(content, &payload); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"archive": archive,
+			"payload": payload,
+		},
+	})
+}
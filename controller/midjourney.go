@@ -11,6 +11,7 @@ import (
 	"io"
 	"net/http"
 	"one-api/common"
+	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/common/requester"
 	"one-api/model"
@@ -175,11 +176,12 @@ func MjTaskHandler(midjourneyChannel *model.Channel, taskIds []string, taskM map
 	for _, responseItem := range responseItems {
 		task := taskM[responseItem.MjId]
 
-		useTime := (time.Now().UnixNano() / int64(time.Millisecond)) - task.SubmitTime
-		// 如果时间超过一小时，且进度不是100%，则认为任务失败
-		if useTime > 3600000 && task.Progress != "100%" {
-			responseItem.FailReason = "上游任务超时（超过1小时）"
+		// 提交时间为毫秒，超时且进度不是100%，则认为任务失败
+		refundReason := model.TaskRefundReasonFailure
+		if model.IsTaskTimeout(task.SubmitTime/1000) && task.Progress != "100%" {
+			responseItem.FailReason = fmt.Sprintf("上游任务超时（超过%d分钟）", config.TaskTimeoutMinutes)
 			responseItem.Status = "FAILURE"
+			refundReason = model.TaskRefundReasonTimeout
 		}
 
 		if !checkMjTaskNeedUpdate(task, responseItem) {
@@ -207,19 +209,8 @@ func MjTaskHandler(midjourneyChannel *model.Channel, taskIds []string, taskM map
 		if (task.Progress != "100%" && responseItem.FailReason != "") || (task.Progress == "100%" && task.Status == "FAILURE") {
 			logger.LogError(ctx, task.MjId+" 构建失败，"+task.FailReason)
 			task.Progress = "100%"
-			err = model.CacheUpdateUserQuota(task.UserId)
-			if err != nil {
-				logger.LogError(ctx, "error update user quota cache: "+err.Error())
-			} else {
-				quota := task.Quota
-				if quota != 0 {
-					err = model.IncreaseUserQuota(task.UserId, quota)
-					if err != nil {
-						logger.LogError(ctx, "fail to increase user quota: "+err.Error())
-					}
-					logContent := fmt.Sprintf("构图失败 %s，补偿 %s", task.MjId, common.LogQuota(quota))
-					model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
-				}
+			if _, err = task.RefundQuota(refundReason); err != nil {
+				logger.LogError(ctx, "fail to refund task quota: "+err.Error())
 			}
 		}
 		err = task.Update()
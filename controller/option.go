@@ -91,6 +91,14 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "ModelFallbacks":
+		if _, err := config.ParseModelFallbacks(option.Value); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
 	}
 	err = model.UpdateOption(option.Key, option.Value)
 	if err != nil {
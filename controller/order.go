@@ -136,7 +136,17 @@ func PaymentCallback(c *gin.Context) {
 	}
 
 	payNotify, err := paymentService.HandleCallback(c, paymentService.Payment.Config)
-	if err != nil {
+	if err != nil || payNotify == nil {
+		return
+	}
+
+	if payNotify.SubscriptionEvent != "" {
+		handleSubscriptionEvent(paymentService, payNotify)
+		return
+	}
+
+	if payNotify.DisputeNo != "" {
+		handleDisputeEvent(paymentService, payNotify)
 		return
 	}
 
@@ -162,6 +172,14 @@ func PaymentCallback(c *gin.Context) {
 		return
 	}
 
+	if order.SubscriptionId > 0 {
+		err = paySubscriptionOrder(order, payNotify)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("gateway callback failed to activate subscription, trade_no: %s, error: %s", payNotify.TradeNo, err.Error()))
+		}
+		return
+	}
+
 	err = model.IncreaseUserQuota(order.UserId, order.Quota)
 	if err != nil {
 		logger.SysError(fmt.Sprintf("gateway callback failed to increase user quota, trade_no: %s,", payNotify.TradeNo))
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/payment"
+	"one-api/payment/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RefundOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RefundOrder 原路全额退款并按策略扣回订单充值的额度
+// 先创建待处理的退款记录再调用支付网关，任一步失败后再次调用会使用同一个退款编号继续处理
+func RefundOrder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	var req RefundOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("invalid request"))
+		return
+	}
+
+	order, err := model.GetOrderById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("订单不存在"))
+		return
+	}
+	if order.Status != model.OrderStatusSuccess && order.Status != model.OrderStatusRefunded {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("只能退款支付成功的订单"))
+		return
+	}
+
+	LockOrder(order.TradeNo)
+	defer UnlockOrder(order.TradeNo)
+
+	refund, err := model.CreateOrderRefund(order, &model.OrderRefund{
+		Type:       model.OrderRefundTypeRefund,
+		RefundNo:   utils.GenerateTradeNo(),
+		Amount:     order.OrderAmount,
+		Currency:   order.OrderCurrency,
+		Reason:     req.Reason,
+		OperatorId: c.GetInt("id"),
+	})
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if refund.Status == model.OrderRefundStatusCompleted {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("订单已退款"))
+		return
+	}
+
+	if refund.Type == model.OrderRefundTypeRefund && refund.GatewayRefundNo == "" {
+		paymentService, err := payment.NewPaymentServiceByID(order.GatewayId)
+		if err != nil {
+			common.APIRespondWithError(c, http.StatusOK, err)
+			return
+		}
+		if !paymentService.SupportRefund() {
+			common.APIRespondWithError(c, http.StatusOK, errors.New("该支付方式不支持退款"))
+			return
+		}
+
+		result, err := paymentService.Refund(order, refund.RefundNo, refund.Reason)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("refund order failed, trade_no: %s, refund_no: %s, error: %s", order.TradeNo, refund.RefundNo, err.Error()))
+			common.APIRespondWithError(c, http.StatusOK, fmt.Errorf("退款失败: %s", err.Error()))
+			return
+		}
+		if err := refund.SetGatewayRefundNo(result.GatewayRefundNo); err != nil {
+			logger.SysError(fmt.Sprintf("refund order failed to save gateway refund no, trade_no: %s, error: %s", order.TradeNo, err.Error()))
+		}
+	}
+
+	if _, err := order.ChangeStatus(model.OrderStatusSuccess, model.OrderStatusRefunded); err != nil {
+		logger.SysError(fmt.Sprintf("refund order failed to update status, trade_no: %s, error: %s", order.TradeNo, err.Error()))
+	}
+
+	cancelOrderSubscription(order)
+	if _, err := model.ClawbackOrderQuota(order, refund); err != nil {
+		logger.SysError(fmt.Sprintf("refund order failed to deduct quota, trade_no: %s, error: %s", order.TradeNo, err.Error()))
+		common.APIRespondWithError(c, http.StatusOK, fmt.Errorf("已退款，但扣回额度失败，请稍后重试: %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    refund,
+	})
+}
+
+func GetOrderRefundList(c *gin.Context) {
+	var params model.SearchOrderRefundParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	refunds, err := model.GetOrderRefundList(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    refunds,
+	})
+}
+
+// handleDisputeEvent 用户拒付时标记订单并按策略扣回额度，已退款的订单不再处理
+func handleDisputeEvent(paymentService *payment.PaymentService, payNotify *types.PayNotify) {
+	LockOrder(payNotify.GatewayNo)
+	defer UnlockOrder(payNotify.GatewayNo)
+
+	order, err := model.GetOrderByGatewayNo(paymentService.Payment.ID, payNotify.GatewayNo)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("gateway dispute callback failed to find order, gateway_no: %s, dispute_no: %s", payNotify.GatewayNo, payNotify.DisputeNo))
+		return
+	}
+
+	// 已全额退款的订单不再处理拒付；退款还未完成时沿用退款记录扣回额度
+	claimed, err := order.ChangeStatus(model.OrderStatusSuccess, model.OrderStatusDisputed)
+	if err != nil || (!claimed && order.Status != model.OrderStatusRefunded) {
+		return
+	}
+
+	refund, err := model.CreateOrderRefund(order, &model.OrderRefund{
+		Type:     model.OrderRefundTypeDispute,
+		RefundNo: payNotify.DisputeNo,
+		Amount:   payNotify.Amount,
+		Currency: order.OrderCurrency,
+	})
+	if err != nil {
+		logger.SysError(fmt.Sprintf("gateway dispute callback failed to create refund record, trade_no: %s, error: %s", order.TradeNo, err.Error()))
+		return
+	}
+
+	cancelOrderSubscription(order)
+	if _, err := model.ClawbackOrderQuota(order, refund); err != nil {
+		logger.SysError(fmt.Sprintf("gateway dispute callback failed to deduct quota, trade_no: %s, error: %s", order.TradeNo, err.Error()))
+	}
+}
+
+// cancelOrderSubscription 订阅订单退款或拒付时停止支付网关的周期扣款
+func cancelOrderSubscription(order *model.Order) {
+	if order.SubscriptionId == 0 {
+		return
+	}
+
+	subscription, err := model.GetSubscriptionById(order.SubscriptionId)
+	if err != nil || !subscription.AutoRenew || subscription.GatewaySubscriptionId == "" {
+		return
+	}
+
+	paymentService, err := payment.NewPaymentServiceByID(subscription.GatewayId)
+	if err != nil {
+		return
+	}
+	if err := paymentService.CancelSubscription(subscription.GatewaySubscriptionId); err != nil {
+		logger.SysError(fmt.Sprintf("cancel gateway subscription %s error: %s", subscription.GatewaySubscriptionId, err.Error()))
+	}
+}
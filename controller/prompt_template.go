@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetPromptTemplates(c *gin.Context) {
+	var params model.SearchPromptTemplateParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	templates, err := model.GetPromptTemplatesList(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    templates,
+	})
+}
+
+// GetPromptTemplate 返回模板及其所有历史版本
+func GetPromptTemplate(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	template, err := model.GetPromptTemplateById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	versions, err := model.GetPromptTemplateVersions(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"template": template,
+			"versions": versions,
+		},
+	})
+}
+
+func validatePromptTemplate(template *model.PromptTemplate) error {
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		return errors.New("模板名称不能为空")
+	}
+	if strings.TrimSpace(template.Content) == "" {
+		return errors.New("模板内容不能为空")
+	}
+	return nil
+}
+
+func AddPromptTemplate(c *gin.Context) {
+	template := model.PromptTemplate{}
+	if err := c.ShouldBindJSON(&template); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := validatePromptTemplate(&template); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := template.Insert(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    template,
+	})
+}
+
+// UpdatePromptTemplate 内容变化时生成新版本
+func UpdatePromptTemplate(c *gin.Context) {
+	template := model.PromptTemplate{}
+	if err := c.ShouldBindJSON(&template); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := validatePromptTemplate(&template); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := template.Update(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    template,
+	})
+}
+
+// RollbackPromptTemplate 将指定历史版本发布为新版本
+func RollbackPromptTemplate(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	version, _ := strconv.Atoi(c.Param("version"))
+
+	template, err := model.RollbackPromptTemplate(id, version)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    template,
+	})
+}
+
+func DeletePromptTemplate(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if err := model.DeletePromptTemplate(id); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
@@ -0,0 +1,441 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/payment"
+	"one-api/payment/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetSubscriptionPlanList(c *gin.Context) {
+	var params model.SearchSubscriptionPlanParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	plans, err := model.GetSubscriptionPlanList(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    plans,
+	})
+}
+
+func GetSubscriptionPlan(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	plan, err := model.GetSubscriptionPlanById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    plan,
+	})
+}
+
+func AddSubscriptionPlan(c *gin.Context) {
+	plan := model.SubscriptionPlan{}
+	if err := c.ShouldBindJSON(&plan); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := plan.Validate(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := plan.Insert(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    plan,
+	})
+}
+
+func UpdateSubscriptionPlan(c *gin.Context) {
+	plan := model.SubscriptionPlan{}
+	if err := c.ShouldBindJSON(&plan); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if plan.Id == 0 {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("套餐不存在"))
+		return
+	}
+
+	if err := plan.Validate(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := plan.Update(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    plan,
+	})
+}
+
+func DeleteSubscriptionPlan(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	plan := model.SubscriptionPlan{Id: id}
+	if err := plan.Delete(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func GetSubscriptionList(c *gin.Context) {
+	var params model.SearchSubscriptionParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	subscriptions, err := model.GetSubscriptionList(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    subscriptions,
+	})
+}
+
+func GetUserSubscriptionPlans(c *gin.Context) {
+	plans, err := model.GetUserSubscriptionPlans()
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    plans,
+	})
+}
+
+func GetUserSubscriptions(c *gin.Context) {
+	subscriptions, err := model.GetUserSubscriptions(c.GetInt("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    subscriptions,
+	})
+}
+
+type SubscriptionRequest struct {
+	PlanId int    `json:"plan_id" binding:"required"`
+	UUID   string `json:"uuid" binding:"required"`
+}
+
+// CreateSubscription 订阅套餐，支持周期扣款的网关自动续费，其他网关需要用户手动续费
+func CreateSubscription(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("invalid request"))
+		return
+	}
+
+	plan, err := model.GetSubscriptionPlanById(req.PlanId)
+	if err != nil || plan.Enable == nil || !*plan.Enable {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("套餐不存在"))
+		return
+	}
+
+	userId := c.GetInt("id")
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("用户不存在"))
+		return
+	}
+
+	// 新订阅生效时旧订阅会立即过期，自动续费的订阅需要先取消，避免继续扣款
+	current, err := model.GetUserCurrentSubscription(userId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if current != nil && current.AutoRenew {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("请先取消当前订阅的自动续费"))
+		return
+	}
+
+	go model.CloseUnfinishedOrder()
+
+	paymentService, err := payment.NewPaymentService(req.UUID)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	subscription, err := model.NewSubscription(userId, paymentService.Payment.ID, plan, paymentService.SupportSubscription())
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("创建订阅失败，请稍后再试"))
+		return
+	}
+
+	fee, payMoney := calculateSubscriptionAmount(paymentService.Payment, plan.Price)
+	tradeNo := utils.GenerateTradeNo()
+	payRequest, err := paymentService.PaySubscription(tradeNo, payMoney, user, &types.SubscriptionConfig{
+		Name:          plan.Name,
+		Interval:      string(plan.Interval),
+		IntervalCount: plan.IntervalCount,
+	})
+	if err != nil {
+		logger.SysError(fmt.Sprintf("create subscription payment error: %s", err.Error()))
+		common.APIRespondWithError(c, http.StatusOK, errors.New("创建支付失败，请稍后再试"))
+		return
+	}
+
+	createSubscriptionOrder(c, paymentService, subscription, plan, tradeNo, fee, payMoney, payRequest)
+}
+
+// RenewSubscription 手动续费不支持自动续费的订阅，支付成功后延长一个周期
+func RenewSubscription(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	var req struct {
+		UUID string `json:"uuid" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("invalid request"))
+		return
+	}
+
+	userId := c.GetInt("id")
+	subscription, err := model.GetUserSubscriptionById(userId, id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("订阅不存在"))
+		return
+	}
+	if subscription.Status != model.SubscriptionStatusActive {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("订阅未生效"))
+		return
+	}
+	if subscription.AutoRenew {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("订阅已开启自动续费"))
+		return
+	}
+
+	plan, err := model.GetSubscriptionPlanById(subscription.PlanId)
+	if err != nil || plan.Enable == nil || !*plan.Enable {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("套餐已下架，无法续费"))
+		return
+	}
+
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("用户不存在"))
+		return
+	}
+
+	paymentService, err := payment.NewPaymentService(req.UUID)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	fee, payMoney := calculateSubscriptionAmount(paymentService.Payment, plan.Price)
+	tradeNo := utils.GenerateTradeNo()
+	payRequest, err := paymentService.Pay(tradeNo, payMoney, user)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("创建支付失败，请稍后再试"))
+		return
+	}
+
+	createSubscriptionOrder(c, paymentService, subscription, plan, tradeNo, fee, payMoney, payRequest)
+}
+
+func createSubscriptionOrder(c *gin.Context, paymentService *payment.PaymentService, subscription *model.Subscription, plan *model.SubscriptionPlan, tradeNo string, fee, payMoney float64, payRequest *types.PayRequest) {
+	order := &model.Order{
+		UserId:         subscription.UserId,
+		GatewayId:      paymentService.Payment.ID,
+		TradeNo:        tradeNo,
+		Amount:         int(math.Round(plan.Price)),
+		OrderAmount:    payMoney,
+		OrderCurrency:  paymentService.Payment.Currency,
+		Fee:            fee,
+		Status:         model.OrderStatusPending,
+		Quota:          subscription.Quota,
+		SubscriptionId: subscription.Id,
+	}
+
+	if err := order.Insert(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("创建订单失败，请稍后再试"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": &OrderResponse{
+			TradeNo:    tradeNo,
+			PayRequest: payRequest,
+		},
+	})
+}
+
+// CancelUserSubscription 取消自动续费，已支付的周期结束后过期
+func CancelUserSubscription(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	subscription, err := model.GetUserSubscriptionById(c.GetInt("id"), id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("订阅不存在"))
+		return
+	}
+	if subscription.Status != model.SubscriptionStatusActive {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("订阅未生效"))
+		return
+	}
+
+	if subscription.AutoRenew && subscription.GatewaySubscriptionId != "" {
+		paymentService, err := payment.NewPaymentServiceByID(subscription.GatewayId)
+		if err != nil {
+			common.APIRespondWithError(c, http.StatusOK, err)
+			return
+		}
+		if err := paymentService.CancelSubscription(subscription.GatewaySubscriptionId); err != nil {
+			logger.SysError(fmt.Sprintf("cancel gateway subscription %s error: %s", subscription.GatewaySubscriptionId, err.Error()))
+			common.APIRespondWithError(c, http.StatusOK, errors.New("取消自动续费失败，请稍后再试"))
+			return
+		}
+	}
+
+	if err := subscription.Cancel(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    subscription,
+	})
+}
+
+// paySubscriptionOrder 订阅订单支付成功，激活或续期订阅
+func paySubscriptionOrder(order *model.Order, payNotify *types.PayNotify) error {
+	subscription, err := model.GetSubscriptionById(order.SubscriptionId)
+	if err != nil {
+		return err
+	}
+
+	return subscription.Paid(0, payNotify.SubscriptionNo)
+}
+
+// handleSubscriptionEvent 处理支付网关主动推送的周期扣款事件，续费时补记一条订单
+func handleSubscriptionEvent(paymentService *payment.PaymentService, payNotify *types.PayNotify) {
+	subscription, err := model.GetSubscriptionByGatewayNo(paymentService.Payment.ID, payNotify.SubscriptionNo)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("gateway callback failed to find subscription, subscription_no: %s", payNotify.SubscriptionNo))
+		return
+	}
+
+	switch payNotify.SubscriptionEvent {
+	case types.SubscriptionEventRenewed:
+		LockOrder(payNotify.GatewayNo)
+		defer UnlockOrder(payNotify.GatewayNo)
+
+		// 支付网关会重复推送事件，按扣款编号去重
+		if _, err := model.GetOrderByGatewayNo(paymentService.Payment.ID, payNotify.GatewayNo); err == nil {
+			return
+		}
+
+		order := &model.Order{
+			UserId:         subscription.UserId,
+			GatewayId:      paymentService.Payment.ID,
+			TradeNo:        utils.GenerateTradeNo(),
+			GatewayNo:      payNotify.GatewayNo,
+			OrderAmount:    payNotify.Amount,
+			OrderCurrency:  paymentService.Payment.Currency,
+			Status:         model.OrderStatusSuccess,
+			Quota:          subscription.Quota,
+			SubscriptionId: subscription.Id,
+		}
+		if plan, err := model.GetSubscriptionPlanById(subscription.PlanId); err == nil {
+			order.Amount = int(math.Round(plan.Price))
+		}
+		if err := order.Insert(); err != nil {
+			logger.SysError(fmt.Sprintf("gateway callback failed to create renewal order, gateway_no: %s, error: %s", payNotify.GatewayNo, err.Error()))
+			return
+		}
+
+		if err := subscription.Paid(payNotify.PeriodEnd, ""); err != nil {
+			logger.SysError(fmt.Sprintf("gateway callback failed to renew subscription %d, error: %s", subscription.Id, err.Error()))
+		}
+	case types.SubscriptionEventCanceled:
+		if err := subscription.Cancel(); err != nil {
+			logger.SysError(fmt.Sprintf("gateway callback failed to cancel subscription %d, error: %s", subscription.Id, err.Error()))
+		}
+	}
+}
+
+// fee手续费，payMoney实付金额，订阅不参与充值折扣
+func calculateSubscriptionAmount(payment *model.Payment, price float64) (fee, payMoney float64) {
+	if payment.PercentFee > 0 {
+		fee = utils.Decimal(price*payment.PercentFee, 2)
+	} else if payment.FixedFee > 0 {
+		fee = payment.FixedFee
+	}
+
+	payMoney = utils.Decimal(price+fee, 2)
+	if payment.Currency != model.CurrencyTypeUSD {
+		payMoney = utils.Decimal(payMoney*config.PaymentUSDRate, 2)
+	}
+	return
+}
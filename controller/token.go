@@ -91,6 +91,9 @@ func GetToken(c *gin.Context) {
 		token.Setting.Set(setting)
 	}
 
+	budget := token.Setting.Data().Limits.Budget
+	token.Budget = model.GetTokenBudgetWindows(token.Id, &budget)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -228,6 +231,27 @@ func DeleteToken(c *gin.Context) {
 	})
 }
 
+// ResetTokenWebhookSecret 重新生成异步任务回调的签名密钥
+func ResetTokenWebhookSecret(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	secret, err := model.ResetTokenWebhookSecret(id, c.GetInt("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    secret,
+	})
+}
+
 func UpdateToken(c *gin.Context) {
 	userId := c.GetInt("id")
 	userRole := c.GetInt("role")
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"net/http"
 	"one-api/common"
+	"one-api/common/transform"
 	"one-api/model"
+	"one-api/safty"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -53,6 +55,21 @@ func AddUserGroup(c *gin.Context) {
 		return
 	}
 
+	if !model.IsValidBalanceMode(userGroup.BalanceMode) {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("invalid balance mode"))
+		return
+	}
+
+	if err := safty.ValidateToolNames(userGroup.SafeTools); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := transform.ValidateRules(userGroup.GetTransformRules()); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
 	if err := userGroup.Create(); err != nil {
 		common.APIRespondWithError(c, http.StatusOK, err)
 		return
@@ -72,6 +89,21 @@ func UpdateUserGroup(c *gin.Context) {
 		return
 	}
 
+	if !model.IsValidBalanceMode(userGroup.BalanceMode) {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("invalid balance mode"))
+		return
+	}
+
+	if err := safty.ValidateToolNames(userGroup.SafeTools); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := transform.ValidateRules(userGroup.GetTransformRules()); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
 	if err := userGroup.Update(); err != nil {
 		common.APIRespondWithError(c, http.StatusOK, err)
 		return
@@ -1,11 +1,13 @@
 package cron
 
 import (
+	"fmt"
 	"github.com/spf13/viper"
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/common/scheduler"
 	"one-api/model"
+	"one-api/relay/task"
 	"time"
 
 	"github.com/go-co-op/gocron/v2"
@@ -59,6 +61,69 @@ func InitCron() {
 		}),
 	)
 
+	// 每天凌晨三点清理过期的请求归档
+	err = scheduler.Manager.AddJob(
+		"clean_payload_archives",
+		gocron.DailyJob(1, gocron.NewAtTimes(gocron.NewAtTime(3, 0, 0))),
+		gocron.NewTask(func() {
+			deleted, err := model.DeleteExpiredPayloadArchives()
+			if err != nil {
+				logger.SysError("Clean payload archives error:" + err.Error())
+				return
+			}
+			logger.SysLog(fmt.Sprintf("清理过期请求归档 %d 条", deleted))
+		}),
+	)
+
+	// 每天凌晨三点半清理过期的批处理文件
+	err = scheduler.Manager.AddJob(
+		"clean_batch_files",
+		gocron.DailyJob(1, gocron.NewAtTimes(gocron.NewAtTime(3, 30, 0))),
+		gocron.NewTask(func() {
+			deleted, err := model.DeleteExpiredBatchFiles()
+			if err != nil {
+				logger.SysError("Clean batch files error:" + err.Error())
+				return
+			}
+			logger.SysLog(fmt.Sprintf("清理过期批处理文件 %d 个", deleted))
+		}),
+	)
+
+	// 每天凌晨四点清理过期的 Responses 响应
+	err = scheduler.Manager.AddJob(
+		"clean_stored_responses",
+		gocron.DailyJob(1, gocron.NewAtTimes(gocron.NewAtTime(4, 0, 0))),
+		gocron.NewTask(func() {
+			deleted, err := model.DeleteExpiredStoredResponses()
+			if err != nil {
+				logger.SysError("Clean stored responses error:" + err.Error())
+				return
+			}
+			logger.SysLog(fmt.Sprintf("清理过期 Responses 响应 %d 条", deleted))
+		}),
+	)
+
+	// 每分钟重试失败的异步任务回调
+	err = scheduler.Manager.AddJob(
+		"retry_task_webhooks",
+		gocron.DurationJob(time.Minute),
+		gocron.NewTask(func() {
+			task.RetryTaskWebhooks()
+		}),
+	)
+
+	// 每五分钟处理到期的订阅，续期已支付的并使未支付的过期
+	err = scheduler.Manager.AddJob(
+		"renew_subscriptions",
+		gocron.DurationJob(5*time.Minute),
+		gocron.NewTask(func() {
+			renewed, expired := model.RenewDueSubscriptions()
+			if renewed > 0 || expired > 0 {
+				logger.SysLog(fmt.Sprintf("订阅续期 %d 个，过期 %d 个", renewed, expired))
+			}
+		}),
+	)
+
 	// 开启自动更新 并且设置了有效自动更新时间 同时自动更新模式不是system 则会从服务器拉取最新价格表
 	autoPriceUpdatesInterval := viper.GetInt("auto_price_updates_interval")
 	autoPriceUpdates := viper.GetBool("auto_price_updates")
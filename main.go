@@ -21,6 +21,7 @@ import (
 	"one-api/cron"
 	"one-api/middleware"
 	"one-api/model"
+	"one-api/relay/batch"
 	"one-api/relay/task"
 	"one-api/router"
 	"one-api/safty"
@@ -142,6 +143,7 @@ func initHttpServer() {
 	server.Use(sessions.Sessions("session", store))
 
 	router.SetRouter(server, buildFS, indexPage)
+	batch.InitBatch(server)
 	port := viper.GetString("port")
 
 	err := server.Run(":" + port)
@@ -161,6 +163,7 @@ func SyncChannelCache(frequency int) {
 		logger.SysLog("syncing channels from database")
 		model.ChannelGroup.Load()
 		model.GlobalUserGroupRatio.Load()
+		model.GlobalPromptTemplates.Load()
 		model.PricingInstance.Init()
 		model.ModelOwnedBysInstance.Load()
 	}
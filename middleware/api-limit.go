@@ -3,7 +3,10 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"one-api/common/limit"
+	"one-api/common/utils"
 	"one-api/model"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +17,8 @@ const (
 	INTERNAL                = 1 * time.Minute
 	RATE_LIMIT_EXCEEDED_MSG = "您的速率达到上限，请稍后再试。"
 	SERVER_ERROR_MSG        = "Server error"
+
+	TOKEN_RATE_LIMIT_EXCEEDED_MSG = "当前令牌的速率达到上限，请稍后再试。"
 )
 
 func DynamicRedisRateLimiter() gin.HandlerFunc {
@@ -34,6 +39,43 @@ func DynamicRedisRateLimiter() gin.HandlerFunc {
 			return
 		}
 
+		if !tokenRateLimit(c) {
+			abortWithMessage(c, http.StatusTooManyRequests, TOKEN_RATE_LIMIT_EXCEEDED_MSG)
+			return
+		}
+
 		c.Next()
 	}
 }
+
+// tokenRateLimit 令牌级别的 RPM/TPM 限制，并返回 OpenAI 格式的 x-ratelimit-* 响应头
+func tokenRateLimit(c *gin.Context) bool {
+	tokenSetting, ok := utils.GetGinValue[*model.TokenSetting](c, "token_setting")
+	if !ok || tokenSetting == nil || !tokenSetting.Limits.RateLimit.Enabled {
+		return true
+	}
+
+	setting := tokenSetting.Limits.RateLimit
+	if setting.RPM <= 0 && setting.TPM <= 0 {
+		return true
+	}
+
+	status, allow := limit.AllowTokenRequest(c.GetInt("token_id"), setting.RPM, setting.TPM)
+
+	reset := status.Reset.Round(time.Second).String()
+	if status.LimitRequests > 0 {
+		c.Header("x-ratelimit-limit-requests", strconv.Itoa(status.LimitRequests))
+		c.Header("x-ratelimit-remaining-requests", strconv.Itoa(status.RemainingRequests))
+		c.Header("x-ratelimit-reset-requests", reset)
+	}
+	if status.LimitTokens > 0 {
+		c.Header("x-ratelimit-limit-tokens", strconv.Itoa(status.LimitTokens))
+		c.Header("x-ratelimit-remaining-tokens", strconv.Itoa(status.RemainingTokens))
+		c.Header("x-ratelimit-reset-tokens", reset)
+	}
+	if !allow {
+		c.Header("retry-after", strconv.Itoa(int(status.Reset.Seconds())+1))
+	}
+
+	return allow
+}
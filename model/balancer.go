@@ -27,6 +27,9 @@ type ChannelsChooser struct {
 	Cooldowns sync.Map
 
 	ModelGroup map[string]map[string]bool
+
+	Adaptive *AdaptiveBalancer
+	Breaker  *CircuitBreaker
 }
 
 type ChannelsFilterFunc func(channelId int, choice *ChannelChoice) bool
@@ -43,6 +46,13 @@ func FilterChannelTypes(channelTypes []int) ChannelsFilterFunc {
 	}
 }
 
+// FilterChannelTypesOrCompatibleClaude 允许指定类型的渠道和开启了 Claude 兼容的渠道
+func FilterChannelTypesOrCompatibleClaude(channelTypes []int) ChannelsFilterFunc {
+	return func(_ int, choice *ChannelChoice) bool {
+		return !choice.Channel.CompatibleClaude && !utils.Contains(choice.Channel.Type, channelTypes)
+	}
+}
+
 func FilterOnlyChat() ChannelsFilterFunc {
 	return func(channelId int, choice *ChannelChoice) bool {
 		return choice.Channel.OnlyChat
@@ -56,6 +66,8 @@ func FilterDisabledStream(modelName string) ChannelsFilterFunc {
 }
 
 func init() {
+	ChannelGroup.Breaker.OnStateChange = notifyCircuitStateChange
+
 	// 每小时清理一次过期的冷却时间
 	go func() {
 		ticker := time.NewTicker(1 * time.Hour)
@@ -121,6 +133,10 @@ func (cc *ChannelsChooser) Enable(channelId int) {
 	}
 
 	cc.Channels[channelId].Disable = false
+
+	if cc.Breaker != nil {
+		cc.Breaker.Reset(channelId)
+	}
 }
 
 func (cc *ChannelsChooser) ChangeStatus(channelId int, status bool) {
@@ -131,7 +147,7 @@ func (cc *ChannelsChooser) ChangeStatus(channelId int, status bool) {
 	}
 }
 
-func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFunc, modelName string) *Channel {
+func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFunc, modelName string, mode string) *Channel {
 	totalWeight := 0
 
 	validChannels := make([]*ChannelChoice, 0, len(channelIds))
@@ -145,6 +161,10 @@ func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFu
 			continue
 		}
 
+		if cc.Breaker != nil && !cc.Breaker.Available(channelId, modelName) {
+			continue
+		}
+
 		isSkip := false
 		for _, filter := range filters {
 			if filter(channelId, choice) {
@@ -161,24 +181,55 @@ func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFu
 		validChannels = append(validChannels, choice)
 	}
 
-	if len(validChannels) == 0 {
-		return nil
+	// 只对最终选中的渠道占用半开状态的探测名额，未放行时从剩余渠道中重新选择
+	for len(validChannels) > 0 {
+		index := cc.choose(validChannels, totalWeight, modelName, mode)
+		choice := validChannels[index]
+		if cc.Breaker == nil || cc.Breaker.Allow(choice.Channel.Id, modelName) {
+			return choice.Channel
+		}
+
+		totalWeight -= int(*choice.Channel.Weight)
+		validChannels = append(validChannels[:index], validChannels[index+1:]...)
 	}
 
+	return nil
+}
+
+// choose 按负载均衡模式从候选渠道中选择一个，返回其下标
+func (cc *ChannelsChooser) choose(validChannels []*ChannelChoice, totalWeight int, modelName string, mode string) int {
 	if len(validChannels) == 1 {
-		return validChannels[0].Channel
+		return 0
+	}
+
+	if mode == BalanceModeAdaptive && cc.Adaptive != nil {
+		channels := make([]*Channel, len(validChannels))
+		for i, choice := range validChannels {
+			channels[i] = choice.Channel
+		}
+		channel := cc.Adaptive.Choose(channels, modelName)
+		for i, choice := range validChannels {
+			if choice.Channel == channel {
+				return i
+			}
+		}
+		return 0
+	}
+
+	if totalWeight <= 0 {
+		return 0
 	}
 
 	choiceWeight := rand.Intn(totalWeight)
-	for _, choice := range validChannels {
+	for i, choice := range validChannels {
 		weight := int(*choice.Channel.Weight)
 		choiceWeight -= weight
 		if choiceWeight < 0 {
-			return choice.Channel
+			return i
 		}
 	}
 
-	return nil
+	return 0
 }
 
 func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilterFunc) (*Channel, error) {
@@ -201,8 +252,9 @@ func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilt
 		return nil, errors.New("channel not found")
 	}
 
+	mode := GlobalUserGroupRatio.GetBalanceMode(group)
 	for _, priority := range channelsPriority {
-		channel := cc.balancer(priority, filters, modelName)
+		channel := cc.balancer(priority, filters, modelName, mode)
 		if channel != nil {
 			return channel, nil
 		}
@@ -245,7 +297,98 @@ func (cc *ChannelsChooser) GetChannel(channelId int) *Channel {
 	return nil
 }
 
-var ChannelGroup = ChannelsChooser{}
+var ChannelGroup = ChannelsChooser{
+	Adaptive: NewAdaptiveBalancer(time.Now, rand.New(rand.NewSource(time.Now().UnixNano()))),
+	Breaker:  NewCircuitBreaker(time.Now, rand.New(rand.NewSource(time.Now().UnixNano()))),
+}
+
+// RecordResult 记录渠道请求结果，供自适应负载均衡和熔断使用
+func (cc *ChannelsChooser) RecordResult(channelId int, modelName string, success bool, ttft, latency time.Duration) {
+	if cc.Adaptive != nil {
+		cc.Adaptive.Record(channelId, modelName, success, ttft, latency)
+	}
+	if cc.Breaker != nil {
+		cc.Breaker.Record(channelId, modelName, success)
+	}
+}
+
+// GetCircuitBreakerStatus 返回渠道下处于熔断或半开状态的模型
+func (cc *ChannelsChooser) GetCircuitBreakerStatus(channelId int) []*CircuitBreakerStatus {
+	if cc.Breaker == nil {
+		return nil
+	}
+	return cc.Breaker.GetChannelStatus(channelId)
+}
+
+type AdaptiveWeightInfo struct {
+	Group           string         `json:"group"`
+	Model           string         `json:"model"`
+	Priority        int            `json:"priority"` // 优先级层级，0 为最高
+	ChannelId       int            `json:"channel_id"`
+	ChannelName     string         `json:"channel_name"`
+	Weight          uint           `json:"weight"`
+	EffectiveWeight float64        `json:"effective_weight"`
+	Stats           *AdaptiveStats `json:"stats"`
+}
+
+// GetAdaptiveWeights 返回各分组模型下渠道的当前有效权重，group 和 modelName 为空表示不过滤
+func (cc *ChannelsChooser) GetAdaptiveWeights(group, modelName string) []*AdaptiveWeightInfo {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	result := make([]*AdaptiveWeightInfo, 0)
+	if cc.Adaptive == nil {
+		return result
+	}
+
+	for groupName, models := range cc.Rule {
+		if group != "" && group != groupName {
+			continue
+		}
+		for model, priorities := range models {
+			if modelName != "" && modelName != model {
+				continue
+			}
+			for level, channelIds := range priorities {
+				channels := make([]*Channel, 0, len(channelIds))
+				for _, channelId := range channelIds {
+					if choice, ok := cc.Channels[channelId]; ok && !choice.Disable {
+						channels = append(channels, choice.Channel)
+					}
+				}
+
+				weights := cc.Adaptive.EffectiveWeights(channels, model)
+				for i, channel := range channels {
+					result = append(result, &AdaptiveWeightInfo{
+						Group:           groupName,
+						Model:           model,
+						Priority:        level,
+						ChannelId:       channel.Id,
+						ChannelName:     channel.Name,
+						Weight:          *channel.Weight,
+						EffectiveWeight: weights[i],
+						Stats:           cc.Adaptive.Stats(channel.Id, model),
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Group != result[j].Group {
+			return result[i].Group < result[j].Group
+		}
+		if result[i].Model != result[j].Model {
+			return result[i].Model < result[j].Model
+		}
+		if result[i].Priority != result[j].Priority {
+			return result[i].Priority < result[j].Priority
+		}
+		return result[i].ChannelId < result[j].ChannelId
+	})
+
+	return result
+}
 
 func (cc *ChannelsChooser) Load() {
 	var channels []*Channel
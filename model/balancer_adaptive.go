@@ -0,0 +1,227 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	BalanceModeWeight   = "weight"
+	BalanceModeAdaptive = "adaptive"
+)
+
+func IsValidBalanceMode(mode string) bool {
+	return mode == "" || mode == BalanceModeWeight || mode == BalanceModeAdaptive
+}
+
+type adaptiveSample struct {
+	at      time.Time
+	success bool
+	latency time.Duration
+	ttft    time.Duration // 非流式请求为 0
+}
+
+type channelModelStats struct {
+	sync.Mutex
+	samples []adaptiveSample
+}
+
+// AdaptiveStats 渠道+模型在统计窗口内的表现
+type AdaptiveStats struct {
+	Requests    int     `json:"requests"`
+	SuccessRate float64 `json:"success_rate"`
+	AvgTTFT     int64   `json:"avg_ttft"`    // 毫秒
+	P95Latency  int64   `json:"p95_latency"` // 毫秒
+}
+
+// AdaptiveBalancer 根据渠道近期的成功率、首字时间和延迟动态调整权重，静态权重作为先验
+type AdaptiveBalancer struct {
+	Window         time.Duration // 统计窗口
+	MaxSamples     int           // 每个渠道+模型最多保留的样本数
+	MinWeightRatio float64       // 有效权重的下限（相对静态权重），保证不健康的渠道仍有少量探测流量
+
+	stats  sync.Map // "channelId:model" -> *channelModelStats
+	now    func() time.Time
+	randMu sync.Mutex
+	rand   *rand.Rand
+}
+
+func NewAdaptiveBalancer(now func() time.Time, random *rand.Rand) *AdaptiveBalancer {
+	return &AdaptiveBalancer{
+		Window:         5 * time.Minute,
+		MaxSamples:     200,
+		MinWeightRatio: 0.05,
+		now:            now,
+		rand:           random,
+	}
+}
+
+func adaptiveStatsKey(channelId int, modelName string) string {
+	return fmt.Sprintf("%d:%s", channelId, modelName)
+}
+
+// Record 记录一次请求结果，ttft 为 0 表示没有首字时间
+func (ab *AdaptiveBalancer) Record(channelId int, modelName string, success bool, ttft, latency time.Duration) {
+	if channelId == 0 || modelName == "" {
+		return
+	}
+
+	value, _ := ab.stats.LoadOrStore(adaptiveStatsKey(channelId, modelName), &channelModelStats{})
+	stats := value.(*channelModelStats)
+
+	stats.Lock()
+	defer stats.Unlock()
+
+	stats.samples = append(stats.samples, adaptiveSample{
+		at:      ab.now(),
+		success: success,
+		latency: latency,
+		ttft:    ttft,
+	})
+
+	if len(stats.samples) > ab.MaxSamples {
+		stats.samples = stats.samples[len(stats.samples)-ab.MaxSamples:]
+	}
+}
+
+// Stats 返回统计窗口内的表现，没有样本时返回 nil
+func (ab *AdaptiveBalancer) Stats(channelId int, modelName string) *AdaptiveStats {
+	value, ok := ab.stats.Load(adaptiveStatsKey(channelId, modelName))
+	if !ok {
+		return nil
+	}
+	stats := value.(*channelModelStats)
+
+	stats.Lock()
+	defer stats.Unlock()
+
+	// 丢弃窗口外的样本
+	deadline := ab.now().Add(-ab.Window)
+	start := 0
+	for start < len(stats.samples) && stats.samples[start].at.Before(deadline) {
+		start++
+	}
+	stats.samples = stats.samples[start:]
+
+	if len(stats.samples) == 0 {
+		return nil
+	}
+
+	success := 0
+	var ttftTotal time.Duration
+	ttftCount := 0
+	latencies := make([]time.Duration, 0, len(stats.samples))
+	for _, sample := range stats.samples {
+		if !sample.success {
+			continue
+		}
+		success++
+		latencies = append(latencies, sample.latency)
+		if sample.ttft > 0 {
+			ttftTotal += sample.ttft
+			ttftCount++
+		}
+	}
+
+	result := &AdaptiveStats{
+		Requests:    len(stats.samples),
+		SuccessRate: float64(success) / float64(len(stats.samples)),
+	}
+
+	if ttftCount > 0 {
+		result.AvgTTFT = (ttftTotal / time.Duration(ttftCount)).Milliseconds()
+	}
+
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		index := int(math.Ceil(float64(len(latencies))*0.95)) - 1
+		result.P95Latency = latencies[index].Milliseconds()
+	}
+
+	return result
+}
+
+// speedMetric 优先使用首字时间，没有时使用 p95 延迟
+func (s *AdaptiveStats) speedMetric() int64 {
+	if s == nil {
+		return 0
+	}
+	if s.AvgTTFT > 0 {
+		return s.AvgTTFT
+	}
+	return s.P95Latency
+}
+
+// EffectiveWeights 计算同一优先级内各渠道的有效权重
+func (ab *AdaptiveBalancer) EffectiveWeights(channels []*Channel, modelName string) []float64 {
+	stats := make([]*AdaptiveStats, len(channels))
+	speeds := make([]int64, 0, len(channels))
+	for i, channel := range channels {
+		stats[i] = ab.Stats(channel.Id, modelName)
+		if speed := stats[i].speedMetric(); speed > 0 {
+			speeds = append(speeds, speed)
+		}
+	}
+
+	// 以同层渠道的速度中位数作为参照
+	var reference float64
+	if len(speeds) > 0 {
+		sort.Slice(speeds, func(i, j int) bool { return speeds[i] < speeds[j] })
+		reference = float64(speeds[len(speeds)/2])
+	}
+
+	weights := make([]float64, len(channels))
+	for i, channel := range channels {
+		prior := float64(*channel.Weight)
+		weight := prior
+
+		if stats[i] != nil {
+			// 拉普拉斯平滑，样本少时接近 0.5 而不是极端值
+			success := stats[i].SuccessRate * float64(stats[i].Requests)
+			smoothed := (success + 1) / (float64(stats[i].Requests) + 2)
+			weight *= smoothed * smoothed / 0.25
+
+			if speed := stats[i].speedMetric(); speed > 0 && reference > 0 {
+				weight *= math.Max(0.25, math.Min(2, reference/float64(speed)))
+			}
+		}
+
+		weights[i] = math.Max(weight, prior*ab.MinWeightRatio)
+	}
+
+	return weights
+}
+
+// Choose 按有效权重随机选择一个渠道
+func (ab *AdaptiveBalancer) Choose(channels []*Channel, modelName string) *Channel {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	if len(channels) == 1 {
+		return channels[0]
+	}
+
+	weights := ab.EffectiveWeights(channels, modelName)
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+
+	ab.randMu.Lock()
+	choice := ab.rand.Float64() * total
+	ab.randMu.Unlock()
+
+	for i, weight := range weights {
+		choice -= weight
+		if choice < 0 {
+			return channels[i]
+		}
+	}
+
+	return channels[len(channels)-1]
+}
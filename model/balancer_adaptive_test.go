@@ -0,0 +1,86 @@
+package model_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"one-api/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func newTestChannel(id int, weight uint) *model.Channel {
+	return &model.Channel{Id: id, Weight: &weight}
+}
+
+func TestAdaptiveBalancerStats(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	balancer := model.NewAdaptiveBalancer(clock.Now, rand.New(rand.NewSource(1)))
+
+	for i := 0; i < 19; i++ {
+		balancer.Record(1, "gpt-4o", true, 200*time.Millisecond, time.Duration(i+1)*100*time.Millisecond)
+	}
+	balancer.Record(1, "gpt-4o", false, 0, time.Second)
+
+	stats := balancer.Stats(1, "gpt-4o")
+	assert.Equal(t, 20, stats.Requests)
+	assert.InDelta(t, 0.95, stats.SuccessRate, 0.0001)
+	assert.Equal(t, int64(200), stats.AvgTTFT)
+	assert.Equal(t, int64(1900), stats.P95Latency)
+
+	// 超出统计窗口后样本被丢弃
+	clock.now = clock.now.Add(balancer.Window + time.Second)
+	assert.Nil(t, balancer.Stats(1, "gpt-4o"))
+}
+
+func TestAdaptiveBalancerShiftsTraffic(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	balancer := model.NewAdaptiveBalancer(clock.Now, rand.New(rand.NewSource(1)))
+
+	healthy := newTestChannel(1, 1)
+	failing := newTestChannel(2, 1)
+	slow := newTestChannel(3, 1)
+
+	for i := 0; i < 50; i++ {
+		balancer.Record(healthy.Id, "gpt-4o", true, 300*time.Millisecond, time.Second)
+		balancer.Record(failing.Id, "gpt-4o", i%2 == 0, 300*time.Millisecond, time.Second)
+		balancer.Record(slow.Id, "gpt-4o", true, 3*time.Second, 10*time.Second)
+	}
+
+	channels := []*model.Channel{healthy, failing, slow}
+	weights := balancer.EffectiveWeights(channels, "gpt-4o")
+	assert.Greater(t, weights[0], weights[1])
+	assert.Greater(t, weights[0], weights[2])
+
+	picked := map[int]int{}
+	for i := 0; i < 1000; i++ {
+		picked[balancer.Choose(channels, "gpt-4o").Id]++
+	}
+	assert.Greater(t, picked[healthy.Id], picked[failing.Id])
+	assert.Greater(t, picked[healthy.Id], picked[slow.Id])
+	assert.Greater(t, picked[slow.Id], 0)
+
+	// 相同的随机源得到相同的选择序列
+	replay := model.NewAdaptiveBalancer(clock.Now, rand.New(rand.NewSource(1)))
+	first := model.NewAdaptiveBalancer(clock.Now, rand.New(rand.NewSource(1)))
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first.Choose(channels, "gpt-4o").Id, replay.Choose(channels, "gpt-4o").Id)
+	}
+}
+
+func TestAdaptiveBalancerStaticWeightPrior(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	balancer := model.NewAdaptiveBalancer(clock.Now, rand.New(rand.NewSource(1)))
+
+	weights := balancer.EffectiveWeights([]*model.Channel{newTestChannel(1, 3), newTestChannel(2, 1)}, "gpt-4o")
+	assert.Equal(t, []float64{3, 1}, weights)
+}
@@ -0,0 +1,294 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/storage"
+	"one-api/common/utils"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+const (
+	BatchFilePurposeBatch  = "batch"
+	BatchFilePurposeOutput = "batch_output"
+)
+
+// 每次清理处理的文件数量
+const batchFileCleanBatch = 500
+
+// BatchError 批处理校验失败的原因，Line 从 1 开始
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// Batch 网关本地执行的批处理，对外按 OpenAI Batch 对象返回
+type Batch struct {
+	Id               string                                `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserId           int                                   `json:"user_id" gorm:"index"`
+	TokenId          int                                   `json:"token_id" gorm:"index"`
+	SourceIp         string                                `json:"-" gorm:"type:varchar(64);default:''"`
+	Endpoint         string                                `json:"endpoint" gorm:"type:varchar(64)"`
+	InputFileId      string                                `json:"input_file_id" gorm:"type:varchar(64)"`
+	OutputFileId     string                                `json:"output_file_id" gorm:"type:varchar(64);default:''"`
+	ErrorFileId      string                                `json:"error_file_id" gorm:"type:varchar(64);default:''"`
+	CompletionWindow string                                `json:"completion_window" gorm:"type:varchar(16)"`
+	Status           string                                `json:"status" gorm:"type:varchar(20);index"`
+	Errors           datatypes.JSONSlice[BatchError]       `json:"errors" gorm:"type:json"`
+	Metadata         datatypes.JSONType[map[string]string] `json:"metadata" gorm:"type:json"`
+	Total            int                                   `json:"total"`
+	Completed        int                                   `json:"completed"`
+	Failed           int                                   `json:"failed"`
+	CreatedAt        int64                                 `json:"created_at" gorm:"bigint;index"`
+	InProgressAt     int64                                 `json:"in_progress_at" gorm:"bigint"`
+	ExpiresAt        int64                                 `json:"expires_at" gorm:"bigint"`
+	FinalizingAt     int64                                 `json:"finalizing_at" gorm:"bigint"`
+	CompletedAt      int64                                 `json:"completed_at" gorm:"bigint"`
+	FailedAt         int64                                 `json:"failed_at" gorm:"bigint"`
+	ExpiredAt        int64                                 `json:"expired_at" gorm:"bigint"`
+	CancellingAt     int64                                 `json:"cancelling_at" gorm:"bigint"`
+	CancelledAt      int64                                 `json:"cancelled_at" gorm:"bigint"`
+}
+
+// BatchRequest 批处理中单个请求的执行结果，批处理结束后合并为输出文件并删除
+type BatchRequest struct {
+	Id        int    `json:"id" gorm:"primaryKey"`
+	BatchId   string `json:"batch_id" gorm:"type:varchar(64);index"`
+	Line      int    `json:"line"`
+	CustomId  string `json:"custom_id" gorm:"type:varchar(255)"`
+	Failed    bool   `json:"failed"`
+	Output    string `json:"output" gorm:"type:text"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint"`
+}
+
+// BatchFile 上传的批处理输入文件和生成的输出文件，内容保存在存储中
+type BatchFile struct {
+	Id        string `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserId    int    `json:"user_id" gorm:"index"`
+	Purpose   string `json:"purpose" gorm:"type:varchar(32)"`
+	Filename  string `json:"filename" gorm:"type:varchar(255)"`
+	Bytes     int    `json:"bytes"`
+	Storage   string `json:"storage" gorm:"type:varchar(32)"`
+	ObjectKey string `json:"object_key" gorm:"type:varchar(255)"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (b *Batch) Insert() error {
+	return DB.Create(b).Error
+}
+
+// UpdateStatus 更新状态、时间和结果文件，不覆盖执行中的请求计数
+func (b *Batch) UpdateStatus() error {
+	return DB.Model(b).Select("status", "errors", "output_file_id", "error_file_id", "in_progress_at", "finalizing_at",
+		"completed_at", "failed_at", "expired_at", "cancelling_at", "cancelled_at").Updates(b).Error
+}
+
+// UpdateCounts 只更新请求计数，避免覆盖并发修改的状态
+func (b *Batch) UpdateCounts() error {
+	return DB.Model(b).Select("completed", "failed").Updates(b).Error
+}
+
+// IsFinished 是否已经结束，结束的批处理不会再执行
+func (b *Batch) IsFinished() bool {
+	switch b.Status {
+	case BatchStatusFailed, BatchStatusCompleted, BatchStatusExpired, BatchStatusCancelled:
+		return true
+	}
+
+	return false
+}
+
+func GetBatchById(userId int, id string) (*Batch, error) {
+	var batch Batch
+	err := DB.Where("id = ? and user_id = ?", id, userId).First(&batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("batch not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+// GetBatchStatus 读取最新状态，用于执行器感知其他节点的取消操作
+func GetBatchStatus(id string) (string, error) {
+	var batch Batch
+	err := DB.Select("status").Where("id = ?", id).First(&batch).Error
+
+	return batch.Status, err
+}
+
+// GetUserBatches 按创建时间倒序分页，after 为上一页最后一个批处理的 id
+func GetUserBatches(userId int, after string, limit int) ([]*Batch, error) {
+	tx := DB.Where("user_id = ?", userId)
+	if after != "" {
+		var last Batch
+		if err := DB.Select("created_at").Where("id = ? and user_id = ?", after, userId).First(&last).Error; err == nil {
+			tx = tx.Where("created_at < ? or (created_at = ? and id < ?)", last.CreatedAt, last.CreatedAt, after)
+		}
+	}
+
+	var batches []*Batch
+	err := tx.Order("created_at desc, id desc").Limit(limit).Find(&batches).Error
+
+	return batches, err
+}
+
+// GetUnfinishedBatches 服务重启后需要继续执行的批处理
+func GetUnfinishedBatches() ([]*Batch, error) {
+	var batches []*Batch
+	err := DB.Where("status in ?", []string{BatchStatusInProgress, BatchStatusFinalizing, BatchStatusCancelling}).Order("created_at").Find(&batches).Error
+
+	return batches, err
+}
+
+func InsertBatchRequest(request *BatchRequest) error {
+	request.CreatedAt = utils.GetTimestamp()
+	return DB.Create(request).Error
+}
+
+// GetBatchDoneLines 已经执行完成的行号
+func GetBatchDoneLines(batchId string) (map[int]bool, error) {
+	var lines []int
+	err := DB.Model(&BatchRequest{}).Where("batch_id = ?", batchId).Pluck("line", &lines).Error
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]bool, len(lines))
+	for _, line := range lines {
+		done[line] = true
+	}
+
+	return done, nil
+}
+
+// GetBatchRequests 按行号顺序读取执行结果
+func GetBatchRequests(batchId string) ([]*BatchRequest, error) {
+	var requests []*BatchRequest
+	err := DB.Where("batch_id = ?", batchId).Order("line").Find(&requests).Error
+
+	return requests, err
+}
+
+func DeleteBatchRequests(batchId string) error {
+	return DB.Where("batch_id = ?", batchId).Delete(&BatchRequest{}).Error
+}
+
+// CreateBatchFile 将文件内容写入存储并记录
+func CreateBatchFile(file *BatchFile, data []byte) error {
+	file.Storage = config.BatchStorage
+	file.ObjectKey = fmt.Sprintf("batches/%d/%s.jsonl", file.UserId, file.Id)
+	file.Bytes = len(data)
+	file.CreatedAt = utils.GetTimestamp()
+
+	if err := storage.PutArchive(file.Storage, file.ObjectKey, data); err != nil {
+		return err
+	}
+
+	return DB.Create(file).Error
+}
+
+func GetBatchFileById(userId int, id string) (*BatchFile, error) {
+	var file BatchFile
+	err := DB.Where("id = ? and user_id = ?", id, userId).First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("file not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// GetUserBatchFiles 按创建时间倒序分页，after 为上一页最后一个文件的 id
+func GetUserBatchFiles(userId int, purpose string, after string, limit int) ([]*BatchFile, error) {
+	tx := DB.Where("user_id = ?", userId)
+	if purpose != "" {
+		tx = tx.Where("purpose = ?", purpose)
+	}
+	if after != "" {
+		var last BatchFile
+		if err := DB.Select("created_at").Where("id = ? and user_id = ?", after, userId).First(&last).Error; err == nil {
+			tx = tx.Where("created_at < ? or (created_at = ? and id < ?)", last.CreatedAt, last.CreatedAt, after)
+		}
+	}
+
+	var files []*BatchFile
+	err := tx.Order("created_at desc, id desc").Limit(limit).Find(&files).Error
+
+	return files, err
+}
+
+// GetContent 从存储中读取文件内容
+func (f *BatchFile) GetContent() ([]byte, error) {
+	return storage.GetArchive(f.Storage, f.ObjectKey)
+}
+
+func (f *BatchFile) Delete() error {
+	if err := storage.DeleteArchive(f.Storage, f.ObjectKey); err != nil {
+		return err
+	}
+
+	return DB.Delete(f).Error
+}
+
+// DeleteExpiredBatchFiles 删除超过保留天数的批处理文件
+func DeleteExpiredBatchFiles() (int64, error) {
+	if config.BatchFileRetentionDays <= 0 {
+		return 0, nil
+	}
+
+	targetTimestamp := time.Now().AddDate(0, 0, -config.BatchFileRetentionDays).Unix()
+
+	var deleted int64
+	for {
+		var files []*BatchFile
+		err := DB.Where("created_at < ?", targetTimestamp).Order("created_at").Limit(batchFileCleanBatch).Find(&files).Error
+		if err != nil {
+			return deleted, err
+		}
+		if len(files) == 0 {
+			return deleted, nil
+		}
+
+		ids := make([]string, 0, len(files))
+		for _, file := range files {
+			if err := storage.DeleteArchive(file.Storage, file.ObjectKey); err != nil {
+				// 存储中删除失败时也删除记录，避免一直重试
+				logger.SysError(fmt.Sprintf("failed to delete batch file %s: %s", file.Id, err.Error()))
+			}
+			ids = append(ids, file.Id)
+		}
+
+		result := DB.Where("id IN ?", ids).Delete(&BatchFile{})
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+
+		if len(files) < batchFileCleanBatch {
+			return deleted, nil
+		}
+	}
+}
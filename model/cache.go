@@ -8,6 +8,7 @@ import (
 	"one-api/common/logger"
 	"one-api/common/redis"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -142,19 +143,33 @@ func CacheGetUsername(id int) (username string, err error) {
 }
 
 func CacheDecreaseUserRealtimeQuota(id int, quota int) (int64, error) {
-	if !config.RedisEnabled {
-		return 0, nil
-	}
 	return CacheUpdateUserRealtimeQuota(id, -quota)
 }
 
 func CacheIncreaseUserRealtimeQuota(id int, quota int) (int64, error) {
-	if !config.RedisEnabled {
-		return 0, nil
-	}
 	return CacheUpdateUserRealtimeQuota(id, quota)
 }
 
+// 未开启 Redis 时实时配额记录在本进程内，只统计当前节点的会话
+var (
+	userRealtimeQuota   = make(map[int]int64)
+	userRealtimeQuotaMu sync.Mutex
+)
+
+func updateMemoryUserRealtimeQuota(id int, quota int) int64 {
+	userRealtimeQuotaMu.Lock()
+	defer userRealtimeQuotaMu.Unlock()
+
+	newValue := max(userRealtimeQuota[id]+int64(quota), 0)
+	if newValue == 0 {
+		delete(userRealtimeQuota, id)
+	} else {
+		userRealtimeQuota[id] = newValue
+	}
+
+	return newValue
+}
+
 var (
 	updateQuotaScript = redis.NewScript(`
 		local key = KEYS[1]
@@ -178,7 +193,7 @@ var (
 
 func CacheUpdateUserRealtimeQuota(id int, quota int) (int64, error) {
 	if !config.RedisEnabled {
-		return 0, nil
+		return updateMemoryUserRealtimeQuota(id, quota), nil
 	}
 	key := fmt.Sprintf(UserRealtimeQuotaKey, id)
 
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/common/transform"
 	"one-api/common/utils"
 	"slices"
 	"strings"
@@ -40,12 +41,17 @@ type Channel struct {
 	OnlyChat           bool    `json:"only_chat" form:"only_chat" gorm:"default:false"`
 	PreCost            int     `json:"pre_cost" form:"pre_cost" gorm:"default:1"`
 	CompatibleResponse bool    `json:"compatible_response" gorm:"default:false"`
+	CompatibleClaude   bool    `json:"compatible_claude" gorm:"default:false"` // 通过对话接口提供 Claude Messages API
 	AllowExtraBody     bool    `json:"allow_extra_body" form:"allow_extra_body" gorm:"default:false"`
 
 	DisabledStream *datatypes.JSONSlice[string] `json:"disabled_stream,omitempty" gorm:"type:json"`
+	// 请求和响应改写规则，在分组规则之后执行
+	TransformRules *datatypes.JSONSlice[transform.Rule] `json:"transform_rules,omitempty" gorm:"type:json"`
 
 	Plugin    *datatypes.JSONType[PluginType] `json:"plugin" form:"plugin" gorm:"type:json"`
 	DeletedAt gorm.DeletedAt                  `json:"-" gorm:"index"`
+
+	CircuitBreakers []*CircuitBreakerStatus `json:"circuit_breakers,omitempty" gorm:"-"`
 }
 
 func (c *Channel) AllowStream(modelName string) bool {
@@ -56,6 +62,14 @@ func (c *Channel) AllowStream(modelName string) bool {
 	return !slices.Contains(*c.DisabledStream, modelName)
 }
 
+func (c *Channel) GetTransformRules() []transform.Rule {
+	if c.TransformRules == nil {
+		return nil
+	}
+
+	return *c.TransformRules
+}
+
 type PluginType map[string]map[string]interface{}
 
 var allowedChannelOrderFields = map[string]bool{
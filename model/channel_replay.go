@@ -0,0 +1,132 @@
+package model
+
+import (
+	"one-api/common/utils"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	ChannelReplayStatusRunning   = 1
+	ChannelReplayStatusCompleted = 2
+	ChannelReplayStatusFailed    = 3
+)
+
+const (
+	ChannelReplaySourceLogs = "logs"
+	ChannelReplaySourceFile = "file"
+)
+
+// ChannelReplay 将历史请求重放到指定渠道，用于迁移渠道前验证结果是否一致
+type ChannelReplay struct {
+	Id         int    `json:"id"`
+	ChannelId  int    `json:"channel_id" gorm:"index"`
+	ModelName  string `json:"model_name" gorm:"type:varchar(255);default:''"` // 不为空时替换请求中的模型
+	Source     string `json:"source" gorm:"type:varchar(16)"`
+	Status     int    `json:"status" gorm:"default:1"`
+	Total      int    `json:"total" gorm:"default:0"`
+	Finished   int    `json:"finished" gorm:"default:0"`
+	Succeeded  int    `json:"succeeded" gorm:"default:0"`
+	Message    string `json:"message" gorm:"type:text"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint"`
+	FinishedAt int64  `json:"finished_at" gorm:"bigint;default:0"`
+
+	Results []*ChannelReplayResult `json:"results,omitempty" gorm:"-"`
+}
+
+// ChannelReplayResult 单个请求的重放结果，Original 开头的字段来自原始请求
+type ChannelReplayResult struct {
+	Id        int    `json:"id"`
+	ReplayId  int    `json:"replay_id" gorm:"index"`
+	Seq       int    `json:"seq"`
+	LogId     int    `json:"log_id" gorm:"default:0"`
+	ArchiveId string `json:"archive_id" gorm:"type:varchar(64);default:''"`
+	ModelName string `json:"model_name" gorm:"type:varchar(255);default:''"`
+
+	OriginalStatusCode       int `json:"original_status_code"`
+	StatusCode               int `json:"status_code"`
+	OriginalLatency          int `json:"original_latency"` // 毫秒
+	Latency                  int `json:"latency"`
+	OriginalPromptTokens     int `json:"original_prompt_tokens"`
+	OriginalCompletionTokens int `json:"original_completion_tokens"`
+	PromptTokens             int `json:"prompt_tokens"`
+	CompletionTokens         int `json:"completion_tokens"`
+
+	Diff  datatypes.JSONType[ChannelReplayDiff] `json:"diff" gorm:"type:json"`
+	Error string                                `json:"error" gorm:"type:text"`
+}
+
+// ChannelReplayDiff 重放响应与原始响应的差异
+type ChannelReplayDiff struct {
+	HasOriginal          bool     `json:"has_original"`
+	StatusEqual          bool     `json:"status_equal"`
+	ContentEqual         bool     `json:"content_equal"`
+	Similarity           float64  `json:"similarity"` // 0-1，按字符编辑距离计算
+	FinishReasonEqual    bool     `json:"finish_reason_equal"`
+	ToolCallsEqual       bool     `json:"tool_calls_equal"`
+	OriginalContent      string   `json:"original_content"`
+	Content              string   `json:"content"`
+	OriginalFinishReason string   `json:"original_finish_reason"`
+	FinishReason         string   `json:"finish_reason"`
+	OriginalToolCalls    []string `json:"original_tool_calls"`
+	ToolCalls            []string `json:"tool_calls"`
+}
+
+var allowedChannelReplayOrderFields = map[string]bool{
+	"id":         true,
+	"channel_id": true,
+	"created_at": true,
+}
+
+func CreateChannelReplay(replay *ChannelReplay) error {
+	replay.Status = ChannelReplayStatusRunning
+	replay.CreatedAt = utils.GetTimestamp()
+
+	return DB.Create(replay).Error
+}
+
+// UpdateProgress 更新重放进度
+func (r *ChannelReplay) UpdateProgress() error {
+	return DB.Model(r).Select("finished", "succeeded").Updates(r).Error
+}
+
+// Finish 结束重放，message 记录失败原因
+func (r *ChannelReplay) Finish(status int, message string) error {
+	r.Status = status
+	r.Message = message
+	r.FinishedAt = utils.GetTimestamp()
+
+	return DB.Model(r).Select("status", "message", "finished", "succeeded", "finished_at").Updates(r).Error
+}
+
+func InsertChannelReplayResult(result *ChannelReplayResult) error {
+	return DB.Create(result).Error
+}
+
+func GetChannelReplayList(params *PaginationParams) (*DataResult[ChannelReplay], error) {
+	var replays []*ChannelReplay
+
+	return PaginateAndOrder(DB, params, &replays, allowedChannelReplayOrderFields)
+}
+
+// GetChannelReplayById 获取重放任务及全部结果
+func GetChannelReplayById(id int) (*ChannelReplay, error) {
+	var replay ChannelReplay
+	if err := DB.First(&replay, id).Error; err != nil {
+		return nil, err
+	}
+
+	err := DB.Where("replay_id = ?", id).Order("seq").Find(&replay.Results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &replay, nil
+}
+
+func GetLogsByIds(ids []int) ([]*Log, error) {
+	var logs []*Log
+	err := DB.Where("id IN ?", ids).Order("id").Find(&logs).Error
+
+	return logs, err
+}
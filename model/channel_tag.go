@@ -158,7 +158,9 @@ func UpdateChannelsTag(tag string, channel *Channel) error {
 			Plugin:             channel.Plugin,
 			PreCost:            channel.PreCost,
 			DisabledStream:     channel.DisabledStream,
+			TransformRules:     channel.TransformRules,
 			CompatibleResponse: channel.CompatibleResponse,
+			CompatibleClaude:   channel.CompatibleClaude,
 		}).Error
 
 	if err != nil {
@@ -0,0 +1,318 @@
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"one-api/common/config"
+	"one-api/common/notify"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CircuitState string
+
+const (
+	CircuitStateClosed   CircuitState = "closed"
+	CircuitStateOpen     CircuitState = "open"
+	CircuitStateHalfOpen CircuitState = "half_open"
+)
+
+type CircuitBreakerSettings struct {
+	Enabled           bool
+	Window            time.Duration
+	MinRequests       int
+	FailureRate       float64
+	OpenDuration      time.Duration
+	HalfOpenRatio     float64
+	HalfOpenSuccesses int
+}
+
+func GetCircuitBreakerSettings() CircuitBreakerSettings {
+	return CircuitBreakerSettings{
+		Enabled:           config.CircuitBreakerEnabled,
+		Window:            time.Duration(config.CircuitBreakerWindowSeconds) * time.Second,
+		MinRequests:       config.CircuitBreakerMinRequests,
+		FailureRate:       config.CircuitBreakerFailureRate,
+		OpenDuration:      time.Duration(config.CircuitBreakerOpenSeconds) * time.Second,
+		HalfOpenRatio:     config.CircuitBreakerHalfOpenRatio,
+		HalfOpenSuccesses: config.CircuitBreakerHalfOpenSuccesses,
+	}
+}
+
+type CircuitStateChangeFunc func(channelId int, modelName string, from, to CircuitState)
+
+type circuitSample struct {
+	at      time.Time
+	success bool
+}
+
+type circuit struct {
+	sync.Mutex
+	state          CircuitState
+	samples        []circuitSample
+	openedAt       time.Time
+	probeSuccesses int
+}
+
+// CircuitBreakerStatus 渠道某个模型当前的熔断状态
+type CircuitBreakerStatus struct {
+	Model       string       `json:"model"`
+	State       CircuitState `json:"state"`
+	OpenedAt    int64        `json:"opened_at,omitempty"`
+	Requests    int          `json:"requests"`
+	FailureRate float64      `json:"failure_rate"`
+}
+
+// CircuitBreaker 按 渠道:模型 维护 关闭/打开/半开 三种状态
+// 打开状态下不分配流量，超过熔断时间后进入半开状态，只放行一小部分实际请求用于判断是否恢复
+type CircuitBreaker struct {
+	Settings      func() CircuitBreakerSettings
+	OnStateChange CircuitStateChangeFunc
+
+	circuits sync.Map // "channelId:model" -> *circuit
+	now      func() time.Time
+	randMu   sync.Mutex
+	rand     *rand.Rand
+}
+
+func NewCircuitBreaker(now func() time.Time, random *rand.Rand) *CircuitBreaker {
+	return &CircuitBreaker{
+		Settings: GetCircuitBreakerSettings,
+		now:      now,
+		rand:     random,
+	}
+}
+
+func circuitKey(channelId int, modelName string) string {
+	return fmt.Sprintf("%d:%s", channelId, modelName)
+}
+
+func (cb *CircuitBreaker) getCircuit(channelId int, modelName string) *circuit {
+	value, _ := cb.circuits.LoadOrStore(circuitKey(channelId, modelName), &circuit{state: CircuitStateClosed})
+	return value.(*circuit)
+}
+
+func (cb *CircuitBreaker) changeState(channelId int, modelName string, c *circuit, to CircuitState) {
+	from := c.state
+	if from == to {
+		return
+	}
+
+	c.state = to
+	switch to {
+	case CircuitStateOpen:
+		c.openedAt = cb.now()
+		c.probeSuccesses = 0
+	case CircuitStateHalfOpen:
+		c.probeSuccesses = 0
+	case CircuitStateClosed:
+		c.samples = nil
+		c.probeSuccesses = 0
+	}
+
+	if cb.OnStateChange != nil {
+		cb.OnStateChange(channelId, modelName, from, to)
+	}
+}
+
+// Available 选择渠道时过滤打开且未到熔断时间的渠道，不改变状态也不占用半开状态的探测名额
+func (cb *CircuitBreaker) Available(channelId int, modelName string) bool {
+	settings := cb.Settings()
+	if !settings.Enabled {
+		return true
+	}
+
+	value, ok := cb.circuits.Load(circuitKey(channelId, modelName))
+	if !ok {
+		return true
+	}
+	c := value.(*circuit)
+
+	c.Lock()
+	defer c.Unlock()
+
+	return c.state != CircuitStateOpen || cb.now().Sub(c.openedAt) >= settings.OpenDuration
+}
+
+// Allow 判断是否可以向选中的渠道的模型分配请求，半开状态下按比例放行探测请求
+func (cb *CircuitBreaker) Allow(channelId int, modelName string) bool {
+	settings := cb.Settings()
+	if !settings.Enabled {
+		return true
+	}
+
+	value, ok := cb.circuits.Load(circuitKey(channelId, modelName))
+	if !ok {
+		return true
+	}
+	c := value.(*circuit)
+
+	c.Lock()
+	defer c.Unlock()
+
+	switch c.state {
+	case CircuitStateOpen:
+		if cb.now().Sub(c.openedAt) < settings.OpenDuration {
+			return false
+		}
+		cb.changeState(channelId, modelName, c, CircuitStateHalfOpen)
+		return cb.probe(settings)
+	case CircuitStateHalfOpen:
+		return cb.probe(settings)
+	default:
+		return true
+	}
+}
+
+func (cb *CircuitBreaker) probe(settings CircuitBreakerSettings) bool {
+	cb.randMu.Lock()
+	defer cb.randMu.Unlock()
+
+	return cb.rand.Float64() < settings.HalfOpenRatio
+}
+
+// Record 记录请求结果并根据失败率切换状态
+func (cb *CircuitBreaker) Record(channelId int, modelName string, success bool) {
+	settings := cb.Settings()
+	if !settings.Enabled || channelId == 0 || modelName == "" {
+		return
+	}
+
+	c := cb.getCircuit(channelId, modelName)
+	c.Lock()
+	defer c.Unlock()
+
+	now := cb.now()
+	switch c.state {
+	case CircuitStateOpen:
+		// 熔断前已发出的请求，不影响状态
+		return
+	case CircuitStateHalfOpen:
+		if !success {
+			cb.changeState(channelId, modelName, c, CircuitStateOpen)
+			return
+		}
+		c.probeSuccesses++
+		if c.probeSuccesses >= settings.HalfOpenSuccesses {
+			cb.changeState(channelId, modelName, c, CircuitStateClosed)
+		}
+		return
+	}
+
+	c.samples = append(c.samples, circuitSample{at: now, success: success})
+	c.trim(now.Add(-settings.Window))
+
+	if success || len(c.samples) < settings.MinRequests {
+		return
+	}
+
+	if c.failureRate() >= settings.FailureRate {
+		cb.changeState(channelId, modelName, c, CircuitStateOpen)
+	}
+}
+
+func (c *circuit) trim(deadline time.Time) {
+	start := 0
+	for start < len(c.samples) && c.samples[start].at.Before(deadline) {
+		start++
+	}
+	c.samples = c.samples[start:]
+}
+
+func (c *circuit) failureRate() float64 {
+	if len(c.samples) == 0 {
+		return 0
+	}
+
+	failures := 0
+	for _, sample := range c.samples {
+		if !sample.success {
+			failures++
+		}
+	}
+
+	return float64(failures) / float64(len(c.samples))
+}
+
+// State 返回当前状态，未记录过的返回 closed
+func (cb *CircuitBreaker) State(channelId int, modelName string) CircuitState {
+	value, ok := cb.circuits.Load(circuitKey(channelId, modelName))
+	if !ok {
+		return CircuitStateClosed
+	}
+	c := value.(*circuit)
+
+	c.Lock()
+	defer c.Unlock()
+
+	return c.state
+}
+
+// GetChannelStatus 返回渠道下所有非关闭状态的模型
+func (cb *CircuitBreaker) GetChannelStatus(channelId int) []*CircuitBreakerStatus {
+	prefix := strconv.Itoa(channelId) + ":"
+	result := make([]*CircuitBreakerStatus, 0)
+
+	cb.circuits.Range(func(key, value any) bool {
+		modelName, ok := strings.CutPrefix(key.(string), prefix)
+		if !ok {
+			return true
+		}
+
+		c := value.(*circuit)
+		c.Lock()
+		defer c.Unlock()
+
+		if c.state == CircuitStateClosed {
+			return true
+		}
+
+		result = append(result, &CircuitBreakerStatus{
+			Model:       modelName,
+			State:       c.state,
+			OpenedAt:    c.openedAt.Unix(),
+			Requests:    len(c.samples),
+			FailureRate: c.failureRate(),
+		})
+		return true
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Model < result[j].Model
+	})
+
+	return result
+}
+
+// Reset 清除渠道的熔断状态，渠道被手动启用或删除时调用
+func (cb *CircuitBreaker) Reset(channelId int) {
+	prefix := strconv.Itoa(channelId) + ":"
+	cb.circuits.Range(func(key, _ any) bool {
+		if strings.HasPrefix(key.(string), prefix) {
+			cb.circuits.Delete(key)
+		}
+		return true
+	})
+}
+
+var circuitStateNames = map[CircuitState]string{
+	CircuitStateClosed:   "关闭",
+	CircuitStateOpen:     "打开",
+	CircuitStateHalfOpen: "半开",
+}
+
+func notifyCircuitStateChange(channelId int, modelName string, from, to CircuitState) {
+	go func() {
+		channelName := ""
+		if channel := ChannelGroup.GetChannel(channelId); channel != nil {
+			channelName = channel.Name
+		}
+
+		subject := fmt.Sprintf("通道「%s」（#%d）模型 %s 熔断状态变更为%s", channelName, channelId, modelName, circuitStateNames[to])
+		content := fmt.Sprintf("通道「%s」（#%d）模型 %s 熔断状态由%s变更为%s", channelName, channelId, modelName, circuitStateNames[from], circuitStateNames[to])
+		notify.Send(subject, content)
+	}()
+}
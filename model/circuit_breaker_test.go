@@ -0,0 +1,151 @@
+package model_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"one-api/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCircuitBreaker(clock *fakeClock) (*model.CircuitBreaker, *[]model.CircuitState) {
+	breaker := model.NewCircuitBreaker(clock.Now, rand.New(rand.NewSource(1)))
+	breaker.Settings = func() model.CircuitBreakerSettings {
+		return model.CircuitBreakerSettings{
+			Enabled:           true,
+			Window:            time.Minute,
+			MinRequests:       4,
+			FailureRate:       0.5,
+			OpenDuration:      30 * time.Second,
+			HalfOpenRatio:     0.5,
+			HalfOpenSuccesses: 2,
+		}
+	}
+
+	transitions := make([]model.CircuitState, 0)
+	breaker.OnStateChange = func(_ int, _ string, _, to model.CircuitState) {
+		transitions = append(transitions, to)
+	}
+
+	return breaker, &transitions
+}
+
+func TestCircuitBreakerOpenAndRecover(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	breaker, transitions := newTestCircuitBreaker(clock)
+
+	breaker.Record(1, "gpt-4o", true)
+	breaker.Record(1, "gpt-4o", false)
+	breaker.Record(1, "gpt-4o", true)
+	assert.Equal(t, model.CircuitStateClosed, breaker.State(1, "gpt-4o"))
+
+	breaker.Record(1, "gpt-4o", false)
+	assert.Equal(t, model.CircuitStateOpen, breaker.State(1, "gpt-4o"))
+	assert.False(t, breaker.Allow(1, "gpt-4o"))
+	// 其他模型不受影响
+	assert.True(t, breaker.Allow(1, "gpt-4o-mini"))
+
+	clock.now = clock.now.Add(31 * time.Second)
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		if breaker.Allow(1, "gpt-4o") {
+			allowed++
+		}
+	}
+	assert.Equal(t, model.CircuitStateHalfOpen, breaker.State(1, "gpt-4o"))
+	assert.Greater(t, allowed, 0)
+	assert.Less(t, allowed, 100)
+
+	breaker.Record(1, "gpt-4o", true)
+	breaker.Record(1, "gpt-4o", true)
+	assert.Equal(t, model.CircuitStateClosed, breaker.State(1, "gpt-4o"))
+	assert.Equal(t, []model.CircuitState{model.CircuitStateOpen, model.CircuitStateHalfOpen, model.CircuitStateClosed}, *transitions)
+}
+
+func TestCircuitBreakerHalfOpenFailure(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	breaker, _ := newTestCircuitBreaker(clock)
+
+	for i := 0; i < 4; i++ {
+		breaker.Record(2, "gpt-4o", false)
+	}
+	assert.Len(t, breaker.GetChannelStatus(2), 1)
+
+	clock.now = clock.now.Add(31 * time.Second)
+	breaker.Allow(2, "gpt-4o")
+	breaker.Record(2, "gpt-4o", false)
+	assert.Equal(t, model.CircuitStateOpen, breaker.State(2, "gpt-4o"))
+
+	breaker.Reset(2)
+	assert.Equal(t, model.CircuitStateClosed, breaker.State(2, "gpt-4o"))
+	assert.Empty(t, breaker.GetChannelStatus(2))
+}
+
+func TestCircuitBreakerAvailable(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	breaker, transitions := newTestCircuitBreaker(clock)
+
+	for i := 0; i < 4; i++ {
+		breaker.Record(3, "gpt-4o", false)
+	}
+	assert.False(t, breaker.Available(3, "gpt-4o"))
+	assert.True(t, breaker.Available(3, "gpt-4o-mini"))
+
+	// 到达熔断时间后可以作为候选，但在被选中前保持打开状态
+	clock.now = clock.now.Add(31 * time.Second)
+	assert.True(t, breaker.Available(3, "gpt-4o"))
+	assert.Equal(t, model.CircuitStateOpen, breaker.State(3, "gpt-4o"))
+	assert.Equal(t, []model.CircuitState{model.CircuitStateOpen}, *transitions)
+}
+
+func newTestChooser(breaker *model.CircuitBreaker, channels ...*model.Channel) *model.ChannelsChooser {
+	chooser := &model.ChannelsChooser{
+		Channels: make(map[int]*model.ChannelChoice),
+		Rule:     map[string]map[string][][]int{"default": {"gpt-4o": {{}}}},
+		Breaker:  breaker,
+	}
+	for _, channel := range channels {
+		chooser.Channels[channel.Id] = &model.ChannelChoice{Channel: channel}
+		chooser.Rule["default"]["gpt-4o"][0] = append(chooser.Rule["default"]["gpt-4o"][0], channel.Id)
+	}
+	return chooser
+}
+
+func TestChannelsChooserCircuitBreaker(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	breaker, _ := newTestCircuitBreaker(clock)
+	chooser := newTestChooser(breaker, newTestChannel(1, 10), newTestChannel(2, 1))
+
+	for i := 0; i < 4; i++ {
+		breaker.Record(1, "gpt-4o", false)
+	}
+	for i := 0; i < 20; i++ {
+		channel, err := chooser.Next("default", "gpt-4o")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, channel.Id)
+	}
+
+	// 被过滤的渠道不会进入半开状态
+	clock.now = clock.now.Add(31 * time.Second)
+	channel, err := chooser.Next("default", "gpt-4o", model.FilterChannelId([]int{1}))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, channel.Id)
+	assert.Equal(t, model.CircuitStateOpen, breaker.State(1, "gpt-4o"))
+
+	// 半开状态的渠道探测未放行时选择其他渠道
+	settings := breaker.Settings()
+	settings.HalfOpenRatio = 0
+	breaker.Settings = func() model.CircuitBreakerSettings { return settings }
+	for i := 0; i < 20; i++ {
+		channel, err := chooser.Next("default", "gpt-4o")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, channel.Id)
+	}
+	assert.Equal(t, model.CircuitStateHalfOpen, breaker.State(1, "gpt-4o"))
+
+	// 只有半开状态的渠道且探测未放行时没有可用渠道
+	_, err = chooser.Next("default", "gpt-4o", model.FilterChannelId([]int{2}))
+	assert.Error(t, err)
+}
@@ -38,6 +38,8 @@ const (
 	LogTypeConsume
 	LogTypeManage
 	LogTypeSystem
+	LogTypeViolation
+	LogTypeRefund
 )
 
 func RecordQuotaLog(userId int, logType int, quota int, ip string, content string) {
@@ -79,6 +81,46 @@ func RecordLog(userId int, logType int, content string) {
 	}
 }
 
+// RecordRefundLog 记录退款，与充值日志一样记录额度，metadata 关联退款的来源
+func RecordRefundLog(userId int, quota int, content string, metadata map[string]any) {
+	username, _ := CacheGetUsername(userId)
+
+	log := &Log{
+		UserId:    userId,
+		Username:  username,
+		CreatedAt: utils.GetTimestamp(),
+		Type:      LogTypeRefund,
+		Content:   content,
+		Quota:     quota,
+		Metadata:  datatypes.NewJSONType(metadata),
+	}
+	err := DB.Create(log).Error
+	if err != nil {
+		logger.SysError("failed to record log: " + err.Error())
+	}
+}
+
+// RecordViolationLog 记录内容审查未通过的请求
+func RecordViolationLog(userId int, tokenName string, modelName string, sourceIp string, content string, metadata map[string]any) {
+	username, _ := CacheGetUsername(userId)
+
+	log := &Log{
+		UserId:    userId,
+		Username:  username,
+		CreatedAt: utils.GetTimestamp(),
+		Type:      LogTypeViolation,
+		Content:   content,
+		TokenName: tokenName,
+		ModelName: modelName,
+		SourceIp:  sourceIp,
+		Metadata:  datatypes.NewJSONType(metadata),
+	}
+	err := DB.Create(log).Error
+	if err != nil {
+		logger.SysError("failed to record log: " + err.Error())
+	}
+}
+
 func RecordConsumeLog(
 	ctx context.Context,
 	userId int,
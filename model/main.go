@@ -27,6 +27,7 @@ func SetupDB() {
 	}
 	ChannelGroup.Load()
 	GlobalUserGroupRatio.Load()
+	GlobalPromptTemplates.Load()
 	config.RootUserEmail = GetRootUserEmail()
 	NewModelOwnedBys()
 
@@ -128,6 +129,10 @@ func InitDB() (err error) {
 		if err != nil {
 			return err
 		}
+		err = db.AutoMigrate(&TokenBudgetUsage{})
+		if err != nil {
+			return err
+		}
 		err = db.AutoMigrate(&User{})
 		if err != nil {
 			return err
@@ -144,6 +149,26 @@ func InitDB() (err error) {
 		if err != nil {
 			return err
 		}
+		err = db.AutoMigrate(&PayloadArchive{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&ChannelReplay{}, &ChannelReplayResult{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&Batch{}, &BatchRequest{}, &BatchFile{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&StoredResponse{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&PromptTemplate{}, &PromptTemplateVersion{})
+		if err != nil {
+			return err
+		}
 		err = db.AutoMigrate(&TelegramMenu{})
 		if err != nil {
 			return err
@@ -161,11 +186,15 @@ func InitDB() (err error) {
 		if err != nil {
 			return err
 		}
-		err = db.AutoMigrate(&Order{})
+		err = db.AutoMigrate(&Order{}, &OrderRefund{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&SubscriptionPlan{}, &Subscription{})
 		if err != nil {
 			return err
 		}
-		err = db.AutoMigrate(&Task{})
+		err = db.AutoMigrate(&Task{}, &TaskWebhookDelivery{}, &TaskRefund{})
 		if err != nil {
 			return err
 		}
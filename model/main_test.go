@@ -0,0 +1,22 @@
+package model_test
+
+import (
+	"testing"
+
+	"one-api/common/config"
+	"one-api/model"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupTestDB 使用内存 SQLite 作为数据库，并关闭 Redis
+func setupTestDB(t *testing.T, models ...any) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(models...))
+	model.DB = db
+	config.RedisEnabled = false
+}
@@ -71,6 +71,7 @@ func CreateModelInfo(modelInfo *ModelInfo) error {
 	if err != nil {
 		return err
 	}
+	reloadModelInfoPricing()
 	return nil
 }
 
@@ -79,6 +80,7 @@ func UpdateModelInfo(modelInfo *ModelInfo) error {
 	if err != nil {
 		return err
 	}
+	reloadModelInfoPricing()
 	return nil
 }
 
@@ -114,9 +116,17 @@ func DeleteModelInfo(id int) error {
 	if err != nil {
 		return err
 	}
+	reloadModelInfoPricing()
 	return nil
 }
 
+// reloadModelInfoPricing 模型信息缓存在价格中，修改后重新加载，上下文管理依赖其中的上下文长度
+func reloadModelInfoPricing() {
+	if err := PricingInstance.Init(); err != nil {
+		logger.SysError("failed to reload pricing: " + err.Error())
+	}
+}
+
 func InitModelInfo() {
 	// Auto migrate logic is handled centrally usually, but if needed here:
 	err := DB.AutoMigrate(&ModelInfo{})
@@ -94,6 +94,7 @@ func InitOptionMap() {
 	config.GlobalOption.RegisterString("ChatImageRequestProxy", &config.ChatImageRequestProxy)
 	config.GlobalOption.RegisterFloat("PaymentUSDRate", &config.PaymentUSDRate)
 	config.GlobalOption.RegisterInt("PaymentMinAmount", &config.PaymentMinAmount)
+	config.GlobalOption.RegisterString("PaymentRefundQuotaPolicy", &config.PaymentRefundQuotaPolicy)
 
 	config.GlobalOption.RegisterCustom("RechargeDiscount", func() string {
 		return common.RechargeDiscount2JSONString()
@@ -120,8 +121,75 @@ func InitOptionMap() {
 
 	config.GlobalOption.RegisterInt("RetryTimeOut", &config.RetryTimeOut)
 
+	config.GlobalOption.RegisterBool("ChatCacheEnabled", &config.ChatCacheEnabled)
+	config.GlobalOption.RegisterInt("ChatCacheExpireMinute", &config.ChatCacheExpireMinute)
+	config.GlobalOption.RegisterFloat("ChatCacheBillingRatio", &config.ChatCacheBillingRatio)
+	config.GlobalOption.RegisterInt("ChatCacheMaxEntries", &config.ChatCacheMaxEntries)
+
+	config.GlobalOption.RegisterBool("CircuitBreakerEnabled", &config.CircuitBreakerEnabled)
+	config.GlobalOption.RegisterInt("CircuitBreakerWindowSeconds", &config.CircuitBreakerWindowSeconds)
+	config.GlobalOption.RegisterInt("CircuitBreakerMinRequests", &config.CircuitBreakerMinRequests)
+	config.GlobalOption.RegisterFloat("CircuitBreakerFailureRate", &config.CircuitBreakerFailureRate)
+	config.GlobalOption.RegisterInt("CircuitBreakerOpenSeconds", &config.CircuitBreakerOpenSeconds)
+	config.GlobalOption.RegisterFloat("CircuitBreakerHalfOpenRatio", &config.CircuitBreakerHalfOpenRatio)
+	config.GlobalOption.RegisterInt("CircuitBreakerHalfOpenSuccesses", &config.CircuitBreakerHalfOpenSuccesses)
+
+	config.GlobalOption.RegisterBool("HedgeEnabled", &config.HedgeEnabled)
+	config.GlobalOption.RegisterInt("HedgeDelayMilliseconds", &config.HedgeDelayMilliseconds)
+
+	config.GlobalOption.RegisterString("ArchiveStorage", &config.ArchiveStorage)
+	config.GlobalOption.RegisterInt("ArchiveRetentionDays", &config.ArchiveRetentionDays)
+	config.GlobalOption.RegisterInt("ArchiveMaxBodySize", &config.ArchiveMaxBodySize)
+	config.GlobalOption.RegisterString("ArchiveRedactKeys", &config.ArchiveRedactKeys)
+	config.GlobalOption.RegisterCustom("ArchiveRedactPatterns", func() string {
+		return strings.Join(config.ArchiveRedactPatterns, "\n")
+	}, func(value string) error {
+		config.ArchiveRedactPatterns = strings.Split(value, "\n")
+		return nil
+	}, "")
+
+	config.GlobalOption.RegisterString("BatchStorage", &config.BatchStorage)
+	config.GlobalOption.RegisterFloat("BatchDiscount", &config.BatchDiscount)
+	config.GlobalOption.RegisterInt("BatchConcurrency", &config.BatchConcurrency)
+	config.GlobalOption.RegisterInt("BatchMaxRequests", &config.BatchMaxRequests)
+	config.GlobalOption.RegisterInt("BatchMaxFileSize", &config.BatchMaxFileSize)
+	config.GlobalOption.RegisterInt("BatchFileRetentionDays", &config.BatchFileRetentionDays)
+
+	config.GlobalOption.RegisterInt("ResponsesRetentionDays", &config.ResponsesRetentionDays)
+	config.GlobalOption.RegisterInt("ResponsesMaxHistory", &config.ResponsesMaxHistory)
+
+	config.GlobalOption.RegisterBool("EmbeddingCacheEnabled", &config.EmbeddingCacheEnabled)
+	config.GlobalOption.RegisterString("EmbeddingCacheStore", &config.EmbeddingCacheStore)
+	config.GlobalOption.RegisterInt("EmbeddingCacheExpireHours", &config.EmbeddingCacheExpireHours)
+	config.GlobalOption.RegisterInt("EmbeddingCacheMaxSize", &config.EmbeddingCacheMaxSize)
+
+	config.GlobalOption.RegisterInt("RealtimeMaxSessions", &config.RealtimeMaxSessions)
+	config.GlobalOption.RegisterInt("RealtimeMaxSessionMinutes", &config.RealtimeMaxSessionMinutes)
+
+	config.GlobalOption.RegisterInt("ContextSummaryMaxTokens", &config.ContextSummaryMaxTokens)
+
+	config.GlobalOption.RegisterInt("TaskWebhookMaxAttempts", &config.TaskWebhookMaxAttempts)
+	config.GlobalOption.RegisterInt("TaskWebhookRetryInterval", &config.TaskWebhookRetryInterval)
+	config.GlobalOption.RegisterInt("TaskWebhookTimeout", &config.TaskWebhookTimeout)
+
+	config.GlobalOption.RegisterBool("VideoTaskRehost", &config.VideoTaskRehost)
+	config.GlobalOption.RegisterInt("VideoTaskMaxSeconds", &config.VideoTaskMaxSeconds)
+	config.GlobalOption.RegisterInt("VideoTaskMaxDownloadMB", &config.VideoTaskMaxDownloadMB)
+
+	config.GlobalOption.RegisterInt("TaskTimeoutMinutes", &config.TaskTimeoutMinutes)
+	config.GlobalOption.RegisterFloat("TaskTimeoutRefundRatio", &config.TaskTimeoutRefundRatio)
+
+	config.GlobalOption.RegisterInt("SubscriptionRenewGraceHours", &config.SubscriptionRenewGraceHours)
+
 	config.GlobalOption.RegisterBool("EnableSafe", &config.EnableSafe)
 	config.GlobalOption.RegisterString("SafeToolName", &config.SafeToolName)
+	config.GlobalOption.RegisterBool("SafeCheckOutput", &config.SafeCheckOutput)
+	config.GlobalOption.RegisterInt("SafeStreamWindowSize", &config.SafeStreamWindowSize)
+	config.GlobalOption.RegisterInt("SafeModerationChannelId", &config.SafeModerationChannelId)
+	config.GlobalOption.RegisterString("SafeModerationModel", &config.SafeModerationModel)
+	config.GlobalOption.RegisterString("SafeWebhookURL", &config.SafeWebhookURL)
+	config.GlobalOption.RegisterString("SafeWebhookToken", &config.SafeWebhookToken)
+	config.GlobalOption.RegisterInt("SafeWebhookTimeout", &config.SafeWebhookTimeout)
 	config.GlobalOption.RegisterCustom("SafeKeyWords", func() string {
 		return strings.Join(config.SafeKeyWords, "\n")
 	}, func(value string) error {
@@ -13,24 +13,30 @@ const (
 	OrderStatusSuccess OrderStatus = "success"
 	OrderStatusFailed  OrderStatus = "failed"
 	OrderStatusClosed  OrderStatus = "closed"
+	// 已原路退款，额度已扣回
+	OrderStatusRefunded OrderStatus = "refunded"
+	// 用户向发卡行发起拒付，额度已扣回
+	OrderStatusDisputed OrderStatus = "disputed"
 )
 
 type Order struct {
-	ID            int            `json:"id"`
-	UserId        int            `json:"user_id"`
-	GatewayId     int            `json:"gateway_id"`
-	TradeNo       string         `json:"trade_no" gorm:"type:varchar(50);uniqueIndex"`
-	GatewayNo     string         `json:"gateway_no" gorm:"type:varchar(100)"`
-	Amount        int            `json:"amount" gorm:"default:0"`
-	OrderAmount   float64        `json:"order_amount" gorm:"type:decimal(10,2);default:0"`
-	OrderCurrency CurrencyType   `json:"order_currency" gorm:"type:varchar(16)"`
-	Quota         int            `json:"quota" gorm:"type:int;default:0"`
-	Fee           float64        `json:"fee" gorm:"type:decimal(10,2);default:0"`
-	Discount      float64        `json:"discount" gorm:"type:decimal(10,2);default:0"`
-	Status        OrderStatus    `json:"status" gorm:"type:varchar(32)"`
-	CreatedAt     int            `json:"created_at"`
-	UpdatedAt     int            `json:"-"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID            int          `json:"id"`
+	UserId        int          `json:"user_id"`
+	GatewayId     int          `json:"gateway_id"`
+	TradeNo       string       `json:"trade_no" gorm:"type:varchar(50);uniqueIndex"`
+	GatewayNo     string       `json:"gateway_no" gorm:"type:varchar(100)"`
+	Amount        int          `json:"amount" gorm:"default:0"`
+	OrderAmount   float64      `json:"order_amount" gorm:"type:decimal(10,2);default:0"`
+	OrderCurrency CurrencyType `json:"order_currency" gorm:"type:varchar(16)"`
+	Quota         int          `json:"quota" gorm:"type:int;default:0"`
+	Fee           float64      `json:"fee" gorm:"type:decimal(10,2);default:0"`
+	Discount      float64      `json:"discount" gorm:"type:decimal(10,2);default:0"`
+	Status        OrderStatus  `json:"status" gorm:"type:varchar(32)"`
+	// 订阅套餐的订单，支付成功后激活或续期订阅而不是直接充值
+	SubscriptionId int            `json:"subscription_id" gorm:"default:0;index"`
+	CreatedAt      int            `json:"created_at"`
+	UpdatedAt      int            `json:"-"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // 查询并关闭未完成的订单
@@ -40,12 +46,24 @@ func CloseUnfinishedOrder() error {
 	return DB.Model(&Order{}).Where("status = ? AND created_at < ?", OrderStatusPending, unixTime).Update("status", OrderStatusClosed).Error
 }
 
+func GetOrderById(id int) (*Order, error) {
+	var order Order
+	err := DB.First(&order, id).Error
+	return &order, err
+}
+
 func GetOrderByTradeNo(tradeNo string) (*Order, error) {
 	var order Order
 	err := DB.Where("trade_no = ?", tradeNo).First(&order).Error
 	return &order, err
 }
 
+func GetOrderByGatewayNo(gatewayId int, gatewayNo string) (*Order, error) {
+	var order Order
+	err := DB.Where("gateway_id = ? AND gateway_no = ?", gatewayId, gatewayNo).First(&order).Error
+	return &order, err
+}
+
 func GetUserOrder(userId int, tradeNo string) (*Order, error) {
 	var order Order
 	err := DB.Where("user_id = ? AND trade_no = ?", userId, tradeNo).First(&order).Error
@@ -60,6 +78,17 @@ func (o *Order) Update() error {
 	return DB.Save(o).Error
 }
 
+// ChangeStatus 仅在订单处于 from 状态时修改，用于退款和拒付时抢占订单
+func (o *Order) ChangeStatus(from, to OrderStatus) (bool, error) {
+	result := DB.Model(&Order{}).Where("id = ? AND status = ?", o.ID, from).Update("status", to)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+
+	o.Status = to
+	return true, nil
+}
+
 var allowedOrderFields = map[string]bool{
 	"id":         true,
 	"gateway_id": true,
@@ -0,0 +1,216 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/redis"
+	"one-api/common/utils"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	OrderRefundTypeRefund  = "refund"
+	OrderRefundTypeDispute = "dispute"
+)
+
+const (
+	// 已创建记录，等待支付网关退款或扣回额度
+	OrderRefundStatusPending = "pending"
+	// 已扣回额度
+	OrderRefundStatusCompleted = "completed"
+)
+
+const (
+	PaymentRefundQuotaPolicyNegative = "negative"
+	PaymentRefundQuotaPolicyBlock    = "block"
+)
+
+// OrderRefund 订单的退款和拒付记录，每个订单只会扣回一次额度
+type OrderRefund struct {
+	Id      int    `json:"id"`
+	OrderId int    `json:"order_id" gorm:"uniqueIndex"`
+	TradeNo string `json:"trade_no" gorm:"type:varchar(50)"`
+	UserId  int    `json:"user_id" gorm:"index"`
+	Type    string `json:"type" gorm:"type:varchar(20)"`
+	Status  string `json:"status" gorm:"type:varchar(20);index"`
+	// 退款时为本站的退款编号，拒付时为支付网关的拒付编号
+	RefundNo        string       `json:"refund_no" gorm:"type:varchar(100)"`
+	GatewayRefundNo string       `json:"gateway_refund_no" gorm:"type:varchar(100)"`
+	Amount          float64      `json:"amount" gorm:"type:decimal(10,2);default:0"`
+	Currency        CurrencyType `json:"currency" gorm:"type:varchar(16)"`
+	Quota           int          `json:"quota"`          // 订单充值的额度
+	DeductedQuota   int          `json:"deducted_quota"` // 实际扣回的额度
+	Policy          string       `json:"policy" gorm:"type:varchar(20)"`
+	Blocked         bool         `json:"blocked"` // 余额不足时是否禁用了用户
+	Reason          string       `json:"reason" gorm:"type:varchar(255)"`
+	OperatorId      int          `json:"operator_id"` // 操作的管理员，拒付为 0
+	CreatedAt       int64        `json:"created_at" gorm:"bigint"`
+}
+
+// CreateOrderRefund 创建待处理的退款记录，订单已有记录时返回已有的记录，用于重试
+// 退款编号在重试时保持不变，支付网关按退款编号去重，不会重复退款
+func CreateOrderRefund(order *Order, refund *OrderRefund) (*OrderRefund, error) {
+	refund.OrderId = order.ID
+	refund.TradeNo = order.TradeNo
+	refund.UserId = order.UserId
+	refund.Quota = order.Quota
+	refund.Status = OrderRefundStatusPending
+	refund.CreatedAt = utils.GetTimestamp()
+
+	result := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(refund)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return refund, nil
+	}
+
+	return GetOrderRefundByOrderId(order.ID)
+}
+
+func GetOrderRefundByOrderId(orderId int) (*OrderRefund, error) {
+	var refund OrderRefund
+	err := DB.Where("order_id = ?", orderId).First(&refund).Error
+	return &refund, err
+}
+
+// SetGatewayRefundNo 支付网关退款成功后保存网关的退款编号，重试时不再调用网关
+func (refund *OrderRefund) SetGatewayRefundNo(gatewayRefundNo string) error {
+	refund.GatewayRefundNo = gatewayRefundNo
+	return DB.Model(refund).Update("gateway_refund_no", gatewayRefundNo).Error
+}
+
+// ClawbackOrderQuota 按策略扣回订单充值的额度并记录日志，返回是否扣回
+// 通过退款记录的状态保证只扣回一次，扣回失败时恢复为待处理以便重试
+func ClawbackOrderQuota(order *Order, refund *OrderRefund) (bool, error) {
+	refund.Policy = config.PaymentRefundQuotaPolicy
+	if refund.Policy != PaymentRefundQuotaPolicyBlock {
+		refund.Policy = PaymentRefundQuotaPolicyNegative
+	}
+
+	result := DB.Model(&OrderRefund{}).Where("id = ? AND status = ?", refund.Id, OrderRefundStatusPending).Updates(map[string]any{
+		"status": OrderRefundStatusCompleted,
+		"policy": refund.Policy,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	refund.Status = OrderRefundStatusCompleted
+
+	deducted := refund.Quota
+	if refund.Policy == PaymentRefundQuotaPolicyBlock {
+		balance, err := GetUserQuota(order.UserId)
+		if err != nil {
+			refund.resetPending()
+			return false, err
+		}
+		deducted = min(deducted, max(balance, 0))
+		refund.Blocked = deducted < refund.Quota
+	}
+
+	if deducted > 0 {
+		if err := ChangeUserQuota(order.UserId, -deducted, false); err != nil {
+			refund.resetPending()
+			return false, err
+		}
+	}
+	refund.DeductedQuota = deducted
+
+	if refund.Blocked {
+		refund.Blocked = disableUser(order.UserId)
+	}
+
+	DB.Model(refund).Select("deducted_quota", "blocked").Updates(refund)
+
+	// 订阅订单退款后订阅立即过期
+	if order.SubscriptionId > 0 {
+		if subscription, err := GetSubscriptionById(order.SubscriptionId); err == nil {
+			subscription.Expire()
+		}
+	}
+
+	RecordRefundLog(order.UserId, -deducted, refund.logContent(), map[string]any{
+		"order_refund_id": refund.Id,
+		"order_id":        order.ID,
+		"trade_no":        order.TradeNo,
+		"type":            refund.Type,
+		"refund_no":       refund.RefundNo,
+		"amount":          refund.Amount,
+		"currency":        refund.Currency,
+		"quota":           refund.Quota,
+		"policy":          refund.Policy,
+		"blocked":         refund.Blocked,
+		"operator_id":     refund.OperatorId,
+	})
+
+	return true, nil
+}
+
+func (refund *OrderRefund) resetPending() {
+	refund.Status = OrderRefundStatusPending
+	refund.Blocked = false
+	DB.Model(refund).Update("status", OrderRefundStatusPending)
+}
+
+func (refund *OrderRefund) logContent() string {
+	content := fmt.Sprintf("订单 %s 已退款 %.2f %s，扣回额度 %s", refund.TradeNo, refund.Amount, refund.Currency, common.LogQuota(refund.DeductedQuota))
+	if refund.Type == OrderRefundTypeDispute {
+		content = fmt.Sprintf("订单 %s 被拒付 %.2f %s，扣回额度 %s", refund.TradeNo, refund.Amount, refund.Currency, common.LogQuota(refund.DeductedQuota))
+	}
+	if refund.Blocked {
+		content += fmt.Sprintf("，余额不足 %s，用户已禁用", common.LogQuota(refund.Quota-refund.DeductedQuota))
+	}
+	if refund.Reason != "" {
+		content += "，原因: " + refund.Reason
+	}
+	return content
+}
+
+// disableUser 禁用用户，超级管理员不会被禁用
+func disableUser(userId int) bool {
+	result := DB.Model(&User{}).Where("id = ? AND role != ?", userId, config.RoleRootUser).Update("status", config.UserStatusDisabled)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+
+	if config.RedisEnabled {
+		redis.RedisDel(fmt.Sprintf(UserEnabledCacheKey, userId))
+	}
+	return true
+}
+
+var allowedOrderRefundOrderFields = map[string]bool{
+	"id":         true,
+	"order_id":   true,
+	"user_id":    true,
+	"created_at": true,
+}
+
+type SearchOrderRefundParams struct {
+	UserId  int    `form:"user_id"`
+	OrderId int    `form:"order_id"`
+	Type    string `form:"type"`
+	PaginationParams
+}
+
+func GetOrderRefundList(params *SearchOrderRefundParams) (*DataResult[OrderRefund], error) {
+	var refunds []*OrderRefund
+	db := DB
+
+	if params.UserId != 0 {
+		db = db.Where("user_id = ?", params.UserId)
+	}
+	if params.OrderId != 0 {
+		db = db.Where("order_id = ?", params.OrderId)
+	}
+	if params.Type != "" {
+		db = db.Where("type = ?", params.Type)
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &refunds, allowedOrderRefundOrderFields)
+}
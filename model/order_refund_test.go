@@ -0,0 +1,59 @@
+package model_test
+
+import (
+	"testing"
+
+	"one-api/common/config"
+	"one-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClawbackOrderQuota(t *testing.T) {
+	setupTestDB(t, &model.User{}, &model.Order{}, &model.OrderRefund{}, &model.Log{})
+	config.PaymentRefundQuotaPolicy = model.PaymentRefundQuotaPolicyBlock
+
+	user := &model.User{Username: "refund", Quota: 1000, Status: config.UserStatusEnabled, Role: config.RoleCommonUser}
+	require.NoError(t, model.DB.Create(user).Error)
+
+	order := &model.Order{UserId: user.Id, TradeNo: "T1", Quota: 600, Status: model.OrderStatusSuccess}
+	require.NoError(t, order.Insert())
+
+	refund, err := model.CreateOrderRefund(order, &model.OrderRefund{Type: model.OrderRefundTypeRefund, RefundNo: "R1"})
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderRefundStatusPending, refund.Status)
+
+	// 重试时沿用第一次的退款编号
+	retry, err := model.CreateOrderRefund(order, &model.OrderRefund{Type: model.OrderRefundTypeRefund, RefundNo: "R2"})
+	require.NoError(t, err)
+	assert.Equal(t, refund.Id, retry.Id)
+	assert.Equal(t, "R1", retry.RefundNo)
+
+	ok, err := model.ClawbackOrderQuota(order, retry)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = model.ClawbackOrderQuota(order, refund)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	quota, _ := model.GetUserQuota(user.Id)
+	assert.Equal(t, 400, quota)
+
+	// 余额不足时最多扣到零并禁用用户
+	order2 := &model.Order{UserId: user.Id, TradeNo: "T2", Quota: 800, Status: model.OrderStatusSuccess}
+	require.NoError(t, order2.Insert())
+	refund2, err := model.CreateOrderRefund(order2, &model.OrderRefund{Type: model.OrderRefundTypeDispute, RefundNo: "dp_1"})
+	require.NoError(t, err)
+	ok, err = model.ClawbackOrderQuota(order2, refund2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 400, refund2.DeductedQuota)
+	assert.True(t, refund2.Blocked)
+
+	quota, _ = model.GetUserQuota(user.Id)
+	assert.Equal(t, 0, quota)
+	enabled, _ := model.IsUserEnabled(user.Id)
+	assert.False(t, enabled)
+}
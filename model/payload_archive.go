@@ -0,0 +1,97 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/storage"
+	"one-api/common/utils"
+	"time"
+)
+
+// 每次清理处理的归档数量
+const payloadArchiveCleanBatch = 500
+
+// PayloadArchive 请求和响应内容的归档记录，内容保存在存储中，日志通过 metadata.archive_id 关联
+type PayloadArchive struct {
+	Id         string `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserId     int    `json:"user_id" gorm:"index"`
+	TokenId    int    `json:"token_id" gorm:"index"`
+	ChannelId  int    `json:"channel_id"`
+	ModelName  string `json:"model_name" gorm:"type:varchar(255);default:''"`
+	Path       string `json:"path" gorm:"type:varchar(255);default:''"`
+	StatusCode int    `json:"status_code"`
+	Storage    string `json:"storage" gorm:"type:varchar(32)"`
+	ObjectKey  string `json:"object_key" gorm:"type:varchar(255)"`
+	Size       int    `json:"size"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+// SavePayloadArchive 将归档内容写入存储并记录
+func SavePayloadArchive(archive *PayloadArchive, data []byte) error {
+	archive.Storage = config.ArchiveStorage
+	archive.ObjectKey = fmt.Sprintf("archives/%s/%s.json", time.Now().Format("2006-01-02"), archive.Id)
+	archive.Size = len(data)
+	archive.CreatedAt = utils.GetTimestamp()
+
+	if err := storage.PutArchive(archive.Storage, archive.ObjectKey, data); err != nil {
+		return err
+	}
+
+	return DB.Create(archive).Error
+}
+
+func GetPayloadArchiveById(id string) (*PayloadArchive, error) {
+	var archive PayloadArchive
+	err := DB.Where("id = ?", id).First(&archive).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &archive, nil
+}
+
+// GetContent 从存储中读取归档内容
+func (a *PayloadArchive) GetContent() ([]byte, error) {
+	return storage.GetArchive(a.Storage, a.ObjectKey)
+}
+
+// DeleteExpiredPayloadArchives 删除超过保留天数的归档
+func DeleteExpiredPayloadArchives() (int64, error) {
+	if config.ArchiveRetentionDays <= 0 {
+		return 0, nil
+	}
+
+	targetTimestamp := time.Now().AddDate(0, 0, -config.ArchiveRetentionDays).Unix()
+
+	var deleted int64
+	for {
+		var archives []*PayloadArchive
+		err := DB.Where("created_at < ?", targetTimestamp).Order("created_at").Limit(payloadArchiveCleanBatch).Find(&archives).Error
+		if err != nil {
+			return deleted, err
+		}
+		if len(archives) == 0 {
+			return deleted, nil
+		}
+
+		ids := make([]string, 0, len(archives))
+		for _, archive := range archives {
+			if err := storage.DeleteArchive(archive.Storage, archive.ObjectKey); err != nil {
+				// 存储中删除失败时也删除记录，避免一直重试
+				logger.SysError(fmt.Sprintf("failed to delete archive %s: %s", archive.Id, err.Error()))
+			}
+			ids = append(ids, archive.Id)
+		}
+
+		result := DB.Where("id IN ?", ids).Delete(&PayloadArchive{})
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+
+		if len(archives) < payloadArchiveCleanBatch {
+			return deleted, nil
+		}
+	}
+}
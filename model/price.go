@@ -11,6 +11,7 @@ import (
 const (
 	TokensPriceType    = "tokens"
 	TimesPriceType     = "times"
+	SecondsPriceType   = "seconds" // 按秒计费，用于视频等异步任务，价格为每秒的价格
 	DefaultPrice       = 30.0
 	DollarRate         = 0.002
 	RMBRate            = 0.014
@@ -113,13 +114,18 @@ func (price *Price) GetInput() float64 {
 }
 
 func (price *Price) GetOutput() float64 {
-	if price.Output <= 0 || price.Type == TimesPriceType {
+	if price.Output <= 0 || price.IsUnitPrice() {
 		return 0
 	}
 
 	return price.Output
 }
 
+// IsUnitPrice 按次或按秒计费，不按 token 计算
+func (price *Price) IsUnitPrice() bool {
+	return price.Type == TimesPriceType || price.Type == SecondsPriceType
+}
+
 func (price *Price) GetExtraRatio(key string) float64 {
 	if price.ExtraRatios != nil {
 		extraRatios := price.ExtraRatios.Data()
@@ -0,0 +1,261 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// PromptTemplate 管理员维护的系统提示词模板，由令牌或分组引用后注入对话请求
+// 每次修改内容都会生成新版本，引用时可以固定版本
+type PromptTemplate struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name" gorm:"type:varchar(100);uniqueIndex"`
+	Description string `json:"description" gorm:"type:varchar(255);default:''"`
+	Content     string `json:"content" gorm:"type:text"`
+	Version     int    `json:"version" gorm:"default:1"` // 当前版本
+	Enabled     bool   `json:"enabled" gorm:"default:true"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt   int64  `json:"updated_at" gorm:"bigint"`
+}
+
+type PromptTemplateVersion struct {
+	Id         int    `json:"id"`
+	TemplateId int    `json:"template_id" gorm:"uniqueIndex:idx_prompt_template_version"`
+	Version    int    `json:"version" gorm:"uniqueIndex:idx_prompt_template_version"`
+	Content    string `json:"content" gorm:"type:text"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint"`
+}
+
+type SearchPromptTemplateParams struct {
+	Name string `form:"name"`
+	PaginationParams
+}
+
+var allowedPromptTemplateOrderFields = map[string]bool{
+	"id":         true,
+	"name":       true,
+	"updated_at": true,
+}
+
+func GetPromptTemplatesList(params *SearchPromptTemplateParams) (*DataResult[PromptTemplate], error) {
+	var templates []*PromptTemplate
+	db := DB
+
+	if params.Name != "" {
+		db = db.Where("name LIKE ?", params.Name+"%")
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &templates, allowedPromptTemplateOrderFields)
+}
+
+func GetPromptTemplateById(id int) (*PromptTemplate, error) {
+	var template PromptTemplate
+	err := DB.Where("id = ?", id).First(&template).Error
+	return &template, err
+}
+
+func GetPromptTemplateVersions(templateId int) ([]*PromptTemplateVersion, error) {
+	var versions []*PromptTemplateVersion
+	err := DB.Where("template_id = ?", templateId).Order("version desc").Find(&versions).Error
+	return versions, err
+}
+
+func (t *PromptTemplate) Insert() error {
+	now := utils.GetTimestamp()
+	t.Version = 1
+	t.CreatedAt = now
+	t.UpdatedAt = now
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&PromptTemplateVersion{
+			TemplateId: t.Id,
+			Version:    t.Version,
+			Content:    t.Content,
+			CreatedAt:  now,
+		}).Error
+	})
+	if err == nil {
+		GlobalPromptTemplates.Load()
+	}
+
+	return err
+}
+
+// Update 内容变化时生成新版本，其余字段直接更新
+func (t *PromptTemplate) Update() error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var current PromptTemplate
+		if err := tx.Where("id = ?", t.Id).First(&current).Error; err != nil {
+			return err
+		}
+
+		t.Version = current.Version
+		t.CreatedAt = current.CreatedAt
+		t.UpdatedAt = utils.GetTimestamp()
+		if t.Content != current.Content {
+			t.Version++
+			err := tx.Create(&PromptTemplateVersion{
+				TemplateId: t.Id,
+				Version:    t.Version,
+				Content:    t.Content,
+				CreatedAt:  t.UpdatedAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Select("name", "description", "content", "version", "enabled", "updated_at").Updates(t).Error
+	})
+	if err == nil {
+		GlobalPromptTemplates.Load()
+	}
+
+	return err
+}
+
+// RollbackPromptTemplate 将历史版本的内容发布为新版本
+func RollbackPromptTemplate(id int, version int) (*PromptTemplate, error) {
+	var history PromptTemplateVersion
+	if err := DB.Where("template_id = ? AND version = ?", id, version).First(&history).Error; err != nil {
+		return nil, err
+	}
+
+	template, err := GetPromptTemplateById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Content = history.Content
+	if err := template.Update(); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func DeletePromptTemplate(id int) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", id).Delete(&PromptTemplateVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&PromptTemplate{}, id).Error
+	})
+	if err == nil {
+		GlobalPromptTemplates.Load()
+	}
+
+	return err
+}
+
+// PromptTemplateRef 令牌或分组对模板的引用，Version 为 0 时使用最新版本
+type PromptTemplateRef struct {
+	TemplateId int `json:"template_id"`
+	Version    int `json:"version"`
+}
+
+// RenderedPromptTemplate 渲染后的模板内容
+type RenderedPromptTemplate struct {
+	Name    string
+	Version int
+	Content string
+}
+
+// String 用于日志，格式为 名称@版本
+func (r *RenderedPromptTemplate) String() string {
+	return fmt.Sprintf("%s@%d", r.Name, r.Version)
+}
+
+// PromptTemplates 启用的模板及其所有版本，数量很少，全部缓存在内存中
+type PromptTemplates struct {
+	sync.RWMutex
+	templates map[int]*PromptTemplate
+	versions  map[int]map[int]string
+}
+
+var GlobalPromptTemplates = &PromptTemplates{}
+
+func (p *PromptTemplates) Load() {
+	var templates []*PromptTemplate
+	if err := DB.Where("enabled = ?", true).Find(&templates).Error; err != nil {
+		logger.SysError("failed to load prompt templates: " + err.Error())
+		return
+	}
+
+	newTemplates := make(map[int]*PromptTemplate, len(templates))
+	newVersions := make(map[int]map[int]string, len(templates))
+	ids := make([]int, 0, len(templates))
+	for _, template := range templates {
+		newTemplates[template.Id] = template
+		newVersions[template.Id] = make(map[int]string)
+		ids = append(ids, template.Id)
+	}
+
+	if len(ids) > 0 {
+		var versions []*PromptTemplateVersion
+		if err := DB.Where("template_id IN ?", ids).Find(&versions).Error; err != nil {
+			logger.SysError("failed to load prompt template versions: " + err.Error())
+			return
+		}
+		for _, version := range versions {
+			newVersions[version.TemplateId][version.Version] = version.Content
+		}
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	p.templates = newTemplates
+	p.versions = newVersions
+}
+
+// Render 渲染引用的模板，模板不存在、已停用或版本不存在时返回错误
+func (p *PromptTemplates) Render(ref *PromptTemplateRef, vars map[string]string) (*RenderedPromptTemplate, error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	template, ok := p.templates[ref.TemplateId]
+	if !ok {
+		return nil, fmt.Errorf("prompt template %d not found", ref.TemplateId)
+	}
+
+	version := ref.Version
+	if version <= 0 {
+		version = template.Version
+	}
+
+	content, ok := p.versions[template.Id][version]
+	if !ok {
+		return nil, errors.New("prompt template version not found")
+	}
+
+	return &RenderedPromptTemplate{
+		Name:    template.Name,
+		Version: version,
+		Content: RenderPromptTemplate(content, vars),
+	}, nil
+}
+
+// RenderPromptTemplate 替换 {{name}} 形式的变量，未知变量保持原样
+func RenderPromptTemplate(content string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(content, "{{") {
+		return content
+	}
+
+	pairs := make([]string, 0, len(vars)*2)
+	for key, value := range vars {
+		pairs = append(pairs, "{{"+key+"}}", value)
+	}
+
+	return strings.NewReplacer(pairs...).Replace(content)
+}
@@ -0,0 +1,20 @@
+package model_test
+
+import (
+	"testing"
+
+	"one-api/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderPromptTemplate(t *testing.T) {
+	content := "Hello {{user_name}}, today is {{date}}. {{unknown}}"
+	rendered := model.RenderPromptTemplate(content, map[string]string{
+		"user_name": "alice",
+		"date":      "2024-01-02",
+	})
+	assert.Equal(t, "Hello alice, today is 2024-01-02. {{unknown}}", rendered)
+
+	assert.Equal(t, content, model.RenderPromptTemplate(content, nil))
+}
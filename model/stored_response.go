@@ -0,0 +1,68 @@
+package model
+
+import (
+	"errors"
+	"one-api/common/config"
+	"one-api/common/utils"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var ErrStoredResponseNotFound = errors.New("response not found")
+
+// StoredResponse Responses API 兼容模式下网关保存的响应
+// Input 只包含本次请求的输入项，完整的对话通过 PreviousResponseId 向前追溯
+type StoredResponse struct {
+	Id                 string         `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserId             int            `json:"user_id" gorm:"index"`
+	TokenId            int            `json:"token_id"`
+	PreviousResponseId string         `json:"previous_response_id" gorm:"type:varchar(64);default:''"`
+	Model              string         `json:"model" gorm:"type:varchar(255);default:''"`
+	Input              datatypes.JSON `json:"input" gorm:"type:json"`
+	Response           datatypes.JSON `json:"response" gorm:"type:json"`
+	CreatedAt          int64          `json:"created_at" gorm:"bigint;index"`
+}
+
+func (r *StoredResponse) Insert() error {
+	r.CreatedAt = utils.GetTimestamp()
+	return DB.Create(r).Error
+}
+
+func GetStoredResponse(userId int, id string) (*StoredResponse, error) {
+	var response StoredResponse
+	err := DB.Where("id = ? and user_id = ?", id, userId).First(&response).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrStoredResponseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func DeleteStoredResponse(userId int, id string) error {
+	result := DB.Where("id = ? and user_id = ?", id, userId).Delete(&StoredResponse{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStoredResponseNotFound
+	}
+
+	return nil
+}
+
+// DeleteExpiredStoredResponses 删除超过保留天数的响应
+func DeleteExpiredStoredResponses() (int64, error) {
+	if config.ResponsesRetentionDays <= 0 {
+		return 0, nil
+	}
+
+	targetTimestamp := time.Now().AddDate(0, 0, -config.ResponsesRetentionDays).Unix()
+	result := DB.Where("created_at < ?", targetTimestamp).Delete(&StoredResponse{})
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,60 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"one-api/common/config"
+	"one-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoredResponseUserScope(t *testing.T) {
+	setupTestDB(t, &model.StoredResponse{})
+
+	stored := &model.StoredResponse{Id: "resp_1", UserId: 1, Input: []byte("[]"), Response: []byte("{}")}
+	require.NoError(t, stored.Insert())
+
+	response, err := model.GetStoredResponse(1, "resp_1")
+	require.NoError(t, err)
+	assert.Equal(t, "resp_1", response.Id)
+	assert.NotZero(t, response.CreatedAt)
+
+	// 其他用户的响应不可见，也不能删除
+	_, err = model.GetStoredResponse(2, "resp_1")
+	assert.ErrorIs(t, err, model.ErrStoredResponseNotFound)
+	assert.ErrorIs(t, model.DeleteStoredResponse(2, "resp_1"), model.ErrStoredResponseNotFound)
+
+	require.NoError(t, model.DeleteStoredResponse(1, "resp_1"))
+	_, err = model.GetStoredResponse(1, "resp_1")
+	assert.ErrorIs(t, err, model.ErrStoredResponseNotFound)
+	assert.ErrorIs(t, model.DeleteStoredResponse(1, "resp_1"), model.ErrStoredResponseNotFound)
+}
+
+func TestDeleteExpiredStoredResponses(t *testing.T) {
+	setupTestDB(t, &model.StoredResponse{})
+
+	originalRetentionDays := config.ResponsesRetentionDays
+	t.Cleanup(func() { config.ResponsesRetentionDays = originalRetentionDays })
+
+	require.NoError(t, (&model.StoredResponse{Id: "resp_new", UserId: 1}).Insert())
+	require.NoError(t, model.DB.Create(&model.StoredResponse{Id: "resp_old", UserId: 1, CreatedAt: time.Now().AddDate(0, 0, -31).Unix()}).Error)
+
+	// 永久保留时不删除
+	config.ResponsesRetentionDays = 0
+	deleted, err := model.DeleteExpiredStoredResponses()
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	config.ResponsesRetentionDays = 30
+	deleted, err = model.DeleteExpiredStoredResponses()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	_, err = model.GetStoredResponse(1, "resp_old")
+	assert.ErrorIs(t, err, model.ErrStoredResponseNotFound)
+	_, err = model.GetStoredResponse(1, "resp_new")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,494 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"one-api/common/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SubscriptionInterval string
+
+const (
+	SubscriptionIntervalDay   SubscriptionInterval = "day"
+	SubscriptionIntervalWeek  SubscriptionInterval = "week"
+	SubscriptionIntervalMonth SubscriptionInterval = "month"
+	SubscriptionIntervalYear  SubscriptionInterval = "year"
+)
+
+func (i SubscriptionInterval) IsValid() bool {
+	switch i {
+	case SubscriptionIntervalDay, SubscriptionIntervalWeek, SubscriptionIntervalMonth, SubscriptionIntervalYear:
+		return true
+	}
+	return false
+}
+
+type SubscriptionStatus string
+
+const (
+	// 已下单，等待首次支付
+	SubscriptionStatusPending SubscriptionStatus = "pending"
+	SubscriptionStatusActive  SubscriptionStatus = "active"
+	// 已取消自动续费，当前周期结束后过期
+	SubscriptionStatusCanceled SubscriptionStatus = "canceled"
+	SubscriptionStatusExpired  SubscriptionStatus = "expired"
+)
+
+// SubscriptionPlan 订阅套餐，价格以美元计，下单时按支付网关的币种换算
+type SubscriptionPlan struct {
+	Id            int                  `json:"id"`
+	Name          string               `json:"name" form:"name" gorm:"type:varchar(100);not null"`
+	Description   string               `json:"description" form:"description" gorm:"type:varchar(500)"`
+	Price         float64              `json:"price" form:"price" gorm:"type:decimal(10,2);default:0"`
+	Quota         int                  `json:"quota" form:"quota" gorm:"default:0"` // 每个周期发放的额度
+	Group         string               `json:"group" form:"group" gorm:"type:varchar(32);default:''"`
+	Interval      SubscriptionInterval `json:"interval" form:"interval" gorm:"type:varchar(10);default:'month'"`
+	IntervalCount int                  `json:"interval_count" form:"interval_count" gorm:"default:1"`
+	Sort          int                  `json:"sort" form:"sort" gorm:"default:1"`
+	Enable        *bool                `json:"enable" form:"enable" gorm:"default:true"`
+	CreatedAt     int64                `json:"created_at" gorm:"bigint"`
+	UpdatedAt     int64                `json:"-" gorm:"bigint"`
+	DeletedAt     gorm.DeletedAt       `json:"-" gorm:"index"`
+}
+
+// Subscription 用户订阅，下单时复制套餐内容，修改套餐不影响已有订阅
+type Subscription struct {
+	Id            int                  `json:"id"`
+	UserId        int                  `json:"user_id" gorm:"index"`
+	PlanId        int                  `json:"plan_id" gorm:"index"`
+	PlanName      string               `json:"plan_name" gorm:"type:varchar(100)"`
+	Quota         int                  `json:"quota"`
+	Group         string               `json:"group" gorm:"type:varchar(32)"`
+	PreviousGroup string               `json:"previous_group" gorm:"type:varchar(32)"` // 订阅生效前的分组，过期后恢复
+	Interval      SubscriptionInterval `json:"interval" gorm:"type:varchar(10)"`
+	IntervalCount int                  `json:"interval_count"`
+	Status        SubscriptionStatus   `json:"status" gorm:"type:varchar(20);index"`
+	GatewayId     int                  `json:"gateway_id"`
+	// 支付网关的周期扣款编号，不支持自动续费的网关为空
+	GatewaySubscriptionId string `json:"gateway_subscription_id" gorm:"type:varchar(100);index"`
+	AutoRenew             bool   `json:"auto_renew"`
+	CurrentPeriodStart    int64  `json:"current_period_start" gorm:"bigint"`
+	CurrentPeriodEnd      int64  `json:"current_period_end" gorm:"bigint;index"`
+	PaidUntil             int64  `json:"paid_until" gorm:"bigint"` // 已支付的截止时间，超过当前周期时由定时任务续期
+	CanceledAt            int64  `json:"canceled_at" gorm:"bigint"`
+	ExpiredAt             int64  `json:"expired_at" gorm:"bigint"`
+	CreatedAt             int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt             int64  `json:"updated_at" gorm:"bigint"`
+}
+
+func GetSubscriptionPlanById(id int) (*SubscriptionPlan, error) {
+	var plan SubscriptionPlan
+	err := DB.First(&plan, id).Error
+	return &plan, err
+}
+
+var allowedSubscriptionPlanOrderFields = map[string]bool{
+	"id":         true,
+	"name":       true,
+	"price":      true,
+	"sort":       true,
+	"enable":     true,
+	"created_at": true,
+}
+
+type SearchSubscriptionPlanParams struct {
+	SubscriptionPlan
+	PaginationParams
+}
+
+func GetSubscriptionPlanList(params *SearchSubscriptionPlanParams) (*DataResult[SubscriptionPlan], error) {
+	var plans []*SubscriptionPlan
+	db := DB
+
+	if params.Name != "" {
+		db = db.Where("name LIKE ?", params.Name+"%")
+	}
+
+	if params.Enable != nil {
+		db = db.Where("enable = ?", *params.Enable)
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &plans, allowedSubscriptionPlanOrderFields)
+}
+
+func GetUserSubscriptionPlans() ([]*SubscriptionPlan, error) {
+	var plans []*SubscriptionPlan
+	err := DB.Where("enable = ?", true).Order("sort desc, id").Find(&plans).Error
+	return plans, err
+}
+
+func (p *SubscriptionPlan) Validate() error {
+	if p.Name == "" {
+		return errors.New("套餐名称不能为空")
+	}
+	if p.Price <= 0 {
+		return errors.New("套餐价格必须大于 0")
+	}
+	if p.Quota < 0 {
+		return errors.New("套餐额度不能为负数")
+	}
+	if !p.Interval.IsValid() {
+		return errors.New("不支持的订阅周期")
+	}
+	if p.IntervalCount <= 0 {
+		p.IntervalCount = 1
+	}
+	if p.Group != "" && GlobalUserGroupRatio.GetBySymbol(p.Group) == nil {
+		return errors.New("用户分组不存在")
+	}
+	return nil
+}
+
+func (p *SubscriptionPlan) Insert() error {
+	return DB.Create(p).Error
+}
+
+func (p *SubscriptionPlan) Update() error {
+	return DB.Model(p).Select("name", "description", "price", "quota", "group", "interval", "interval_count", "sort", "enable").Updates(p).Error
+}
+
+func (p *SubscriptionPlan) Delete() error {
+	return DB.Delete(p).Error
+}
+
+// NextPeriodEnd 计算从 start 开始 count 个周期后的时间
+func NextPeriodEnd(start int64, interval SubscriptionInterval, count int) int64 {
+	count = max(count, 1)
+	t := time.Unix(start, 0)
+	switch interval {
+	case SubscriptionIntervalDay:
+		t = t.AddDate(0, 0, count)
+	case SubscriptionIntervalWeek:
+		t = t.AddDate(0, 0, 7*count)
+	case SubscriptionIntervalYear:
+		t = t.AddDate(count, 0, 0)
+	default:
+		t = t.AddDate(0, count, 0)
+	}
+	return t.Unix()
+}
+
+func (s *Subscription) nextPeriodEnd(start int64) int64 {
+	return NextPeriodEnd(start, s.Interval, s.IntervalCount)
+}
+
+// NewSubscription 按套餐创建待支付的订阅，支付成功后才会生效
+func NewSubscription(userId, gatewayId int, plan *SubscriptionPlan, autoRenew bool) (*Subscription, error) {
+	subscription := &Subscription{
+		UserId:        userId,
+		PlanId:        plan.Id,
+		PlanName:      plan.Name,
+		Quota:         plan.Quota,
+		Group:         plan.Group,
+		Interval:      plan.Interval,
+		IntervalCount: plan.IntervalCount,
+		Status:        SubscriptionStatusPending,
+		GatewayId:     gatewayId,
+		AutoRenew:     autoRenew,
+	}
+
+	err := DB.Create(subscription).Error
+	return subscription, err
+}
+
+func GetSubscriptionById(id int) (*Subscription, error) {
+	var subscription Subscription
+	err := DB.First(&subscription, id).Error
+	return &subscription, err
+}
+
+func GetUserSubscriptionById(userId, id int) (*Subscription, error) {
+	var subscription Subscription
+	err := DB.Where("user_id = ? AND id = ?", userId, id).First(&subscription).Error
+	return &subscription, err
+}
+
+func GetSubscriptionByGatewayNo(gatewayId int, gatewaySubscriptionId string) (*Subscription, error) {
+	var subscription Subscription
+	err := DB.Where("gateway_id = ? AND gateway_subscription_id = ?", gatewayId, gatewaySubscriptionId).First(&subscription).Error
+	return &subscription, err
+}
+
+// GetUserCurrentSubscription 获取用户生效中的订阅，没有时返回 nil
+func GetUserCurrentSubscription(userId int) (*Subscription, error) {
+	var subscriptions []*Subscription
+	err := DB.Where("user_id = ? AND status IN ?", userId, []SubscriptionStatus{SubscriptionStatusActive, SubscriptionStatusCanceled}).
+		Order("id desc").Limit(1).Find(&subscriptions).Error
+	if err != nil || len(subscriptions) == 0 {
+		return nil, err
+	}
+	return subscriptions[0], nil
+}
+
+func GetUserSubscriptions(userId int) ([]*Subscription, error) {
+	var subscriptions []*Subscription
+	err := DB.Where("user_id = ? AND status != ?", userId, SubscriptionStatusPending).Order("id desc").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+var allowedSubscriptionOrderFields = map[string]bool{
+	"id":                 true,
+	"user_id":            true,
+	"plan_id":            true,
+	"status":             true,
+	"current_period_end": true,
+	"created_at":         true,
+}
+
+type SearchSubscriptionParams struct {
+	UserId int    `form:"user_id"`
+	PlanId int    `form:"plan_id"`
+	Status string `form:"status"`
+	PaginationParams
+}
+
+func GetSubscriptionList(params *SearchSubscriptionParams) (*DataResult[Subscription], error) {
+	var subscriptions []*Subscription
+	db := DB
+
+	if params.UserId != 0 {
+		db = db.Where("user_id = ?", params.UserId)
+	}
+	if params.PlanId != 0 {
+		db = db.Where("plan_id = ?", params.PlanId)
+	}
+	if params.Status != "" {
+		db = db.Where("status = ?", params.Status)
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &subscriptions, allowedSubscriptionOrderFields)
+}
+
+// Paid 订阅支付成功：首次支付时开始第一个周期，之后的支付延长已支付的截止时间
+// paidUntil 为 0 时按一个周期计算，gatewaySubscriptionId 为支付网关的周期扣款编号
+func (s *Subscription) Paid(paidUntil int64, gatewaySubscriptionId string) error {
+	if s.Status == SubscriptionStatusPending {
+		return s.start(paidUntil, gatewaySubscriptionId)
+	}
+
+	if s.Status == SubscriptionStatusExpired {
+		return errors.New("订阅已过期")
+	}
+
+	if paidUntil == 0 {
+		paidUntil = s.nextPeriodEnd(max(s.PaidUntil, s.CurrentPeriodEnd))
+	}
+	if paidUntil <= s.PaidUntil {
+		return nil
+	}
+
+	return DB.Model(s).Updates(map[string]any{
+		"paid_until": paidUntil,
+		"updated_at": utils.GetTimestamp(),
+	}).Error
+}
+
+func (s *Subscription) start(paidUntil int64, gatewaySubscriptionId string) error {
+	now := utils.GetTimestamp()
+	periodEnd := s.nextPeriodEnd(now)
+
+	// 用户同时只能有一个生效的订阅，旧订阅立即过期
+	current, err := GetUserCurrentSubscription(s.UserId)
+	if err != nil {
+		return err
+	}
+
+	previousGroup, err := GetUserGroup(s.UserId)
+	if err != nil {
+		return err
+	}
+	// 重复的支付通知可能读到已经由上一次通知激活的自己，不能让它过期
+	if current != nil && current.Id != s.Id {
+		if err := current.Expire(); err != nil {
+			return err
+		}
+		// 旧订阅修改过分组时，恢复的应该是订阅之前的分组
+		if current.Group != "" && current.Group == previousGroup {
+			previousGroup = current.PreviousGroup
+		}
+	}
+
+	result := DB.Model(&Subscription{}).Where("id = ? AND status = ?", s.Id, SubscriptionStatusPending).Updates(map[string]any{
+		"status":                  SubscriptionStatusActive,
+		"gateway_subscription_id": gatewaySubscriptionId,
+		"previous_group":          previousGroup,
+		"current_period_start":    now,
+		"current_period_end":      periodEnd,
+		"paid_until":              max(paidUntil, periodEnd),
+		"updated_at":              now,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	s.Status = SubscriptionStatusActive
+	s.GatewaySubscriptionId = gatewaySubscriptionId
+	s.PreviousGroup = previousGroup
+	s.CurrentPeriodStart = now
+	s.CurrentPeriodEnd = periodEnd
+	s.PaidUntil = max(paidUntil, periodEnd)
+
+	return s.grant(fmt.Sprintf("订阅套餐 %s 生效", s.PlanName))
+}
+
+// grant 发放当前周期的额度并切换到套餐分组
+func (s *Subscription) grant(action string) error {
+	if s.Group != "" {
+		if err := setUserGroup(s.UserId, s.Group); err != nil {
+			return err
+		}
+	}
+
+	if s.Quota > 0 {
+		if err := IncreaseUserQuota(s.UserId, s.Quota); err != nil {
+			return err
+		}
+	}
+
+	RecordQuotaLog(s.UserId, LogTypeTopup, s.Quota, "", fmt.Sprintf("%s，发放额度 %s，有效期至 %s", action, common.LogQuota(s.Quota), time.Unix(s.CurrentPeriodEnd, 0).Format("2006-01-02 15:04:05")))
+	return nil
+}
+
+// Cancel 取消自动续费，已支付的周期结束后过期
+func (s *Subscription) Cancel() error {
+	if s.Status != SubscriptionStatusActive {
+		return nil
+	}
+
+	now := utils.GetTimestamp()
+	err := DB.Model(s).Updates(map[string]any{
+		"status":      SubscriptionStatusCanceled,
+		"auto_renew":  false,
+		"canceled_at": now,
+		"updated_at":  now,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	s.Status = SubscriptionStatusCanceled
+	s.AutoRenew = false
+	s.CanceledAt = now
+	return nil
+}
+
+// Expire 订阅过期，用户仍在套餐分组时恢复到订阅前的分组
+func (s *Subscription) Expire() error {
+	now := utils.GetTimestamp()
+	result := DB.Model(&Subscription{}).Where("id = ? AND status IN ?", s.Id, []SubscriptionStatus{SubscriptionStatusActive, SubscriptionStatusCanceled}).Updates(map[string]any{
+		"status":     SubscriptionStatusExpired,
+		"auto_renew": false,
+		"expired_at": now,
+		"updated_at": now,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	s.Status = SubscriptionStatusExpired
+	s.AutoRenew = false
+	s.ExpiredAt = now
+
+	if s.Group != "" {
+		group, err := GetUserGroup(s.UserId)
+		if err != nil {
+			return err
+		}
+		if group == s.Group {
+			previousGroup := s.PreviousGroup
+			if previousGroup == "" {
+				previousGroup = "default"
+			}
+			if err := setUserGroup(s.UserId, previousGroup); err != nil {
+				return err
+			}
+		}
+	}
+
+	RecordLog(s.UserId, LogTypeSystem, fmt.Sprintf("订阅套餐 %s 已过期", s.PlanName))
+	return nil
+}
+
+// renew 已支付的截止时间超过当前周期时进入下一个周期，通过当前周期的结束时间保证只续期一次
+func (s *Subscription) renew() (bool, error) {
+	periodStart := s.CurrentPeriodEnd
+	periodEnd := s.nextPeriodEnd(periodStart)
+
+	result := DB.Model(&Subscription{}).Where("id = ? AND current_period_end = ?", s.Id, s.CurrentPeriodEnd).Updates(map[string]any{
+		"current_period_start": periodStart,
+		"current_period_end":   periodEnd,
+		"updated_at":           utils.GetTimestamp(),
+	})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+
+	s.CurrentPeriodStart = periodStart
+	s.CurrentPeriodEnd = periodEnd
+	return true, s.grant(fmt.Sprintf("订阅套餐 %s 续期", s.PlanName))
+}
+
+// RenewDueSubscriptions 处理当前周期已结束的订阅：已支付下一个周期的续期，未支付的过期
+// 自动续费的订阅留出 SubscriptionRenewGraceHours 等待支付网关的扣款通知
+func RenewDueSubscriptions() (renewed, expired int) {
+	now := utils.GetTimestamp()
+	var subscriptions []*Subscription
+	err := DB.Where("status IN ? AND current_period_end <= ?", []SubscriptionStatus{SubscriptionStatusActive, SubscriptionStatusCanceled}, now).
+		Find(&subscriptions).Error
+	if err != nil {
+		logger.SysError("get due subscriptions error: " + err.Error())
+		return
+	}
+
+	grace := int64(max(config.SubscriptionRenewGraceHours, 0)) * 3600
+	for _, subscription := range subscriptions {
+		if subscription.PaidUntil > subscription.CurrentPeriodEnd {
+			ok, err := subscription.renew()
+			if err != nil {
+				logger.SysError(fmt.Sprintf("renew subscription %d error: %s", subscription.Id, err.Error()))
+				continue
+			}
+			if ok {
+				renewed++
+			}
+			continue
+		}
+
+		if subscription.Status == SubscriptionStatusActive && subscription.AutoRenew && now < subscription.CurrentPeriodEnd+grace {
+			continue
+		}
+
+		if err := subscription.Expire(); err != nil {
+			logger.SysError(fmt.Sprintf("expire subscription %d error: %s", subscription.Id, err.Error()))
+			continue
+		}
+		expired++
+	}
+
+	return
+}
+
+func setUserGroup(userId int, group string) error {
+	err := DB.Model(&User{}).Where("id = ?", userId).Update("group", group).Error
+	if err != nil {
+		return err
+	}
+
+	if config.RedisEnabled {
+		redis.RedisDel(fmt.Sprintf(UserGroupCacheKey, userId))
+	}
+	return nil
+}
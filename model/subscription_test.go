@@ -0,0 +1,212 @@
+package model_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"one-api/common/config"
+	"one-api/common/utils"
+	"one-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextPeriodEnd(t *testing.T) {
+	start := time.Date(2024, 1, 31, 8, 0, 0, 0, time.Local).Unix()
+
+	assert.Equal(t, time.Date(2024, 2, 1, 8, 0, 0, 0, time.Local).Unix(), model.NextPeriodEnd(start, model.SubscriptionIntervalDay, 1))
+	assert.Equal(t, time.Date(2024, 2, 14, 8, 0, 0, 0, time.Local).Unix(), model.NextPeriodEnd(start, model.SubscriptionIntervalWeek, 2))
+	assert.Equal(t, time.Date(2024, 3, 2, 8, 0, 0, 0, time.Local).Unix(), model.NextPeriodEnd(start, model.SubscriptionIntervalMonth, 1))
+	assert.Equal(t, time.Date(2025, 1, 31, 8, 0, 0, 0, time.Local).Unix(), model.NextPeriodEnd(start, model.SubscriptionIntervalYear, 0))
+}
+
+func TestSubscriptionIntervalIsValid(t *testing.T) {
+	assert.True(t, model.SubscriptionIntervalMonth.IsValid())
+	assert.False(t, model.SubscriptionInterval("hour").IsValid())
+}
+
+func setupSubscriptionTest(t *testing.T) {
+	setupTestDB(t, &model.User{}, &model.Log{}, &model.Subscription{})
+	config.BatchUpdateEnabled = false
+
+	originalGraceHours := config.SubscriptionRenewGraceHours
+	t.Cleanup(func() { config.SubscriptionRenewGraceHours = originalGraceHours })
+	config.SubscriptionRenewGraceHours = 24
+}
+
+func createSubscriptionUser(t *testing.T, id int, group string) {
+	username := fmt.Sprintf("user%d", id)
+	require.NoError(t, model.DB.Create(&model.User{Id: id, Username: username, AccessToken: username, AffCode: username, Group: group}).Error)
+}
+
+func getSubscriptionUser(t *testing.T, id int) *model.User {
+	var user model.User
+	require.NoError(t, model.DB.First(&user, id).Error)
+	return &user
+}
+
+func reloadSubscription(t *testing.T, id int) *model.Subscription {
+	subscription, err := model.GetSubscriptionById(id)
+	require.NoError(t, err)
+	return subscription
+}
+
+func newPaidSubscription(t *testing.T, userId int, group string, quota int, autoRenew bool) *model.Subscription {
+	plan := &model.SubscriptionPlan{Id: 1, Name: "plan-" + group, Quota: quota, Group: group, Interval: model.SubscriptionIntervalDay, IntervalCount: 1}
+	subscription, err := model.NewSubscription(userId, 1, plan, autoRenew)
+	require.NoError(t, err)
+	require.NoError(t, subscription.Paid(0, ""))
+	return subscription
+}
+
+func TestSubscriptionStart(t *testing.T) {
+	setupSubscriptionTest(t)
+	createSubscriptionUser(t, 1, "default")
+
+	plan := &model.SubscriptionPlan{Id: 1, Name: "vip", Quota: 100, Group: "vip", Interval: model.SubscriptionIntervalDay, IntervalCount: 1}
+	first, err := model.NewSubscription(1, 1, plan, true)
+	require.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusPending, first.Status)
+	stale := *first
+
+	// 首次支付开始第一个周期，发放额度并切换分组
+	now := utils.GetTimestamp()
+	require.NoError(t, first.Paid(0, "gw_1"))
+	stored := reloadSubscription(t, first.Id)
+	assert.Equal(t, model.SubscriptionStatusActive, stored.Status)
+	assert.Equal(t, "gw_1", stored.GatewaySubscriptionId)
+	assert.Equal(t, "default", stored.PreviousGroup)
+	assert.GreaterOrEqual(t, stored.CurrentPeriodStart, now)
+	assert.Equal(t, model.NextPeriodEnd(stored.CurrentPeriodStart, model.SubscriptionIntervalDay, 1), stored.CurrentPeriodEnd)
+	assert.Equal(t, stored.CurrentPeriodEnd, stored.PaidUntil)
+	assert.Equal(t, "vip", getSubscriptionUser(t, 1).Group)
+	assert.Equal(t, 100, getSubscriptionUser(t, 1).Quota)
+
+	// 重复的支付通知使用旧的待支付数据时不会重复发放，也不会让订阅过期
+	require.NoError(t, stale.Paid(0, "gw_1"))
+	assert.Equal(t, model.SubscriptionStatusActive, reloadSubscription(t, first.Id).Status)
+	assert.Equal(t, 100, getSubscriptionUser(t, 1).Quota)
+
+	// 订阅新套餐时旧订阅立即过期，恢复的分组仍然是订阅之前的分组
+	second := newPaidSubscription(t, 1, "svip", 200, false)
+	assert.Equal(t, model.SubscriptionStatusExpired, reloadSubscription(t, first.Id).Status)
+	stored = reloadSubscription(t, second.Id)
+	assert.Equal(t, model.SubscriptionStatusActive, stored.Status)
+	assert.Equal(t, "default", stored.PreviousGroup)
+	assert.Equal(t, "svip", getSubscriptionUser(t, 1).Group)
+	assert.Equal(t, 300, getSubscriptionUser(t, 1).Quota)
+
+	// 管理员修改过分组时，以修改后的分组作为订阅前的分组
+	require.NoError(t, model.DB.Model(&model.User{}).Where("id = ?", 1).Update("group", "partner").Error)
+	third := newPaidSubscription(t, 1, "vip", 0, false)
+	assert.Equal(t, model.SubscriptionStatusExpired, reloadSubscription(t, second.Id).Status)
+	assert.Equal(t, "partner", reloadSubscription(t, third.Id).PreviousGroup)
+	assert.Equal(t, "vip", getSubscriptionUser(t, 1).Group)
+
+	// 已过期的订阅不能再支付
+	expired := reloadSubscription(t, first.Id)
+	assert.Error(t, expired.Paid(0, ""))
+}
+
+func TestSubscriptionExpire(t *testing.T) {
+	setupSubscriptionTest(t)
+	createSubscriptionUser(t, 1, "partner")
+	createSubscriptionUser(t, 2, "partner")
+	createSubscriptionUser(t, 3, "")
+	require.NoError(t, model.DB.Model(&model.User{}).Where("id = ?", 3).Update("group", "").Error)
+
+	// 用户仍在套餐分组时恢复到订阅前的分组
+	restored := newPaidSubscription(t, 1, "vip", 0, false)
+	require.NoError(t, restored.Expire())
+	assert.Equal(t, model.SubscriptionStatusExpired, reloadSubscription(t, restored.Id).Status)
+	assert.Equal(t, "partner", getSubscriptionUser(t, 1).Group)
+
+	// 用户已被调整到其他分组时不修改
+	moved := newPaidSubscription(t, 2, "vip", 0, false)
+	require.NoError(t, model.DB.Model(&model.User{}).Where("id = ?", 2).Update("group", "enterprise").Error)
+	require.NoError(t, moved.Expire())
+	assert.Equal(t, "enterprise", getSubscriptionUser(t, 2).Group)
+
+	// 订阅前没有分组时恢复到 default
+	empty := newPaidSubscription(t, 3, "vip", 0, false)
+	assert.Empty(t, reloadSubscription(t, empty.Id).PreviousGroup)
+	require.NoError(t, empty.Expire())
+	assert.Equal(t, "default", getSubscriptionUser(t, 3).Group)
+
+	// 重复过期不会再修改分组
+	require.NoError(t, model.DB.Model(&model.User{}).Where("id = ?", 3).Update("group", "vip").Error)
+	require.NoError(t, reloadSubscription(t, empty.Id).Expire())
+	assert.Equal(t, "vip", getSubscriptionUser(t, 3).Group)
+}
+
+func TestRenewDueSubscriptions(t *testing.T) {
+	setupSubscriptionTest(t)
+	now := utils.GetTimestamp()
+	hour := int64(3600)
+
+	tests := []struct {
+		name      string
+		autoRenew bool
+		canceled  bool
+		periodEnd int64
+		paid      bool
+		status    model.SubscriptionStatus
+		quota     int
+		group     string
+	}{
+		{name: "paid next period", autoRenew: true, periodEnd: now - hour, paid: true, status: model.SubscriptionStatusActive, quota: 200, group: "vip"},
+		{name: "canceled but paid", canceled: true, periodEnd: now - hour, paid: true, status: model.SubscriptionStatusCanceled, quota: 200, group: "vip"},
+		{name: "auto renew within grace", autoRenew: true, periodEnd: now - hour, status: model.SubscriptionStatusActive, quota: 100, group: "vip"},
+		{name: "auto renew after grace", autoRenew: true, periodEnd: now - 25*hour, status: model.SubscriptionStatusExpired, quota: 100, group: "default"},
+		{name: "canceled unpaid", canceled: true, periodEnd: now - hour, status: model.SubscriptionStatusExpired, quota: 100, group: "default"},
+		{name: "no auto renew unpaid", periodEnd: now - hour, status: model.SubscriptionStatusExpired, quota: 100, group: "default"},
+		{name: "period not ended", periodEnd: now + hour, status: model.SubscriptionStatusActive, quota: 100, group: "vip"},
+	}
+
+	subscriptions := make([]*model.Subscription, len(tests))
+	for i, tt := range tests {
+		userId := i + 1
+		createSubscriptionUser(t, userId, "default")
+		subscription := newPaidSubscription(t, userId, "vip", 100, tt.autoRenew)
+		if tt.canceled {
+			require.NoError(t, subscription.Cancel())
+		}
+
+		paidUntil := tt.periodEnd
+		if tt.paid {
+			paidUntil = model.NextPeriodEnd(tt.periodEnd, model.SubscriptionIntervalDay, 1)
+		}
+		require.NoError(t, model.DB.Model(subscription).Updates(map[string]any{
+			"current_period_start": tt.periodEnd - 24*hour,
+			"current_period_end":   tt.periodEnd,
+			"paid_until":           paidUntil,
+		}).Error)
+		subscriptions[i] = subscription
+	}
+
+	renewed, expired := model.RenewDueSubscriptions()
+	assert.Equal(t, 2, renewed)
+	assert.Equal(t, 3, expired)
+
+	// 同一周期只续期一次
+	renewed, expired = model.RenewDueSubscriptions()
+	assert.Zero(t, renewed)
+	assert.Zero(t, expired)
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := reloadSubscription(t, subscriptions[i].Id)
+			assert.Equal(t, tt.status, stored.Status)
+			if tt.paid {
+				assert.Equal(t, tt.periodEnd, stored.CurrentPeriodStart)
+				assert.Equal(t, stored.PaidUntil, stored.CurrentPeriodEnd)
+			}
+
+			user := getSubscriptionUser(t, i+1)
+			assert.Equal(t, tt.quota, user.Quota)
+			assert.Equal(t, tt.group, user.Group)
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 const (
 	TaskPlatformSuno  = "suno"
 	TaskPlatformKling = "kling"
+	TaskPlatformVideo = "video"
 )
 
 type TaskStatus string
@@ -79,6 +80,12 @@ func (Task *Task) Update() error {
 	return DB.Save(Task).Error
 }
 
+// UpdateUnfinishedTask 只在任务未结束时更新，返回是否更新，避免取消和轮询重复结算
+func UpdateUnfinishedTask(task *Task) (bool, error) {
+	result := DB.Model(task).Where("progress != ?", 100).Select("*").Updates(task)
+	return result.RowsAffected > 0, result.Error
+}
+
 func TaskBulkUpdate(TaskIds []string, params map[string]any) error {
 	if len(TaskIds) == 0 {
 		return nil
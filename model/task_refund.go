@@ -0,0 +1,123 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/utils"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	TaskRefundReasonFailure = "failure"
+	TaskRefundReasonTimeout = "timeout"
+	TaskRefundReasonCancel  = "cancel"
+)
+
+const TaskPlatformMidjourney = "midjourney"
+
+// TaskRefund 异步任务的退款记录，平台和任务 ID 唯一，保证同一任务只退还一次
+type TaskRefund struct {
+	Id       int    `json:"id"`
+	Platform string `json:"platform" gorm:"type:varchar(30);uniqueIndex:idx_task_refund_task"`
+	// Task 或 Midjourney 表的主键
+	TaskId int64 `json:"task_id" gorm:"uniqueIndex:idx_task_refund_task"`
+	// 上游的任务 ID，用于日志展示
+	TaskRef      string  `json:"task_ref" gorm:"type:varchar(100)"`
+	UserId       int     `json:"user_id" gorm:"index"`
+	Reason       string  `json:"reason" gorm:"type:varchar(20)"`
+	ChargedQuota int     `json:"charged_quota"`
+	Ratio        float64 `json:"ratio"`
+	Quota        int     `json:"quota"`
+	CreatedAt    int64   `json:"created_at" gorm:"bigint"`
+}
+
+// GetTaskRefundRatio 退款策略：上游失败和用户取消全额退还，超时按配置的比例退还
+func GetTaskRefundRatio(reason string) float64 {
+	if reason == TaskRefundReasonTimeout {
+		return min(max(config.TaskTimeoutRefundRatio, 0), 1)
+	}
+	return 1
+}
+
+// IsTaskTimeout 任务提交后超过 TaskTimeoutMinutes 仍未完成，submitTime 为秒级时间戳
+func IsTaskTimeout(submitTime int64) bool {
+	if config.TaskTimeoutMinutes <= 0 || submitTime <= 0 {
+		return false
+	}
+	return utils.GetTimestamp()-submitTime > int64(config.TaskTimeoutMinutes)*60
+}
+
+// RefundTaskQuota 按退款策略退还任务预扣的额度并记录退款日志，返回是否退还
+// 先写入退款记录占用唯一键，多个节点同时结算同一任务时只有一个会退还
+func RefundTaskQuota(refund *TaskRefund) (bool, error) {
+	refund.Ratio = GetTaskRefundRatio(refund.Reason)
+	refund.Quota = int(math.Floor(float64(refund.ChargedQuota) * refund.Ratio))
+	if refund.Quota <= 0 {
+		return false, nil
+	}
+
+	refund.CreatedAt = utils.GetTimestamp()
+	result := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(refund)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if err := IncreaseUserQuota(refund.UserId, refund.Quota); err != nil {
+		// 删除记录以便下次结算时重试
+		DB.Delete(refund)
+		return false, err
+	}
+
+	RecordRefundLog(refund.UserId, refund.Quota, refund.logContent(), map[string]any{
+		"refund_id":     refund.Id,
+		"task_platform": refund.Platform,
+		"task_id":       refund.TaskId,
+		"task_ref":      refund.TaskRef,
+		"reason":        refund.Reason,
+		"ratio":         refund.Ratio,
+		"charged_quota": refund.ChargedQuota,
+	})
+
+	return true, nil
+}
+
+func (refund *TaskRefund) logContent() string {
+	switch refund.Reason {
+	case TaskRefundReasonTimeout:
+		return fmt.Sprintf("异步任务超时 %s，按 %.0f%% 退还 %s", refund.TaskRef, refund.Ratio*100, common.LogQuota(refund.Quota))
+	case TaskRefundReasonCancel:
+		return fmt.Sprintf("异步任务已取消 %s，退还 %s", refund.TaskRef, common.LogQuota(refund.Quota))
+	default:
+		return fmt.Sprintf("异步任务执行失败 %s，退还 %s", refund.TaskRef, common.LogQuota(refund.Quota))
+	}
+}
+
+// RefundQuota 退还任务预扣的额度
+func (task *Task) RefundQuota(reason string) (bool, error) {
+	return RefundTaskQuota(&TaskRefund{
+		Platform:     task.Platform,
+		TaskId:       task.ID,
+		TaskRef:      task.TaskID,
+		UserId:       task.UserId,
+		Reason:       reason,
+		ChargedQuota: task.Quota,
+	})
+}
+
+// RefundQuota 退还绘图任务预扣的额度
+func (task *Midjourney) RefundQuota(reason string) (bool, error) {
+	return RefundTaskQuota(&TaskRefund{
+		Platform:     TaskPlatformMidjourney,
+		TaskId:       int64(task.Id),
+		TaskRef:      task.MjId,
+		UserId:       task.UserId,
+		Reason:       reason,
+		ChargedQuota: task.Quota,
+	})
+}
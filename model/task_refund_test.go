@@ -0,0 +1,112 @@
+package model_test
+
+import (
+	"testing"
+
+	"one-api/common/config"
+	"one-api/common/utils"
+	"one-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTaskRefundRatio(t *testing.T) {
+	config.TaskTimeoutRefundRatio = 0.5
+
+	assert.Equal(t, 1.0, model.GetTaskRefundRatio(model.TaskRefundReasonFailure))
+	assert.Equal(t, 1.0, model.GetTaskRefundRatio(model.TaskRefundReasonCancel))
+	assert.Equal(t, 0.5, model.GetTaskRefundRatio(model.TaskRefundReasonTimeout))
+
+	config.TaskTimeoutRefundRatio = 1.5
+	assert.Equal(t, 1.0, model.GetTaskRefundRatio(model.TaskRefundReasonTimeout))
+}
+
+func TestIsTaskTimeout(t *testing.T) {
+	config.TaskTimeoutMinutes = 60
+	now := utils.GetTimestamp()
+
+	assert.False(t, model.IsTaskTimeout(now-30*60))
+	assert.True(t, model.IsTaskTimeout(now-61*60))
+	assert.False(t, model.IsTaskTimeout(0))
+
+	config.TaskTimeoutMinutes = 0
+	assert.False(t, model.IsTaskTimeout(now-61*60))
+}
+
+func countRefundLogs(t *testing.T, userId int) int64 {
+	var count int64
+	require.NoError(t, model.DB.Model(&model.Log{}).Where("user_id = ? AND type = ?", userId, model.LogTypeRefund).Count(&count).Error)
+	return count
+}
+
+func getUserQuota(t *testing.T, userId int) int {
+	var user model.User
+	require.NoError(t, model.DB.First(&user, userId).Error)
+	return user.Quota
+}
+
+func TestRefundTaskQuotaOnce(t *testing.T) {
+	setupTestDB(t, &model.User{}, &model.Log{}, &model.TaskRefund{})
+	config.BatchUpdateEnabled = false
+	config.TaskTimeoutRefundRatio = 0.5
+	require.NoError(t, model.DB.Create(&model.User{Id: 1, Username: "refund", Quota: 100}).Error)
+
+	task := &model.Task{ID: 10, TaskID: "task_10", Platform: model.TaskPlatformVideo, UserId: 1, Quota: 1000}
+
+	refunded, err := task.RefundQuota(model.TaskRefundReasonTimeout)
+	require.NoError(t, err)
+	assert.True(t, refunded)
+	assert.Equal(t, 600, getUserQuota(t, 1))
+
+	// 其他节点或其他原因再次结算同一任务时不会重复退还
+	refunded, err = task.RefundQuota(model.TaskRefundReasonTimeout)
+	require.NoError(t, err)
+	assert.False(t, refunded)
+	refunded, err = task.RefundQuota(model.TaskRefundReasonFailure)
+	require.NoError(t, err)
+	assert.False(t, refunded)
+
+	assert.Equal(t, 600, getUserQuota(t, 1))
+	assert.Equal(t, int64(1), countRefundLogs(t, 1))
+
+	// 不同平台的任务 ID 相同时分别退还
+	drawing := &model.Midjourney{Id: 10, MjId: "mj_10", UserId: 1, Quota: 100}
+	refunded, err = drawing.RefundQuota(model.TaskRefundReasonFailure)
+	require.NoError(t, err)
+	assert.True(t, refunded)
+	assert.Equal(t, 700, getUserQuota(t, 1))
+
+	// 没有需要退还的额度时不记录
+	refunded, err = (&model.Task{ID: 11, Platform: model.TaskPlatformVideo, UserId: 1}).RefundQuota(model.TaskRefundReasonFailure)
+	require.NoError(t, err)
+	assert.False(t, refunded)
+	assert.Equal(t, int64(2), countRefundLogs(t, 1))
+}
+
+func TestRefundTaskQuotaRetryAfterFailure(t *testing.T) {
+	// 没有用户表时增加余额失败
+	setupTestDB(t, &model.Log{}, &model.TaskRefund{})
+	config.BatchUpdateEnabled = false
+
+	task := &model.Task{ID: 10, TaskID: "task_10", Platform: model.TaskPlatformVideo, UserId: 1, Quota: 1000}
+
+	refunded, err := task.RefundQuota(model.TaskRefundReasonFailure)
+	assert.Error(t, err)
+	assert.False(t, refunded)
+
+	// 失败时删除退款记录，下次结算时可以重试
+	var count int64
+	require.NoError(t, model.DB.Model(&model.TaskRefund{}).Count(&count).Error)
+	assert.Zero(t, count)
+	assert.Zero(t, countRefundLogs(t, 1))
+
+	require.NoError(t, model.DB.AutoMigrate(&model.User{}))
+	require.NoError(t, model.DB.Create(&model.User{Id: 1, Username: "refund"}).Error)
+
+	refunded, err = task.RefundQuota(model.TaskRefundReasonFailure)
+	require.NoError(t, err)
+	assert.True(t, refunded)
+	assert.Equal(t, 1000, getUserQuota(t, 1))
+	assert.Equal(t, int64(1), countRefundLogs(t, 1))
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"one-api/common/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	TaskWebhookStatusPending = "pending"
+	TaskWebhookStatusSuccess = "success"
+	TaskWebhookStatusFailed  = "failed"
+)
+
+const (
+	TaskWebhookEventSucceeded = "task.succeeded"
+	TaskWebhookEventFailed    = "task.failed"
+)
+
+// TaskWebhookDelivery 异步任务结束时向客户端回调地址的投递记录，同时作为投递日志
+// 每个任务的每种事件只有一条记录，多个节点同时结算同一任务时只会投递一次
+type TaskWebhookDelivery struct {
+	Id           int    `json:"id"`
+	TaskId       int64  `json:"task_id" gorm:"uniqueIndex:idx_task_webhook_event"`
+	UserId       int    `json:"user_id" gorm:"index"`
+	TokenId      int    `json:"token_id"`
+	Url          string `json:"url" gorm:"type:varchar(500)"`
+	Event        string `json:"event" gorm:"type:varchar(30);uniqueIndex:idx_task_webhook_event"`
+	Payload      string `json:"payload" gorm:"type:text"`
+	Status       string `json:"status" gorm:"type:varchar(20);index"`
+	Attempts     int    `json:"attempts"`
+	NextRetryAt  int64  `json:"next_retry_at" gorm:"bigint;index"`
+	ResponseCode int    `json:"response_code"`
+	ResponseBody string `json:"response_body" gorm:"type:text"`
+	Error        string `json:"error" gorm:"type:text"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt    int64  `json:"updated_at" gorm:"bigint"`
+}
+
+type TaskWebhookQueryParams struct {
+	PaginationParams
+	TaskId int64  `form:"task_id"`
+	Status string `form:"status"`
+}
+
+var allowedTaskWebhookOrderFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// Insert 创建投递记录，任务的该事件已有记录时返回 false
+func (d *TaskWebhookDelivery) Insert() (bool, error) {
+	now := utils.GetTimestamp()
+	d.CreatedAt = now
+	d.UpdatedAt = now
+
+	result := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(d)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SaveResult 保存一次投递的结果
+func (d *TaskWebhookDelivery) SaveResult() error {
+	d.UpdatedAt = utils.GetTimestamp()
+	return DB.Model(d).Select("status", "attempts", "next_retry_at", "response_code", "response_body", "error", "updated_at").Updates(d).Error
+}
+
+// ClaimTaskWebhookDelivery 多个节点同时重试时只有一个能认领成功，认领后推迟下次重试时间作为租约
+func ClaimTaskWebhookDelivery(d *TaskWebhookDelivery, leaseUntil int64) bool {
+	result := DB.Model(&TaskWebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_retry_at = ?", d.Id, TaskWebhookStatusPending, d.NextRetryAt).
+		Update("next_retry_at", leaseUntil)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+
+	d.NextRetryAt = leaseUntil
+	return true
+}
+
+// GetDueTaskWebhookDeliveries 到了重试时间的投递
+func GetDueTaskWebhookDeliveries(limit int) ([]*TaskWebhookDelivery, error) {
+	var deliveries []*TaskWebhookDelivery
+	err := DB.Where("status = ? AND next_retry_at <= ?", TaskWebhookStatusPending, utils.GetTimestamp()).
+		Order("next_retry_at").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func GetTaskWebhookDelivery(id int, userId int) (*TaskWebhookDelivery, error) {
+	var delivery TaskWebhookDelivery
+	db := DB.Where("id = ?", id)
+	if userId > 0 {
+		db = db.Where("user_id = ?", userId)
+	}
+	err := db.First(&delivery).Error
+	return &delivery, err
+}
+
+// GetTaskWebhookDeliveries userId 为 0 时查询所有用户
+// 用户只能看到响应状态码，响应内容只对管理员可见，避免回调地址被用来读取其他服务的响应
+func GetTaskWebhookDeliveries(userId int, params *TaskWebhookQueryParams) (*DataResult[TaskWebhookDelivery], error) {
+	var deliveries []*TaskWebhookDelivery
+	db := DB.Omit("payload")
+
+	if userId > 0 {
+		db = DB.Omit("payload", "response_body").Where("user_id = ?", userId)
+	}
+	if params.TaskId > 0 {
+		db = db.Where("task_id = ?", params.TaskId)
+	}
+	if params.Status != "" {
+		db = db.Where("status = ?", params.Status)
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &deliveries, allowedTaskWebhookOrderFields)
+}
+
+// ResetTaskWebhookDelivery 手动重新投递，重置重试次数
+func ResetTaskWebhookDelivery(d *TaskWebhookDelivery) error {
+	d.Status = TaskWebhookStatusPending
+	d.Attempts = 0
+	d.NextRetryAt = utils.GetTimestamp()
+	d.UpdatedAt = d.NextRetryAt
+
+	return DB.Model(d).Select("status", "attempts", "next_retry_at", "updated_at").Updates(d).Error
+}
+
+// GetTokenWebhookSecret 读取令牌的回调签名密钥，旧令牌没有密钥时生成并保存
+func GetTokenWebhookSecret(tokenId int) (string, error) {
+	var token Token
+	if err := DB.Select("id", "webhook_secret").Where("id = ?", tokenId).First(&token).Error; err != nil {
+		return "", err
+	}
+	if token.WebhookSecret != "" {
+		return token.WebhookSecret, nil
+	}
+
+	secret := "whsec_" + utils.GetRandomString(32)
+	err := DB.Model(&Token{}).Where("id = ? AND (webhook_secret = '' OR webhook_secret IS NULL)", tokenId).Update("webhook_secret", secret).Error
+	if err != nil {
+		return "", err
+	}
+
+	// 并发生成时以数据库中的为准
+	if err := DB.Select("id", "webhook_secret").Where("id = ?", tokenId).First(&token).Error; err != nil {
+		return "", err
+	}
+	return token.WebhookSecret, nil
+}
+
+// ResetTokenWebhookSecret 重新生成令牌的回调签名密钥
+func ResetTokenWebhookSecret(tokenId int, userId int) (string, error) {
+	secret := "whsec_" + utils.GetRandomString(32)
+	result := DB.Model(&Token{}).Where("id = ? AND user_id = ?", tokenId, userId).Update("webhook_secret", secret)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return secret, nil
+}
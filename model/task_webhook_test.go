@@ -0,0 +1,35 @@
+package model_test
+
+import (
+	"testing"
+
+	"one-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskWebhookDeliveryInsertOnce(t *testing.T) {
+	setupTestDB(t, &model.TaskWebhookDelivery{})
+
+	newDelivery := func(event string) *model.TaskWebhookDelivery {
+		return &model.TaskWebhookDelivery{TaskId: 1, UserId: 1, Event: event, Status: model.TaskWebhookStatusPending}
+	}
+
+	created, err := newDelivery(model.TaskWebhookEventSucceeded).Insert()
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	// 其他节点结算同一任务时不再创建投递记录
+	created, err = newDelivery(model.TaskWebhookEventSucceeded).Insert()
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	created, err = newDelivery(model.TaskWebhookEventFailed).Insert()
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	var count int64
+	model.DB.Model(&model.TaskWebhookDelivery{}).Where("task_id = ?", 1).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
@@ -10,6 +10,7 @@ import (
 	"one-api/common/redis"
 	"one-api/common/stmp"
 	"one-api/common/utils"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -38,8 +39,12 @@ type Token struct {
 	Group          string         `json:"group" gorm:"default:''"`
 	BackupGroup    string         `json:"backup_group" gorm:"default:''"`
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+	// 异步任务回调的签名密钥
+	WebhookSecret string `json:"webhook_secret,omitempty" gorm:"type:varchar(64);default:''"`
 
 	Setting database.JSONType[TokenSetting] `json:"setting" form:"setting" gorm:"type:json"`
+
+	Budget []*TokenBudgetWindow `json:"budget,omitempty" gorm:"-"`
 }
 
 var allowedTokenOrderFields = map[string]bool{
@@ -67,6 +72,13 @@ type TokenSetting struct {
 	Heartbeat  HeartbeatSetting `json:"heartbeat,omitempty"`
 	Limits     LimitsConfig     `json:"limits,omitempty"`
 	BillingTag *string          `json:"billing_tag,omitempty"` // 费用标签，用于按分组统计费用，仅可信内部员工和管理员可见
+	ChatCache  ChatCacheSetting `json:"chat_cache,omitempty"`
+	Hedge      HedgeSetting     `json:"hedge,omitempty"`
+	Archive    ArchiveSetting   `json:"archive,omitempty"`
+	// 注入对话请求的系统提示词模板，在分组模板之后
+	PromptTemplate PromptTemplateRef `json:"prompt_template,omitempty"`
+	// 对话超出模型上下文时自动裁剪或摘要最早的对话轮次
+	ContextTrim ContextTrimSetting `json:"context_trim,omitempty"`
 }
 
 type HeartbeatSetting struct {
@@ -74,9 +86,66 @@ type HeartbeatSetting struct {
 	TimeoutSeconds int  `json:"timeout_seconds"`
 }
 
+// ChatCacheSetting 令牌级别的对话缓存开关，仅 temperature 为 0 的请求会被缓存
+type ChatCacheSetting struct {
+	Enabled bool `json:"enabled"`
+}
+
+// HedgeSetting 令牌级别的对冲请求开关，首字超时后会并发请求第二个渠道
+type HedgeSetting struct {
+	Enabled bool `json:"enabled"`
+}
+
+// ContextTrimSetting 令牌级别的上下文窗口管理，始终保留 system 消息和最近 KeepTurns 轮对话
+type ContextTrimSetting struct {
+	Enabled   bool   `json:"enabled"`
+	Mode      string `json:"mode"`       // trim 直接丢弃，summarize 将丢弃的内容摘要后保留，默认 trim
+	KeepTurns int    `json:"keep_turns"` // 默认 2
+}
+
+// ArchiveSetting 令牌级别的请求归档开关，开启后保存脱敏后的请求和响应内容
+type ArchiveSetting struct {
+	Enabled bool `json:"enabled"`
+}
+
 type LimitsConfig struct {
 	LimitModelSetting LimitModelSetting `json:"limit_model_setting,omitempty"`
 	LimitsIPSetting   LimitsIPSetting   `json:"limits_ip_setting,omitempty"`
+	Budget            BudgetSetting     `json:"budget,omitempty"`
+	RateLimit         RateLimitSetting  `json:"rate_limit,omitempty"`
+	Realtime          RealtimeSetting   `json:"realtime,omitempty"`
+}
+
+// BudgetSetting 令牌按自然日/自然月的消费上限，单位为额度，0 表示该窗口不限制
+type BudgetSetting struct {
+	Enabled       bool `json:"enabled"`
+	DailyQuota    int  `json:"daily_quota"`
+	MonthlyQuota  int  `json:"monthly_quota"`
+	NotifyEnabled bool `json:"notify_enabled"` // 消费达到预算的 80% 时邮件通知
+}
+
+// RateLimitSetting 令牌级别的每分钟请求数和 token 数限制，0 表示不限制
+type RateLimitSetting struct {
+	Enabled bool `json:"enabled"`
+	RPM     int  `json:"rpm"`
+	TPM     int  `json:"tpm"` // 按请求完成后的实际用量累计
+}
+
+// RealtimeSetting 令牌级别的 Realtime 会话限制，开启后覆盖全局设置，0 表示不限制
+type RealtimeSetting struct {
+	Enabled           bool `json:"enabled"`
+	MaxSessions       int  `json:"max_sessions"`        // 同时进行的会话数
+	MaxSessionMinutes int  `json:"max_session_minutes"` // 单个会话的最长时长，单位分钟
+}
+
+// Realtime 返回令牌生效的会话数和时长限制
+func (s *TokenSetting) Realtime() (maxSessions int, maxDuration time.Duration) {
+	maxSessions, maxMinutes := config.RealtimeMaxSessions, config.RealtimeMaxSessionMinutes
+	if s != nil && s.Limits.Realtime.Enabled {
+		maxSessions, maxMinutes = s.Limits.Realtime.MaxSessions, s.Limits.Realtime.MaxSessionMinutes
+	}
+
+	return maxSessions, time.Duration(maxMinutes) * time.Minute
 }
 
 type LimitModelSetting struct {
@@ -0,0 +1,371 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"one-api/common/stmp"
+	"one-api/common/utils"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	TokenBudgetWindowDay   = "day"
+	TokenBudgetWindowMonth = "month"
+
+	TokenBudgetNotifyRatio = 0.8
+)
+
+var ErrTokenBudgetExceeded = errors.New("令牌预算不足")
+
+var TokenBudgetCacheKey = "token_budget:%d:%s"
+
+// TokenBudgetUsage 按窗口记录令牌的消费，开启 Redis 时 Redis 只作为缓存
+type TokenBudgetUsage struct {
+	Id        int    `json:"id"`
+	TokenId   int    `json:"token_id" gorm:"uniqueIndex:idx_token_budget_period"`
+	Period    string `json:"period" gorm:"type:varchar(16);uniqueIndex:idx_token_budget_period"`
+	UsedQuota int    `json:"used_quota" gorm:"default:0"`
+	UpdatedAt int64  `json:"updated_at" gorm:"bigint"`
+}
+
+// TokenBudgetWindow 令牌在某个预算窗口内的消费情况
+type TokenBudgetWindow struct {
+	Window    string `json:"window"`
+	Limit     int    `json:"limit"`
+	Used      int    `json:"used"`
+	ResetTime int64  `json:"reset_time"`
+}
+
+// TokenBudgetPeriod 返回窗口的标识和结束时间，窗口按服务器时区的自然日/自然月计算
+func TokenBudgetPeriod(window string, now time.Time) (string, time.Time) {
+	if window == TokenBudgetWindowMonth {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return "m:" + start.Format("200601"), start.AddDate(0, 1, 0)
+	}
+
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return "d:" + start.Format("20060102"), start.AddDate(0, 0, 1)
+}
+
+func (b *BudgetSetting) windows() map[string]int {
+	windows := make(map[string]int)
+	if b == nil || !b.Enabled {
+		return windows
+	}
+	if b.DailyQuota > 0 {
+		windows[TokenBudgetWindowDay] = b.DailyQuota
+	}
+	if b.MonthlyQuota > 0 {
+		windows[TokenBudgetWindowMonth] = b.MonthlyQuota
+	}
+
+	return windows
+}
+
+// getTokenBudgetUsed 优先读取 Redis 缓存，缓存不存在时从数据库读取并写回缓存
+func getTokenBudgetUsed(tokenId int, period string, expireAt time.Time) (int, error) {
+	if config.RedisEnabled {
+		value, err := redis.RedisGet(fmt.Sprintf(TokenBudgetCacheKey, tokenId, period))
+		if err == nil {
+			return strconv.Atoi(value)
+		}
+		if !errors.Is(err, redis.Nil) {
+			return 0, err
+		}
+	}
+
+	var usage TokenBudgetUsage
+	err := DB.Where("token_id = ? AND period = ?", tokenId, period).First(&usage).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	cacheTokenBudgetUsed(tokenId, period, expireAt, usage.UsedQuota)
+	return usage.UsedQuota, nil
+}
+
+// increaseTokenBudgetUsed 消费记录保存在数据库中，Redis 清空或重启后不会丢失
+func increaseTokenBudgetUsed(tokenId int, period string, quota int) (int, error) {
+	err := DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "token_id"}, {Name: "period"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"used_quota": gorm.Expr("used_quota + ?", quota),
+			"updated_at": utils.GetTimestamp(),
+		}),
+	}).Create(&TokenBudgetUsage{
+		TokenId:   tokenId,
+		Period:    period,
+		UsedQuota: quota,
+		UpdatedAt: utils.GetTimestamp(),
+	}).Error
+	if err != nil {
+		return 0, err
+	}
+	deleteTokenBudgetCache(tokenId, period)
+
+	var usage TokenBudgetUsage
+	if err := DB.Where("token_id = ? AND period = ?", tokenId, period).First(&usage).Error; err != nil {
+		return 0, err
+	}
+
+	return usage.UsedQuota, nil
+}
+
+// reserveTokenBudgetUsed 只有预扣后不超过 limit 时才增加消费，返回是否预扣成功
+func reserveTokenBudgetUsed(tokenId int, period string, limit, quota int) (bool, error) {
+	if quota <= 0 {
+		var usage TokenBudgetUsage
+		err := DB.Where("token_id = ? AND period = ?", tokenId, period).First(&usage).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, err
+		}
+		return usage.UsedQuota < limit, nil
+	}
+
+	err := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&TokenBudgetUsage{
+		TokenId:   tokenId,
+		Period:    period,
+		UpdatedAt: utils.GetTimestamp(),
+	}).Error
+	if err != nil {
+		return false, err
+	}
+
+	result := DB.Model(&TokenBudgetUsage{}).
+		Where("token_id = ? AND period = ? AND used_quota + ? <= ?", tokenId, period, quota, limit).
+		Updates(map[string]any{
+			"used_quota": gorm.Expr("used_quota + ?", quota),
+			"updated_at": utils.GetTimestamp(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	deleteTokenBudgetCache(tokenId, period)
+	return true, nil
+}
+
+func cacheTokenBudgetUsed(tokenId int, period string, expireAt time.Time, used int) {
+	if !config.RedisEnabled {
+		return
+	}
+
+	err := redis.RedisSet(fmt.Sprintf(TokenBudgetCacheKey, tokenId, period), strconv.Itoa(used), time.Until(expireAt.Add(time.Hour)))
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to cache token %d budget usage: %s", tokenId, err.Error()))
+	}
+}
+
+// deleteTokenBudgetCache 写入后删除缓存而不是覆盖，避免并发写入时较旧的值最后写入缓存
+func deleteTokenBudgetCache(tokenId int, period string) {
+	if !config.RedisEnabled {
+		return
+	}
+
+	if err := redis.RedisDel(fmt.Sprintf(TokenBudgetCacheKey, tokenId, period)); err != nil {
+		logger.SysError(fmt.Sprintf("failed to delete token %d budget cache: %s", tokenId, err.Error()))
+	}
+}
+
+// GetTokenBudgetWindows 返回令牌当前各预算窗口的消费情况，读取失败的窗口按 0 展示
+func GetTokenBudgetWindows(tokenId int, budget *BudgetSetting) []*TokenBudgetWindow {
+	windows, err := getTokenBudgetWindows(tokenId, budget, time.Now())
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to get token %d budget usage: %s", tokenId, err.Error()))
+	}
+
+	return windows
+}
+
+func getTokenBudgetWindows(tokenId int, budget *BudgetSetting, now time.Time) ([]*TokenBudgetWindow, error) {
+	result := make([]*TokenBudgetWindow, 0)
+	limits := budget.windows()
+
+	var lastErr error
+	for _, window := range []string{TokenBudgetWindowDay, TokenBudgetWindowMonth} {
+		limit, ok := limits[window]
+		if !ok {
+			continue
+		}
+
+		period, resetTime := TokenBudgetPeriod(window, now)
+		used, err := getTokenBudgetUsed(tokenId, period, resetTime)
+		if err != nil {
+			lastErr = err
+		}
+
+		result = append(result, &TokenBudgetWindow{
+			Window:    window,
+			Limit:     limit,
+			Used:      used,
+			ResetTime: resetTime.Unix(),
+		})
+	}
+
+	return result, lastErr
+}
+
+// tokenBudgetPeriod 预扣时所在的窗口，结算和撤销都记到同一个窗口，跨零点的请求不会记到下一个窗口
+type tokenBudgetPeriod struct {
+	window string
+	period string
+	limit  int
+}
+
+// TokenBudgetReservation 请求开始时预扣的令牌预算，请求结束后按实际消费结算或撤销
+type TokenBudgetReservation struct {
+	sync.Mutex
+	tokenId int
+	notify  bool
+	// 尚未结算的预扣额度
+	quota   int
+	periods []tokenBudgetPeriod
+}
+
+// ReserveTokenBudget 在令牌各预算窗口内原子地预扣 quota，任一窗口不足时撤销已预扣的窗口并返回 ErrTokenBudgetExceeded
+// 未开启预算时返回 nil
+func ReserveTokenBudget(tokenId int, budget *BudgetSetting, quota int) (*TokenBudgetReservation, error) {
+	limits := budget.windows()
+	if len(limits) == 0 {
+		return nil, nil
+	}
+
+	reservation := &TokenBudgetReservation{
+		tokenId: tokenId,
+		notify:  budget.NotifyEnabled,
+	}
+
+	now := time.Now()
+	for _, window := range []string{TokenBudgetWindowDay, TokenBudgetWindowMonth} {
+		limit, ok := limits[window]
+		if !ok {
+			continue
+		}
+
+		period, resetTime := TokenBudgetPeriod(window, now)
+		reserved, err := reserveTokenBudgetUsed(tokenId, period, limit, quota)
+		if err == nil && !reserved {
+			err = tokenBudgetExceededError(tokenId, window, period, limit, resetTime)
+		}
+		if err != nil {
+			reservation.apply(-quota)
+			return nil, err
+		}
+
+		reservation.periods = append(reservation.periods, tokenBudgetPeriod{window: window, period: period, limit: limit})
+	}
+	reservation.quota = quota
+
+	return reservation, nil
+}
+
+func tokenBudgetExceededError(tokenId int, window, period string, limit int, resetTime time.Time) error {
+	used, err := getTokenBudgetUsed(tokenId, period, resetTime)
+	if err != nil {
+		return err
+	}
+
+	windowName := "今日"
+	if window == TokenBudgetWindowMonth {
+		windowName = "本月"
+	}
+	return fmt.Errorf("%w，%s已使用 %d / %d，将于 %s 重置", ErrTokenBudgetExceeded, windowName, used, limit, resetTime.Format("2006-01-02 15:04:05"))
+}
+
+// apply 在预扣的各窗口上增加 quota，返回各窗口增加后的消费
+func (r *TokenBudgetReservation) apply(quota int) []int {
+	used := make([]int, len(r.periods))
+	for i, period := range r.periods {
+		value, err := increaseTokenBudgetUsed(r.tokenId, period.period, quota)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("failed to increase token %d budget usage: %s", r.tokenId, err.Error()))
+			continue
+		}
+		used[i] = value
+	}
+
+	return used
+}
+
+// take 取出尚未结算的预扣额度，保证预扣额度只被结算或撤销一次
+func (r *TokenBudgetReservation) take() int {
+	r.Lock()
+	defer r.Unlock()
+
+	quota := r.quota
+	r.quota = 0
+	return quota
+}
+
+// Settle 按实际消费结算预扣的预算，首次达到预算的 80% 时通知令牌所有者
+func (r *TokenBudgetReservation) Settle(quota int) {
+	if r == nil {
+		return
+	}
+
+	delta := quota - r.take()
+	if delta == 0 && quota <= 0 {
+		return
+	}
+
+	used := r.apply(delta)
+	if !r.notify || quota <= 0 {
+		return
+	}
+
+	for i, period := range r.periods {
+		threshold := int(float64(period.limit) * TokenBudgetNotifyRatio)
+		if used[i]-quota < threshold && used[i] >= threshold {
+			go sendTokenBudgetWarningEmail(r.tokenId, period.window, used[i], period.limit)
+		}
+	}
+}
+
+// Release 撤销尚未结算的预扣额度
+func (r *TokenBudgetReservation) Release() {
+	if r == nil {
+		return
+	}
+
+	if quota := r.take(); quota > 0 {
+		r.apply(-quota)
+	}
+}
+
+func sendTokenBudgetWarningEmail(tokenId int, window string, used, limit int) {
+	token, err := GetTokenById(tokenId)
+	if err != nil {
+		logger.SysError("failed to fetch token: " + err.Error())
+		return
+	}
+
+	user := User{Id: token.UserId}
+	if err := user.FillUserById(); err != nil {
+		logger.SysError("failed to fetch user email: " + err.Error())
+		return
+	}
+
+	if user.Email == "" {
+		return
+	}
+
+	userName := user.DisplayName
+	if userName == "" {
+		userName = user.Username
+	}
+
+	if err := stmp.SendTokenBudgetWarningEmail(userName, user.Email, token.Name, window == TokenBudgetWindowMonth, used, limit); err != nil {
+		logger.SysError("failed to send email" + err.Error())
+	}
+}
@@ -0,0 +1,127 @@
+package model_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"one-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBudgetPeriod(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	now := time.Date(2026, 12, 31, 23, 59, 59, 0, loc)
+
+	period, resetTime := model.TokenBudgetPeriod(model.TokenBudgetWindowDay, now)
+	assert.Equal(t, "d:20261231", period)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, loc), resetTime)
+
+	period, resetTime = model.TokenBudgetPeriod(model.TokenBudgetWindowMonth, now)
+	assert.Equal(t, "m:202612", period)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, loc), resetTime)
+
+	// 按自然月计算，不受月份天数影响
+	period, resetTime = model.TokenBudgetPeriod(model.TokenBudgetWindowMonth, time.Date(2026, 1, 31, 12, 0, 0, 0, loc))
+	assert.Equal(t, "m:202601", period)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, loc), resetTime)
+}
+
+func TestTokenBudget(t *testing.T) {
+	setupTestDB(t, &model.TokenBudgetUsage{})
+
+	budget := &model.BudgetSetting{Enabled: true, DailyQuota: 100, MonthlyQuota: 150}
+	used := func(tokenId int) []int {
+		result := make([]int, 0)
+		for _, window := range model.GetTokenBudgetWindows(tokenId, budget) {
+			result = append(result, window.Used)
+		}
+		return result
+	}
+
+	_, err := model.ReserveTokenBudget(1, budget, 101)
+	assert.True(t, errors.Is(err, model.ErrTokenBudgetExceeded))
+	assert.Equal(t, []int{0, 0}, used(1))
+
+	// 预扣后其他请求不能再占用同一部分预算
+	first, err := model.ReserveTokenBudget(1, budget, 60)
+	require.NoError(t, err)
+	_, err = model.ReserveTokenBudget(1, budget, 41)
+	assert.True(t, errors.Is(err, model.ErrTokenBudgetExceeded))
+	assert.Equal(t, []int{60, 60}, used(1))
+	// 其他令牌不受影响
+	_, err = model.ReserveTokenBudget(2, budget, 100)
+	assert.NoError(t, err)
+
+	// 按实际消费结算，重复结算或撤销不会再改变消费
+	first.Settle(30)
+	assert.Equal(t, []int{30, 30}, used(1))
+	first.Release()
+	first.Settle(0)
+	assert.Equal(t, []int{30, 30}, used(1))
+
+	second, err := model.ReserveTokenBudget(1, budget, 70)
+	require.NoError(t, err)
+	second.Release()
+	second.Release()
+	assert.Equal(t, []int{30, 30}, used(1))
+
+	// 撤销后再结算时按全部实际消费记账
+	second.Settle(70)
+	assert.Equal(t, []int{100, 100}, used(1))
+	_, err = model.ReserveTokenBudget(1, budget, 0)
+	assert.True(t, errors.Is(err, model.ErrTokenBudgetExceeded))
+
+	// 日预算充足但月预算不足时，已预扣的日预算被撤销
+	_, err = model.ReserveTokenBudget(2, &model.BudgetSetting{Enabled: true, DailyQuota: 200, MonthlyQuota: 150}, 60)
+	assert.True(t, errors.Is(err, model.ErrTokenBudgetExceeded))
+	assert.Equal(t, []int{100, 100}, used(2))
+
+	// 未开启预算时不限制
+	reservation, err := model.ReserveTokenBudget(1, &model.BudgetSetting{DailyQuota: 100}, 1000)
+	assert.NoError(t, err)
+	assert.Nil(t, reservation)
+	reservation.Settle(1000)
+	reservation.Release()
+	assert.Empty(t, model.GetTokenBudgetWindows(1, nil))
+}
+
+func TestTokenBudgetConcurrentReserve(t *testing.T) {
+	setupTestDB(t, &model.TokenBudgetUsage{})
+	sqlDB, err := model.DB.DB()
+	require.NoError(t, err)
+	// 内存数据库每个连接是独立的库
+	sqlDB.SetMaxOpenConns(1)
+
+	budget := &model.BudgetSetting{Enabled: true, DailyQuota: 100}
+
+	var reserved atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := model.ReserveTokenBudget(1, budget, 10); err == nil {
+				reserved.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(10), reserved.Load())
+	assert.Equal(t, 100, model.GetTokenBudgetWindows(1, budget)[0].Used)
+}
+
+func TestTokenBudgetDatabaseError(t *testing.T) {
+	// 没有消费记录表时读取失败，不能当作未消费放行
+	setupTestDB(t)
+
+	budget := &model.BudgetSetting{Enabled: true, DailyQuota: 100}
+	_, err := model.ReserveTokenBudget(1, budget, 1)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, model.ErrTokenBudgetExceeded))
+}
@@ -6,7 +6,11 @@ import (
 	"one-api/common/limit"
 	"one-api/common/logger"
 	"one-api/common/redis"
+	"one-api/common/transform"
+	"strings"
 	"sync"
+
+	"gorm.io/datatypes"
 )
 
 type UserGroup struct {
@@ -20,6 +24,25 @@ type UserGroup struct {
 	Min       int     `json:"min" form:"min" gorm:"default:0"`                 // 晋级条件最小值
 	Max       int     `json:"max" form:"max" gorm:"default:0"`                 // 晋级条件最大值
 	Enable    *bool   `json:"enable" form:"enable" gorm:"default:true"`        // 是否启用
+
+	BalanceMode string `json:"balance_mode" form:"balance_mode" gorm:"type:varchar(20);default:''"` // 渠道负载均衡模式：weight(默认) / adaptive
+
+	SafeTools       string `json:"safe_tools" form:"safe_tools" gorm:"type:varchar(255);default:''"` // 内容审查工具，多个用逗号分隔，为空时使用系统设置
+	SafeCheckOutput bool   `json:"safe_check_output" form:"safe_check_output" gorm:"default:false"`  // 是否审查模型输出
+
+	ArchiveEnabled bool `json:"archive_enabled" form:"archive_enabled" gorm:"default:false"` // 是否归档请求和响应内容
+
+	TransformRules *datatypes.JSONSlice[transform.Rule] `json:"transform_rules,omitempty" gorm:"type:json"` // 请求和响应改写规则，对分组内所有渠道生效
+
+	PromptTemplate PromptTemplateRef `json:"prompt_template" gorm:"embedded;embeddedPrefix:prompt_"` // 注入对话请求的系统提示词模板，在令牌模板之前
+}
+
+func (c *UserGroup) GetTransformRules() []transform.Rule {
+	if c.TransformRules == nil {
+		return nil
+	}
+
+	return *c.TransformRules
 }
 
 type SearchUserGroupParams struct {
@@ -75,7 +98,7 @@ func (c *UserGroup) Create() error {
 }
 
 func (c *UserGroup) Update() error {
-	err := DB.Select("name", "ratio", "public", "api_rate", "promotion", "min", "max").Updates(c).Error
+	err := DB.Select("name", "ratio", "public", "api_rate", "promotion", "min", "max", "balance_mode", "safe_tools", "safe_check_output", "archive_enabled", "transform_rules", "prompt_template_id", "prompt_version").Updates(c).Error
 	if err == nil {
 		GlobalUserGroupRatio.Load()
 	}
@@ -175,6 +198,59 @@ func (cgrm *UserGroupRatio) GetAPIRate(symbol string) int {
 	return userGroup.APIRate
 }
 
+// GetBalanceMode 获取分组的渠道负载均衡模式
+func (cgrm *UserGroupRatio) GetBalanceMode(symbol string) string {
+	userGroup := cgrm.GetBySymbol(symbol)
+	if userGroup == nil || userGroup.BalanceMode == "" {
+		return BalanceModeWeight
+	}
+
+	return userGroup.BalanceMode
+}
+
+// GetSafeTools 获取分组使用的内容审查工具，未设置时返回 nil
+func (cgrm *UserGroupRatio) GetSafeTools(symbol string) []string {
+	userGroup := cgrm.GetBySymbol(symbol)
+	if userGroup == nil || userGroup.SafeTools == "" {
+		return nil
+	}
+
+	tools := make([]string, 0)
+	for _, name := range strings.Split(userGroup.SafeTools, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			tools = append(tools, name)
+		}
+	}
+
+	return tools
+}
+
+// IsArchiveEnabled 分组是否开启请求归档
+func (cgrm *UserGroupRatio) IsArchiveEnabled(symbol string) bool {
+	userGroup := cgrm.GetBySymbol(symbol)
+	return userGroup != nil && userGroup.ArchiveEnabled
+}
+
+// GetPromptTemplate 获取分组引用的提示词模板，未设置时返回 nil
+func (cgrm *UserGroupRatio) GetPromptTemplate(symbol string) *PromptTemplateRef {
+	userGroup := cgrm.GetBySymbol(symbol)
+	if userGroup == nil || userGroup.PromptTemplate.TemplateId == 0 {
+		return nil
+	}
+
+	return &userGroup.PromptTemplate
+}
+
+// GetTransformRules 获取分组的改写规则
+func (cgrm *UserGroupRatio) GetTransformRules(symbol string) []transform.Rule {
+	userGroup := cgrm.GetBySymbol(symbol)
+	if userGroup == nil {
+		return nil
+	}
+
+	return userGroup.GetTransformRules()
+}
+
 func (cgrm *UserGroupRatio) GetPublicGroupList() []string {
 	cgrm.RLock()
 	defer cgrm.RUnlock()
@@ -207,7 +283,7 @@ func CheckAndUpgradeUserGroup(userId int, rechargeAmount int) error {
 
 	// Calculate cumulative recharge amount
 	cumulativeAmount := user.Quota + user.UsedQuota + rechargeAmount
-	logger.SysError(fmt.Sprintf("use:%f q:%f  cumulative:%f rechargeAmount:%f", (float64)(user.UsedQuota)/config.QuotaPerUnit, (float64)(user.Quota)/config.QuotaPerUnit, cumulativeAmount, rechargeAmount))
+	logger.SysError(fmt.Sprintf("use:%f q:%f  cumulative:%d rechargeAmount:%d", (float64)(user.UsedQuota)/config.QuotaPerUnit, (float64)(user.Quota)/config.QuotaPerUnit, cumulativeAmount, rechargeAmount))
 	// Get all promotion-enabled user groups
 	var promotionGroups []*UserGroup
 	err = DB.Where("promotion = ? AND enable = ?", true, true).Find(&promotionGroups).Error
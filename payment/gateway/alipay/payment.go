@@ -1,11 +1,13 @@
 package alipay
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"one-api/model"
 	"one-api/payment/types"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/smartwalle/alipay/v3"
@@ -103,6 +105,38 @@ func getAlipayConfig(gatewayConfig string) (*AlipayConfig, error) {
 	return &alipayConfig, nil
 }
 
+// Refund 按商户订单号原路退款
+func (a *Alipay) Refund(config *types.RefundConfig, gatewayConfig string) (*types.RefundResult, error) {
+	alipayConfig, err := getAlipayConfig(gatewayConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		err := a.InitClient(alipayConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var p = alipay.TradeRefund{}
+	p.OutTradeNo = config.TradeNo
+	p.RefundAmount = strconv.FormatFloat(config.Money, 'f', 2, 64)
+	p.RefundReason = config.Reason
+	p.OutRequestNo = config.RefundNo
+	alipayRes, err := client.TradeRefund(context.Background(), p)
+	if err != nil {
+		return nil, fmt.Errorf("alipay trade refund failed: %s", err.Error())
+	}
+	if !alipayRes.IsSuccess() {
+		return nil, fmt.Errorf("alipay trade refund failed: %s", alipayRes.Error.Error())
+	}
+
+	return &types.RefundResult{
+		GatewayRefundNo: alipayRes.TradeNo,
+	}, nil
+}
+
 func (a *Alipay) CreatedPay(_ string, _ *model.Payment) error {
 	return nil
 }
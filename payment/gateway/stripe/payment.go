@@ -7,6 +7,7 @@ import (
 	"one-api/model"
 	"one-api/payment/types"
 	"strconv"
+	"strings"
 
 	sysconfig "one-api/common/config"
 
@@ -87,8 +88,177 @@ func (e *Stripe) Pay(config *types.PayConfig, gatewayConfig string) (*types.PayR
 	return payRequest, nil
 }
 
+// PaySubscription 创建订阅模式的支付，由 Stripe 按周期自动扣款
+func (e *Stripe) PaySubscription(config *types.PayConfig, subscription *types.SubscriptionConfig, gatewayConfig string) (*types.PayRequest, error) {
+	var stripeConfig StripeConfig
+	err := json.Unmarshal([]byte(gatewayConfig), &stripeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &client.API{}
+	sc.Init(stripeConfig.SecretKey, nil)
+	currency := stripe.String("USD")
+	if config.Currency == "CNY" {
+		currency = stripe.String("CNY")
+	}
+
+	params := &stripe.CheckoutSessionParams{
+		Mode:              stripe.String(string(stripe.CheckoutSessionModeSubscription)),
+		SuccessURL:        stripe.String(config.ReturnURL),
+		ClientReferenceID: stripe.String(config.TradeNo),
+
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+					Currency: currency,
+					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+						Name: stripe.String(sysconfig.SystemName + "-" + subscription.Name),
+					},
+					Recurring: &stripe.CheckoutSessionLineItemPriceDataRecurringParams{
+						Interval:      stripe.String(subscription.Interval),
+						IntervalCount: stripe.Int64(int64(max(subscription.IntervalCount, 1))),
+					},
+					UnitAmount: stripe.Int64(int64(math.Round(config.Money * 100))),
+				},
+				Quantity: stripe.Int64(1),
+			},
+		},
+		SubscriptionData: &stripe.CheckoutSessionSubscriptionDataParams{
+			Metadata: map[string]string{
+				"trade_no": config.TradeNo,
+				"user_id":  fmt.Sprintf("%d", config.User.Id),
+			},
+		},
+		Metadata: map[string]string{
+			"user_id": fmt.Sprintf("%d", config.User.Id),
+		},
+	}
+
+	if config.User.Email != "" {
+		params.CustomerEmail = stripe.String(config.User.Email)
+	}
+
+	result, err := sc.CheckoutSessions.New(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.PayRequest{
+		Type: 1,
+		Data: types.PayRequestData{
+			URL: result.URL,
+			Params: map[string]interface{}{
+				"tradeNo": config.TradeNo,
+				"linkId":  result.ID,
+			},
+		},
+	}, nil
+}
+
+// CancelSubscription 在当前周期结束时取消 Stripe 的订阅，不再扣款
+func (e *Stripe) CancelSubscription(subscriptionNo string, gatewayConfig string) error {
+	var stripeConfig StripeConfig
+	err := json.Unmarshal([]byte(gatewayConfig), &stripeConfig)
+	if err != nil {
+		return err
+	}
+
+	sc := &client.API{}
+	sc.Init(stripeConfig.SecretKey, nil)
+	_, err = sc.Subscriptions.Update(subscriptionNo, &stripe.SubscriptionParams{
+		CancelAtPeriodEnd: stripe.Bool(true),
+	})
+	return err
+}
+
+// Refund 原路全额退款，订阅订单记录的是订阅或账单编号，需要先找到对应的 PaymentIntent
+func (e *Stripe) Refund(config *types.RefundConfig, gatewayConfig string) (*types.RefundResult, error) {
+	var stripeConfig StripeConfig
+	err := json.Unmarshal([]byte(gatewayConfig), &stripeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &client.API{}
+	sc.Init(stripeConfig.SecretKey, nil)
+
+	paymentIntentID, err := getPaymentIntentID(sc, config.GatewayNo)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(paymentIntentID),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+		Metadata: map[string]string{
+			"trade_no":  config.TradeNo,
+			"refund_no": config.RefundNo,
+		},
+	}
+	params.SetIdempotencyKey(config.RefundNo)
+
+	result, err := sc.Refunds.New(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.RefundResult{
+		GatewayRefundNo: result.ID,
+	}, nil
+}
+
+func getPaymentIntentID(sc *client.API, gatewayNo string) (string, error) {
+	var invoice *stripe.Invoice
+	var err error
+
+	switch {
+	case strings.HasPrefix(gatewayNo, "pi_"):
+		return gatewayNo, nil
+	case strings.HasPrefix(gatewayNo, "in_"):
+		invoice, err = sc.Invoices.Get(gatewayNo, nil)
+	case strings.HasPrefix(gatewayNo, "sub_"):
+		// 订阅首次支付的订单记录的是订阅编号，对应订阅的第一张账单，列表按创建时间倒序
+		iter := sc.Invoices.List(&stripe.InvoiceListParams{Subscription: stripe.String(gatewayNo)})
+		for iter.Next() {
+			invoice = iter.Invoice()
+		}
+		err = iter.Err()
+	default:
+		return "", fmt.Errorf("unsupported gateway no: %s", gatewayNo)
+	}
+
+	if err != nil {
+		return "", err
+	}
+	if invoice == nil || invoice.PaymentIntent == nil {
+		return "", fmt.Errorf("payment intent not found for %s", gatewayNo)
+	}
+	return invoice.PaymentIntent.ID, nil
+}
+
+// getDisputeGatewayNo 找到被拒付的支付对应订单记录的编号
+// 普通支付记录的是 PaymentIntent，订阅首次支付记录的是订阅编号，续费记录的是账单编号
+func getDisputeGatewayNo(sc *client.API, paymentIntentID string) (string, error) {
+	params := &stripe.PaymentIntentParams{}
+	params.AddExpand("invoice")
+	paymentIntent, err := sc.PaymentIntents.Get(paymentIntentID, params)
+	if err != nil {
+		return "", err
+	}
+
+	invoice := paymentIntent.Invoice
+	if invoice == nil || invoice.Subscription == nil {
+		return paymentIntentID, nil
+	}
+
+	if invoice.BillingReason == stripe.InvoiceBillingReasonSubscriptionCreate {
+		return invoice.Subscription.ID, nil
+	}
+	return invoice.ID, nil
+}
+
 func (e *Stripe) CreatedPay(notifyURL string, gatewayConfig *model.Payment) error {
-	eventName := "checkout.session.completed"
 	var stripeConfig StripeConfig
 	err := json.Unmarshal([]byte(gatewayConfig.Config), &stripeConfig)
 	if err != nil {
@@ -103,7 +273,7 @@ func (e *Stripe) CreatedPay(notifyURL string, gatewayConfig *model.Payment) erro
 	var existingWebhook *stripe.WebhookEndpoint
 	for i.Next() {
 		webhook := i.WebhookEndpoint()
-		if webhook.URL == notifyURL && contains(webhook.EnabledEvents, eventName) {
+		if webhook.URL == notifyURL {
 			existingWebhook = webhook
 			break
 		}
@@ -117,11 +287,9 @@ func (e *Stripe) CreatedPay(notifyURL string, gatewayConfig *model.Payment) erro
 
 	if existingWebhook == nil {
 		createParams := &stripe.WebhookEndpointParams{
-			URL: stripe.String(notifyURL),
-			EnabledEvents: []*string{
-				stripe.String(eventName),
-			},
-			APIVersion: stripe.String("2024-09-30.acacia"),
+			URL:           stripe.String(notifyURL),
+			EnabledEvents: stripe.StringSlice(webhookEvents),
+			APIVersion:    stripe.String("2024-09-30.acacia"),
 		}
 		newWebhook, err := webhookendpoint.New(createParams)
 		if err != nil {
@@ -129,11 +297,27 @@ func (e *Stripe) CreatedPay(notifyURL string, gatewayConfig *model.Payment) erro
 		}
 		wh = newWebhook
 		fmt.Printf("Created new webhook: %s\n", newWebhook.ID)
+	} else if !containsAll(existingWebhook.EnabledEvents, webhookEvents) {
+		// 旧版本创建的 Webhook 只订阅了支付完成事件，补充订阅相关的事件
+		updateParams := &stripe.WebhookEndpointParams{
+			EnabledEvents: stripe.StringSlice(webhookEvents),
+		}
+		updatedWebhook, err := webhookendpoint.Update(existingWebhook.ID, updateParams)
+		if err != nil {
+			return fmt.Errorf("error updating webhook: %v", err)
+		}
+		wh = updatedWebhook
+		fmt.Printf("Updated webhook events: %s\n", updatedWebhook.ID)
 	} else {
 		fmt.Printf("Webhook already exists: %s\n", existingWebhook.ID)
 		wh = existingWebhook
 	}
 
+	// 只有创建时才会返回密钥，已存在的 Webhook 保留配置中的密钥
+	if wh.Secret == "" {
+		return nil
+	}
+
 	stripeConfig.WebhookSecret = wh.Secret
 	config, err := json.Marshal(stripeConfig)
 	if err != nil {
@@ -158,6 +342,15 @@ func contains(slice []string, str string) bool {
 	return false
 }
 
+func containsAll(slice []string, items []string) bool {
+	for _, item := range items {
+		if !contains(slice, item) {
+			return false
+		}
+	}
+	return true
+}
+
 // HandleCallback 处理支付回调
 func (e *Stripe) HandleCallback(c *gin.Context, gatewayConfig string) (*types.PayNotify, error) {
 	body, err := c.GetRawData()
@@ -192,6 +385,22 @@ func (e *Stripe) HandleCallback(c *gin.Context, gatewayConfig string) (*types.Pa
 		// 获取订单号
 		orderID := session.ClientReferenceID
 
+		// 订阅模式没有 PaymentIntent，使用 Stripe 的订阅编号
+		if session.Mode == stripe.CheckoutSessionModeSubscription {
+			if session.Subscription == nil {
+				return nil, fmt.Errorf("subscription not found in session: %s", session.ID)
+			}
+			return &types.PayNotify{
+				TradeNo:        orderID,
+				GatewayNo:      session.Subscription.ID,
+				SubscriptionNo: session.Subscription.ID,
+			}, nil
+		}
+
+		if session.PaymentIntent == nil {
+			return nil, fmt.Errorf("payment intent not found in session: %s", session.ID)
+		}
+
 		// 构造 PayNotify
 		payNotify := &types.PayNotify{
 			TradeNo:   orderID,
@@ -199,6 +408,67 @@ func (e *Stripe) HandleCallback(c *gin.Context, gatewayConfig string) (*types.Pa
 		}
 
 		return payNotify, nil
+	case "invoice.paid":
+		var invoice stripe.Invoice
+		err := json.Unmarshal(event.Data.Raw, &invoice)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse invoice data: %v", err)
+		}
+
+		// 首次扣款由 checkout.session.completed 处理，这里只处理周期续费
+		if invoice.BillingReason != stripe.InvoiceBillingReasonSubscriptionCycle || invoice.Subscription == nil {
+			return nil, nil
+		}
+
+		var periodEnd int64
+		if invoice.Lines != nil {
+			for _, line := range invoice.Lines.Data {
+				if line.Period != nil && line.Period.End > periodEnd {
+					periodEnd = line.Period.End
+				}
+			}
+		}
+
+		return &types.PayNotify{
+			GatewayNo:         invoice.ID,
+			SubscriptionNo:    invoice.Subscription.ID,
+			SubscriptionEvent: types.SubscriptionEventRenewed,
+			PeriodEnd:         periodEnd,
+			Amount:            float64(invoice.AmountPaid) / 100,
+		}, nil
+	case "charge.dispute.created":
+		var dispute stripe.Dispute
+		err := json.Unmarshal(event.Data.Raw, &dispute)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dispute data: %v", err)
+		}
+
+		if dispute.PaymentIntent == nil {
+			return nil, fmt.Errorf("payment intent not found in dispute: %s", dispute.ID)
+		}
+
+		gatewayNo, err := getDisputeGatewayNo(sc, dispute.PaymentIntent.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find dispute order: %v", err)
+		}
+
+		return &types.PayNotify{
+			GatewayNo: gatewayNo,
+			DisputeNo: dispute.ID,
+			Amount:    float64(dispute.Amount) / 100,
+		}, nil
+	case "customer.subscription.deleted":
+		var subscription stripe.Subscription
+		err := json.Unmarshal(event.Data.Raw, &subscription)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse subscription data: %v", err)
+		}
+
+		return &types.PayNotify{
+			GatewayNo:         subscription.ID,
+			SubscriptionNo:    subscription.ID,
+			SubscriptionEvent: types.SubscriptionEventCanceled,
+		}, nil
 	default:
 		return nil, nil
 	}
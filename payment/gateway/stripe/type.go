@@ -6,3 +6,11 @@ type StripeConfig struct {
 	SecretKey     string `json:"secret_key"`
 	WebhookSecret string `json:"webhook_secret"`
 }
+
+// 支付完成、订阅续费、订阅终止和拒付时的回调事件
+var webhookEvents = []string{
+	"checkout.session.completed",
+	"invoice.paid",
+	"customer.subscription.deleted",
+	"charge.dispute.created",
+}
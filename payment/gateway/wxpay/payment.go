@@ -17,6 +17,7 @@ import (
 	"github.com/wechatpay-apiv3/wechatpay-go/core/notify"
 	"github.com/wechatpay-apiv3/wechatpay-go/core/option"
 	"github.com/wechatpay-apiv3/wechatpay-go/services/payments"
+	"github.com/wechatpay-apiv3/wechatpay-go/services/refunddomestic"
 	"github.com/wechatpay-apiv3/wechatpay-go/utils"
 )
 
@@ -104,7 +105,7 @@ func (w *WeChatPay) HandleCallback(c *gin.Context, gatewayConfig string) (*types
 	}
 	if *transaction.TradeState != "SUCCESS" {
 		c.Status(http.StatusNoContent)
-		return nil, fmt.Errorf("tradeNo: %s, TransactionId: %s,  err: %v", transaction.OutTradeNo, transaction.TransactionId, err)
+		return nil, fmt.Errorf("tradeNo: %s, TransactionId: %s, tradeState: %s", *transaction.OutTradeNo, *transaction.TransactionId, *transaction.TradeState)
 	}
 
 	payNotify := &types.PayNotify{
@@ -125,6 +126,50 @@ func getWeChatConfig(gatewayConfig string) (*WeChatConfig, error) {
 	return &wechatConfig, nil
 }
 
+// Refund 按商户订单号原路退款，金额与支付时一样按分计算
+func (w *WeChatPay) Refund(config *types.RefundConfig, gatewayConfig string) (*types.RefundResult, error) {
+	wechatConfig, err := getWeChatConfig(gatewayConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		err := w.InitClient(wechatConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	total := int64(config.Money * 100)
+	req := refunddomestic.CreateRequest{
+		OutTradeNo:  core.String(config.TradeNo),
+		OutRefundNo: core.String(config.RefundNo),
+		Amount: &refunddomestic.AmountReq{
+			Refund:   core.Int64(total),
+			Total:    core.Int64(total),
+			Currency: core.String("CNY"),
+		},
+	}
+	if config.Reason != "" {
+		req.Reason = core.String(config.Reason)
+	}
+
+	rService := refunddomestic.RefundsApiService{Client: client}
+	resp, result, err := rService.Create(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("wechat refund failed: %s", err.Error())
+	}
+	if result.Response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("wechat refund failed: %s", result.Response.Status)
+	}
+
+	refundResult := &types.RefundResult{}
+	if resp.RefundId != nil {
+		refundResult.GatewayRefundNo = *resp.RefundId
+	}
+	return refundResult, nil
+}
+
 func (w *WeChatPay) CreatedPay(_ string, _ *model.Payment) error {
 	return nil
 }
@@ -18,6 +18,17 @@ type PaymentProcessor interface {
 	HandleCallback(c *gin.Context, gatewayConfig string) (*types.PayNotify, error)
 }
 
+// SubscriptionProcessor 支持周期扣款的支付网关，不支持的网关订阅时按单次支付处理，由用户手动续费
+type SubscriptionProcessor interface {
+	PaySubscription(config *types.PayConfig, subscription *types.SubscriptionConfig, gatewayConfig string) (*types.PayRequest, error)
+	CancelSubscription(subscriptionNo string, gatewayConfig string) error
+}
+
+// RefundProcessor 支持原路退款的支付网关
+type RefundProcessor interface {
+	Refund(config *types.RefundConfig, gatewayConfig string) (*types.RefundResult, error)
+}
+
 var Gateways = make(map[string]PaymentProcessor)
 
 func init() {
@@ -39,6 +39,24 @@ func NewPaymentService(uuid string) (*PaymentService, error) {
 	}, nil
 }
 
+// NewPaymentServiceByID 按 ID 获取支付网关，用于处理已有订阅，网关停用后仍可取消周期扣款
+func NewPaymentServiceByID(id int) (*PaymentService, error) {
+	payment, err := model.GetPaymentByID(id)
+	if err != nil {
+		return nil, errors.New("payment not found")
+	}
+
+	gateway, ok := Gateways[payment.Type]
+	if !ok {
+		return nil, errors.New("payment gateway not found")
+	}
+
+	return &PaymentService{
+		Payment: payment,
+		gateway: gateway,
+	}, nil
+}
+
 func (s *PaymentService) CreatedPay() error {
 	notifyURL := s.getNotifyURL()
 	return s.gateway.CreatedPay(notifyURL, s.Payment)
@@ -61,6 +79,63 @@ func (s *PaymentService) Pay(tradeNo string, amount float64, user *model.User) (
 	return payRequest, nil
 }
 
+// SupportSubscription 支付网关是否支持周期扣款
+func (s *PaymentService) SupportSubscription() bool {
+	_, ok := s.gateway.(SubscriptionProcessor)
+	return ok
+}
+
+// PaySubscription 发起订阅支付，不支持周期扣款的网关按单次支付处理
+func (s *PaymentService) PaySubscription(tradeNo string, amount float64, user *model.User, subscription *types.SubscriptionConfig) (*types.PayRequest, error) {
+	processor, ok := s.gateway.(SubscriptionProcessor)
+	if !ok {
+		return s.Pay(tradeNo, amount, user)
+	}
+
+	config := &types.PayConfig{
+		Money:     amount,
+		TradeNo:   tradeNo,
+		NotifyURL: s.getNotifyURL(),
+		ReturnURL: s.getReturnURL(),
+		Currency:  s.Payment.Currency,
+		User:      user,
+	}
+	return processor.PaySubscription(config, subscription, s.Payment.Config)
+}
+
+// CancelSubscription 取消支付网关的周期扣款
+func (s *PaymentService) CancelSubscription(subscriptionNo string) error {
+	processor, ok := s.gateway.(SubscriptionProcessor)
+	if !ok || subscriptionNo == "" {
+		return nil
+	}
+	return processor.CancelSubscription(subscriptionNo, s.Payment.Config)
+}
+
+// SupportRefund 支付网关是否支持原路退款
+func (s *PaymentService) SupportRefund() bool {
+	_, ok := s.gateway.(RefundProcessor)
+	return ok
+}
+
+// Refund 原路全额退还订单的支付金额
+func (s *PaymentService) Refund(order *model.Order, refundNo, reason string) (*types.RefundResult, error) {
+	processor, ok := s.gateway.(RefundProcessor)
+	if !ok {
+		return nil, errors.New("payment gateway does not support refund")
+	}
+
+	config := &types.RefundConfig{
+		TradeNo:   order.TradeNo,
+		GatewayNo: order.GatewayNo,
+		RefundNo:  refundNo,
+		Money:     order.OrderAmount,
+		Currency:  order.OrderCurrency,
+		Reason:    reason,
+	}
+	return processor.Refund(config, s.Payment.Config)
+}
+
 func (s *PaymentService) HandleCallback(c *gin.Context, gatewayConfig string) (*types.PayNotify, error) {
 	payNotify, err := s.gateway.HandleCallback(c, gatewayConfig)
 	if err != nil {
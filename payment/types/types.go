@@ -24,8 +24,46 @@ type PayRequestData struct {
 	Params any    `json:"params,omitempty"`
 }
 
+// 订阅套餐的周期扣款配置
+type SubscriptionConfig struct {
+	Name          string `json:"name"`
+	Interval      string `json:"interval"` // day, week, month, year
+	IntervalCount int    `json:"interval_count"`
+}
+
+const (
+	// 周期扣款成功，PeriodEnd 为本次支付的周期结束时间
+	SubscriptionEventRenewed = "renewed"
+	// 支付网关侧的周期扣款已终止
+	SubscriptionEventCanceled = "canceled"
+)
+
 // 支付回调时的数据结构
 type PayNotify struct {
 	TradeNo   string `json:"trade_no"`
 	GatewayNo string `json:"gateway_no"`
+
+	// 支付网关的周期扣款编号，订阅首次支付和后续事件时返回
+	SubscriptionNo string `json:"subscription_no,omitempty"`
+	// 不关联订单的订阅事件，为空时按 TradeNo 处理订单
+	SubscriptionEvent string  `json:"subscription_event,omitempty"`
+	PeriodEnd         int64   `json:"period_end,omitempty"`
+	Amount            float64 `json:"amount,omitempty"` // 续费的实付金额或拒付的金额
+
+	// 拒付（争议）编号，不为空时 GatewayNo 为被拒付的支付编号
+	DisputeNo string `json:"dispute_no,omitempty"`
+}
+
+// 退款请求的数据结构，目前只支持全额退款
+type RefundConfig struct {
+	TradeNo   string             `json:"trade_no"`
+	GatewayNo string             `json:"gateway_no"`
+	RefundNo  string             `json:"refund_no"` // 本次退款的唯一编号
+	Money     float64            `json:"money"`     // 退款金额，与支付时的币种一致
+	Currency  model.CurrencyType `json:"currency"`
+	Reason    string             `json:"reason"`
+}
+
+type RefundResult struct {
+	GatewayRefundNo string `json:"gateway_refund_no"`
 }
@@ -0,0 +1,501 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/common/utils"
+	"one-api/types"
+	"strings"
+)
+
+// 以下为 Claude Messages API 与 OpenAI Chat API 的互相转换，用于通过对话渠道提供 Claude 接口
+
+// ConvertToChatRequest 将 Claude 请求转换为对话请求
+func ConvertToChatRequest(request *ClaudeRequest) (*types.ChatCompletionRequest, error) {
+	chatRequest := &types.ChatCompletionRequest{
+		Model:       request.Model,
+		Messages:    make([]types.ChatCompletionMessage, 0, len(request.Messages)+1),
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+		Stream:      request.Stream,
+	}
+
+	if request.TopK != nil {
+		topK := float64(*request.TopK)
+		chatRequest.TopK = &topK
+	}
+
+	if len(request.StopSequences) > 0 {
+		chatRequest.Stop = request.StopSequences
+	}
+
+	if request.Stream {
+		chatRequest.StreamOptions = &types.StreamOptions{IncludeUsage: true}
+	}
+
+	if system := claudeContentText(request.System); system != "" {
+		chatRequest.Messages = append(chatRequest.Messages, types.ChatCompletionMessage{
+			Role:    types.ChatMessageRoleSystem,
+			Content: system,
+		})
+	}
+
+	for _, message := range request.Messages {
+		messages, err := convertClaudeMessage(&message)
+		if err != nil {
+			return nil, err
+		}
+		chatRequest.Messages = append(chatRequest.Messages, messages...)
+	}
+
+	for _, tool := range request.Tools {
+		// 服务端工具（如 web_search、bash）无法在其他渠道执行
+		if tool.InputSchema == nil || (tool.Type != "" && tool.Type != "custom") {
+			continue
+		}
+		chatRequest.Tools = append(chatRequest.Tools, &types.ChatCompletionTool{
+			Type: types.ChatMessageRoleFunction,
+			Function: types.ChatCompletionFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	if request.ToolChoice != nil && len(chatRequest.Tools) > 0 {
+		chatRequest.ToolChoice = convertClaudeToolChoice(request.ToolChoice)
+	}
+
+	if request.Thinking != nil && request.Thinking.Type == "enabled" {
+		chatRequest.Reasoning = &types.ChatReasoning{
+			MaxTokens: request.Thinking.BudgetTokens,
+		}
+	}
+
+	return chatRequest, nil
+}
+
+func convertClaudeToolChoice(choice *ToolChoice) any {
+	switch choice.Type {
+	case "any":
+		return types.ToolChoiceTypeRequired
+	case "none":
+		return "none"
+	case "tool":
+		return map[string]any{
+			"type": types.ChatMessageRoleFunction,
+			"function": map[string]string{
+				"name": choice.Name,
+			},
+		}
+	default:
+		return "auto"
+	}
+}
+
+// compatibleContent thinking 内容块的文本在 thinking 字段中
+type compatibleContent struct {
+	MessageContent
+	Thinking string `json:"thinking,omitempty"`
+}
+
+// convertClaudeMessage 一条 Claude 消息可能包含多个工具结果，需要拆成多条对话消息
+func convertClaudeMessage(message *Message) ([]types.ChatCompletionMessage, error) {
+	if text, ok := message.Content.(string); ok {
+		return []types.ChatCompletionMessage{{Role: message.Role, Content: text}}, nil
+	}
+
+	var contents []compatibleContent
+	if err := remarshal(message.Content, &contents); err != nil {
+		return nil, fmt.Errorf("invalid message content: %w", err)
+	}
+
+	messages := make([]types.ChatCompletionMessage, 0)
+	parts := make([]types.ChatMessagePart, 0)
+	toolCalls := make([]*types.ChatCompletionToolCalls, 0)
+	var reasoning strings.Builder
+
+	for _, content := range contents {
+		switch content.Type {
+		case ContentTypeText:
+			parts = append(parts, types.ChatMessagePart{
+				Type: types.ContentTypeText,
+				Text: content.Text,
+			})
+		case ContentTypeImage:
+			if imageURL := claudeSourceURL(content.Source); imageURL != "" {
+				parts = append(parts, types.ChatMessagePart{
+					Type:     types.ContentTypeImageURL,
+					ImageURL: &types.ChatMessageImageURL{URL: imageURL},
+				})
+			}
+		case "document":
+			if fileData := claudeSourceURL(content.Source); fileData != "" {
+				parts = append(parts, types.ChatMessagePart{
+					Type: "file",
+					File: &types.ChatMessageFile{FileData: fileData},
+				})
+			}
+		case ContentTypeThinking:
+			reasoning.WriteString(content.Thinking)
+		case ContentTypeToolUes:
+			arguments, _ := json.Marshal(content.Input)
+			toolCalls = append(toolCalls, &types.ChatCompletionToolCalls{
+				Id:   content.Id,
+				Type: types.ChatMessageRoleFunction,
+				Function: &types.ChatCompletionToolCallsFunction{
+					Name:      content.Name,
+					Arguments: string(arguments),
+				},
+			})
+		case ContentTypeToolResult:
+			result := claudeContentText(content.Content)
+			if content.IsError != nil && *content.IsError {
+				result = "Error: " + result
+			}
+			messages = append(messages, types.ChatCompletionMessage{
+				Role:       types.ChatMessageRoleTool,
+				Content:    result,
+				ToolCallID: content.ToolUseId,
+			})
+		}
+	}
+
+	if message.Role == types.ChatMessageRoleAssistant {
+		assistant := types.ChatCompletionMessage{
+			Role:             types.ChatMessageRoleAssistant,
+			Content:          joinTextParts(parts),
+			ReasoningContent: reasoning.String(),
+		}
+		if len(toolCalls) > 0 {
+			assistant.ToolCalls = toolCalls
+		}
+		return append(messages, assistant), nil
+	}
+
+	// 工具结果需要紧跟在工具调用之后，其余内容放到之后的用户消息中
+	if len(parts) > 0 {
+		messages = append(messages, types.ChatCompletionMessage{
+			Role:    message.Role,
+			Content: parts,
+		})
+	}
+
+	return messages, nil
+}
+
+func remarshal(from any, to any) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, to)
+}
+
+func joinTextParts(parts []types.ChatMessagePart) string {
+	var text strings.Builder
+	for _, part := range parts {
+		text.WriteString(part.Text)
+	}
+
+	return text.String()
+}
+
+func claudeSourceURL(source *ContentSource) string {
+	if source == nil {
+		return ""
+	}
+
+	if source.Type == "base64" {
+		return fmt.Sprintf("data:%s;base64,%s", source.MediaType, source.Data)
+	}
+
+	return source.Url
+}
+
+// claudeContentText 提取 system 或工具结果中的文本，支持字符串和内容块数组
+func claudeContentText(content any) string {
+	switch v := content.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+
+	var blocks []MessageContent
+	if err := remarshal(content, &blocks); err != nil {
+		return ""
+	}
+
+	texts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type == ContentTypeText {
+			texts = append(texts, block.Text)
+		}
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+func stopReasonOpenAI2Claude(reason string) string {
+	switch reason {
+	case types.FinishReasonStop:
+		return "end_turn"
+	case types.FinishReasonLength:
+		return "max_tokens"
+	case types.FinishReasonToolCalls, types.FinishReasonFunctionCall:
+		return "tool_use"
+	case types.FinishReasonContentFilter:
+		return "refusal"
+	case "":
+		return "end_turn"
+	default:
+		return reason
+	}
+}
+
+// ConvertFromChatResponse 将对话响应转换为 Claude 响应
+func ConvertFromChatResponse(response *types.ChatCompletionResponse, modelName string, usage *types.Usage) *ClaudeResponse {
+	claudeResponse := &ClaudeResponse{
+		Id:      claudeMessageId(response.ID),
+		Type:    "message",
+		Role:    types.ChatMessageRoleAssistant,
+		Model:   modelName,
+		Content: make([]ResContent, 0),
+	}
+
+	finishReason := ""
+	for _, choice := range response.Choices {
+		if choice.Message.ReasoningContent != "" {
+			claudeResponse.Content = append(claudeResponse.Content, ResContent{
+				Type:     ContentTypeThinking,
+				Thinking: choice.Message.ReasoningContent,
+			})
+		}
+
+		if text := choice.Message.StringContent(); text != "" {
+			claudeResponse.Content = append(claudeResponse.Content, ResContent{
+				Type: ContentTypeText,
+				Text: text,
+			})
+		}
+
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function == nil {
+				continue
+			}
+			claudeResponse.Content = append(claudeResponse.Content, ResContent{
+				Type:  ContentTypeToolUes,
+				Id:    toolCall.Id,
+				Name:  toolCall.Function.Name,
+				Input: parseToolArguments(toolCall.Function.Arguments),
+			})
+		}
+
+		if choice.FinishReason != "" {
+			finishReason = choice.FinishReason
+		}
+	}
+
+	claudeResponse.StopReason = stopReasonOpenAI2Claude(finishReason)
+	if usage != nil {
+		claudeResponse.Usage = Usage{
+			InputTokens:  usage.PromptTokens,
+			OutputTokens: usage.CompletionTokens,
+		}
+	}
+
+	return claudeResponse
+}
+
+func parseToolArguments(arguments string) any {
+	input := make(map[string]any)
+	if arguments != "" {
+		json.Unmarshal([]byte(arguments), &input)
+	}
+
+	return input
+}
+
+func claudeMessageId(id string) string {
+	if id == "" {
+		return "msg_" + utils.GetUUID()
+	}
+	if strings.HasPrefix(id, "msg_") {
+		return id
+	}
+
+	return "msg_" + id
+}
+
+// ChatToClaudeStreamConverter 将对话流式响应转换为 Claude 流式事件
+type ChatToClaudeStreamConverter struct {
+	modelName     string
+	usage         *types.Usage
+	started       bool
+	blockIndex    int
+	blockType     string
+	toolCallIndex int
+	stopReason    string
+}
+
+func NewChatToClaudeStreamConverter(modelName string, usage *types.Usage) *ChatToClaudeStreamConverter {
+	return &ChatToClaudeStreamConverter{
+		modelName:     modelName,
+		usage:         usage,
+		blockIndex:    -1,
+		toolCallIndex: -1,
+	}
+}
+
+// Convert 转换一个对话流式数据块，返回 Claude SSE 事件
+func (s *ChatToClaudeStreamConverter) Convert(data string) string {
+	var chunk types.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return ""
+	}
+
+	var events strings.Builder
+	if !s.started {
+		s.started = true
+		s.writeEvent(&events, "message_start", map[string]any{
+			"type": "message_start",
+			"message": map[string]any{
+				"id":            claudeMessageId(chunk.ID),
+				"type":          "message",
+				"role":          types.ChatMessageRoleAssistant,
+				"model":         s.modelName,
+				"content":       []any{},
+				"stop_reason":   nil,
+				"stop_sequence": nil,
+				"usage": map[string]int{
+					"input_tokens":  s.usage.PromptTokens,
+					"output_tokens": 0,
+				},
+			},
+		})
+	}
+
+	for _, choice := range chunk.Choices {
+		delta := choice.Delta
+		reasoning := delta.ReasoningContent
+		if reasoning == "" {
+			reasoning = delta.Reasoning
+		}
+
+		if reasoning != "" {
+			s.startBlock(&events, ContentTypeThinking, map[string]any{"type": ContentTypeThinking, "thinking": ""})
+			s.writeDelta(&events, map[string]any{"type": "thinking_delta", "thinking": reasoning})
+		}
+
+		if delta.Content != "" {
+			s.startBlock(&events, ContentTypeText, map[string]any{"type": ContentTypeText, "text": ""})
+			s.writeDelta(&events, map[string]any{"type": "text_delta", "text": delta.Content})
+		}
+
+		for _, toolCall := range delta.ToolCalls {
+			if toolCall.Function == nil {
+				continue
+			}
+			// 新的工具调用开始新的内容块
+			if toolCall.Id != "" || toolCall.Index != s.toolCallIndex {
+				s.toolCallIndex = toolCall.Index
+				s.stopBlock(&events)
+				s.startBlock(&events, ContentTypeToolUes, map[string]any{
+					"type":  ContentTypeToolUes,
+					"id":    toolCall.Id,
+					"name":  toolCall.Function.Name,
+					"input": map[string]any{},
+				})
+			}
+			if toolCall.Function.Arguments != "" {
+				s.writeDelta(&events, map[string]any{"type": "input_json_delta", "partial_json": toolCall.Function.Arguments})
+			}
+		}
+
+		if reason, ok := choice.FinishReason.(string); ok && reason != "" {
+			s.stopReason = reason
+		}
+	}
+
+	return events.String()
+}
+
+// Finish 流结束时关闭内容块并输出用量
+func (s *ChatToClaudeStreamConverter) Finish() string {
+	var events strings.Builder
+	if !s.started {
+		return ""
+	}
+
+	s.stopBlock(&events)
+
+	// 部分渠道流式响应不返回用量，按输出内容计算
+	outputTokens := s.usage.CompletionTokens
+	if outputTokens == 0 && s.usage.TextBuilder.Len() > 0 {
+		outputTokens = common.CountTokenText(s.usage.TextBuilder.String(), s.modelName)
+	}
+
+	s.writeEvent(&events, "message_delta", map[string]any{
+		"type": "message_delta",
+		"delta": map[string]any{
+			"stop_reason":   stopReasonOpenAI2Claude(s.stopReason),
+			"stop_sequence": nil,
+		},
+		"usage": map[string]int{
+			"input_tokens":  s.usage.PromptTokens,
+			"output_tokens": outputTokens,
+		},
+	})
+	s.writeEvent(&events, "message_stop", map[string]any{"type": "message_stop"})
+
+	return events.String()
+}
+
+func (s *ChatToClaudeStreamConverter) startBlock(events *strings.Builder, blockType string, contentBlock map[string]any) {
+	if s.blockType == blockType && blockType != ContentTypeToolUes {
+		return
+	}
+
+	s.stopBlock(events)
+	s.blockIndex++
+	s.blockType = blockType
+	s.writeEvent(events, "content_block_start", map[string]any{
+		"type":          "content_block_start",
+		"index":         s.blockIndex,
+		"content_block": contentBlock,
+	})
+}
+
+func (s *ChatToClaudeStreamConverter) stopBlock(events *strings.Builder) {
+	if s.blockType == "" {
+		return
+	}
+
+	s.writeEvent(events, "content_block_stop", map[string]any{
+		"type":  "content_block_stop",
+		"index": s.blockIndex,
+	})
+	s.blockType = ""
+}
+
+func (s *ChatToClaudeStreamConverter) writeDelta(events *strings.Builder, delta map[string]any) {
+	s.writeEvent(events, "content_block_delta", map[string]any{
+		"type":  "content_block_delta",
+		"index": s.blockIndex,
+		"delta": delta,
+	})
+}
+
+func (s *ChatToClaudeStreamConverter) writeEvent(events *strings.Builder, event string, data any) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	events.WriteString("event: " + event + "\ndata: " + string(body) + "\n\n")
+}
@@ -0,0 +1,83 @@
+package claude_test
+
+import (
+	"encoding/json"
+	"one-api/providers/claude"
+	"one-api/types"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertToChatRequest(t *testing.T) {
+	body := `{
+		"model": "claude-sonnet-4",
+		"max_tokens": 1024,
+		"system": [{"type": "text", "text": "You are helpful."}],
+		"tools": [
+			{"name": "get_weather", "description": "Get weather", "input_schema": {"type": "object"}},
+			{"type": "web_search_20250305", "name": "web_search"}
+		],
+		"tool_choice": {"type": "any"},
+		"thinking": {"type": "enabled", "budget_tokens": 2048},
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "text", "text": "weather?"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "AAAA"}}
+			]},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "need tool"},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": "sunny"},
+				{"type": "text", "text": "thanks"}
+			]}
+		]
+	}`
+
+	var request claude.ClaudeRequest
+	assert.Nil(t, json.Unmarshal([]byte(body), &request))
+
+	chatRequest, err := claude.ConvertToChatRequest(&request)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1024, chatRequest.MaxTokens)
+	assert.Equal(t, types.ToolChoiceTypeRequired, chatRequest.ToolChoice)
+	assert.Equal(t, 2048, chatRequest.Reasoning.MaxTokens)
+	assert.Len(t, chatRequest.Tools, 1)
+	assert.Equal(t, "get_weather", chatRequest.Tools[0].Function.Name)
+
+	messages := chatRequest.Messages
+	assert.Len(t, messages, 5)
+	assert.Equal(t, types.ChatMessageRoleSystem, messages[0].Role)
+	assert.Equal(t, "You are helpful.", messages[0].Content)
+
+	parts, ok := messages[1].Content.([]types.ChatMessagePart)
+	assert.True(t, ok)
+	assert.Equal(t, "data:image/png;base64,AAAA", parts[1].ImageURL.URL)
+
+	assert.Equal(t, "need tool", messages[2].ReasoningContent)
+	assert.Equal(t, `{"city":"Paris"}`, messages[2].ToolCalls[0].Function.Arguments)
+
+	assert.Equal(t, types.ChatMessageRoleTool, messages[3].Role)
+	assert.Equal(t, "toolu_1", messages[3].ToolCallID)
+	assert.Equal(t, types.ChatMessageRoleUser, messages[4].Role)
+}
+
+func TestChatToClaudeStreamConverter(t *testing.T) {
+	usage := &types.Usage{PromptTokens: 10, CompletionTokens: 3}
+	converter := claude.NewChatToClaudeStreamConverter("claude-sonnet-4", usage)
+
+	events := converter.Convert(`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"Hi"}}]}`)
+	events += converter.Convert(`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"f","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`)
+	events += converter.Finish()
+
+	for _, event := range []string{"message_start", "content_block_start", "text_delta", "input_json_delta", "content_block_stop", "message_delta", "message_stop"} {
+		assert.Contains(t, events, event)
+	}
+	assert.Equal(t, 2, strings.Count(events, "event: content_block_stop"))
+	assert.Contains(t, events, `"stop_reason":"tool_use"`)
+	assert.Contains(t, events, `"output_tokens":3`)
+}
@@ -0,0 +1,455 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/common/utils"
+	"one-api/types"
+	"strings"
+)
+
+// 以下为 Gemini generateContent 与 OpenAI Chat API 的互相转换，用于通过对话渠道提供 Gemini 接口
+// 对话接口没有安全设置，safetySettings 无法透传，上游因内容过滤结束时返回 SAFETY
+
+// ConvertToChatRequest 将 Gemini 请求转换为对话请求
+func ConvertToChatRequest(request *GeminiChatRequest) (*types.ChatCompletionRequest, error) {
+	generationConfig := request.GenerationConfig
+	chatRequest := &types.ChatCompletionRequest{
+		Model:       request.Model,
+		Messages:    make([]types.ChatCompletionMessage, 0, len(request.Contents)+1),
+		MaxTokens:   generationConfig.MaxOutputTokens,
+		Temperature: generationConfig.Temperature,
+		TopP:        generationConfig.TopP,
+		TopK:        generationConfig.TopK,
+		Stream:      request.Stream,
+	}
+
+	if len(generationConfig.StopSequences) > 0 {
+		chatRequest.Stop = generationConfig.StopSequences
+	}
+
+	if generationConfig.CandidateCount > 1 {
+		chatRequest.N = &generationConfig.CandidateCount
+	}
+
+	if request.Stream {
+		chatRequest.StreamOptions = &types.StreamOptions{IncludeUsage: true}
+	}
+
+	if generationConfig.ResponseMimeType == "application/json" {
+		chatRequest.ResponseFormat = &types.ChatCompletionResponseFormat{Type: "json_object"}
+		if generationConfig.ResponseSchema != nil {
+			chatRequest.ResponseFormat = &types.ChatCompletionResponseFormat{
+				Type: "json_schema",
+				JsonSchema: &types.FormatJsonSchema{
+					Name:   "response",
+					Schema: generationConfig.ResponseSchema,
+				},
+			}
+		}
+	}
+
+	if thinkingConfig := generationConfig.ThinkingConfig; thinkingConfig != nil {
+		reasoning := &types.ChatReasoning{
+			Effort: strings.ToLower(thinkingConfig.ThinkingLevel),
+		}
+		if thinkingConfig.ThinkingBudget != nil && *thinkingConfig.ThinkingBudget > 0 {
+			reasoning.MaxTokens = *thinkingConfig.ThinkingBudget
+		}
+		if reasoning.MaxTokens > 0 || reasoning.Effort != "" {
+			chatRequest.Reasoning = reasoning
+		}
+	}
+
+	if system := geminiSystemText(request.SystemInstruction); system != "" {
+		chatRequest.Messages = append(chatRequest.Messages, types.ChatCompletionMessage{
+			Role:    types.ChatMessageRoleSystem,
+			Content: system,
+		})
+	}
+
+	// Gemini 通过函数名对应调用和结果，转换时按顺序为每个调用生成 id
+	callIds := make(map[string][]string)
+	for _, content := range request.Contents {
+		messages, err := convertGeminiContent(&content, callIds)
+		if err != nil {
+			return nil, err
+		}
+		chatRequest.Messages = append(chatRequest.Messages, messages...)
+	}
+
+	for _, tool := range request.Tools {
+		// googleSearch、codeExecution 等内置工具无法在其他渠道执行
+		for i := range tool.FunctionDeclarations {
+			function := tool.FunctionDeclarations[i]
+			chatRequest.Tools = append(chatRequest.Tools, &types.ChatCompletionTool{
+				Type:     types.ChatMessageRoleFunction,
+				Function: function,
+			})
+		}
+	}
+
+	if len(chatRequest.Tools) > 0 && request.ToolConfig != nil && request.ToolConfig.FunctionCallingConfig != nil {
+		chatRequest.ToolChoice = convertGeminiToolChoice(request.ToolConfig.FunctionCallingConfig)
+	}
+
+	return chatRequest, nil
+}
+
+func convertGeminiToolChoice(config *GeminiFunctionCallingConfig) any {
+	switch config.Mode {
+	case "ANY":
+		// 只允许一个函数时指定该函数
+		if names, ok := config.AllowedFunctionNames.([]any); ok && len(names) == 1 {
+			if name, ok := names[0].(string); ok {
+				return map[string]any{
+					"type": types.ChatMessageRoleFunction,
+					"function": map[string]string{
+						"name": name,
+					},
+				}
+			}
+		}
+		return types.ToolChoiceTypeRequired
+	case "NONE":
+		return "none"
+	default:
+		return "auto"
+	}
+}
+
+func convertGeminiContent(content *GeminiChatContent, callIds map[string][]string) ([]types.ChatCompletionMessage, error) {
+	messages := make([]types.ChatCompletionMessage, 0)
+	parts := make([]types.ChatMessagePart, 0)
+	toolCalls := make([]*types.ChatCompletionToolCalls, 0)
+	reasoning := make([]string, 0)
+
+	for _, part := range content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			id := "call_" + utils.GetRandomString(24)
+			callIds[part.FunctionCall.Name] = append(callIds[part.FunctionCall.Name], id)
+
+			args, _ := json.Marshal(part.FunctionCall.Args)
+			if part.FunctionCall.Args == nil {
+				args = []byte("{}")
+			}
+			toolCalls = append(toolCalls, &types.ChatCompletionToolCalls{
+				Id:    id,
+				Type:  types.ChatMessageRoleFunction,
+				Index: len(toolCalls),
+				Function: &types.ChatCompletionToolCallsFunction{
+					Name:      part.FunctionCall.Name,
+					Arguments: string(args),
+				},
+			})
+		case part.FunctionResponse != nil:
+			name := part.FunctionResponse.Name
+			id := "call_" + utils.GetRandomString(24)
+			if ids := callIds[name]; len(ids) > 0 {
+				id = ids[0]
+				callIds[name] = ids[1:]
+			}
+
+			result, err := json.Marshal(part.FunctionResponse.Response)
+			if err != nil {
+				return nil, fmt.Errorf("invalid function response: %w", err)
+			}
+			messages = append(messages, types.ChatCompletionMessage{
+				Role:       types.ChatMessageRoleTool,
+				Content:    string(result),
+				Name:       &name,
+				ToolCallID: id,
+			})
+		case part.InlineData != nil:
+			parts = append(parts, inlineDataToPart(part.InlineData))
+		case part.FileData != nil:
+			if strings.HasPrefix(part.FileData.MimeType, "image/") {
+				parts = append(parts, types.ChatMessagePart{
+					Type:     types.ContentTypeImageURL,
+					ImageURL: &types.ChatMessageImageURL{URL: part.FileData.FileUri},
+				})
+			}
+		case part.Thought:
+			reasoning = append(reasoning, part.Text)
+		case part.Text != "":
+			parts = append(parts, types.ChatMessagePart{
+				Type: types.ContentTypeText,
+				Text: part.Text,
+			})
+		}
+	}
+
+	if content.Role == "model" {
+		assistant := types.ChatCompletionMessage{
+			Role:             types.ChatMessageRoleAssistant,
+			Content:          joinTextParts(parts),
+			ReasoningContent: strings.Join(reasoning, "\n"),
+		}
+		if len(toolCalls) > 0 {
+			assistant.ToolCalls = toolCalls
+		}
+		return append(messages, assistant), nil
+	}
+
+	// 函数结果需要紧跟在函数调用之后，其余内容放到之后的用户消息中
+	if len(parts) > 0 {
+		messages = append(messages, types.ChatCompletionMessage{
+			Role:    types.ChatMessageRoleUser,
+			Content: parts,
+		})
+	}
+
+	return messages, nil
+}
+
+func inlineDataToPart(data *GeminiInlineData) types.ChatMessagePart {
+	dataURL := fmt.Sprintf("data:%s;base64,%s", data.MimeType, data.Data)
+
+	switch {
+	case strings.HasPrefix(data.MimeType, "image/"):
+		return types.ChatMessagePart{
+			Type:     types.ContentTypeImageURL,
+			ImageURL: &types.ChatMessageImageURL{URL: dataURL},
+		}
+	case strings.HasPrefix(data.MimeType, "audio/"):
+		return types.ChatMessagePart{
+			Type: "input_audio",
+			InputAudio: &types.InputAudio{
+				Data:   data.Data,
+				Format: strings.TrimPrefix(data.MimeType, "audio/"),
+			},
+		}
+	default:
+		return types.ChatMessagePart{
+			Type: "file",
+			File: &types.ChatMessageFile{FileData: dataURL},
+		}
+	}
+}
+
+func joinTextParts(parts []types.ChatMessagePart) string {
+	var text strings.Builder
+	for _, part := range parts {
+		text.WriteString(part.Text)
+	}
+
+	return text.String()
+}
+
+// geminiSystemText systemInstruction 可以是字符串或 Content 对象
+func geminiSystemText(systemInstruction any) string {
+	switch v := systemInstruction.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+
+	data, err := json.Marshal(systemInstruction)
+	if err != nil {
+		return ""
+	}
+
+	var content GeminiChatContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return ""
+	}
+
+	texts := make([]string, 0, len(content.Parts))
+	for _, part := range content.Parts {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+func finishReasonOpenAI2Gemini(reason string) string {
+	switch reason {
+	case types.FinishReasonLength:
+		return "MAX_TOKENS"
+	case types.FinishReasonContentFilter:
+		return "SAFETY"
+	default:
+		return "STOP"
+	}
+}
+
+func usageToGemini(usage *types.Usage) *GeminiUsageMetadata {
+	if usage == nil {
+		return nil
+	}
+
+	return &GeminiUsageMetadata{
+		PromptTokenCount:        usage.PromptTokens,
+		CandidatesTokenCount:    usage.CompletionTokens,
+		TotalTokenCount:         usage.PromptTokens + usage.CompletionTokens,
+		CachedContentTokenCount: usage.PromptTokensDetails.CachedTokens,
+		ThoughtsTokenCount:      usage.CompletionTokensDetails.ReasoningTokens,
+	}
+}
+
+func functionCallPart(name, arguments string) GeminiPart {
+	args := make(map[string]any)
+	if arguments != "" {
+		json.Unmarshal([]byte(arguments), &args)
+	}
+
+	return GeminiPart{
+		FunctionCall: &GeminiFunctionCall{
+			Name: name,
+			Args: args,
+		},
+	}
+}
+
+// ConvertFromChatResponse 将对话响应转换为 Gemini 响应
+func ConvertFromChatResponse(response *types.ChatCompletionResponse, modelName string, usage *types.Usage) *GeminiChatResponse {
+	geminiResponse := &GeminiChatResponse{
+		Candidates:    make([]GeminiChatCandidate, 0, len(response.Choices)),
+		UsageMetadata: usageToGemini(usage),
+		ModelVersion:  modelName,
+		ResponseId:    response.ID,
+	}
+
+	for _, choice := range response.Choices {
+		parts := make([]GeminiPart, 0)
+		if choice.Message.ReasoningContent != "" {
+			parts = append(parts, GeminiPart{Text: choice.Message.ReasoningContent, Thought: true})
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			parts = append(parts, GeminiPart{Text: text})
+		}
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function != nil {
+				parts = append(parts, functionCallPart(toolCall.Function.Name, toolCall.Function.Arguments))
+			}
+		}
+
+		finishReason := finishReasonOpenAI2Gemini(choice.FinishReason)
+		geminiResponse.Candidates = append(geminiResponse.Candidates, GeminiChatCandidate{
+			Index:         int64(choice.Index),
+			Content:       GeminiChatContent{Role: "model", Parts: parts},
+			FinishReason:  &finishReason,
+			SafetyRatings: []GeminiChatSafetyRating{},
+		})
+	}
+
+	return geminiResponse
+}
+
+type streamToolCall struct {
+	name      string
+	arguments strings.Builder
+}
+
+// ChatToGeminiStreamConverter 将对话流式响应转换为 Gemini 流式响应
+// Gemini 的函数调用不分片，参数拼接完整后在结束时一起输出
+type ChatToGeminiStreamConverter struct {
+	modelName    string
+	usage        *types.Usage
+	responseId   string
+	toolCalls    []*streamToolCall
+	finishReason string
+}
+
+func NewChatToGeminiStreamConverter(modelName string, usage *types.Usage) *ChatToGeminiStreamConverter {
+	return &ChatToGeminiStreamConverter{
+		modelName: modelName,
+		usage:     usage,
+	}
+}
+
+// Convert 转换一个对话流式数据块，返回 Gemini SSE 数据
+func (s *ChatToGeminiStreamConverter) Convert(data string) string {
+	var chunk types.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return ""
+	}
+
+	if s.responseId == "" {
+		s.responseId = chunk.ID
+	}
+
+	parts := make([]GeminiPart, 0)
+	for _, choice := range chunk.Choices {
+		delta := choice.Delta
+		reasoning := delta.ReasoningContent
+		if reasoning == "" {
+			reasoning = delta.Reasoning
+		}
+		if reasoning != "" {
+			parts = append(parts, GeminiPart{Text: reasoning, Thought: true})
+		}
+		if delta.Content != "" {
+			parts = append(parts, GeminiPart{Text: delta.Content})
+		}
+
+		for _, toolCall := range delta.ToolCalls {
+			if toolCall.Function == nil {
+				continue
+			}
+			for len(s.toolCalls) <= toolCall.Index {
+				s.toolCalls = append(s.toolCalls, &streamToolCall{})
+			}
+			call := s.toolCalls[toolCall.Index]
+			if toolCall.Function.Name != "" {
+				call.name = toolCall.Function.Name
+			}
+			call.arguments.WriteString(toolCall.Function.Arguments)
+		}
+
+		if reason, ok := choice.FinishReason.(string); ok && reason != "" {
+			s.finishReason = reason
+		}
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return s.event(parts, nil, nil)
+}
+
+// Finish 流结束时输出函数调用、结束原因和用量
+func (s *ChatToGeminiStreamConverter) Finish() string {
+	parts := make([]GeminiPart, 0, len(s.toolCalls))
+	for _, call := range s.toolCalls {
+		if call.name != "" {
+			parts = append(parts, functionCallPart(call.name, call.arguments.String()))
+		}
+	}
+
+	// 部分渠道流式响应不返回用量，按输出内容计算
+	usage := usageToGemini(s.usage)
+	if usage.CandidatesTokenCount == 0 && s.usage.TextBuilder.Len() > 0 {
+		usage.CandidatesTokenCount = common.CountTokenText(s.usage.TextBuilder.String(), s.modelName)
+		usage.TotalTokenCount = usage.PromptTokenCount + usage.CandidatesTokenCount
+	}
+
+	finishReason := finishReasonOpenAI2Gemini(s.finishReason)
+	return s.event(parts, &finishReason, usage)
+}
+
+func (s *ChatToGeminiStreamConverter) event(parts []GeminiPart, finishReason *string, usage *GeminiUsageMetadata) string {
+	response := GeminiChatResponse{
+		Candidates: []GeminiChatCandidate{{
+			Content:       GeminiChatContent{Role: "model", Parts: parts},
+			FinishReason:  finishReason,
+			SafetyRatings: []GeminiChatSafetyRating{},
+		}},
+		UsageMetadata: usage,
+		ModelVersion:  s.modelName,
+		ResponseId:    s.responseId,
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return ""
+	}
+
+	return "data: " + string(body) + "\n\n"
+}
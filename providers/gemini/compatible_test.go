@@ -0,0 +1,70 @@
+package gemini_test
+
+import (
+	"encoding/json"
+	"one-api/providers/gemini"
+	"one-api/types"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertToChatRequest(t *testing.T) {
+	body := `{
+		"systemInstruction": {"parts": [{"text": "You are helpful."}]},
+		"contents": [
+			{"role": "user", "parts": [
+				{"text": "weather?"},
+				{"inlineData": {"mimeType": "image/png", "data": "AAAA"}}
+			]},
+			{"role": "model", "parts": [{"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}}]},
+			{"role": "user", "parts": [{"functionResponse": {"name": "get_weather", "response": {"result": "sunny"}}}]}
+		],
+		"tools": [{"functionDeclarations": [{"name": "get_weather", "description": "Get weather", "parameters": {"type": "object"}}]}],
+		"toolConfig": {"functionCallingConfig": {"mode": "ANY"}},
+		"safetySettings": [{"category": "HARM_CATEGORY_HARASSMENT", "threshold": "BLOCK_NONE"}],
+		"generationConfig": {"maxOutputTokens": 512, "stopSequences": ["END"]}
+	}`
+
+	var request gemini.GeminiChatRequest
+	assert.Nil(t, json.Unmarshal([]byte(body), &request))
+	request.Model = "gemini-2.5-pro"
+
+	chatRequest, err := gemini.ConvertToChatRequest(&request)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 512, chatRequest.MaxTokens)
+	assert.Equal(t, []string{"END"}, chatRequest.Stop)
+	assert.Equal(t, types.ToolChoiceTypeRequired, chatRequest.ToolChoice)
+	assert.Len(t, chatRequest.Tools, 1)
+
+	messages := chatRequest.Messages
+	assert.Len(t, messages, 4)
+	assert.Equal(t, "You are helpful.", messages[0].Content)
+
+	parts, ok := messages[1].Content.([]types.ChatMessagePart)
+	assert.True(t, ok)
+	assert.Equal(t, "data:image/png;base64,AAAA", parts[1].ImageURL.URL)
+
+	assert.Equal(t, `{"city":"Paris"}`, messages[2].ToolCalls[0].Function.Arguments)
+	assert.Equal(t, types.ChatMessageRoleTool, messages[3].Role)
+	assert.Equal(t, messages[2].ToolCalls[0].Id, messages[3].ToolCallID)
+}
+
+func TestChatToGeminiStreamConverter(t *testing.T) {
+	usage := &types.Usage{PromptTokens: 10, CompletionTokens: 3}
+	converter := gemini.NewChatToGeminiStreamConverter("gpt-4o", usage)
+
+	data := converter.Convert(`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"Hi"}}]}`)
+	assert.Contains(t, data, `"text":"Hi"`)
+
+	assert.Empty(t, converter.Convert(`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"f","arguments":"{\"a\":"}}]}}]}`))
+	converter.Convert(`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"1}"}}]},"finish_reason":"tool_calls"}]}`)
+
+	final := converter.Finish()
+	assert.True(t, strings.HasPrefix(final, "data: "))
+	assert.Contains(t, final, `"functionCall":{"name":"f","args":{"a":1}}`)
+	assert.Contains(t, final, `"finishReason":"STOP"`)
+	assert.Contains(t, final, `"candidatesTokenCount":3`)
+}
@@ -23,6 +23,8 @@ type SunoSubmitReq struct {
 	TaskID               string  `json:"task_id,omitempty"`
 	ContinueClipId       string  `json:"continue_clip_id,omitempty"`
 	MakeInstrumental     bool    `json:"make_instrumental"`
+	// 客户端的回调地址，由网关在任务结束时回调，不转发给上游
+	NotifyHook string `json:"notify_hook,omitempty"`
 }
 
 type FetchReq struct {
@@ -21,6 +21,8 @@ type relayBase struct {
 	otherArg       string
 	allowHeartbeat bool
 	heartbeat      *relay_util.Heartbeat
+	cache          *relay_util.ChatCacheProps
+	hedge          *relay_util.HedgeInfo
 
 	firstResponseTime time.Time
 }
@@ -31,10 +33,13 @@ type RelayBaseInterface interface {
 	setRequest() error
 	getRequest() any
 	setProvider(modelName string) error
+	setOriginalModel(modelName string)
 	getProvider() providersBase.ProviderInterface
 	getOriginalModel() string
 	getModelName() string
 	getContext() *gin.Context
+	getChatCache() *relay_util.ChatCacheProps
+	getHedge() *relay_util.HedgeInfo
 	IsStream() bool
 	// HandleError(err *types.OpenAIErrorWithStatusCode)
 	GetFirstResponseTime() time.Time
@@ -83,6 +88,14 @@ func (r *relayBase) getContext() *gin.Context {
 	return r.c
 }
 
+func (r *relayBase) getChatCache() *relay_util.ChatCacheProps {
+	return r.cache
+}
+
+func (r *relayBase) getHedge() *relay_util.HedgeInfo {
+	return r.hedge
+}
+
 func (r *relayBase) getProvider() providersBase.ProviderInterface {
 	return r.provider
 }
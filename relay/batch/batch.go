@@ -0,0 +1,279 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+type CreateBatchRequest struct {
+	InputFileId      string            `json:"input_file_id" binding:"required"`
+	Endpoint         string            `json:"endpoint" binding:"required"`
+	CompletionWindow string            `json:"completion_window" binding:"required"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// Relay 处理 /v1/files 和 /v1/batches
+// 指定渠道时保持原来的透传，否则由网关本地执行
+func Relay(c *gin.Context) {
+	if c.GetInt("specific_channel_id") > 0 {
+		c.Set("specific_channel_id_ignore", false)
+		relay.RelayOnly(c)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(c.Request.URL.Path, "/v1"), "/"), "/")
+	method := c.Request.Method
+
+	switch {
+	case len(parts) == 1 && parts[0] == "files" && method == http.MethodGet:
+		ListFiles(c)
+	case len(parts) == 1 && parts[0] == "files" && method == http.MethodPost:
+		UploadFile(c)
+	case len(parts) == 2 && parts[0] == "files" && method == http.MethodGet:
+		RetrieveFile(c, parts[1])
+	case len(parts) == 2 && parts[0] == "files" && method == http.MethodDelete:
+		DeleteFile(c, parts[1])
+	case len(parts) == 3 && parts[0] == "files" && parts[2] == "content" && method == http.MethodGet:
+		RetrieveFileContent(c, parts[1])
+	case len(parts) == 1 && parts[0] == "batches" && method == http.MethodGet:
+		ListBatches(c)
+	case len(parts) == 1 && parts[0] == "batches" && method == http.MethodPost:
+		CreateBatch(c)
+	case len(parts) == 2 && parts[0] == "batches" && method == http.MethodGet:
+		RetrieveBatch(c, parts[1])
+	case len(parts) == 3 && parts[0] == "batches" && parts[2] == "cancel" && method == http.MethodPost:
+		CancelBatch(c, parts[1])
+	default:
+		common.AbortWithMessage(c, http.StatusNotFound, "Invalid URL ("+method+" "+c.Request.URL.Path+")")
+	}
+}
+
+func CreateBatch(c *gin.Context) {
+	var request CreateBatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !allowEndpoints[request.Endpoint] {
+		common.AbortWithMessage(c, http.StatusBadRequest, "unsupported endpoint: "+request.Endpoint)
+		return
+	}
+
+	if request.CompletionWindow != completionWindow {
+		common.AbortWithMessage(c, http.StatusBadRequest, "completion_window must be "+completionWindow)
+		return
+	}
+
+	userId := c.GetInt("id")
+	file, err := model.GetBatchFileById(userId, request.InputFileId)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if file.Purpose != model.BatchFilePurposeBatch {
+		common.AbortWithMessage(c, http.StatusBadRequest, "input file purpose must be batch")
+		return
+	}
+
+	data, err := file.GetContent()
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, "failed to read file: "+err.Error())
+		return
+	}
+
+	now := utils.GetTimestamp()
+	batch := &model.Batch{
+		Id:               "batch_" + utils.GetUUID(),
+		UserId:           userId,
+		TokenId:          c.GetInt("token_id"),
+		SourceIp:         c.ClientIP(),
+		Endpoint:         request.Endpoint,
+		InputFileId:      request.InputFileId,
+		CompletionWindow: request.CompletionWindow,
+		Metadata:         datatypes.NewJSONType(request.Metadata),
+		CreatedAt:        now,
+		ExpiresAt:        now + int64((24 * time.Hour).Seconds()),
+	}
+
+	lines, errs := ParseInput(data, request.Endpoint)
+	if len(errs) > 0 {
+		batch.Status = model.BatchStatusFailed
+		batch.Errors = errs
+		batch.FailedAt = now
+	} else {
+		batch.Status = model.BatchStatusInProgress
+		batch.InProgressAt = now
+		batch.Total = len(lines)
+	}
+
+	if err := batch.Insert(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if batch.Status == model.BatchStatusInProgress {
+		startRunner(batch)
+	}
+
+	c.JSON(http.StatusOK, toBatchObject(batch))
+}
+
+func ListBatches(c *gin.Context) {
+	limit := getListLimit(c)
+	batches, err := model.GetUserBatches(c.GetInt("id"), c.Query("after"), limit+1)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := &ListResponse[*BatchObject]{
+		Object: "list",
+		Data:   make([]*BatchObject, 0, len(batches)),
+	}
+	if len(batches) > limit {
+		response.HasMore = true
+		batches = batches[:limit]
+	}
+	for _, batch := range batches {
+		response.Data = append(response.Data, toBatchObject(batch))
+	}
+	if len(batches) > 0 {
+		response.FirstId = batches[0].Id
+		response.LastId = batches[len(batches)-1].Id
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func RetrieveBatch(c *gin.Context, batchId string) {
+	batch, err := model.GetBatchById(c.GetInt("id"), batchId)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, toBatchObject(batch))
+}
+
+func CancelBatch(c *gin.Context, batchId string) {
+	batch, err := model.GetBatchById(c.GetInt("id"), batchId)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if batch.Status != model.BatchStatusInProgress && batch.Status != model.BatchStatusValidating {
+		common.AbortWithMessage(c, http.StatusConflict, fmt.Sprintf("cannot cancel a batch with status %s", batch.Status))
+		return
+	}
+
+	batch.Status = model.BatchStatusCancelling
+	batch.CancellingAt = utils.GetTimestamp()
+	if err := batch.UpdateStatus(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// 其他节点上的执行器会在轮询状态时停止
+	cancelRunner(batch.Id)
+
+	c.JSON(http.StatusOK, toBatchObject(batch))
+}
+
+// ParseInput 校验输入文件，返回需要执行的请求，行号从 1 开始，空行会被跳过
+// 请求体中的 stream 会被强制关闭
+func ParseInput(data []byte, endpoint string) ([]*InputLine, []model.BatchError) {
+	var lines []*InputLine
+	var errs []model.BatchError
+	customIds := make(map[string]bool)
+
+	addError := func(line int, code, message string) {
+		errs = append(errs, model.BatchError{Code: code, Message: message, Line: line})
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var request RequestLine
+		if err := json.Unmarshal(text, &request); err != nil {
+			addError(lineNo, "invalid_json_line", "This line is not parseable as valid JSON.")
+			continue
+		}
+
+		if request.CustomId == "" {
+			addError(lineNo, "missing_required_parameter", "custom_id is required.")
+			continue
+		}
+		if customIds[request.CustomId] {
+			addError(lineNo, "duplicate_custom_id", "The custom_id for this request is a duplicate of another request.")
+			continue
+		}
+		customIds[request.CustomId] = true
+
+		if request.Method != http.MethodPost {
+			addError(lineNo, "invalid_method", "Only POST requests are supported.")
+			continue
+		}
+		if request.Url != endpoint {
+			addError(lineNo, "mismatched_url", "The url of this request does not match the endpoint of the batch.")
+			continue
+		}
+
+		var body map[string]any
+		if err := json.Unmarshal(request.Body, &body); err != nil || body == nil {
+			addError(lineNo, "invalid_request", "body must be a JSON object.")
+			continue
+		}
+		if modelName, ok := body["model"].(string); !ok || modelName == "" {
+			addError(lineNo, "missing_required_parameter", "body.model is required.")
+			continue
+		}
+
+		delete(body, "stream")
+		delete(body, "stream_options")
+		normalized, _ := json.Marshal(body)
+
+		lines = append(lines, &InputLine{
+			Line:     lineNo,
+			CustomId: request.CustomId,
+			Url:      request.Url,
+			Body:     normalized,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		addError(0, "invalid_file", err.Error())
+	}
+
+	if len(errs) == 0 {
+		if len(lines) == 0 {
+			addError(0, "empty_file", "The input file is empty.")
+		} else if len(lines) > config.BatchMaxRequests {
+			addError(0, "too_many_requests", fmt.Sprintf("The input file contains more than %d requests.", config.BatchMaxRequests))
+		}
+	}
+
+	return lines, errs
+}
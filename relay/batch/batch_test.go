@@ -0,0 +1,42 @@
+package batch_test
+
+import (
+	"one-api/relay/batch"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInput(t *testing.T) {
+	data := `{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o","stream":true,"messages":[]}}
+
+{"custom_id":"b","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o","messages":[]}}
+`
+	lines, errs := batch.ParseInput([]byte(data), "/v1/chat/completions")
+	assert.Empty(t, errs)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, 1, lines[0].Line)
+	assert.Equal(t, 3, lines[1].Line)
+	assert.NotContains(t, string(lines[0].Body), "stream")
+}
+
+func TestParseInputErrors(t *testing.T) {
+	data := `{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o"}}
+{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o"}}
+{"custom_id":"b","method":"GET","url":"/v1/chat/completions","body":{"model":"gpt-4o"}}
+{"custom_id":"c","method":"POST","url":"/v1/embeddings","body":{"model":"gpt-4o"}}
+{"custom_id":"d","method":"POST","url":"/v1/chat/completions","body":{}}
+not json`
+	_, errs := batch.ParseInput([]byte(data), "/v1/chat/completions")
+
+	codes := make([]string, 0, len(errs))
+	for _, err := range errs {
+		codes = append(codes, err.Code)
+	}
+	assert.Equal(t, []string{"duplicate_custom_id", "invalid_method", "mismatched_url", "missing_required_parameter", "invalid_json_line"}, codes)
+	assert.Equal(t, 2, errs[0].Line)
+	assert.Equal(t, 6, errs[4].Line)
+
+	_, errs = batch.ParseInput([]byte("\n\n"), "/v1/chat/completions")
+	assert.Equal(t, "empty_file", errs[0].Code)
+}
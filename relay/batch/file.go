@@ -0,0 +1,152 @@
+package batch
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/utils"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+func getListLimit(c *gin.Context) int {
+	limit := utils.String2Int(c.Query("limit"))
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
+
+// UploadFile 上传批处理输入文件
+func UploadFile(c *gin.Context) {
+	purpose := c.PostForm("purpose")
+	if purpose != model.BatchFilePurposeBatch {
+		common.AbortWithMessage(c, http.StatusBadRequest, "only purpose 'batch' is supported")
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, "file is required")
+		return
+	}
+
+	maxSize := int64(config.BatchMaxFileSize) * 1024 * 1024
+	if header.Size > maxSize {
+		common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("file exceeds the maximum size of %d MB", config.BatchMaxFileSize))
+		return
+	}
+
+	reader, err := header.Open()
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if int64(len(data)) > maxSize {
+		common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("file exceeds the maximum size of %d MB", config.BatchMaxFileSize))
+		return
+	}
+
+	file := &model.BatchFile{
+		Id:       "file-" + utils.GetUUID(),
+		UserId:   c.GetInt("id"),
+		Purpose:  purpose,
+		Filename: header.Filename,
+	}
+	if err := model.CreateBatchFile(file, data); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, "failed to save file: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, toFileObject(file))
+}
+
+func ListFiles(c *gin.Context) {
+	limit := getListLimit(c)
+	files, err := model.GetUserBatchFiles(c.GetInt("id"), c.Query("purpose"), c.Query("after"), limit+1)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := &ListResponse[*FileObject]{
+		Object: "list",
+		Data:   make([]*FileObject, 0, len(files)),
+	}
+	if len(files) > limit {
+		response.HasMore = true
+		files = files[:limit]
+	}
+	for _, file := range files {
+		response.Data = append(response.Data, toFileObject(file))
+	}
+	if len(files) > 0 {
+		response.FirstId = files[0].Id
+		response.LastId = files[len(files)-1].Id
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func RetrieveFile(c *gin.Context, fileId string) {
+	file, err := model.GetBatchFileById(c.GetInt("id"), fileId)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, toFileObject(file))
+}
+
+func RetrieveFileContent(c *gin.Context, fileId string) {
+	file, err := model.GetBatchFileById(c.GetInt("id"), fileId)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	data, err := file.GetContent()
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, "failed to read file: "+err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "application/jsonl", data)
+}
+
+func DeleteFile(c *gin.Context, fileId string) {
+	file, err := model.GetBatchFileById(c.GetInt("id"), fileId)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if err := file.Delete(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":      file.Id,
+		"object":  "file",
+		"deleted": true,
+	})
+}
@@ -0,0 +1,170 @@
+package batch
+
+import (
+	"encoding/json"
+	"one-api/model"
+)
+
+// 支持批处理的接口
+var allowEndpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	"/v1/embeddings":       true,
+	"/v1/responses":        true,
+	"/v1/moderations":      true,
+}
+
+// 目前只支持 24 小时内完成
+const completionWindow = "24h"
+
+// FileObject OpenAI 格式的文件对象
+type FileObject struct {
+	Id        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int    `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status"`
+}
+
+func toFileObject(file *model.BatchFile) *FileObject {
+	return &FileObject{
+		Id:        file.Id,
+		Object:    "file",
+		Bytes:     file.Bytes,
+		CreatedAt: file.CreatedAt,
+		Filename:  file.Filename,
+		Purpose:   file.Purpose,
+		Status:    "processed",
+	}
+}
+
+type BatchErrors struct {
+	Object string             `json:"object"`
+	Data   []model.BatchError `json:"data"`
+}
+
+type RequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// BatchObject OpenAI 格式的批处理对象
+type BatchObject struct {
+	Id               string            `json:"id"`
+	Object           string            `json:"object"`
+	Endpoint         string            `json:"endpoint"`
+	Errors           *BatchErrors      `json:"errors"`
+	InputFileId      string            `json:"input_file_id"`
+	CompletionWindow string            `json:"completion_window"`
+	Status           string            `json:"status"`
+	OutputFileId     *string           `json:"output_file_id"`
+	ErrorFileId      *string           `json:"error_file_id"`
+	CreatedAt        int64             `json:"created_at"`
+	InProgressAt     *int64            `json:"in_progress_at"`
+	ExpiresAt        *int64            `json:"expires_at"`
+	FinalizingAt     *int64            `json:"finalizing_at"`
+	CompletedAt      *int64            `json:"completed_at"`
+	FailedAt         *int64            `json:"failed_at"`
+	ExpiredAt        *int64            `json:"expired_at"`
+	CancellingAt     *int64            `json:"cancelling_at"`
+	CancelledAt      *int64            `json:"cancelled_at"`
+	RequestCounts    RequestCounts     `json:"request_counts"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func nullableTime(value int64) *int64 {
+	if value == 0 {
+		return nil
+	}
+	return &value
+}
+
+func toBatchObject(batch *model.Batch) *BatchObject {
+	object := &BatchObject{
+		Id:               batch.Id,
+		Object:           "batch",
+		Endpoint:         batch.Endpoint,
+		InputFileId:      batch.InputFileId,
+		CompletionWindow: batch.CompletionWindow,
+		Status:           batch.Status,
+		OutputFileId:     nullableString(batch.OutputFileId),
+		ErrorFileId:      nullableString(batch.ErrorFileId),
+		CreatedAt:        batch.CreatedAt,
+		InProgressAt:     nullableTime(batch.InProgressAt),
+		ExpiresAt:        nullableTime(batch.ExpiresAt),
+		FinalizingAt:     nullableTime(batch.FinalizingAt),
+		CompletedAt:      nullableTime(batch.CompletedAt),
+		FailedAt:         nullableTime(batch.FailedAt),
+		ExpiredAt:        nullableTime(batch.ExpiredAt),
+		CancellingAt:     nullableTime(batch.CancellingAt),
+		CancelledAt:      nullableTime(batch.CancelledAt),
+		RequestCounts: RequestCounts{
+			Total:     batch.Total,
+			Completed: batch.Completed,
+			Failed:    batch.Failed,
+		},
+		Metadata: batch.Metadata.Data(),
+	}
+
+	if len(batch.Errors) > 0 {
+		object.Errors = &BatchErrors{
+			Object: "list",
+			Data:   batch.Errors,
+		}
+	}
+
+	return object
+}
+
+type ListResponse[T any] struct {
+	Object  string `json:"object"`
+	Data    []T    `json:"data"`
+	FirstId string `json:"first_id,omitempty"`
+	LastId  string `json:"last_id,omitempty"`
+	HasMore bool   `json:"has_more"`
+}
+
+// RequestLine 输入文件中的一行
+type RequestLine struct {
+	CustomId string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	Url      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+type ResponseBody struct {
+	StatusCode int             `json:"status_code"`
+	RequestId  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type ResponseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ResponseLine 输出文件和错误文件中的一行
+type ResponseLine struct {
+	Id       string         `json:"id"`
+	CustomId string         `json:"custom_id"`
+	Response *ResponseBody  `json:"response"`
+	Error    *ResponseError `json:"error"`
+}
+
+// InputLine 校验后的待执行请求
+type InputLine struct {
+	Line     int
+	CustomId string
+	Url      string
+	Body     []byte
+}
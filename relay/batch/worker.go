@@ -0,0 +1,447 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// 被限流时的默认等待时间
+	defaultRetryDelay = 5 * time.Second
+	maxRetryDelay     = 60 * time.Second
+	// 轮询批处理状态的间隔，用于感知其他节点上的取消操作
+	statusPollInterval = 10 * time.Second
+)
+
+type worker struct {
+	handler http.Handler
+	// 全局并发限制，所有批处理共享
+	sem chan struct{}
+
+	mu      sync.Mutex
+	runners map[string]context.CancelFunc
+}
+
+var batchWorker *worker
+
+// InitBatch 初始化批处理执行器，请求通过 handler 走完整的中继流程（鉴权、限流、计费）
+// 主节点会继续执行服务重启前未完成的批处理
+func InitBatch(handler http.Handler) {
+	concurrency := config.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	batchWorker = &worker{
+		handler: handler,
+		sem:     make(chan struct{}, concurrency),
+		runners: make(map[string]context.CancelFunc),
+	}
+
+	if !config.IsMasterNode {
+		return
+	}
+
+	batches, err := model.GetUnfinishedBatches()
+	if err != nil {
+		logger.SysError("failed to load unfinished batches: " + err.Error())
+		return
+	}
+
+	for _, batch := range batches {
+		startRunner(batch)
+	}
+
+	if len(batches) > 0 {
+		logger.SysLog(fmt.Sprintf("resumed %d unfinished batches", len(batches)))
+	}
+}
+
+func startRunner(batch *model.Batch) {
+	if batchWorker == nil {
+		logger.SysError("batch worker is not initialized, batch " + batch.Id + " will not run")
+		return
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Unix(batch.ExpiresAt, 0))
+
+	batchWorker.mu.Lock()
+	if _, ok := batchWorker.runners[batch.Id]; ok {
+		batchWorker.mu.Unlock()
+		cancel()
+		return
+	}
+	batchWorker.runners[batch.Id] = cancel
+	batchWorker.mu.Unlock()
+
+	go batchWorker.run(ctx, batch)
+}
+
+func cancelRunner(batchId string) {
+	if batchWorker == nil {
+		return
+	}
+
+	batchWorker.mu.Lock()
+	cancel, ok := batchWorker.runners[batchId]
+	batchWorker.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+func (w *worker) run(ctx context.Context, batch *model.Batch) {
+	defer func() {
+		w.mu.Lock()
+		cancel := w.runners[batch.Id]
+		delete(w.runners, batch.Id)
+		w.mu.Unlock()
+		cancel()
+	}()
+
+	lines, err := w.loadInput(batch)
+	if err != nil {
+		w.fail(batch, err)
+		return
+	}
+
+	if batch.Status == model.BatchStatusInProgress {
+		if err := w.execute(ctx, batch, lines); err != nil {
+			w.fail(batch, err)
+			return
+		}
+	}
+
+	w.finalize(ctx, batch, lines)
+}
+
+func (w *worker) loadInput(batch *model.Batch) ([]*InputLine, error) {
+	file, err := model.GetBatchFileById(batch.UserId, batch.InputFileId)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := file.GetContent()
+	if err != nil {
+		return nil, err
+	}
+
+	lines, errs := ParseInput(data, batch.Endpoint)
+	if len(errs) > 0 {
+		return nil, errors.New(errs[0].Message)
+	}
+
+	return lines, nil
+}
+
+// execute 依次发送未完成的请求，直到全部完成、被取消或过期
+func (w *worker) execute(ctx context.Context, batch *model.Batch, lines []*InputLine) error {
+	token, err := model.GetTokenById(batch.TokenId)
+	if err != nil {
+		return fmt.Errorf("token not found: %w", err)
+	}
+
+	done, err := model.GetBatchDoneLines(batch.Id)
+	if err != nil {
+		return err
+	}
+
+	go w.watchStatus(ctx, batch.Id)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+dispatch:
+	for _, line := range lines {
+		if done[line.Line] {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case w.sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(line *InputLine) {
+			defer wg.Done()
+			defer func() { <-w.sem }()
+
+			result := w.send(ctx, batch, token.Key, line)
+			if result == nil {
+				return
+			}
+
+			failed := result.Response == nil || result.Response.StatusCode >= http.StatusMultipleChoices
+			output, _ := json.Marshal(result)
+			err := model.InsertBatchRequest(&model.BatchRequest{
+				BatchId:  batch.Id,
+				Line:     line.Line,
+				CustomId: line.CustomId,
+				Failed:   failed,
+				Output:   string(output),
+			})
+			if err != nil {
+				logger.SysError(fmt.Sprintf("failed to save batch %s request %s: %s", batch.Id, line.CustomId, err.Error()))
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if failed {
+				batch.Failed++
+			} else {
+				batch.Completed++
+			}
+			if err := batch.UpdateCounts(); err != nil {
+				logger.SysError(fmt.Sprintf("failed to update batch %s counts: %s", batch.Id, err.Error()))
+			}
+		}(line)
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// watchStatus 定时检查批处理是否在其他节点被取消
+func (w *worker) watchStatus(ctx context.Context, batchId string) {
+	ticker := time.NewTicker(statusPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			status, err := model.GetBatchStatus(batchId)
+			if err == nil && status == model.BatchStatusCancelling {
+				cancelRunner(batchId)
+				return
+			}
+		}
+	}
+}
+
+// send 通过完整的中继流程发送单个请求，被限流时等待后重试
+// 在取消或过期前没有执行完成时返回 nil
+func (w *worker) send(ctx context.Context, batch *model.Batch, tokenKey string, line *InputLine) *ResponseLine {
+	// 已经发出的请求不随批处理取消而中断，避免已计费的结果丢失
+	requestCtx := relay_util.WithBatch(context.WithoutCancel(ctx), batch.Id)
+
+	for {
+		req, err := http.NewRequestWithContext(requestCtx, http.MethodPost, line.Url, bytes.NewReader(line.Body))
+		if err != nil {
+			return newErrorLine(line, "invalid_request", err.Error())
+		}
+		req.Header.Set("Authorization", "Bearer sk-"+tokenKey)
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = batch.SourceIp + ":0"
+
+		recorder := httptest.NewRecorder()
+		w.handler.ServeHTTP(recorder, req)
+
+		if recorder.Code == http.StatusTooManyRequests {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(getRetryDelay(recorder.Header())):
+				continue
+			}
+		}
+
+		body := recorder.Body.Bytes()
+		if !json.Valid(body) {
+			body, _ = json.Marshal(string(body))
+		}
+
+		return &ResponseLine{
+			Id:       "batch_req_" + utils.GetUUID(),
+			CustomId: line.CustomId,
+			Response: &ResponseBody{
+				StatusCode: recorder.Code,
+				RequestId:  recorder.Header().Get(logger.RequestIdKey),
+				Body:       body,
+			},
+		}
+	}
+}
+
+func getRetryDelay(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return defaultRetryDelay
+	}
+
+	delay := time.Duration(seconds) * time.Second
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+func newErrorLine(line *InputLine, code, message string) *ResponseLine {
+	return &ResponseLine{
+		Id:       "batch_req_" + utils.GetUUID(),
+		CustomId: line.CustomId,
+		Error: &ResponseError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+// finalize 合并执行结果生成输出文件和错误文件，未执行的请求写入错误文件
+func (w *worker) finalize(ctx context.Context, batch *model.Batch, lines []*InputLine) {
+	// 重新读取，取消操作可能已经修改了状态
+	latest, err := model.GetBatchById(batch.UserId, batch.Id)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to get batch %s: %s", batch.Id, err.Error()))
+		return
+	}
+	*batch = *latest
+	status := batch.Status
+
+	finalStatus := model.BatchStatusCompleted
+	unexecutedCode, unexecutedMessage := "", ""
+	switch {
+	case status == model.BatchStatusCancelling:
+		finalStatus = model.BatchStatusCancelled
+		unexecutedCode, unexecutedMessage = "batch_cancelled", "This request was not executed because the batch was cancelled."
+	case status == model.BatchStatusInProgress && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		finalStatus = model.BatchStatusExpired
+		unexecutedCode, unexecutedMessage = "batch_expired", "This request could not be executed before the completion window expired."
+	}
+
+	if status != model.BatchStatusFinalizing {
+		batch.Status = model.BatchStatusFinalizing
+		batch.FinalizingAt = utils.GetTimestamp()
+		if err := batch.UpdateStatus(); err != nil {
+			logger.SysError(fmt.Sprintf("failed to update batch %s status: %s", batch.Id, err.Error()))
+			return
+		}
+	}
+
+	requests, err := model.GetBatchRequests(batch.Id)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to get batch %s requests: %s", batch.Id, err.Error()))
+		return
+	}
+
+	var output, errorOutput bytes.Buffer
+	executed := make(map[int]bool, len(requests))
+	completed, failed := 0, 0
+	for _, request := range requests {
+		executed[request.Line] = true
+		if request.Failed {
+			failed++
+			errorOutput.WriteString(request.Output)
+			errorOutput.WriteByte('\n')
+		} else {
+			completed++
+			output.WriteString(request.Output)
+			output.WriteByte('\n')
+		}
+	}
+
+	for _, line := range lines {
+		if executed[line.Line] {
+			continue
+		}
+		if unexecutedCode == "" {
+			// 正常结束时所有请求都应该有结果，缺失说明保存失败
+			unexecutedCode, unexecutedMessage = "batch_request_lost", "The result of this request was lost."
+		}
+		data, _ := json.Marshal(newErrorLine(line, unexecutedCode, unexecutedMessage))
+		errorOutput.Write(data)
+		errorOutput.WriteByte('\n')
+	}
+
+	if output.Len() > 0 {
+		batch.OutputFileId, err = w.saveOutput(batch, "output", output.Bytes())
+		if err != nil {
+			logger.SysError(fmt.Sprintf("failed to save batch %s output file: %s", batch.Id, err.Error()))
+			return
+		}
+	}
+	if errorOutput.Len() > 0 {
+		batch.ErrorFileId, err = w.saveOutput(batch, "error", errorOutput.Bytes())
+		if err != nil {
+			logger.SysError(fmt.Sprintf("failed to save batch %s error file: %s", batch.Id, err.Error()))
+			return
+		}
+	}
+
+	batch.Completed = completed
+	batch.Failed = failed
+	if err := batch.UpdateCounts(); err != nil {
+		logger.SysError(fmt.Sprintf("failed to update batch %s counts: %s", batch.Id, err.Error()))
+	}
+
+	now := utils.GetTimestamp()
+	batch.Status = finalStatus
+	switch finalStatus {
+	case model.BatchStatusCancelled:
+		batch.CancelledAt = now
+	case model.BatchStatusExpired:
+		batch.ExpiredAt = now
+	default:
+		batch.CompletedAt = now
+	}
+	if err := batch.UpdateStatus(); err != nil {
+		logger.SysError(fmt.Sprintf("failed to update batch %s status: %s", batch.Id, err.Error()))
+		return
+	}
+
+	if err := model.DeleteBatchRequests(batch.Id); err != nil {
+		logger.SysError(fmt.Sprintf("failed to delete batch %s requests: %s", batch.Id, err.Error()))
+	}
+}
+
+func (w *worker) saveOutput(batch *model.Batch, kind string, data []byte) (string, error) {
+	file := &model.BatchFile{
+		Id:       "file-" + utils.GetUUID(),
+		UserId:   batch.UserId,
+		Purpose:  model.BatchFilePurposeOutput,
+		Filename: fmt.Sprintf("%s_%s.jsonl", batch.Id, kind),
+	}
+	if err := model.CreateBatchFile(file, data); err != nil {
+		return "", err
+	}
+
+	return file.Id, nil
+}
+
+// fail 批处理无法继续执行时标记为失败
+func (w *worker) fail(batch *model.Batch, err error) {
+	logger.SysError(fmt.Sprintf("batch %s failed: %s", batch.Id, err.Error()))
+
+	batch.Status = model.BatchStatusFailed
+	batch.FailedAt = utils.GetTimestamp()
+	batch.Errors = append(batch.Errors, model.BatchError{
+		Code:    "batch_failed",
+		Message: err.Error(),
+	})
+	if err := batch.UpdateStatus(); err != nil {
+		logger.SysError(fmt.Sprintf("failed to update batch %s status: %s", batch.Id, err.Error()))
+	}
+
+	if err := model.DeleteBatchRequests(batch.Id); err != nil {
+		logger.SysError(fmt.Sprintf("failed to delete batch %s requests: %s", batch.Id, err.Error()))
+	}
+}
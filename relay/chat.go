@@ -11,6 +11,7 @@ import (
 	"one-api/common/requester"
 	"one-api/common/utils"
 	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
 	"one-api/safty"
 	"one-api/types"
 	"time"
@@ -21,6 +22,9 @@ import (
 type relayChat struct {
 	relayBase
 	chatRequest types.ChatCompletionRequest
+	// 上下文摘要的计费，等待主请求预扣费后结算
+	summaryQuota *relay_util.Quota
+	summaryUsage *types.Usage
 }
 
 func NewRelayChat(c *gin.Context) *relayChat {
@@ -51,6 +55,7 @@ func (r *relayChat) setRequest() error {
 	}
 
 	r.setOriginalModel(r.chatRequest.Model)
+	injectChatPrompt(r.c, &r.chatRequest)
 
 	otherArg := r.getOtherArg()
 
@@ -59,6 +64,8 @@ func (r *relayChat) setRequest() error {
 		return nil
 	}
 
+	r.cache = relay_util.NewChatCacheProps(r.c, &r.chatRequest)
+
 	return nil
 }
 
@@ -71,6 +78,8 @@ func (r *relayChat) IsStream() bool {
 }
 
 func (r *relayChat) getPromptTokens() (int, error) {
+	r.manageContextWindow()
+
 	channel := r.provider.GetChannel()
 	return common.CountTokenMessages(r.chatRequest.Messages, r.modelName, channel.PreCost), nil
 }
@@ -105,21 +114,21 @@ func (r *relayChat) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 	r.chatRequest.Model = r.modelName
 	// 内容审查
 	if config.EnableSafe {
-		for _, message := range r.chatRequest.Messages {
-			if message.Content != nil {
-				CheckResult, _ := safty.CheckContent(message.Content)
-				if !CheckResult.IsSafe {
-					err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
-					done = true
-					return
-				}
-			}
+		CheckResult := safty.CheckInput(r.c, r.chatRequest.Messages)
+		if !CheckResult.IsSafe {
+			err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
+			done = true
+			return
 		}
 	}
 
 	if r.chatRequest.Stream {
 		var response requester.StreamReaderInterface[string]
-		response, err = chatProvider.CreateChatCompletionStream(&r.chatRequest)
+		if delay := getHedgeDelay(r.c); delay > 0 {
+			response, err = r.hedgeStream(delay, r.hedgeOpen())
+		} else {
+			response, err = chatProvider.CreateChatCompletionStream(&r.chatRequest)
+		}
 		if err != nil {
 			return
 		}
@@ -132,8 +141,10 @@ func (r *relayChat) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 			return r.getUsageResponse()
 		}
 
+		moderator := newStreamModerator(r.c, r.provider.GetUsage(), r.modelName, extractOpenAIStreamText, r.HandleStreamError)
+
 		var firstResponseTime time.Time
-		firstResponseTime, err = responseStreamClient(r.c, response, doneStr)
+		firstResponseTime, err = responseStreamClient(r.c, response, r.cache, moderator, doneStr)
 		r.SetFirstResponseTime(firstResponseTime)
 	} else {
 		var response *types.ChatCompletionResponse
@@ -142,12 +153,18 @@ func (r *relayChat) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 			return
 		}
 
+		if CheckResult := safty.CheckOutput(r.c, response.Choices); !CheckResult.IsSafe {
+			err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
+			done = true
+			return
+		}
+
 		if r.heartbeat != nil {
 			r.heartbeat.Stop()
 		}
 
 		err = responseJsonClient(r.c, response)
-
+		r.cache.SetResponse(response)
 	}
 
 	if err != nil {
@@ -157,6 +174,31 @@ func (r *relayChat) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 	return
 }
 
+// hedgeOpen 主渠道使用原请求，对冲渠道使用请求的深拷贝，避免两个渠道同时修改请求
+// 副本需要在主渠道开始请求前生成，否则会和主渠道对请求的修改产生竞争
+func (r *relayChat) hedgeOpen() hedgeOpenFunc {
+	primary := r.provider
+	hedgeRequest, cloneErr := r.chatRequest.Clone()
+
+	return func(provider providersBase.ProviderInterface, modelName string) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode) {
+		chatProvider, ok := provider.(providersBase.ChatInterface)
+		if !ok || need2Response[modelName] {
+			return nil, common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+		}
+
+		if provider == primary {
+			return chatProvider.CreateChatCompletionStream(&r.chatRequest)
+		}
+
+		if cloneErr != nil {
+			return nil, common.ErrorWrapperLocal(cloneErr, "hedge_request_failed", http.StatusInternalServerError)
+		}
+
+		hedgeRequest.Model = modelName
+		return chatProvider.CreateChatCompletionStream(hedgeRequest)
+	}
+}
+
 func (r *relayChat) getUsageResponse() string {
 	if r.chatRequest.StreamOptions != nil && r.chatRequest.StreamOptions.IncludeUsage {
 		usageResponse := types.ChatCompletionStreamResponse{
@@ -198,8 +240,10 @@ func (r *relayChat) compatibleSend(resProvider providersBase.ResponsesInterface)
 			return r.getUsageResponse()
 		}
 
+		moderator := newStreamModerator(r.c, r.provider.GetUsage(), r.modelName, extractOpenAIStreamText, r.HandleStreamError)
+
 		var firstResponseTime time.Time
-		firstResponseTime, err = responseStreamClient(r.c, response, doneStr)
+		firstResponseTime, err = responseStreamClient(r.c, response, r.cache, moderator, doneStr)
 		r.SetFirstResponseTime(firstResponseTime)
 	} else {
 		var response *types.OpenAIResponsesResponses
@@ -211,7 +255,14 @@ func (r *relayChat) compatibleSend(resProvider providersBase.ResponsesInterface)
 		if r.heartbeat != nil {
 			r.heartbeat.Stop()
 		}
-		err = responseJsonClient(r.c, response.ToChat())
+		chatResponse := response.ToChat()
+		if CheckResult := safty.CheckOutput(r.c, chatResponse.Choices); !CheckResult.IsSafe {
+			err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
+			done = true
+			return
+		}
+		err = responseJsonClient(r.c, chatResponse)
+		r.cache.SetResponse(chatResponse)
 	}
 
 	if err != nil {
@@ -6,6 +6,8 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/requester"
+	"one-api/common/utils"
+	providersBase "one-api/providers/base"
 	"one-api/providers/claude"
 	"one-api/safty"
 	"one-api/types"
@@ -22,7 +24,6 @@ type relayClaudeOnly struct {
 }
 
 func NewRelayClaudeOnly(c *gin.Context) *relayClaudeOnly {
-	c.Set("allow_channel_type", AllowChannelType)
 	relay := &relayClaudeOnly{
 		relayBase: relayBase{
 			allowHeartbeat: true,
@@ -39,6 +40,14 @@ func (r *relayClaudeOnly) setRequest() error {
 		return err
 	}
 	r.setOriginalModel(r.claudeRequest.Model)
+	injectClaudePrompt(r.c, r.claudeRequest)
+
+	// 开启兼容的模型可以使用任意对话渠道，其他模型只能使用原生渠道或开启了 Claude 兼容的渠道
+	if !config.ClaudeSettingsInstance.IsCompatibleModel(r.claudeRequest.Model) {
+		r.c.Set("allow_channel_type", AllowChannelType)
+		r.c.Set("allow_compatible_claude", true)
+	}
+
 	return nil
 }
 
@@ -57,24 +66,26 @@ func (r *relayClaudeOnly) getPromptTokens() (int, error) {
 
 func (r *relayClaudeOnly) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 	chatProvider, ok := r.provider.(claude.ClaudeChatInterface)
-	if !ok {
-		err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
-		done = true
-		return
+	if !ok || !utils.Contains(r.provider.GetChannel().Type, AllowChannelType) {
+		// 非原生渠道做一层Chat的兼容
+		openaiProvider, ok := r.provider.(providersBase.ChatInterface)
+		if !ok {
+			err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+			done = true
+			return
+		}
+
+		return r.compatibleSend(openaiProvider)
 	}
 
 	r.claudeRequest.Model = r.modelName
 	// 内容审查
 	if config.EnableSafe {
-		for _, message := range r.claudeRequest.Messages {
-			if message.Content != nil {
-				CheckResult, _ := safty.CheckContent(message.Content)
-				if !CheckResult.IsSafe {
-					err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
-					done = true
-					return
-				}
-			}
+		CheckResult := safty.CheckInput(r.c, r.claudeRequest.Messages)
+		if !CheckResult.IsSafe {
+			err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
+			done = true
+			return
 		}
 	}
 
@@ -92,7 +103,8 @@ func (r *relayClaudeOnly) send() (err *types.OpenAIErrorWithStatusCode, done boo
 		doneStr := func() string {
 			return ""
 		}
-		firstResponseTime := responseGeneralStreamClient(r.c, response, doneStr)
+		moderator := newStreamModerator(r.c, r.provider.GetUsage(), r.modelName, extractClaudeStreamText, r.HandleStreamError)
+		firstResponseTime := responseGeneralStreamClient(r.c, response, moderator, doneStr)
 		r.SetFirstResponseTime(firstResponseTime)
 	} else {
 		var response *claude.ClaudeResponse
@@ -154,6 +166,23 @@ func CountTokenMessages(request *claude.ClaudeRequest, preCostType int) (int, er
 	tokensPerMessage := 4
 	var textMsg strings.Builder
 
+	switch system := request.System.(type) {
+	case string:
+		if system != "" {
+			tokenNum += tokensPerMessage
+			textMsg.WriteString(system)
+		}
+	case []any:
+		tokenNum += tokensPerMessage
+		for _, block := range system {
+			if content, ok := block.(map[string]any); ok {
+				if text, ok := content["text"].(string); ok {
+					textMsg.WriteString(text)
+				}
+			}
+		}
+	}
+
 	for _, message := range request.Messages {
 		tokenNum += tokensPerMessage
 		switch v := message.Content.(type) {
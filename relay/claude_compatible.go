@@ -0,0 +1,69 @@
+package relay
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/requester"
+	providersBase "one-api/providers/base"
+	"one-api/providers/claude"
+	"one-api/safty"
+	"one-api/types"
+)
+
+// compatibleSend 通过对话渠道提供 Claude 接口，请求和响应在两种格式之间转换
+func (r *relayClaudeOnly) compatibleSend(chatProvider providersBase.ChatInterface) (err *types.OpenAIErrorWithStatusCode, done bool) {
+	chatRequest, convertErr := claude.ConvertToChatRequest(r.claudeRequest)
+	if convertErr != nil {
+		return common.ErrorWrapperLocal(convertErr, "invalid_claude_request", http.StatusBadRequest), true
+	}
+	chatRequest.Model = r.modelName
+
+	// 内容审查
+	if config.EnableSafe {
+		CheckResult := safty.CheckInput(r.c, chatRequest.Messages)
+		if !CheckResult.IsSafe {
+			err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
+			done = true
+			return
+		}
+	}
+
+	if chatRequest.Stream {
+		var response requester.StreamReaderInterface[string]
+		response, err = chatProvider.CreateChatCompletionStream(chatRequest)
+		if err != nil {
+			return
+		}
+
+		if r.heartbeat != nil {
+			r.heartbeat.Stop()
+		}
+
+		converter := claude.NewChatToClaudeStreamConverter(r.modelName, r.provider.GetUsage())
+		stream := newConvertedStream(response, converter.Convert)
+		doneStr := func() string {
+			return converter.Finish()
+		}
+		moderator := newStreamModerator(r.c, r.provider.GetUsage(), r.modelName, extractClaudeStreamText, r.HandleStreamError)
+		firstResponseTime := responseGeneralStreamClient(r.c, stream, moderator, doneStr)
+		r.SetFirstResponseTime(firstResponseTime)
+	} else {
+		var response *types.ChatCompletionResponse
+		response, err = chatProvider.CreateChatCompletion(chatRequest)
+		if err != nil {
+			return
+		}
+
+		if r.heartbeat != nil {
+			r.heartbeat.Stop()
+		}
+
+		err = responseJsonClient(r.c, claude.ConvertFromChatResponse(response, r.modelName, r.provider.GetUsage()))
+	}
+
+	if err != nil {
+		done = true
+	}
+	return
+}
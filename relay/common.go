@@ -17,6 +17,7 @@ import (
 	"one-api/model"
 	"one-api/providers"
 	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
 	"one-api/types"
 	"regexp"
 	"strings"
@@ -121,6 +122,7 @@ func GetProvider(c *gin.Context, modelName string) (provider providersBase.Provi
 	}
 	provider.SetOriginalModel(modelName)
 	c.Set("original_model", modelName)
+	setTransformer(c, provider, channel, modelName)
 
 	newModelName, fail = provider.ModelMappingHandler(modelName)
 	if fail != nil {
@@ -250,7 +252,11 @@ func fetchChannelByModel(c *gin.Context, modelName string) (*model.Channel, erro
 
 	if types, exists := c.Get("allow_channel_type"); exists {
 		if allowTypes, ok := types.([]int); ok {
-			filters = append(filters, model.FilterChannelTypes(allowTypes))
+			if c.GetBool("allow_compatible_claude") {
+				filters = append(filters, model.FilterChannelTypesOrCompatibleClaude(allowTypes))
+			} else {
+				filters = append(filters, model.FilterChannelTypes(allowTypes))
+			}
 		}
 	}
 
@@ -286,7 +292,7 @@ func responseJsonClient(c *gin.Context, data interface{}) *types.OpenAIErrorWith
 
 type StreamEndHandler func() string
 
-func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], endHandler StreamEndHandler) (firstResponseTime time.Time, errWithOP *types.OpenAIErrorWithStatusCode) {
+func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], cache *relay_util.ChatCacheProps, moderator *streamModerator, endHandler StreamEndHandler) (firstResponseTime time.Time, errWithOP *types.OpenAIErrorWithStatusCode) {
 	requester.SetEventStreamHeaders(c)
 	dataChan, errChan := stream.Recv()
 
@@ -298,6 +304,24 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 
 	var isFirstResponse bool
 
+	// 发送审查通过的数据，未开启输出审查时每个数据块都直接发送
+	sendData := func(items []string) {
+		for _, data := range items {
+			streamData := "data: " + data + "\n\n"
+			cache.SetResponse(streamData)
+
+			// 尝试写入数据，如果客户端断开也继续处理
+			select {
+			case <-c.Request.Context().Done():
+				// 客户端已断开，不执行任何操作，直接跳过
+			default:
+				// 客户端正常，发送数据
+				c.Writer.Write([]byte(streamData))
+				c.Writer.Flush()
+			}
+		}
+	}
+
 	// 在新的goroutine中处理stream数据
 	go func() {
 		defer close(done)
@@ -306,26 +330,30 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 			select {
 			case data, ok := <-dataChan:
 				if !ok {
+					sendData(moderator.Finish())
 					return
 				}
-				streamData := "data: " + data + "\n\n"
 
 				if !isFirstResponse {
 					firstResponseTime = time.Now()
 					isFirstResponse = true
 				}
 
-				// 尝试写入数据，如果客户端断开也继续处理
-				select {
-				case <-c.Request.Context().Done():
-					// 客户端已断开，不执行任何操作，直接跳过
-				default:
-					// 客户端正常，发送数据
-					c.Writer.Write([]byte(streamData))
-					c.Writer.Flush()
+				sendData(moderator.Push(data))
+				if moderator.Violated() {
+					// 输出违规，终止流并不再缓存
+					cache.NoCache()
+					drainStream(stream, dataChan, errChan)
+					return
 				}
 
 			case err := <-errChan:
+				sendData(moderator.Finish())
+				if moderator.Violated() {
+					cache.NoCache()
+					return
+				}
+
 				if !errors.Is(err, io.EOF) {
 					// 处理错误情况
 					errMsg := "data: " + err.Error() + "\n\n"
@@ -339,12 +367,14 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 					}
 
 					finalErr = common.StringErrorWrapper(err.Error(), "stream_error", 900)
+					cache.NoCache()
 					logger.LogError(c.Request.Context(), "Stream err:"+err.Error())
 				} else {
 					// 正常结束，处理endHandler
 					if finalErr == nil && endHandler != nil {
 						streamData := endHandler()
 						if streamData != "" {
+							cache.SetResponse("data: " + streamData + "\n\n")
 							select {
 							case <-c.Request.Context().Done():
 								// 客户端已断开，不执行任何操作，直接跳过
@@ -358,6 +388,7 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 
 					// 发送结束标记
 					streamData := "data: [DONE]\n\n"
+					cache.SetResponse(streamData)
 					select {
 					case <-c.Request.Context().Done():
 						// 客户端已断开，不执行任何操作，直接跳过
@@ -373,10 +404,12 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 
 	// 等待处理完成
 	<-done
+	moderator.Settle()
+
 	return firstResponseTime, nil
 }
 
-func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], endHandler StreamEndHandler) (firstResponseTime time.Time) {
+func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], moderator *streamModerator, endHandler StreamEndHandler) (firstResponseTime time.Time) {
 	requester.SetEventStreamHeaders(c)
 	dataChan, errChan := stream.Recv()
 
@@ -387,6 +420,21 @@ func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderIn
 	defer stream.Close()
 	var isFirstResponse bool
 
+	// 发送审查通过的数据，未开启输出审查时每个数据块都直接发送
+	sendData := func(items []string) {
+		for _, data := range items {
+			// 尝试写入数据，如果客户端断开也继续处理
+			select {
+			case <-c.Request.Context().Done():
+				// 客户端已断开，不执行任何操作，直接跳过
+			default:
+				// 客户端正常，发送数据
+				fmt.Fprint(c.Writer, data)
+				c.Writer.Flush()
+			}
+		}
+	}
+
 	// 在新的goroutine中处理stream数据
 	go func() {
 		defer close(done)
@@ -395,23 +443,26 @@ func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderIn
 			select {
 			case data, ok := <-dataChan:
 				if !ok {
+					sendData(moderator.Finish())
 					return
 				}
 				if !isFirstResponse {
 					firstResponseTime = time.Now()
 					isFirstResponse = true
 				}
-				// 尝试写入数据，如果客户端断开也继续处理
-				select {
-				case <-c.Request.Context().Done():
-					// 客户端已断开，不执行任何操作，直接跳过
-				default:
-					// 客户端正常，发送数据
-					fmt.Fprint(c.Writer, data)
-					c.Writer.Flush()
+
+				sendData(moderator.Push(data))
+				if moderator.Violated() {
+					drainStream(stream, dataChan, errChan)
+					return
 				}
 
 			case err := <-errChan:
+				sendData(moderator.Finish())
+				if moderator.Violated() {
+					return
+				}
+
 				if !errors.Is(err, io.EOF) {
 					// 处理错误情况
 					select {
@@ -447,6 +498,7 @@ func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderIn
 
 	// 等待处理完成
 	<-done
+	moderator.Settle()
 
 	return firstResponseTime
 }
@@ -72,7 +72,7 @@ func (r *relayCompletions) send() (err *types.OpenAIErrorWithStatusCode, done bo
 	// 内容审查
 	if config.EnableSafe {
 		if r.request.Prompt != nil {
-			CheckResult, _ := safty.CheckContent(r.request.Prompt)
+			CheckResult := safty.CheckInput(r.c, r.request.Prompt)
 			if !CheckResult.IsSafe {
 				err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
 				done = true
@@ -92,8 +92,10 @@ func (r *relayCompletions) send() (err *types.OpenAIErrorWithStatusCode, done bo
 			return r.getUsageResponse()
 		}
 
+		moderator := newStreamModerator(r.c, r.provider.GetUsage(), r.modelName, extractOpenAIStreamText, r.HandleStreamError)
+
 		var firstResponseTime time.Time
-		firstResponseTime, err = responseStreamClient(r.c, response, doneStr)
+		firstResponseTime, err = responseStreamClient(r.c, response, nil, moderator, doneStr)
 		r.SetFirstResponseTime(firstResponseTime)
 	} else {
 		var response *types.CompletionResponse
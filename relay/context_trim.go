@@ -0,0 +1,181 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"strings"
+)
+
+const contextSummaryPrompt = "你是一个对话摘要助手。请将下面的历史对话压缩为简洁的摘要，保留用户的需求、关键事实、已得出的结论和未完成的事项，省略寒暄和重复内容。摘要的语言需要和对话的语言保持一致，直接输出摘要内容。"
+
+// getModelContextLength 从模型信息中读取上下文长度，未配置时返回 0
+func getModelContextLength(modelName string) int {
+	price := model.PricingInstance.GetPrice(modelName)
+	if price.ModelInfo == nil {
+		return 0
+	}
+	return price.ModelInfo.ContextLength
+}
+
+// manageContextWindow 令牌开启上下文管理时，按上游模型的上下文长度丢弃或摘要最早的对话轮次
+// 每次发送前都会检查，重试或回退到上下文更小的模型时会继续裁剪
+func (r *relayChat) manageContextWindow() {
+	tokenSetting, ok := utils.GetGinValue[*model.TokenSetting](r.c, "token_setting")
+	if !ok || tokenSetting == nil || !tokenSetting.ContextTrim.Enabled {
+		return
+	}
+	setting := tokenSetting.ContextTrim
+
+	contextLength := getModelContextLength(r.modelName)
+	if contextLength == 0 {
+		contextLength = getModelContextLength(r.originalModel)
+	}
+	if contextLength <= 0 {
+		return
+	}
+
+	budget := contextLength - r.reservedCompletionTokens(contextLength)
+	summarize := setting.Mode == relay_util.ContextTrimModeSummarize
+	if summarize {
+		budget -= config.ContextSummaryMaxTokens
+	}
+	if budget <= 0 {
+		return
+	}
+
+	preCost := r.provider.GetChannel().PreCost
+	if preCost == config.PreContNotAll {
+		preCost = config.PreCostNotImage
+	}
+
+	messages := r.chatRequest.Messages
+	costs := make([]int, len(messages))
+	for i := range messages {
+		// 单独计算时会包含每次回复的固定开销
+		costs[i] = common.CountTokenMessages(messages[i:i+1], r.modelName, preCost) - 3
+	}
+
+	kept, dropped, droppedTokens := relay_util.TrimMessages(messages, costs, budget, setting.KeepTurns)
+	if len(dropped) == 0 {
+		return
+	}
+
+	info, _ := utils.GetGinValue[*relay_util.ContextTrimInfo](r.c, "context_trim")
+	if info == nil {
+		info = &relay_util.ContextTrimInfo{}
+	}
+	info.DroppedMessages += len(dropped)
+	info.DroppedTokens += droppedTokens
+
+	// 丢弃的内容本身超出上下文时无法摘要，直接丢弃
+	if summarize && droppedTokens <= budget {
+		summary, err := r.summarizeMessages(dropped)
+		if err != nil {
+			logger.LogWarn(r.c.Request.Context(), "context summary failed: "+err.Error())
+		} else if summary != "" {
+			kept = relay_util.InsertContextSummary(kept, summary)
+			info.Summarized = true
+		}
+	}
+
+	r.chatRequest.Messages = kept
+	r.c.Set("context_trim", info)
+	r.c.Writer.Header().Set("X-Context-Trimmed", info.Header())
+	logger.LogInfo(r.c.Request.Context(), fmt.Sprintf("context trimmed for model %s: %s", r.modelName, info.Header()))
+}
+
+// reservedCompletionTokens 为回复预留的 token 数，最多占上下文的一半
+func (r *relayChat) reservedCompletionTokens(contextLength int) int {
+	reserved := r.chatRequest.MaxCompletionTokens
+	if reserved == 0 {
+		reserved = r.chatRequest.MaxTokens
+	}
+	if reserved == 0 {
+		if info := model.PricingInstance.GetPrice(r.modelName).ModelInfo; info != nil {
+			reserved = info.MaxTokens
+		}
+	}
+
+	return min(reserved, contextLength/2)
+}
+
+// summarizeMessages 使用当前渠道生成摘要，摘要请求单独计费
+func (r *relayChat) summarizeMessages(messages []types.ChatCompletionMessage) (string, error) {
+	chatProvider, ok := r.provider.(providersBase.ChatInterface)
+	if !ok {
+		return "", errors.New("channel not implemented")
+	}
+
+	var transcript strings.Builder
+	for _, message := range messages {
+		content := message.StringContent()
+		if content == "" {
+			continue
+		}
+		transcript.WriteString(message.Role + ": " + content + "\n")
+	}
+	if transcript.Len() == 0 {
+		return "", nil
+	}
+
+	request := &types.ChatCompletionRequest{
+		Model: r.modelName,
+		Messages: []types.ChatCompletionMessage{
+			{
+				Role:    types.ChatMessageRoleSystem,
+				Content: contextSummaryPrompt,
+			},
+			{
+				Role:    types.ChatMessageRoleUser,
+				Content: transcript.String(),
+			},
+		},
+		MaxTokens: config.ContextSummaryMaxTokens,
+	}
+
+	usage := &types.Usage{
+		PromptTokens: common.CountTokenMessages(request.Messages, r.modelName, config.PreCostNotImage),
+	}
+
+	// 先预扣费再请求上游，余额不足时不生成摘要
+	quota := relay_util.NewQuota(r.c, r.modelName, usage.PromptTokens)
+	if opErr := quota.PreQuotaConsumption(); opErr != nil {
+		return "", errors.New(opErr.Message)
+	}
+
+	chatProvider.SetUsage(usage)
+	response, opErr := chatProvider.CreateChatCompletion(request)
+	if opErr != nil {
+		quota.Undo(r.c)
+		return "", errors.New(opErr.Message)
+	}
+
+	// 主请求预扣费成功后才结算摘要费用
+	r.summaryQuota = quota
+	r.summaryUsage = usage
+
+	return strings.TrimSpace(response.GetContent()), nil
+}
+
+// settlePendingQuota 主请求预扣费成功时结算摘要费用，失败时退还摘要的预扣费
+func (r *relayChat) settlePendingQuota(consume bool) {
+	if r.summaryQuota == nil {
+		return
+	}
+
+	if consume {
+		r.summaryQuota.Consume(r.c, r.summaryUsage, false)
+	} else {
+		r.summaryQuota.Undo(r.c)
+	}
+	r.summaryQuota = nil
+	r.summaryUsage = nil
+}
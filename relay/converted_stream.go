@@ -0,0 +1,50 @@
+package relay
+
+import (
+	"io"
+	"one-api/common/requester"
+)
+
+// convertedStream 将上游流式数据逐块转换为另一种接口格式，convert 返回空字符串表示跳过该数据块
+type convertedStream struct {
+	stream  requester.StreamReaderInterface[string]
+	convert func(data string) string
+}
+
+func newConvertedStream(stream requester.StreamReaderInterface[string], convert func(data string) string) *convertedStream {
+	return &convertedStream{
+		stream:  stream,
+		convert: convert,
+	}
+}
+
+// Recv 错误也经由转换协程转发，保证结束前已转换的数据都被读取
+func (s *convertedStream) Recv() (<-chan string, <-chan error) {
+	dataChan, errChan := s.stream.Recv()
+	convertedChan := make(chan string)
+	convertedErrChan := make(chan error, 1)
+
+	go func() {
+		for {
+			select {
+			case data, ok := <-dataChan:
+				if !ok {
+					convertedErrChan <- io.EOF
+					return
+				}
+				if converted := s.convert(data); converted != "" {
+					convertedChan <- converted
+				}
+			case err := <-errChan:
+				convertedErrChan <- err
+				return
+			}
+		}
+	}()
+
+	return convertedChan, convertedErrChan
+}
+
+func (s *convertedStream) Close() {
+	s.stream.Close()
+}
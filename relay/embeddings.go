@@ -5,6 +5,7 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
 	"one-api/safty"
 	"one-api/types"
 	"strings"
@@ -15,6 +16,7 @@ import (
 type relayEmbeddings struct {
 	relayBase
 	request types.EmbeddingRequest
+	cache   *relay_util.EmbeddingCache
 }
 
 func NewRelayEmbeddings(c *gin.Context) *relayEmbeddings {
@@ -34,10 +36,23 @@ func (r *relayEmbeddings) setRequest() error {
 
 	r.setOriginalModel(r.request.Model)
 
+	r.cache = relay_util.NewEmbeddingCache(r.c, &r.request, r.originalModel)
+	if hits := r.cache.HitCount(); hits > 0 {
+		r.c.Set("embedding_cache_hits", hits)
+	}
+
 	return nil
 }
 
+// getPromptTokens 开启向量缓存时只计算未命中的输入
 func (r *relayEmbeddings) getPromptTokens() (int, error) {
+	if r.cache != nil {
+		if r.cache.AllHit() {
+			return 0, nil
+		}
+		return common.CountTokenInput(r.cache.MissInputs(), r.modelName), nil
+	}
+
 	return common.CountTokenInput(r.request.Input, r.modelName), nil
 }
 
@@ -52,7 +67,7 @@ func (r *relayEmbeddings) send() (err *types.OpenAIErrorWithStatusCode, done boo
 	// 内容审查
 	if config.EnableSafe {
 		if r.request.Input != nil {
-			CheckResult, _ := safty.CheckContent(r.request)
+			CheckResult := safty.CheckInput(r.c, r.request.Input)
 			if !CheckResult.IsSafe {
 				err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
 				done = true
@@ -63,6 +78,10 @@ func (r *relayEmbeddings) send() (err *types.OpenAIErrorWithStatusCode, done boo
 
 	r.request.Model = r.modelName
 
+	if r.cache != nil {
+		return r.cacheSend(provider)
+	}
+
 	response, err := provider.CreateEmbeddings(&r.request)
 	if err != nil {
 		return
@@ -75,3 +94,33 @@ func (r *relayEmbeddings) send() (err *types.OpenAIErrorWithStatusCode, done boo
 
 	return
 }
+
+// cacheSend 只把未命中缓存的输入发给上游，再和缓存结果按原始顺序组装
+func (r *relayEmbeddings) cacheSend(provider providersBase.EmbeddingsInterface) (err *types.OpenAIErrorWithStatusCode, done bool) {
+	var response *types.EmbeddingResponse
+	if !r.cache.AllHit() {
+		response, err = provider.CreateEmbeddings(r.cache.MissRequest(&r.request))
+		if err != nil {
+			return
+		}
+	}
+
+	assembled, assembleErr := r.cache.Assemble(r.getOriginalModel(), response)
+	if assembleErr != nil {
+		return common.ErrorWrapper(assembleErr, "embedding_cache_error", http.StatusInternalServerError), true
+	}
+
+	usage := r.provider.GetUsage()
+	if response == nil {
+		usage.PromptTokens = 0
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	assembled.Usage = usage
+
+	err = responseJsonClient(r.c, assembled)
+	if err != nil {
+		done = true
+	}
+
+	return
+}
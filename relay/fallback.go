@@ -0,0 +1,114 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/types"
+	"slices"
+	"strings"
+	"time"
+)
+
+var contextLengthKeywords = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context length",
+	"context window",
+	"prompt is too long",
+	"input token count",
+	"too many tokens",
+}
+
+// fallbackErrorClass 将错误归类为可回退的错误类型，本地错误不回退
+func fallbackErrorClass(apiErr *types.OpenAIErrorWithStatusCode) string {
+	if apiErr == nil || apiErr.LocalError {
+		return ""
+	}
+
+	switch {
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		return config.FallbackOnRateLimit
+	case apiErr.StatusCode/100 == 5:
+		return config.FallbackOnServerError
+	case isContextLengthError(apiErr):
+		return config.FallbackOnContextLength
+	}
+
+	return ""
+}
+
+func isContextLengthError(apiErr *types.OpenAIErrorWithStatusCode) bool {
+	if code, ok := apiErr.OpenAIError.Code.(string); ok && code == config.FallbackOnContextLength {
+		return true
+	}
+
+	message := strings.ToLower(apiErr.OpenAIError.Message)
+	for _, keyword := range contextLengthKeywords {
+		if strings.Contains(message, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// relayFallback 原模型失败后按回退链依次尝试其他模型，每个模型都会按原有逻辑重试渠道
+// 计费使用实际提供服务的模型，并通过响应头和日志记录回退信息
+func relayFallback(relay RelayBaseInterface, apiErr *types.OpenAIErrorWithStatusCode, class string, skipChannelIds []int) *types.OpenAIErrorWithStatusCode {
+	c := relay.getContext()
+	originalModel := relay.getOriginalModel()
+
+	// 指定渠道的请求不回退
+	if c.GetInt("specific_channel_id") > 0 && !c.GetBool("specific_channel_id_ignore") {
+		return apiErr
+	}
+
+	fallback := config.ModelFallbackInstance.Get(originalModel, c.GetString("token_group"))
+	if fallback == nil || !fallback.Triggers(class) {
+		return apiErr
+	}
+
+	startTime := c.GetTime("requestStartTime")
+	timeout := time.Duration(config.RetryTimeOut) * time.Second
+
+	for _, fallbackModel := range fallback.Fallbacks {
+		if time.Since(startTime) > timeout {
+			break
+		}
+
+		// 先检查令牌的模型限制，GetProvider 检查失败时会直接中止请求
+		if err := checkLimitModel(c, fallbackModel); err != nil {
+			continue
+		}
+
+		// 跳过的渠道只针对原模型，回退模型重新选择
+		c.Set("skip_channel_ids", slices.Clone(skipChannelIds))
+		relay.setOriginalModel(fallbackModel)
+		if err := relay.setProvider(relay.getOriginalModel()); err != nil {
+			logger.LogError(c.Request.Context(), fmt.Sprintf("fallback model %s unavailable: %s", fallbackModel, err.Error()))
+			continue
+		}
+
+		logger.LogWarn(c.Request.Context(), fmt.Sprintf("model %s failed (%s), fallback to %s", originalModel, class, fallbackModel))
+		c.Set("fallback_from", originalModel)
+		c.Writer.Header().Set("X-Fallback-From", originalModel)
+		c.Writer.Header().Set("X-Served-Model", relay.getOriginalModel())
+
+		var done bool
+		apiErr, done = relayWithRetry(relay)
+		if apiErr == nil {
+			return nil
+		}
+
+		class = fallbackErrorClass(apiErr)
+		if done || !fallback.Triggers(class) {
+			break
+		}
+	}
+
+	c.Writer.Header().Del("X-Fallback-From")
+	c.Writer.Header().Del("X-Served-Model")
+
+	return apiErr
+}
@@ -7,6 +7,8 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/requester"
+	"one-api/common/utils"
+	providersBase "one-api/providers/base"
 	"one-api/providers/gemini"
 	"one-api/safty"
 	"one-api/types"
@@ -23,7 +25,6 @@ type relayGeminiOnly struct {
 }
 
 func NewRelayGeminiOnly(c *gin.Context) *relayGeminiOnly {
-	c.Set("allow_channel_type", AllowGeminiChannelType)
 	relay := &relayGeminiOnly{
 		relayBase: relayBase{
 			allowHeartbeat: true,
@@ -58,6 +59,7 @@ func (r *relayGeminiOnly) setRequest() error {
 	r.geminiRequest.Model = modelList[0]
 	r.geminiRequest.Stream = isStream
 	r.setOriginalModel(r.geminiRequest.Model)
+	injectGeminiPrompt(r.c, r.geminiRequest)
 
 	return nil
 }
@@ -77,21 +79,25 @@ func (r *relayGeminiOnly) getPromptTokens() (int, error) {
 
 func (r *relayGeminiOnly) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 	chatProvider, ok := r.provider.(gemini.GeminiChatInterface)
-	if !ok {
-		return nil, false
+	if !ok || !utils.Contains(r.provider.GetChannel().Type, AllowGeminiChannelType) {
+		// 非原生渠道做一层Chat的兼容
+		openaiProvider, ok := r.provider.(providersBase.ChatInterface)
+		if !ok {
+			err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+			done = true
+			return
+		}
+
+		return r.compatibleSend(openaiProvider)
 	}
 
 	// 内容审查
 	if config.EnableSafe {
-		for _, message := range r.geminiRequest.Contents {
-			if message.Parts != nil {
-				CheckResult, _ := safty.CheckContent(message.Parts)
-				if !CheckResult.IsSafe {
-					err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
-					done = true
-					return
-				}
-			}
+		CheckResult := safty.CheckInput(r.c, r.geminiRequest.Contents)
+		if !CheckResult.IsSafe {
+			err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
+			done = true
+			return
 		}
 	}
 
@@ -111,7 +117,8 @@ func (r *relayGeminiOnly) send() (err *types.OpenAIErrorWithStatusCode, done boo
 		doneStr := func() string {
 			return ""
 		}
-		firstResponseTime := responseGeneralStreamClient(r.c, response, doneStr)
+		moderator := newStreamModerator(r.c, r.provider.GetUsage(), r.modelName, extractGeminiStreamText, r.HandleStreamError)
+		firstResponseTime := responseGeneralStreamClient(r.c, response, moderator, doneStr)
 		r.SetFirstResponseTime(firstResponseTime)
 	} else {
 		var response *gemini.GeminiChatResponse
@@ -169,6 +176,13 @@ func CountGeminiTokenMessages(request *gemini.GeminiChatRequest, preCostType int
 	tokensPerMessage := 4
 	var textMsg strings.Builder
 
+	if request.SystemInstruction != nil {
+		tokenNum += tokensPerMessage
+		if systemInstruction, err := json.Marshal(request.SystemInstruction); err == nil {
+			textMsg.Write(systemInstruction)
+		}
+	}
+
 	for _, message := range request.Contents {
 		tokenNum += tokensPerMessage
 		for _, part := range message.Parts {
@@ -176,6 +190,12 @@ func CountGeminiTokenMessages(request *gemini.GeminiChatRequest, preCostType int
 				textMsg.WriteString(part.Text)
 			}
 
+			if part.FunctionCall != nil || part.FunctionResponse != nil {
+				if function, err := json.Marshal(part); err == nil {
+					textMsg.Write(function)
+				}
+			}
+
 			if part.InlineData != nil {
 				// 其他类型的，暂时按200个token计算
 				tokenNum += 200
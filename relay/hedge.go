@@ -0,0 +1,319 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 选择渠道时会写入上下文的值，对冲请求需要在两个渠道之间切换
+var hedgeContextKeys = []string{
+	"channel_id",
+	"channel_type",
+	"original_model",
+	"new_model",
+	"billing_original_model",
+	"skip_channel_ids",
+	"is_backupGroup",
+	"group_ratio",
+}
+
+// getHedgeProvider 为对冲请求选择渠道
+var getHedgeProvider = GetProvider
+
+type hedgeOpenFunc func(provider providersBase.ProviderInterface, modelName string) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode)
+
+type hedgeAttempt struct {
+	provider  providersBase.ProviderInterface
+	modelName string
+	values    map[string]any
+	cancel    context.CancelFunc
+}
+
+type hedgeResult struct {
+	attempt *hedgeAttempt
+	stream  *prefetchedStream
+	err     *types.OpenAIErrorWithStatusCode
+}
+
+// getHedgeDelay 返回对冲延迟，未开启对冲时返回 0
+func getHedgeDelay(c *gin.Context) time.Duration {
+	if !config.HedgeEnabled || config.HedgeDelayMilliseconds <= 0 {
+		return 0
+	}
+
+	// 指定渠道的请求不对冲
+	if c.GetInt("specific_channel_id") > 0 && !c.GetBool("specific_channel_id_ignore") {
+		return 0
+	}
+
+	tokenSetting, ok := utils.GetGinValue[*model.TokenSetting](c, "token_setting")
+	if !ok || tokenSetting == nil || !tokenSetting.Hedge.Enabled {
+		return 0
+	}
+
+	return time.Duration(config.HedgeDelayMilliseconds) * time.Millisecond
+}
+
+func snapshotHedgeContext(c *gin.Context) map[string]any {
+	values := make(map[string]any, len(hedgeContextKeys))
+	for _, key := range hedgeContextKeys {
+		value, _ := c.Get(key)
+		values[key] = value
+	}
+
+	return values
+}
+
+func restoreHedgeContext(c *gin.Context, values map[string]any) {
+	for _, key := range hedgeContextKeys {
+		c.Set(key, values[key])
+	}
+}
+
+// hedgeStream 先请求当前渠道，超过 delay 仍未收到首个数据块时再选择一个渠道并发请求
+// 先返回数据的一方胜出，另一方被取消且不计费
+func (r *relayBase) hedgeStream(delay time.Duration, open hedgeOpenFunc) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode) {
+	r.hedge = nil
+	results := make(chan *hedgeResult, 2)
+
+	primary := &hedgeAttempt{
+		provider:  r.provider,
+		modelName: r.modelName,
+		values:    snapshotHedgeContext(r.c),
+	}
+	primary.start(open, results)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var hedge *hedgeAttempt
+	var primaryErr *types.OpenAIErrorWithStatusCode
+	pending := 1
+
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if primaryErr != nil {
+				continue
+			}
+			hedge = r.newHedgeAttempt(primary)
+			if hedge == nil {
+				continue
+			}
+			logger.LogInfo(r.c.Request.Context(), fmt.Sprintf("hedge request fired, channel #%d no first token after %s, racing channel #%d", primary.channelId(), delay, hedge.channelId()))
+			hedge.start(open, results)
+			pending++
+
+		case result := <-results:
+			pending--
+			if result.err != nil {
+				if result.attempt == primary {
+					primaryErr = result.err
+				} else {
+					logger.LogError(r.c.Request.Context(), fmt.Sprintf("hedge channel #%d error: %s", hedge.channelId(), result.err.Message))
+				}
+				continue
+			}
+
+			if hedge != nil {
+				r.useHedgeWinner(result.attempt, primary, hedge, pending > 0, results)
+			}
+			return result.stream, nil
+		}
+	}
+
+	// 两个渠道都失败时，以主渠道为准交给重试逻辑处理
+	if hedge != nil {
+		r.provider = primary.provider
+		r.modelName = primary.modelName
+		restoreHedgeContext(r.c, primary.values)
+		hedge.cancel()
+	}
+	primary.cancel()
+
+	return nil, primaryErr
+}
+
+// newHedgeAttempt 跳过主渠道重新选择一个渠道，上下文保持为主渠道的值
+func (r *relayBase) newHedgeAttempt(primary *hedgeAttempt) *hedgeAttempt {
+	skipChannelIds, _ := utils.GetGinValue[[]int](r.c, "skip_channel_ids")
+	skipChannelIds = append(append([]int{}, skipChannelIds...), primary.channelId())
+	r.c.Set("skip_channel_ids", skipChannelIds)
+
+	provider, modelName, err := getHedgeProvider(r.c, r.originalModel)
+	values := snapshotHedgeContext(r.c)
+	restoreHedgeContext(r.c, primary.values)
+
+	if err != nil {
+		logger.LogError(r.c.Request.Context(), "hedge request skipped: "+err.Error())
+		return nil
+	}
+
+	provider.SetOtherArg(r.otherArg)
+	provider.SetUsage(&types.Usage{
+		PromptTokens: primary.provider.GetUsage().PromptTokens,
+	})
+
+	return &hedgeAttempt{
+		provider:  provider,
+		modelName: modelName,
+		values:    values,
+	}
+}
+
+// useHedgeWinner 切换到胜出的渠道，取消另一方
+func (r *relayBase) useHedgeWinner(winner, primary, hedge *hedgeAttempt, loserPending bool, results <-chan *hedgeResult) {
+	loser := hedge
+	if winner == hedge {
+		loser = primary
+		r.provider = hedge.provider
+		r.modelName = hedge.modelName
+		restoreHedgeContext(r.c, hedge.values)
+	}
+
+	loser.cancel()
+	if loserPending {
+		go func() {
+			if result := <-results; result.stream != nil {
+				result.stream.Close()
+			}
+		}()
+	}
+
+	r.hedge = &relay_util.HedgeInfo{
+		PrimaryChannelId: primary.channelId(),
+		HedgeChannelId:   hedge.channelId(),
+		HedgeWon:         winner == hedge,
+	}
+}
+
+func (a *hedgeAttempt) channelId() int {
+	return a.provider.GetChannel().Id
+}
+
+func (a *hedgeAttempt) start(open hedgeOpenFunc, results chan<- *hedgeResult) {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	if requester := a.provider.GetRequester(); requester != nil {
+		requester.Context = ctx
+	}
+
+	go func() {
+		stream, err := open(a.provider, a.modelName)
+		if err != nil {
+			results <- &hedgeResult{attempt: a, err: err}
+			return
+		}
+
+		prefetched := prefetchStream(stream)
+		prefetched.cancel = a.cancel
+		if prefetched.firstErr != nil && !errors.Is(prefetched.firstErr, io.EOF) {
+			stream.Close()
+			results <- &hedgeResult{attempt: a, err: common.ErrorWrapper(prefetched.firstErr, "stream_error", http.StatusBadGateway)}
+			return
+		}
+
+		results <- &hedgeResult{attempt: a, stream: prefetched}
+	}()
+}
+
+// prefetchedStream 已经读取了首个事件的流，Recv 时先回放首个事件
+type prefetchedStream struct {
+	stream   requester.StreamReaderInterface[string]
+	dataChan <-chan string
+	errChan  <-chan error
+	first    string
+	firstErr error
+	received bool
+	// 取消本次请求的上下文，胜出的流转发完成后关闭时释放
+	cancel context.CancelFunc
+}
+
+func prefetchStream(stream requester.StreamReaderInterface[string]) *prefetchedStream {
+	dataChan, errChan := stream.Recv()
+	prefetched := &prefetchedStream{
+		stream:   stream,
+		dataChan: dataChan,
+		errChan:  errChan,
+	}
+
+	select {
+	case data, ok := <-dataChan:
+		if !ok {
+			prefetched.firstErr = io.EOF
+		}
+		prefetched.first = data
+	case err := <-errChan:
+		prefetched.firstErr = err
+	}
+
+	return prefetched
+}
+
+func (s *prefetchedStream) Recv() (<-chan string, <-chan error) {
+	s.received = true
+	dataChan := make(chan string)
+	errChan := make(chan error)
+
+	go func() {
+		if s.firstErr != nil {
+			errChan <- s.firstErr
+			return
+		}
+
+		dataChan <- s.first
+		for {
+			select {
+			case data, ok := <-s.dataChan:
+				if !ok {
+					close(dataChan)
+					return
+				}
+				dataChan <- data
+			case err := <-s.errChan:
+				errChan <- err
+				return
+			}
+		}
+	}()
+
+	return dataChan, errChan
+}
+
+func (s *prefetchedStream) Close() {
+	s.stream.Close()
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	// 落败的流没有被读取，需要读完剩余的数据，避免读取协程阻塞
+	if !s.received && s.firstErr == nil {
+		go func() {
+			for {
+				select {
+				case _, ok := <-s.dataChan:
+					if !ok {
+						return
+					}
+				case <-s.errChan:
+					return
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,222 @@
+package relay
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// hedgeFakeProvider 延迟 delay 后返回一个数据块，ignoreCancel 为 true 时取消后仍然返回流
+type hedgeFakeProvider struct {
+	providersBase.BaseProvider
+	delay        time.Duration
+	ignoreCancel bool
+	mutate       bool
+	request      *types.ChatCompletionRequest
+	stream       *hedgeFakeStream
+}
+
+func newHedgeFakeProvider(channelId int, delay time.Duration) *hedgeFakeProvider {
+	return &hedgeFakeProvider{
+		BaseProvider: providersBase.BaseProvider{
+			Channel:   &model.Channel{Id: channelId},
+			Requester: &requester.HTTPRequester{},
+			Usage:     &types.Usage{},
+		},
+		delay:  delay,
+		stream: &hedgeFakeStream{},
+	}
+}
+
+func (p *hedgeFakeProvider) GetRequestHeaders() map[string]string {
+	return nil
+}
+
+func (p *hedgeFakeProvider) CreateChatCompletion(request *types.ChatCompletionRequest) (*types.ChatCompletionResponse, *types.OpenAIErrorWithStatusCode) {
+	return nil, nil
+}
+
+func (p *hedgeFakeProvider) CreateChatCompletionStream(request *types.ChatCompletionRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode) {
+	p.request = request
+	// 模拟渠道转换请求时修改消息
+	if p.mutate {
+		for i := range request.Messages {
+			request.Messages[i].Role = "mutated"
+		}
+	}
+
+	if p.ignoreCancel {
+		time.Sleep(p.delay)
+	} else {
+		select {
+		case <-time.After(p.delay):
+		case <-p.Requester.Context.Done():
+			return nil, &types.OpenAIErrorWithStatusCode{StatusCode: http.StatusBadGateway, OpenAIError: types.OpenAIError{Message: "canceled"}}
+		}
+	}
+
+	return p.stream, nil
+}
+
+type hedgeFakeStream struct {
+	closed atomic.Bool
+}
+
+func (s *hedgeFakeStream) Recv() (<-chan string, <-chan error) {
+	dataChan := make(chan string, 1)
+	errChan := make(chan error, 1)
+	dataChan <- "data"
+	close(dataChan)
+	return dataChan, errChan
+}
+
+func (s *hedgeFakeStream) Close() {
+	s.closed.Store(true)
+}
+
+func setupHedgeTest(t *testing.T, primary, hedge *hedgeFakeProvider) *relayChat {
+	logger.Logger = zap.NewNop()
+	model.PricingInstance = &model.Pricing{Prices: map[string]*model.Price{
+		"primary-model": {Type: model.TokensPriceType, Input: 1, Output: 1},
+		"hedge-model":   {Type: model.TokensPriceType, Input: 2, Output: 2},
+	}}
+
+	originalGetHedgeProvider := getHedgeProvider
+	t.Cleanup(func() { getHedgeProvider = originalGetHedgeProvider })
+	getHedgeProvider = func(c *gin.Context, modelName string) (providersBase.ProviderInterface, string, error) {
+		if hedge == nil {
+			return nil, "", errors.New("no channel")
+		}
+		c.Set("channel_id", hedge.Channel.Id)
+		c.Set("group_ratio", 1.0)
+		return hedge, "hedge-model", nil
+	}
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Set("channel_id", primary.Channel.Id)
+	c.Set("group_ratio", 1.0)
+
+	primary.Usage.PromptTokens = 10
+	r := &relayChat{
+		relayBase: relayBase{
+			c:             c,
+			provider:      primary,
+			modelName:     "primary-model",
+			originalModel: "primary-model",
+		},
+		chatRequest: types.ChatCompletionRequest{
+			Model:  "primary-model",
+			Stream: true,
+			Messages: []types.ChatCompletionMessage{
+				{Role: types.ChatMessageRoleUser, Content: "hello"},
+			},
+		},
+	}
+	return r
+}
+
+func TestHedgeStreamHedgeWins(t *testing.T) {
+	primary := newHedgeFakeProvider(1, 500*time.Millisecond)
+	primary.ignoreCancel = true
+	hedge := newHedgeFakeProvider(2, 0)
+	hedge.mutate = true
+	r := setupHedgeTest(t, primary, hedge)
+
+	stream, err := r.hedgeStream(20*time.Millisecond, r.hedgeOpen())
+	require.Nil(t, err)
+	require.NotNil(t, stream)
+
+	// 对冲渠道使用请求的深拷贝，修改不影响原请求
+	require.NotNil(t, hedge.request)
+	assert.Equal(t, "hedge-model", hedge.request.Model)
+	assert.Equal(t, types.ChatMessageRoleUser, r.chatRequest.Messages[0].Role)
+	assert.Equal(t, "primary-model", r.chatRequest.Model)
+
+	// 切换到胜出的渠道
+	assert.Equal(t, hedge, r.provider)
+	assert.Equal(t, "hedge-model", r.modelName)
+	assert.Equal(t, 2, r.c.GetInt("channel_id"))
+
+	info := r.getHedge()
+	require.NotNil(t, info)
+	assert.Equal(t, &relay_util.HedgeInfo{PrimaryChannelId: 1, HedgeChannelId: 2, HedgeWon: true}, info)
+
+	// 落败的主渠道被取消，之后返回的流会被关闭
+	assert.Error(t, primary.Requester.Context.Err())
+	assert.Eventually(t, primary.stream.closed.Load, 2*time.Second, 10*time.Millisecond)
+	assert.False(t, hedge.stream.closed.Load())
+
+	// 胜出的流转发完成关闭后释放请求的上下文
+	assert.NoError(t, hedge.Requester.Context.Err())
+	stream.Close()
+	assert.True(t, hedge.stream.closed.Load())
+	assert.Error(t, hedge.Requester.Context.Err())
+
+	// 按胜出渠道的模型计费
+	quota := relay_util.NewQuota(r.c, "primary-model", 10)
+	quota.SetHedge(r.c, r.modelName, info)
+	assert.Equal(t, 2.0, quota.GetInputRatio())
+}
+
+func TestHedgeStreamPrimaryWins(t *testing.T) {
+	primary := newHedgeFakeProvider(1, 50*time.Millisecond)
+	hedge := newHedgeFakeProvider(2, 500*time.Millisecond)
+	r := setupHedgeTest(t, primary, hedge)
+
+	stream, err := r.hedgeStream(10*time.Millisecond, r.hedgeOpen())
+	require.Nil(t, err)
+	require.NotNil(t, stream)
+
+	assert.Equal(t, primary, r.provider)
+	assert.Equal(t, "primary-model", r.modelName)
+	assert.Equal(t, 1, r.c.GetInt("channel_id"))
+
+	info := r.getHedge()
+	require.NotNil(t, info)
+	assert.False(t, info.HedgeWon)
+
+	// 对冲渠道被取消，没有返回流
+	assert.Error(t, hedge.Requester.Context.Err())
+	assert.False(t, primary.stream.closed.Load())
+
+	assert.NoError(t, primary.Requester.Context.Err())
+	stream.Close()
+	assert.Error(t, primary.Requester.Context.Err())
+
+	quota := relay_util.NewQuota(r.c, "primary-model", 10)
+	quota.SetHedge(r.c, r.modelName, info)
+	assert.Equal(t, 1.0, quota.GetInputRatio())
+}
+
+func TestHedgeStreamWithoutHedgeChannel(t *testing.T) {
+	primary := newHedgeFakeProvider(1, 50*time.Millisecond)
+	r := setupHedgeTest(t, primary, nil)
+
+	stream, err := r.hedgeStream(10*time.Millisecond, r.hedgeOpen())
+	require.Nil(t, err)
+	require.NotNil(t, stream)
+
+	assert.Equal(t, primary, r.provider)
+	assert.Nil(t, r.getHedge())
+
+	// 没有触发对冲时同样在关闭后释放上下文
+	stream.Close()
+	assert.Error(t, primary.Requester.Context.Err())
+}
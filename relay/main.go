@@ -14,6 +14,7 @@ import (
 	"one-api/model"
 	"one-api/relay/relay_util"
 	"one-api/types"
+	"slices"
 	"strings"
 	"time"
 
@@ -27,6 +28,9 @@ func Relay(c *gin.Context) {
 		return
 	}
 
+	archive := relay_util.NewPayloadArchive(c)
+	defer archive.Save()
+
 	// Apply pre-mapping before setRequest to ensure request body modifications take effect
 	applyPreMappingBeforeRequest(c)
 
@@ -37,9 +41,25 @@ func Relay(c *gin.Context) {
 	}
 
 	c.Set("is_stream", relay.IsStream())
+
+	if cache := relay.getChatCache().Get(); cache != nil {
+		cacheProcessing(relay, cache)
+		return
+	}
+
+	// 回退模型重新选择渠道时需要恢复请求原本跳过的渠道
+	skipChannelIds, _ := utils.GetGinValue[[]int](c, "skip_channel_ids")
+	skipChannelIds = slices.Clone(skipChannelIds)
+
 	if err := relay.setProvider(relay.getOriginalModel()); err != nil {
 		openaiErr := common.StringErrorWrapperLocal(err.Error(), "one_hub_error", http.StatusServiceUnavailable)
-		relay.HandleJsonError(openaiErr)
+		// 原模型没有可用渠道时按 5xx 尝试回退，模型限制等已中止的请求不回退
+		if !c.IsAborted() {
+			openaiErr = relayFallback(relay, openaiErr, config.FallbackOnServerError, skipChannelIds)
+		}
+		if openaiErr != nil {
+			relay.HandleJsonError(openaiErr)
+		}
 		return
 	}
 
@@ -48,7 +68,27 @@ func Relay(c *gin.Context) {
 		defer heartbeat.Close()
 	}
 
-	apiErr, done := RelayHandler(relay)
+	apiErr, done := relayWithRetry(relay)
+	if apiErr != nil && !done {
+		apiErr = relayFallback(relay, apiErr, fallbackErrorClass(apiErr), skipChannelIds)
+	}
+
+	if apiErr != nil {
+		if heartbeat != nil && heartbeat.IsSafeWriteStream() {
+			relay.HandleStreamError(apiErr)
+			return
+		}
+
+		relay.HandleJsonError(apiErr)
+	}
+}
+
+// relayWithRetry 使用已选择的渠道发送请求，失败时按重试次数切换同一模型的其他渠道
+// done 为 true 时表示不能再尝试其他模型
+func relayWithRetry(relay RelayBaseInterface) (apiErr *types.OpenAIErrorWithStatusCode, done bool) {
+	c := relay.getContext()
+
+	apiErr, done = RelayHandler(relay)
 	if apiErr == nil {
 		metrics.RecordProvider(c, 200)
 		return
@@ -72,6 +112,7 @@ func Relay(c *gin.Context) {
 
 		if time.Since(startTime) > timeout {
 			apiErr = common.StringErrorWrapperLocal("重试超时，上游负载已饱和，请稍后再试", "system_error", http.StatusTooManyRequests)
+			done = true
 			break
 		}
 
@@ -92,19 +133,13 @@ func Relay(c *gin.Context) {
 		}
 	}
 
-	if apiErr != nil {
-		if heartbeat != nil && heartbeat.IsSafeWriteStream() {
-			relay.HandleStreamError(apiErr)
-			return
-		}
-
-		relay.HandleJsonError(apiErr)
-	}
+	return
 }
 
 func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCode, done bool) {
 	promptTokens, tonkeErr := relay.getPromptTokens()
 	if tonkeErr != nil {
+		settlePendingQuota(relay, false)
 		err = common.ErrorWrapperLocal(tonkeErr, "token_error", http.StatusBadRequest)
 		done = true
 		return
@@ -118,11 +153,20 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 
 	quota := relay_util.NewQuota(relay.getContext(), relay.getModelName(), promptTokens)
 	if err = quota.PreQuotaConsumption(); err != nil {
+		settlePendingQuota(relay, false)
 		done = true
 		return
 	}
+	settlePendingQuota(relay, true)
 
+	attemptStart := time.Now()
 	err, done = relay.send()
+	// 触发对冲时只按胜出渠道的用量计费
+	if hedge := relay.getHedge(); hedge != nil {
+		usage = relay.getProvider().GetUsage()
+		quota.SetHedge(relay.getContext(), relay.getModelName(), hedge)
+	}
+	recordChannelResult(relay, err, attemptStart)
 	// 最后处理流式中断时计算tokens
 	if usage.CompletionTokens == 0 && usage.TextBuilder.Len() > 0 {
 		usage.CompletionTokens = common.CountTokenText(usage.TextBuilder.String(), relay.getModelName())
@@ -137,9 +181,66 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 
 	quota.Consume(relay.getContext(), usage, relay.IsStream())
 
+	// 缓存键按原模型生成，回退模型的回答不写入缓存
+	if relay.getContext().GetString("fallback_from") == "" {
+		relay.getChatCache().Store(relay.getProvider().GetChannel().Id, relay.getModelName(), usage)
+	}
+
 	return
 }
 
+// pendingQuotaSettler 计算 prompt tokens 时额外请求过上游的 relay，例如上下文摘要
+type pendingQuotaSettler interface {
+	settlePendingQuota(consume bool)
+}
+
+// settlePendingQuota 主请求预扣费成功后结算额外请求的费用，预扣费失败时一并退还
+func settlePendingQuota(relay RelayBaseInterface, consume bool) {
+	if settler, ok := relay.(pendingQuotaSettler); ok {
+		settler.settlePendingQuota(consume)
+	}
+}
+
+// recordChannelResult 记录本次请求的渠道表现，请求本身的错误不计入渠道统计
+func recordChannelResult(relay RelayBaseInterface, err *types.OpenAIErrorWithStatusCode, attemptStart time.Time) {
+	if err != nil && (err.LocalError || err.StatusCode == http.StatusBadRequest) {
+		return
+	}
+
+	var ttft time.Duration
+	if firstResponseTime := relay.GetFirstResponseTime(); firstResponseTime.After(attemptStart) {
+		ttft = firstResponseTime.Sub(attemptStart)
+	}
+
+	channelId := relay.getProvider().GetChannel().Id
+	model.ChannelGroup.RecordResult(channelId, relay.getOriginalModel(), err == nil, ttft, time.Since(attemptStart))
+}
+
+// cacheProcessing 命中对话缓存时直接回放缓存内容，并按缓存倍率计费
+func cacheProcessing(relay RelayBaseInterface, cache *relay_util.ChatCacheEntry) {
+	c := relay.getContext()
+	if err := checkLimitModel(c, relay.getOriginalModel()); err != nil {
+		relay.HandleJsonError(common.StringErrorWrapperLocal(err.Error(), "one_hub_error", http.StatusNotFound))
+		return
+	}
+
+	usage := &types.Usage{
+		PromptTokens:     cache.PromptTokens,
+		CompletionTokens: cache.CompletionTokens,
+		TotalTokens:      cache.PromptTokens + cache.CompletionTokens,
+	}
+
+	quota := relay_util.NewQuota(c, cache.ModelName, cache.PromptTokens)
+	quota.SetChatCacheHit(cache.ChannelId)
+	if err := quota.PreQuotaConsumption(); err != nil {
+		relay.HandleJsonError(err)
+		return
+	}
+
+	responseCache(c, cache.Response, relay.IsStream())
+	quota.Consume(c, usage, relay.IsStream())
+}
+
 func shouldCooldowns(c *gin.Context, channel *model.Channel, apiErr *types.OpenAIErrorWithStatusCode) {
 	modelName := c.GetString("new_model")
 	channelId := channel.Id
@@ -0,0 +1,36 @@
+package relay
+
+import (
+	"sync"
+	"testing"
+
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// 违规日志等在后台协程中写入，数据库只初始化一次，避免与上一个用例的写入竞争
+var setupDB sync.Once
+
+// setupTestDB 使用内存 SQLite 作为数据库，并关闭 Redis
+func setupTestDB(t *testing.T) {
+	setupDB.Do(func() {
+		logger.Logger = zap.NewNop()
+		config.RedisEnabled = false
+
+		db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormLogger.Discard})
+		require.NoError(t, err)
+		require.NoError(t, db.AutoMigrate(&model.User{}, &model.Log{}, &model.StoredResponse{}))
+		// 内存数据库每个连接是独立的库
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		sqlDB.SetMaxOpenConns(1)
+		model.DB = db
+	})
+}
@@ -0,0 +1,147 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/providers/claude"
+	"one-api/providers/gemini"
+	"one-api/types"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getPromptPreamble 渲染分组和令牌引用的提示词模板，分组模板在前，没有可用模板时返回空字符串
+func getPromptPreamble(c *gin.Context, modelName string) string {
+	refs := make([]*model.PromptTemplateRef, 0, 2)
+	if ref := model.GlobalUserGroupRatio.GetPromptTemplate(c.GetString("token_group")); ref != nil {
+		refs = append(refs, ref)
+	}
+	if tokenSetting, ok := utils.GetGinValue[*model.TokenSetting](c, "token_setting"); ok && tokenSetting != nil && tokenSetting.PromptTemplate.TemplateId > 0 {
+		refs = append(refs, &tokenSetting.PromptTemplate)
+	}
+	if len(refs) == 0 {
+		return ""
+	}
+
+	vars := promptTemplateVars(c, modelName)
+	contents := make([]string, 0, len(refs))
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		rendered, err := model.GlobalPromptTemplates.Render(ref, vars)
+		if err != nil {
+			// 模板被删除或停用时跳过，不影响请求
+			logger.LogWarn(c.Request.Context(), fmt.Sprintf("skip prompt template %d: %s", ref.TemplateId, err.Error()))
+			continue
+		}
+		if rendered.Content == "" {
+			continue
+		}
+		contents = append(contents, rendered.Content)
+		names = append(names, rendered.String())
+	}
+
+	if len(names) > 0 {
+		c.Set("prompt_templates", names)
+	}
+
+	return strings.Join(contents, "\n\n")
+}
+
+func promptTemplateVars(c *gin.Context, modelName string) map[string]string {
+	userName, _ := model.CacheGetUsername(c.GetInt("id"))
+	now := time.Now()
+
+	return map[string]string{
+		"user_name":  userName,
+		"token_name": c.GetString("token_name"),
+		"group":      c.GetString("token_group"),
+		"model":      modelName,
+		"date":       now.Format("2006-01-02"),
+		"datetime":   now.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// injectChatPrompt 合并到首条 system 消息，没有时插入新的 system 消息
+func injectChatPrompt(c *gin.Context, request *types.ChatCompletionRequest) {
+	preamble := getPromptPreamble(c, request.Model)
+	if preamble == "" {
+		return
+	}
+
+	if len(request.Messages) > 0 && request.Messages[0].Role == types.ChatMessageRoleSystem {
+		if content, ok := request.Messages[0].Content.(string); ok {
+			request.Messages[0].Content = preamble + "\n\n" + content
+			return
+		}
+	}
+
+	request.Messages = append([]types.ChatCompletionMessage{{
+		Role:    types.ChatMessageRoleSystem,
+		Content: preamble,
+	}}, request.Messages...)
+}
+
+// injectClaudePrompt system 为字符串时拼接，为内容块数组时在开头插入文本块
+func injectClaudePrompt(c *gin.Context, request *claude.ClaudeRequest) {
+	preamble := getPromptPreamble(c, request.Model)
+	if preamble == "" {
+		return
+	}
+
+	switch system := request.System.(type) {
+	case nil:
+		request.System = preamble
+	case string:
+		if system == "" {
+			request.System = preamble
+		} else {
+			request.System = preamble + "\n\n" + system
+		}
+	case []any:
+		block := map[string]any{"type": "text", "text": preamble}
+		request.System = append([]any{block}, system...)
+	}
+}
+
+// injectGeminiPrompt 在 systemInstruction 开头插入文本，原生渠道转发原始请求体，需要同步修改
+func injectGeminiPrompt(c *gin.Context, request *gemini.GeminiChatRequest) {
+	preamble := getPromptPreamble(c, request.Model)
+	if preamble == "" {
+		return
+	}
+
+	instruction := &gemini.GeminiChatContent{}
+	if request.SystemInstruction != nil {
+		if data, err := json.Marshal(request.SystemInstruction); err == nil {
+			json.Unmarshal(data, instruction)
+		}
+	}
+	instruction.Parts = append([]gemini.GeminiPart{{Text: preamble}}, instruction.Parts...)
+	request.SystemInstruction = instruction
+
+	rawBody, ok := utils.GetGinValue[[]byte](c, config.GinRequestBodyKey)
+	if !ok {
+		return
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rawBody, &body); err != nil {
+		return
+	}
+
+	instructionData, err := json.Marshal(instruction)
+	if err != nil {
+		return
+	}
+	body["systemInstruction"] = instructionData
+
+	if data, err := json.Marshal(body); err == nil {
+		c.Set(config.GinRequestBodyKey, data)
+	}
+}
@@ -1,17 +1,21 @@
 package relay
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"one-api/common"
 	"one-api/common/config"
+	"one-api/common/limit"
 	"one-api/common/logger"
 	"one-api/common/requester"
 	"one-api/common/utils"
 	"one-api/metrics"
+	"one-api/model"
 	providersBase "one-api/providers/base"
 	"one-api/relay/relay_util"
 	"one-api/types"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -25,6 +29,11 @@ type RelayModeChatRealtime struct {
 	providerConn   *websocket.Conn
 	quota          *relay_util.Quota
 	usage          *types.UsageEvent
+
+	sessionId   string
+	startTime   time.Time
+	closeReason string
+	closeOnce   sync.Once
 }
 
 var upgrader = websocket.Upgrader{
@@ -41,6 +50,17 @@ func ChatRealtime(c *gin.Context) {
 		return
 	}
 
+	tokenSetting, _ := utils.GetGinValue[*model.TokenSetting](c, "token_setting")
+	maxSessions, maxDuration := tokenSetting.Realtime()
+
+	// 升级连接前检查并发会话数，超出时直接返回 429
+	release, ok := limit.AcquireRealtimeSession(c.GetInt("token_id"), maxSessions)
+	if !ok {
+		common.AbortWithMessage(c, http.StatusTooManyRequests, "too many concurrent realtime sessions")
+		return
+	}
+	defer release()
+
 	userConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		fmt.Println("upgrade failed", err)
@@ -52,7 +72,9 @@ func ChatRealtime(c *gin.Context) {
 		relayBase: relayBase{
 			c: c,
 		},
-		userConn: userConn,
+		userConn:  userConn,
+		sessionId: "rt_" + utils.GetUUID(),
+		startTime: time.Now(),
 	}
 	relay.setOriginalModel(modelName)
 
@@ -68,22 +90,58 @@ func ChatRealtime(c *gin.Context) {
 
 	wsProxy.Start()
 
-	go func() {
-		var closedBy string
-		select {
-		case <-wsProxy.UserClosed():
-			closedBy = "user"
-		case <-wsProxy.SupplierClosed():
-			closedBy = "provider"
-		}
+	if maxDuration > 0 {
+		timer := time.AfterFunc(maxDuration, func() {
+			relay.setCloseReason("max_duration")
+			eventErr := types.NewErrorEvent("", "invalid_request_error", "session_expired", fmt.Sprintf("realtime session exceeded the maximum duration of %s", maxDuration))
+			wsProxy.Shutdown(websocket.ClosePolicyViolation, "session expired", []byte(eventErr.Error()))
+		})
+		defer timer.Stop()
+	}
+
+	var closedBy string
+	select {
+	case <-wsProxy.UserClosed():
+		closedBy = "user"
+	case <-wsProxy.SupplierClosed():
+		closedBy = "provider"
+	}
 
-		logger.LogInfo(relay.c.Request.Context(), fmt.Sprintf("连接由%s关闭", closedBy))
-		wsProxy.Close()
-		relay.quota.Consume(relay.c, relay.usage.ToChatUsage(), false)
+	logger.LogInfo(relay.c.Request.Context(), fmt.Sprintf("连接由%s关闭", closedBy))
+	wsProxy.Close()
+	relay.setCloseReason("closed_by_" + closedBy)
+	relay.finish()
+}
 
-	}()
+// setCloseReason 记录会话关闭原因，只保留第一次设置的值
+func (r *RelayModeChatRealtime) setCloseReason(reason string) {
+	r.closeOnce.Do(func() {
+		r.closeReason = reason
+	})
+}
 
-	wsProxy.Wait()
+// finish 结算会话用量并输出会话汇总日志
+func (r *RelayModeChatRealtime) finish() {
+	duration := time.Since(r.startTime)
+	usage := r.usage.ToChatUsage()
+
+	logger.LogInfo(r.c.Request.Context(), fmt.Sprintf(
+		"Realtime 会话 %s 结束，原因：%s，时长：%s，输入 %d tokens（音频 %d），输出 %d tokens（音频 %d）",
+		r.sessionId,
+		r.closeReason,
+		duration.Round(time.Second),
+		usage.PromptTokens,
+		usage.PromptTokensDetails.AudioTokens,
+		usage.CompletionTokens,
+		usage.CompletionTokensDetails.AudioTokens,
+	))
+
+	r.quota.SetRealtimeSession(&relay_util.RealtimeSessionInfo{
+		SessionId:   r.sessionId,
+		Duration:    duration,
+		CloseReason: r.closeReason,
+	})
+	r.quota.Consume(r.c, usage, false)
 }
 
 func (r *RelayModeChatRealtime) abortWithMessage(message string) {
@@ -175,7 +233,12 @@ func (r *RelayModeChatRealtime) getRealtimeFirstMessage() bool {
 
 func (r *RelayModeChatRealtime) usageHandler(usage *types.UsageEvent) error {
 	err := r.quota.UpdateUserRealtimeQuota(r.usage, usage)
+	if errors.Is(err, relay_util.ErrRealtimeQuotaNotEnough) {
+		r.setCloseReason("quota_exhausted")
+		return types.NewErrorEvent("", "insufficient_quota", "insufficient_quota", err.Error())
+	}
 	if err != nil {
+		r.setCloseReason("system_error")
 		return types.NewErrorEvent("", "system_error", "system_error", err.Error())
 	}
 
@@ -0,0 +1,179 @@
+package relay_util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const chatCacheKeyPrefix = "chat_cache"
+
+type ChatCacheEntry struct {
+	Response         string `json:"response"`
+	IsStream         bool   `json:"is_stream"`
+	ModelName        string `json:"model_name"`
+	ChannelId        int    `json:"channel_id"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	CreatedAt        int64  `json:"created_at"`
+}
+
+type ChatCacheDriver interface {
+	Get(key string) (*ChatCacheEntry, error)
+	Set(key string, entry *ChatCacheEntry, expire time.Duration) error
+	// Purge 删除匹配分组和模型的缓存，为空表示不限制
+	Purge(group, modelName string) (int, error)
+}
+
+var (
+	chatCacheDriver     ChatCacheDriver
+	chatCacheDriverOnce sync.Once
+)
+
+func GetChatCacheDriver() ChatCacheDriver {
+	chatCacheDriverOnce.Do(func() {
+		if config.RedisEnabled {
+			chatCacheDriver = &ChatCacheRedis{}
+		} else {
+			chatCacheDriver = NewChatCacheMemory()
+		}
+	})
+
+	return chatCacheDriver
+}
+
+// ChatCacheProps 记录单次请求的缓存状态，nil 表示该请求不参与缓存
+type ChatCacheProps struct {
+	key      string
+	isStream bool
+	noCache  bool
+	response strings.Builder
+	driver   ChatCacheDriver
+}
+
+// NewChatCacheProps 根据全局开关、令牌设置和请求参数判断是否启用缓存
+func NewChatCacheProps(c *gin.Context, request *types.ChatCompletionRequest) *ChatCacheProps {
+	if !config.ChatCacheEnabled || request == nil {
+		return nil
+	}
+
+	tokenSetting, ok := utils.GetGinValue[*model.TokenSetting](c, "token_setting")
+	if !ok || tokenSetting == nil || !tokenSetting.ChatCache.Enabled {
+		return nil
+	}
+
+	// 只缓存确定性的请求
+	if request.Temperature == nil || *request.Temperature != 0 {
+		return nil
+	}
+
+	key, err := ChatCacheKey(c.GetString("token_group"), request)
+	if err != nil {
+		logger.LogError(c.Request.Context(), "chat cache key error: "+err.Error())
+		return nil
+	}
+
+	return &ChatCacheProps{
+		key:      key,
+		isStream: request.Stream,
+		driver:   GetChatCacheDriver(),
+	}
+}
+
+// ChatCacheKey 对请求做归一化后计算缓存 key，格式为 chat_cache:{group}:{model}:{hash}
+func ChatCacheKey(group string, request *types.ChatCompletionRequest) (string, error) {
+	normalized := *request
+	// user 字段只用于上游追踪，不影响输出
+	normalized.User = ""
+	if !normalized.Stream {
+		normalized.StreamOptions = nil
+	}
+
+	body, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(body)
+	return fmt.Sprintf("%s:%s:%s:%s", chatCacheKeyPrefix, group, request.Model, hex.EncodeToString(hash[:])), nil
+}
+
+func (p *ChatCacheProps) Get() *ChatCacheEntry {
+	if p == nil {
+		return nil
+	}
+
+	entry, err := p.driver.Get(p.key)
+	if err != nil || entry == nil {
+		return nil
+	}
+
+	if entry.IsStream != p.isStream {
+		return nil
+	}
+
+	return entry
+}
+
+// SetResponse 记录返回内容，流式时为已写出的原始 SSE 数据
+func (p *ChatCacheProps) SetResponse(response any) {
+	if p == nil || p.noCache {
+		return
+	}
+
+	if str, ok := response.(string); ok {
+		p.response.WriteString(str)
+		return
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		p.NoCache()
+		return
+	}
+	p.response.Reset()
+	p.response.Write(body)
+}
+
+func (p *ChatCacheProps) NoCache() {
+	if p == nil {
+		return
+	}
+	p.noCache = true
+}
+
+func (p *ChatCacheProps) Store(channelId int, modelName string, usage *types.Usage) {
+	if p == nil || p.noCache || p.response.Len() == 0 || usage == nil {
+		return
+	}
+
+	entry := &ChatCacheEntry{
+		Response:         p.response.String(),
+		IsStream:         p.isStream,
+		ModelName:        modelName,
+		ChannelId:        channelId,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CreatedAt:        utils.GetTimestamp(),
+	}
+
+	expire := time.Duration(config.ChatCacheExpireMinute) * time.Minute
+	if err := p.driver.Set(p.key, entry, expire); err != nil {
+		logger.SysError("chat cache store error: " + err.Error())
+	}
+}
+
+func PurgeChatCache(group, modelName string) (int, error) {
+	return GetChatCacheDriver().Purge(group, modelName)
+}
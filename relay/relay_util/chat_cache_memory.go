@@ -0,0 +1,116 @@
+package relay_util
+
+import (
+	"container/list"
+	"one-api/common/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+type chatCacheMemoryItem struct {
+	key      string
+	entry    *ChatCacheEntry
+	expireAt time.Time
+}
+
+// ChatCacheMemory 未开启Redis时使用的进程内缓存，超出条数限制时按 LRU 淘汰
+type ChatCacheMemory struct {
+	sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+}
+
+func NewChatCacheMemory() *ChatCacheMemory {
+	return &ChatCacheMemory{
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+func (m *ChatCacheMemory) Get(key string) (*ChatCacheEntry, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	element, ok := m.items[key]
+	if !ok {
+		return nil, nil
+	}
+
+	item := element.Value.(*chatCacheMemoryItem)
+	if time.Now().After(item.expireAt) {
+		m.removeElement(element)
+		return nil, nil
+	}
+
+	m.order.MoveToFront(element)
+	return item.entry, nil
+}
+
+func (m *ChatCacheMemory) Set(key string, entry *ChatCacheEntry, expire time.Duration) error {
+	m.Lock()
+	defer m.Unlock()
+
+	expireAt := time.Now().Add(expire)
+	if element, ok := m.items[key]; ok {
+		item := element.Value.(*chatCacheMemoryItem)
+		item.entry = entry
+		item.expireAt = expireAt
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.items[key] = m.order.PushFront(&chatCacheMemoryItem{
+		key:      key,
+		entry:    entry,
+		expireAt: expireAt,
+	})
+
+	maxEntries := config.ChatCacheMaxEntries
+	for maxEntries > 0 && m.order.Len() > maxEntries {
+		m.removeElement(m.order.Back())
+	}
+
+	return nil
+}
+
+func (m *ChatCacheMemory) Purge(group, modelName string) (int, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	deleted := 0
+	for key, element := range m.items {
+		if matchChatCacheKey(key, group, modelName) {
+			m.removeElement(element)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (m *ChatCacheMemory) removeElement(element *list.Element) {
+	item := element.Value.(*chatCacheMemoryItem)
+	delete(m.items, item.key)
+	m.order.Remove(element)
+}
+
+// matchChatCacheKey 判断 key 是否属于指定的分组和模型，模型名中可能包含 ":" 或 "/"
+func matchChatCacheKey(key, group, modelName string) bool {
+	rest, ok := strings.CutPrefix(key, chatCacheKeyPrefix+":")
+	if !ok {
+		return false
+	}
+
+	keyGroup, rest, ok := strings.Cut(rest, ":")
+	if !ok {
+		return false
+	}
+
+	hashIndex := strings.LastIndex(rest, ":")
+	if hashIndex < 0 {
+		return false
+	}
+
+	return (group == "" || group == keyGroup) && (modelName == "" || modelName == rest[:hashIndex])
+}
@@ -0,0 +1,52 @@
+package relay_util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"one-api/common/redis"
+	"time"
+)
+
+type ChatCacheRedis struct{}
+
+func (r *ChatCacheRedis) Get(key string) (*ChatCacheEntry, error) {
+	value, err := redis.RedisGet(key)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entry ChatCacheEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+func (r *ChatCacheRedis) Set(key string, entry *ChatCacheEntry, expire time.Duration) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return redis.RedisSet(key, string(value), expire)
+}
+
+func (r *ChatCacheRedis) Purge(group, modelName string) (int, error) {
+	return redis.RedisDelByPattern(chatCachePattern(group, modelName))
+}
+
+func chatCachePattern(group, modelName string) string {
+	if group == "" {
+		group = "*"
+	}
+	if modelName == "" {
+		modelName = "*"
+	}
+
+	return fmt.Sprintf("%s:%s:%s:*", chatCacheKeyPrefix, group, modelName)
+}
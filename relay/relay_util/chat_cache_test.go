@@ -0,0 +1,62 @@
+package relay_util_test
+
+import (
+	"testing"
+	"time"
+
+	"one-api/common/config"
+	"one-api/relay/relay_util"
+	"one-api/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCacheRequest(user string) *types.ChatCompletionRequest {
+	temperature := 0.0
+	return &types.ChatCompletionRequest{
+		Model:       "gpt-4o",
+		Temperature: &temperature,
+		User:        user,
+		Messages: []types.ChatCompletionMessage{
+			{Role: "user", Content: "hello"},
+		},
+	}
+}
+
+func TestChatCacheKey(t *testing.T) {
+	keyA, err := relay_util.ChatCacheKey("default", newCacheRequest("a"))
+	assert.Nil(t, err)
+	keyB, _ := relay_util.ChatCacheKey("default", newCacheRequest("b"))
+	keyVip, _ := relay_util.ChatCacheKey("vip", newCacheRequest("a"))
+
+	assert.Equal(t, keyA, keyB)
+	assert.NotEqual(t, keyA, keyVip)
+
+	stream := newCacheRequest("a")
+	stream.Stream = true
+	keyStream, _ := relay_util.ChatCacheKey("default", stream)
+	assert.NotEqual(t, keyA, keyStream)
+}
+
+func TestChatCacheMemory(t *testing.T) {
+	config.ChatCacheMaxEntries = 2
+	memory := relay_util.NewChatCacheMemory()
+
+	memory.Set("chat_cache:default:gpt-4o:aaa", &relay_util.ChatCacheEntry{Response: "a"}, time.Minute)
+	memory.Set("chat_cache:default:qwen/qwen3:32b:bbb", &relay_util.ChatCacheEntry{Response: "b"}, time.Minute)
+	memory.Set("chat_cache:vip:gpt-4o:ccc", &relay_util.ChatCacheEntry{Response: "c"}, time.Minute)
+
+	// 超出条数限制，最早写入的被淘汰
+	entry, _ := memory.Get("chat_cache:default:gpt-4o:aaa")
+	assert.Nil(t, entry)
+
+	deleted, _ := memory.Purge("", "qwen/qwen3:32b")
+	assert.Equal(t, 1, deleted)
+
+	entry, _ = memory.Get("chat_cache:vip:gpt-4o:ccc")
+	assert.Equal(t, "c", entry.Response)
+
+	memory.Set("chat_cache:vip:gpt-4o:ddd", &relay_util.ChatCacheEntry{Response: "d"}, -time.Second)
+	entry, _ = memory.Get("chat_cache:vip:gpt-4o:ddd")
+	assert.Nil(t, entry)
+}
@@ -0,0 +1,99 @@
+package relay_util
+
+import (
+	"fmt"
+	"one-api/types"
+)
+
+const (
+	ContextTrimModeTrim      = "trim"
+	ContextTrimModeSummarize = "summarize"
+
+	defaultContextKeepTurns = 2
+)
+
+// ContextTrimInfo 上下文裁剪的结果，重试或回退时会再次裁剪，结果累加
+type ContextTrimInfo struct {
+	DroppedMessages int
+	DroppedTokens   int
+	Summarized      bool
+}
+
+// Header 写入 X-Context-Trimmed 响应头的内容
+func (i *ContextTrimInfo) Header() string {
+	return fmt.Sprintf("messages=%d; tokens=%d; summarized=%t", i.DroppedMessages, i.DroppedTokens, i.Summarized)
+}
+
+func isSystemRole(role string) bool {
+	return role == types.ChatMessageRoleSystem || role == "developer"
+}
+
+// TrimMessages 从最早的对话轮次开始丢弃，直到消息的 token 数不超过 budget
+// 每轮从一条 user 消息开始，assistant 的工具调用和对应的 tool 结果总在同一轮内，因此不会被拆开
+// system 消息和最近 keepTurns 轮始终保留，costs 为每条消息的 token 数
+func TrimMessages(messages []types.ChatCompletionMessage, costs []int, budget, keepTurns int) (kept, dropped []types.ChatCompletionMessage, droppedTokens int) {
+	// 每次回复的固定开销，和 CountTokenMessages 保持一致
+	total := 3
+	for _, cost := range costs {
+		total += cost
+	}
+	if total <= budget {
+		return messages, nil, 0
+	}
+
+	if keepTurns <= 0 {
+		keepTurns = defaultContextKeepTurns
+	}
+
+	turns := make([][]int, 0)
+	for i, message := range messages {
+		if isSystemRole(message.Role) {
+			continue
+		}
+		if message.Role == types.ChatMessageRoleUser || len(turns) == 0 {
+			turns = append(turns, make([]int, 0, 1))
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], i)
+	}
+
+	dropIndexes := make(map[int]bool)
+	for t := 0; t < len(turns)-keepTurns && total > budget; t++ {
+		for _, i := range turns[t] {
+			dropIndexes[i] = true
+			total -= costs[i]
+			droppedTokens += costs[i]
+		}
+	}
+
+	if len(dropIndexes) == 0 {
+		return messages, nil, 0
+	}
+
+	kept = make([]types.ChatCompletionMessage, 0, len(messages)-len(dropIndexes))
+	dropped = make([]types.ChatCompletionMessage, 0, len(dropIndexes))
+	for i, message := range messages {
+		if dropIndexes[i] {
+			dropped = append(dropped, message)
+		} else {
+			kept = append(kept, message)
+		}
+	}
+
+	return kept, dropped, droppedTokens
+}
+
+// InsertContextSummary 将摘要作为 system 消息插入到开头的 system 消息之后
+func InsertContextSummary(messages []types.ChatCompletionMessage, summary string) []types.ChatCompletionMessage {
+	index := 0
+	for index < len(messages) && isSystemRole(messages[index].Role) {
+		index++
+	}
+
+	result := make([]types.ChatCompletionMessage, 0, len(messages)+1)
+	result = append(result, messages[:index]...)
+	result = append(result, types.ChatCompletionMessage{
+		Role:    types.ChatMessageRoleSystem,
+		Content: "以下是之前对话的摘要：\n" + summary,
+	})
+	return append(result, messages[index:]...)
+}
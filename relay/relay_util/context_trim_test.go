@@ -0,0 +1,62 @@
+package relay_util_test
+
+import (
+	"testing"
+
+	"one-api/relay/relay_util"
+	"one-api/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTrimMessages() ([]types.ChatCompletionMessage, []int) {
+	messages := []types.ChatCompletionMessage{
+		{Role: "system", Content: "be helpful"},
+		{Role: "user", Content: "turn 1"},
+		{Role: "assistant", ToolCalls: []*types.ChatCompletionToolCalls{{Id: "call_1"}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "result"},
+		{Role: "assistant", Content: "answer 1"},
+		{Role: "user", Content: "turn 2"},
+		{Role: "assistant", Content: "answer 2"},
+		{Role: "user", Content: "turn 3"},
+	}
+	costs := []int{10, 10, 10, 100, 10, 10, 10, 10}
+	return messages, costs
+}
+
+func TestTrimMessagesKeepsToolPairs(t *testing.T) {
+	messages, costs := newTrimMessages()
+
+	kept, dropped, tokens := relay_util.TrimMessages(messages, costs, 60, 2)
+	assert.Len(t, dropped, 4)
+	assert.Equal(t, 130, tokens)
+	assert.Equal(t, "system", kept[0].Role)
+	assert.Equal(t, "turn 2", kept[1].Content)
+	assert.Len(t, kept, 4)
+}
+
+func TestTrimMessagesKeepsRecentTurns(t *testing.T) {
+	messages, costs := newTrimMessages()
+
+	// 预算足够时不裁剪
+	kept, dropped, _ := relay_util.TrimMessages(messages, costs, 1000, 2)
+	assert.Nil(t, dropped)
+	assert.Len(t, kept, len(messages))
+
+	// 只有保留的轮次时即使超出也不裁剪
+	kept, dropped, _ = relay_util.TrimMessages(messages, costs, 10, 3)
+	assert.Nil(t, dropped)
+	assert.Len(t, kept, len(messages))
+}
+
+func TestInsertContextSummary(t *testing.T) {
+	messages, _ := newTrimMessages()
+
+	result := relay_util.InsertContextSummary(messages[5:], "earlier")
+	assert.Equal(t, "system", result[0].Role)
+	assert.Equal(t, "turn 2", result[1].Content)
+
+	result = relay_util.InsertContextSummary(messages[:1], "earlier")
+	assert.Equal(t, "be helpful", result[0].Content)
+	assert.Equal(t, "system", result[1].Role)
+}
@@ -0,0 +1,233 @@
+package relay_util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const embeddingCacheKeyPrefix = "embedding_cache"
+
+type EmbeddingCacheDriver interface {
+	// MGet 批量读取，未命中的位置为 nil
+	MGet(keys []string) ([][]byte, error)
+	Set(key string, value []byte, expire time.Duration) error
+}
+
+var (
+	embeddingCacheDriver     EmbeddingCacheDriver
+	embeddingCacheDriverOnce sync.Once
+)
+
+func GetEmbeddingCacheDriver() EmbeddingCacheDriver {
+	embeddingCacheDriverOnce.Do(func() {
+		if config.RedisEnabled && strings.EqualFold(config.EmbeddingCacheStore, "Redis") {
+			embeddingCacheDriver = &EmbeddingCacheRedis{}
+		} else {
+			embeddingCacheDriver = NewEmbeddingCacheDisk(viper.GetString("embedding_cache_dir"))
+		}
+	})
+
+	return embeddingCacheDriver
+}
+
+// EmbeddingCache 记录单次 embeddings 请求的缓存命中情况，nil 表示该请求不参与缓存
+// 相同的输入只会发送一次，结果按原始顺序重新组装
+type EmbeddingCache struct {
+	driver EmbeddingCacheDriver
+	inputs []string
+	keys   []string
+	hits   map[string]json.RawMessage
+	misses []string
+}
+
+// NewEmbeddingCache 查询缓存，只支持字符串输入，token 数组输入不缓存
+func NewEmbeddingCache(c *gin.Context, request *types.EmbeddingRequest, modelName string) *EmbeddingCache {
+	if !config.EmbeddingCacheEnabled || request == nil {
+		return nil
+	}
+
+	inputs, ok := embeddingCacheInputs(request.Input)
+	if !ok {
+		return nil
+	}
+
+	return newEmbeddingCache(GetEmbeddingCacheDriver(), c.GetString("token_group"), modelName, request, inputs)
+}
+
+func newEmbeddingCache(driver EmbeddingCacheDriver, group, modelName string, request *types.EmbeddingRequest, inputs []string) *EmbeddingCache {
+	cache := &EmbeddingCache{
+		driver: driver,
+		inputs: inputs,
+		keys:   make([]string, len(inputs)),
+		hits:   make(map[string]json.RawMessage),
+	}
+
+	uniqueKeys := make([]string, 0, len(inputs))
+	uniqueInputs := make(map[string]string, len(inputs))
+	for i, input := range inputs {
+		key := EmbeddingCacheKey(group, modelName, request, input)
+		cache.keys[i] = key
+		if _, ok := uniqueInputs[key]; !ok {
+			uniqueInputs[key] = input
+			uniqueKeys = append(uniqueKeys, key)
+		}
+	}
+
+	values, err := driver.MGet(uniqueKeys)
+	if err != nil {
+		logger.SysError("embedding cache get error: " + err.Error())
+		values = make([][]byte, len(uniqueKeys))
+	}
+
+	for i, key := range uniqueKeys {
+		if values[i] != nil {
+			cache.hits[key] = values[i]
+		} else {
+			cache.misses = append(cache.misses, uniqueInputs[key])
+		}
+	}
+
+	return cache
+}
+
+// embeddingCacheInputs 输入为字符串或字符串数组时返回所有输入
+func embeddingCacheInputs(input any) ([]string, bool) {
+	switch value := input.(type) {
+	case string:
+		return []string{value}, true
+	case []string:
+		return value, len(value) > 0
+	case []any:
+		inputs := make([]string, 0, len(value))
+		for _, item := range value {
+			str, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			inputs = append(inputs, str)
+		}
+		return inputs, len(inputs) > 0
+	}
+
+	return nil, false
+}
+
+// EmbeddingCacheKey 格式为 embedding_cache:{group}:{model}:{hash}，维度和编码格式不同的结果分开缓存
+func EmbeddingCacheKey(group, modelName string, request *types.EmbeddingRequest, input string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s\x00%s", request.Dimensions, request.EncodingFormat, input)))
+	return fmt.Sprintf("%s:%s:%s:%s", embeddingCacheKeyPrefix, group, modelName, hex.EncodeToString(hash[:]))
+}
+
+// HitCount 命中缓存的输入数量，重复的输入按次数计算
+func (e *EmbeddingCache) HitCount() int {
+	if e == nil {
+		return 0
+	}
+
+	count := 0
+	for _, key := range e.keys {
+		if _, ok := e.hits[key]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+// MissInputs 需要发送给上游的输入，已去重
+func (e *EmbeddingCache) MissInputs() []string {
+	if e == nil {
+		return nil
+	}
+	return e.misses
+}
+
+func (e *EmbeddingCache) AllHit() bool {
+	return e != nil && len(e.misses) == 0
+}
+
+// MissRequest 只包含未命中输入的请求
+func (e *EmbeddingCache) MissRequest(request *types.EmbeddingRequest) *types.EmbeddingRequest {
+	missRequest := *request
+	inputs := make([]any, 0, len(e.misses))
+	for _, input := range e.misses {
+		inputs = append(inputs, input)
+	}
+	missRequest.Input = inputs
+
+	return &missRequest
+}
+
+// Assemble 缓存上游返回的向量，并按原始输入顺序组装完整响应，全部命中时 response 为 nil
+func (e *EmbeddingCache) Assemble(modelName string, response *types.EmbeddingResponse) (*types.EmbeddingResponse, error) {
+	vectors := make(map[string]json.RawMessage, len(e.hits)+len(e.misses))
+	for key, vector := range e.hits {
+		vectors[key] = vector
+	}
+
+	if len(e.misses) > 0 {
+		if response == nil || len(response.Data) != len(e.misses) {
+			return nil, errors.New("embedding response does not match the request inputs")
+		}
+
+		missKeys := make(map[string]string, len(e.misses))
+		for i, key := range e.keys {
+			if _, ok := e.hits[key]; !ok {
+				missKeys[e.inputs[i]] = key
+			}
+		}
+
+		expire := time.Duration(config.EmbeddingCacheExpireHours) * time.Hour
+		for _, data := range response.Data {
+			if data.Index < 0 || data.Index >= len(e.misses) {
+				return nil, errors.New("embedding response index out of range")
+			}
+
+			vector, err := json.Marshal(data.Embedding)
+			if err != nil {
+				return nil, err
+			}
+
+			key := missKeys[e.misses[data.Index]]
+			vectors[key] = vector
+			if err := e.driver.Set(key, vector, expire); err != nil {
+				logger.SysError("embedding cache set error: " + err.Error())
+			}
+		}
+	}
+
+	assembled := &types.EmbeddingResponse{
+		Object: "list",
+		Model:  modelName,
+		Data:   make([]types.Embedding, 0, len(e.keys)),
+	}
+	if response != nil {
+		assembled.Model = response.Model
+		assembled.Usage = response.Usage
+	}
+
+	for i, key := range e.keys {
+		vector, ok := vectors[key]
+		if !ok {
+			return nil, errors.New("embedding missing for input")
+		}
+		assembled.Data = append(assembled.Data, types.Embedding{
+			Object:    "embedding",
+			Embedding: vector,
+			Index:     i,
+		})
+	}
+
+	return assembled, nil
+}
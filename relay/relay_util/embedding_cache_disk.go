@@ -0,0 +1,208 @@
+package relay_util
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type embeddingCacheDiskItem struct {
+	name     string
+	size     int64
+	expireAt time.Time
+}
+
+// EmbeddingCacheDisk 本地磁盘存储，每个向量一个文件，超出 EmbeddingCacheMaxSize 时按 LRU 淘汰
+// 索引只保存在内存中，启动后第一次使用时扫描目录重建
+type EmbeddingCacheDisk struct {
+	sync.Mutex
+	dir    string
+	size   int64
+	loaded bool
+	items  map[string]*list.Element
+	order  *list.List
+}
+
+func NewEmbeddingCacheDisk(dir string) *EmbeddingCacheDisk {
+	return &EmbeddingCacheDisk{
+		dir:   dir,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// 文件名使用 key 的哈希，按前两位分目录
+func (d *EmbeddingCacheDisk) fileName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func (d *EmbeddingCacheDisk) filePath(name string) string {
+	return filepath.Join(d.dir, name[:2], name)
+}
+
+func (d *EmbeddingCacheDisk) MGet(keys []string) ([][]byte, error) {
+	d.Lock()
+	defer d.Unlock()
+	d.load()
+
+	values := make([][]byte, len(keys))
+	now := time.Now()
+	for i, key := range keys {
+		name := d.fileName(key)
+		element, ok := d.items[name]
+		if !ok {
+			continue
+		}
+
+		item := element.Value.(*embeddingCacheDiskItem)
+		if !item.expireAt.IsZero() && now.After(item.expireAt) {
+			d.removeElement(element)
+			continue
+		}
+
+		data, err := os.ReadFile(d.filePath(name))
+		if err != nil {
+			d.removeElement(element)
+			continue
+		}
+
+		d.order.MoveToFront(element)
+		values[i] = data
+	}
+
+	return values, nil
+}
+
+func (d *EmbeddingCacheDisk) Set(key string, value []byte, expire time.Duration) error {
+	d.Lock()
+	defer d.Unlock()
+	d.load()
+
+	name := d.fileName(key)
+	path := d.filePath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免读到写了一半的文件
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, value, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	var expireAt time.Time
+	if expire > 0 {
+		expireAt = time.Now().Add(expire)
+		// 用修改时间记录过期时间，重建索引时使用
+		os.Chtimes(path, expireAt, expireAt)
+	}
+
+	if element, ok := d.items[name]; ok {
+		item := element.Value.(*embeddingCacheDiskItem)
+		d.size += int64(len(value)) - item.size
+		item.size = int64(len(value))
+		item.expireAt = expireAt
+		d.order.MoveToFront(element)
+	} else {
+		d.items[name] = d.order.PushFront(&embeddingCacheDiskItem{
+			name:     name,
+			size:     int64(len(value)),
+			expireAt: expireAt,
+		})
+		d.size += int64(len(value))
+	}
+
+	d.evict()
+
+	return nil
+}
+
+// evict 超出大小上限时删除最久未使用的文件
+func (d *EmbeddingCacheDisk) evict() {
+	maxSize := int64(config.EmbeddingCacheMaxSize) * 1024 * 1024
+	for maxSize > 0 && d.size > maxSize && d.order.Len() > 0 {
+		d.removeElement(d.order.Back())
+	}
+}
+
+func (d *EmbeddingCacheDisk) removeElement(element *list.Element) {
+	item := element.Value.(*embeddingCacheDiskItem)
+	d.order.Remove(element)
+	delete(d.items, item.name)
+	d.size -= item.size
+	os.Remove(d.filePath(item.name))
+}
+
+// load 扫描缓存目录重建索引，过期时间早的视为更久未使用
+func (d *EmbeddingCacheDisk) load() {
+	if d.loaded {
+		return
+	}
+	d.loaded = true
+
+	items := make([]*embeddingCacheDiskItem, 0)
+	now := time.Now()
+	err := filepath.WalkDir(d.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+
+		name := entry.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			// 写入中断残留的临时文件
+			os.Remove(path)
+			return nil
+		}
+		if len(name) != sha256.Size*2 {
+			return nil
+		}
+
+		item := &embeddingCacheDiskItem{
+			name: name,
+			size: info.Size(),
+		}
+		// 修改时间晚于当前时间的为过期时间，否则是未设置有效期时的写入时间
+		if info.ModTime().After(now) {
+			item.expireAt = info.ModTime()
+		} else if config.EmbeddingCacheExpireHours > 0 {
+			os.Remove(path)
+			return nil
+		}
+		items = append(items, item)
+
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		logger.SysError("embedding cache load error: " + err.Error())
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].expireAt.Before(items[j].expireAt)
+	})
+
+	for _, item := range items {
+		d.items[item.name] = d.order.PushFront(item)
+		d.size += item.size
+	}
+
+	d.evict()
+}
@@ -0,0 +1,33 @@
+package relay_util
+
+import (
+	"one-api/common/redis"
+	"time"
+)
+
+// EmbeddingCacheRedis 使用 Redis 存储，容量由 Redis 的 maxmemory 和淘汰策略控制
+type EmbeddingCacheRedis struct{}
+
+func (r *EmbeddingCacheRedis) MGet(keys []string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	results, err := redis.RedisMGet(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, result := range results {
+		if str, ok := result.(string); ok {
+			values[i] = []byte(str)
+		}
+	}
+
+	return values, nil
+}
+
+func (r *EmbeddingCacheRedis) Set(key string, value []byte, expire time.Duration) error {
+	return redis.RedisSet(key, string(value), expire)
+}
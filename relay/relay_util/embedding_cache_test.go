@@ -0,0 +1,71 @@
+package relay_util_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"one-api/common/config"
+	"one-api/relay/relay_util"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddingCache(t *testing.T) {
+	config.EmbeddingCacheEnabled = true
+	viper.Set("embedding_cache_dir", t.TempDir())
+	defer func() { config.EmbeddingCacheEnabled = false }()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("token_group", "default")
+
+	request := &types.EmbeddingRequest{Model: "text-embedding-3-small", Input: []any{"a", "b", "a"}}
+	cache := relay_util.NewEmbeddingCache(c, request, request.Model)
+	assert.Equal(t, 0, cache.HitCount())
+	assert.Equal(t, []string{"a", "b"}, cache.MissInputs())
+
+	response, err := cache.Assemble(request.Model, &types.EmbeddingResponse{
+		Model: request.Model,
+		Data: []types.Embedding{
+			{Object: "embedding", Embedding: []float64{0.2}, Index: 1},
+			{Object: "embedding", Embedding: []float64{0.1}, Index: 0},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, response.Data, 3)
+	assert.Equal(t, "[0.1]", string(response.Data[2].Embedding.(json.RawMessage)))
+	assert.Equal(t, 2, response.Data[2].Index)
+
+	request = &types.EmbeddingRequest{Model: "text-embedding-3-small", Input: []any{"b", "c"}}
+	cache = relay_util.NewEmbeddingCache(c, request, request.Model)
+	assert.Equal(t, 1, cache.HitCount())
+	assert.Equal(t, []string{"c"}, cache.MissInputs())
+	assert.Equal(t, []any{"c"}, cache.MissRequest(request).Input)
+
+	// token 数组输入不缓存
+	assert.Nil(t, relay_util.NewEmbeddingCache(c, &types.EmbeddingRequest{Input: []any{1.0, 2.0}}, "m"))
+}
+
+func TestEmbeddingCacheDiskEviction(t *testing.T) {
+	maxSize := config.EmbeddingCacheMaxSize
+	config.EmbeddingCacheMaxSize = 1
+	defer func() { config.EmbeddingCacheMaxSize = maxSize }()
+
+	dir := t.TempDir()
+	disk := relay_util.NewEmbeddingCacheDisk(dir)
+	value := make([]byte, 600*1024)
+	assert.Nil(t, disk.Set("a", value, time.Hour))
+	assert.Nil(t, disk.Set("b", value, time.Hour))
+
+	values, _ := disk.MGet([]string{"a", "b"})
+	assert.Nil(t, values[0])
+	assert.NotNil(t, values[1])
+
+	// 重启后从目录重建索引
+	values, _ = relay_util.NewEmbeddingCacheDisk(dir).MGet([]string{"b"})
+	assert.Len(t, values[0], len(value))
+}
@@ -0,0 +1,268 @@
+package relay_util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const archiveRedacted = "[REDACTED]"
+
+// ArchivePayload 单次请求的归档内容
+type ArchivePayload struct {
+	Id                string `json:"id"`
+	CreatedAt         int64  `json:"created_at"`
+	UserId            int    `json:"user_id"`
+	TokenId           int    `json:"token_id"`
+	TokenName         string `json:"token_name"`
+	Group             string `json:"group"`
+	ChannelId         int    `json:"channel_id"`
+	ModelName         string `json:"model_name"`
+	Method            string `json:"method"`
+	Path              string `json:"path"`
+	StatusCode        int    `json:"status_code"`
+	Request           string `json:"request"`
+	RequestTruncated  bool   `json:"request_truncated"`
+	Response          string `json:"response"`
+	ResponseTruncated bool   `json:"response_truncated"`
+}
+
+// PayloadArchive 记录单次请求的归档状态，nil 表示该请求不归档
+type PayloadArchive struct {
+	id        string
+	c         *gin.Context
+	writer    *archiveWriter
+	createdAt int64
+}
+
+// archiveWriter 在写给客户端的同时保存响应内容
+type archiveWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *archiveWriter) capture(data []byte) {
+	if w.truncated {
+		return
+	}
+
+	if remain := w.limit - w.body.Len(); len(data) > remain {
+		data = data[:remain]
+		w.truncated = true
+	}
+	w.body.Write(data)
+}
+
+func (w *archiveWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *archiveWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// NewPayloadArchive 分组或令牌开启归档时接管响应输出，日志通过 archive_id 关联归档
+func NewPayloadArchive(c *gin.Context) *PayloadArchive {
+	if !isArchiveEnabled(c) {
+		return nil
+	}
+
+	archive := &PayloadArchive{
+		id:        utils.GetUUID(),
+		c:         c,
+		createdAt: utils.GetTimestamp(),
+		writer: &archiveWriter{
+			ResponseWriter: c.Writer,
+			limit:          archiveMaxBodySize(),
+		},
+	}
+	c.Writer = archive.writer
+	c.Set("archive_id", archive.id)
+
+	return archive
+}
+
+func isArchiveEnabled(c *gin.Context) bool {
+	if model.GlobalUserGroupRatio.IsArchiveEnabled(c.GetString("token_group")) {
+		return true
+	}
+
+	tokenSetting, ok := utils.GetGinValue[*model.TokenSetting](c, "token_setting")
+	return ok && tokenSetting != nil && tokenSetting.Archive.Enabled
+}
+
+func archiveMaxBodySize() int {
+	if config.ArchiveMaxBodySize <= 0 {
+		return 1024 * 1024
+	}
+
+	return config.ArchiveMaxBodySize * 1024
+}
+
+// Save 请求结束后脱敏并异步写入存储
+func (a *PayloadArchive) Save() {
+	if a == nil {
+		return
+	}
+
+	c := a.c
+	request, requestTruncated := getArchiveRequestBody(c)
+	payload := &ArchivePayload{
+		Id:                a.id,
+		CreatedAt:         a.createdAt,
+		UserId:            c.GetInt("id"),
+		TokenId:           c.GetInt("token_id"),
+		TokenName:         c.GetString("token_name"),
+		Group:             c.GetString("token_group"),
+		ChannelId:         c.GetInt("channel_id"),
+		ModelName:         c.GetString("original_model"),
+		Method:            c.Request.Method,
+		Path:              c.Request.URL.Path,
+		StatusCode:        a.writer.Status(),
+		Request:           request,
+		RequestTruncated:  requestTruncated,
+		Response:          a.writer.body.String(),
+		ResponseTruncated: a.writer.truncated,
+	}
+
+	go func() {
+		payload.Request = RedactPayload(payload.Request)
+		payload.Response = RedactPayload(payload.Response)
+
+		data, err := json.Marshal(payload)
+		if err != nil {
+			logger.SysError("failed to marshal payload archive: " + err.Error())
+			return
+		}
+
+		err = model.SavePayloadArchive(&model.PayloadArchive{
+			Id:         payload.Id,
+			UserId:     payload.UserId,
+			TokenId:    payload.TokenId,
+			ChannelId:  payload.ChannelId,
+			ModelName:  payload.ModelName,
+			Path:       payload.Path,
+			StatusCode: payload.StatusCode,
+		}, data)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("failed to save payload archive %s: %s", payload.Id, err.Error()))
+		}
+	}()
+}
+
+func getArchiveRequestBody(c *gin.Context) (string, bool) {
+	body, ok := utils.GetGinValue[[]byte](c, config.GinRequestBodyKey)
+	if !ok {
+		return "", false
+	}
+
+	// multipart 等非文本请求只记录类型
+	if !strings.Contains(c.ContentType(), "json") {
+		return fmt.Sprintf("[%s, %d bytes]", c.ContentType(), len(body)), false
+	}
+
+	if limit := archiveMaxBodySize(); len(body) > limit {
+		return string(body[:limit]), true
+	}
+
+	return string(body), false
+}
+
+var (
+	archivePatternsSource string
+	archivePatterns       []*regexp.Regexp
+	archivePatternsMu     sync.Mutex
+)
+
+// getArchivePatterns 编译脱敏正则，配置变化时重新编译
+func getArchivePatterns() []*regexp.Regexp {
+	archivePatternsMu.Lock()
+	defer archivePatternsMu.Unlock()
+
+	source := strings.Join(config.ArchiveRedactPatterns, "\n")
+	if archivePatterns != nil && source == archivePatternsSource {
+		return archivePatterns
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(config.ArchiveRedactPatterns))
+	for _, pattern := range config.ArchiveRedactPatterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("invalid archive redact pattern %s: %s", pattern, err.Error()))
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+
+	archivePatternsSource = source
+	archivePatterns = patterns
+
+	return patterns
+}
+
+func getArchiveRedactKeys() map[string]bool {
+	keys := make(map[string]bool)
+	for _, key := range strings.Split(config.ArchiveRedactKeys, ",") {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			keys[key] = true
+		}
+	}
+
+	return keys
+}
+
+// RedactPayload JSON 内容先按字段名脱敏，再对全部文本按正则脱敏
+func RedactPayload(content string) string {
+	if content == "" {
+		return content
+	}
+
+	var data any
+	if err := json.Unmarshal([]byte(content), &data); err == nil {
+		if redacted, err := json.Marshal(redactKeys(data, getArchiveRedactKeys())); err == nil {
+			content = string(redacted)
+		}
+	}
+
+	for _, re := range getArchivePatterns() {
+		content = re.ReplaceAllString(content, archiveRedacted)
+	}
+
+	return content
+}
+
+func redactKeys(data any, keys map[string]bool) any {
+	switch v := data.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if keys[strings.ToLower(key)] {
+				v[key] = archiveRedacted
+				continue
+			}
+			v[key] = redactKeys(value, keys)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactKeys(value, keys)
+		}
+	}
+
+	return data
+}
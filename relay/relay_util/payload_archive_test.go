@@ -0,0 +1,30 @@
+package relay_util_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"one-api/relay/relay_util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactPayloadKeys(t *testing.T) {
+	content := `{"model":"gpt-4o","api_key":"abc","metadata":{"Password":"123"},"messages":[{"role":"user","content":"hi"}]}`
+
+	var data map[string]any
+	assert.Nil(t, json.Unmarshal([]byte(relay_util.RedactPayload(content)), &data))
+	assert.Equal(t, "gpt-4o", data["model"])
+	assert.Equal(t, "[REDACTED]", data["api_key"])
+	assert.Equal(t, "[REDACTED]", data["metadata"].(map[string]any)["Password"])
+	assert.Equal(t, "hi", data["messages"].([]any)[0].(map[string]any)["content"])
+}
+
+func TestRedactPayloadPatterns(t *testing.T) {
+	content := "data: {\"content\":\"mail me at test@example.com or 13800138000\"}\n\n"
+
+	redacted := relay_util.RedactPayload(content)
+	assert.NotContains(t, redacted, "test@example.com")
+	assert.NotContains(t, redacted, "13800138000")
+	assert.Contains(t, redacted, "mail me at [REDACTED]")
+}
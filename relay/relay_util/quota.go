@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"one-api/common"
 	"one-api/common/config"
+	"one-api/common/limit"
 	"one-api/common/logger"
+	"one-api/common/utils"
 	"one-api/model"
 	"one-api/types"
 	"time"
@@ -36,6 +38,61 @@ type Quota struct {
 	startTime         time.Time
 	firstResponseTime time.Time
 	extraBillingData  map[string]ExtraBillingData
+
+	chatCacheHit       bool
+	chatCacheChannelId int
+
+	hedge *HedgeInfo
+
+	budget            *model.BudgetSetting
+	budgetReservation *model.TokenBudgetReservation
+	recordTokenRate   bool
+
+	archiveId string
+	batchId   string
+	// 命中向量缓存的输入数，这部分不计费
+	embeddingCacheHits int
+
+	// 注入的提示词模板，格式为 名称@版本
+	promptTemplates []string
+	// 触发模型回退时原本请求的模型
+	fallbackFrom string
+	contextTrim  *ContextTrimInfo
+
+	// 实时会话开始后查询的令牌剩余额度
+	tokenRemainQuota *int
+	realtime         *RealtimeSessionInfo
+
+	// 按次或按秒计费时的计费单位数，如视频的条数或总秒数，0 视为 1
+	units int
+}
+
+type RealtimeSessionInfo struct {
+	SessionId   string
+	Duration    time.Duration
+	CloseReason string
+}
+
+var ErrRealtimeQuotaNotEnough = errors.New("user quota is not enough")
+
+type batchContextKey struct{}
+
+// WithBatch 标记请求由批处理发起，批处理请求按 BatchDiscount 计费
+// 只能通过请求的 context 设置，客户端无法伪造
+func WithBatch(ctx context.Context, batchId string) context.Context {
+	return context.WithValue(ctx, batchContextKey{}, batchId)
+}
+
+func getBatchId(c *gin.Context) string {
+	batchId, _ := c.Request.Context().Value(batchContextKey{}).(string)
+	return batchId
+}
+
+// HedgeInfo 对冲请求的结果，只有胜出的渠道会被计费
+type HedgeInfo struct {
+	PrimaryChannelId int
+	HedgeChannelId   int
+	HedgeWon         bool
 }
 
 func NewQuota(c *gin.Context, modelName string, promptTokens int) *Quota {
@@ -52,24 +109,67 @@ func NewQuota(c *gin.Context, modelName string, promptTokens int) *Quota {
 		isBackupGroup:  isBackupGroup, // 记录是否使用备用分组
 	}
 
-	quota.price = *model.PricingInstance.GetPrice(quota.modelName)
 	quota.groupName = c.GetString("token_group")
 	quota.backupGroupName = c.GetString("token_backup_group")
-	quota.groupRatio = c.GetFloat64("group_ratio") // 这里的倍率已经在 common.go 中正确设置了
-	quota.inputRatio = quota.price.GetInput() * quota.groupRatio
-	quota.outputRatio = quota.price.GetOutput() * quota.groupRatio
+	quota.archiveId = c.GetString("archive_id")
+	quota.batchId = getBatchId(c)
+	quota.embeddingCacheHits = c.GetInt("embedding_cache_hits")
+	quota.promptTemplates, _ = utils.GetGinValue[[]string](c, "prompt_templates")
+	quota.fallbackFrom = c.GetString("fallback_from")
+	quota.contextTrim, _ = utils.GetGinValue[*ContextTrimInfo](c, "context_trim")
+	quota.setPrice(c)
+
+	if tokenSetting, ok := utils.GetGinValue[*model.TokenSetting](c, "token_setting"); ok && tokenSetting != nil {
+		if tokenSetting.Limits.Budget.Enabled {
+			quota.budget = &tokenSetting.Limits.Budget
+		}
+		quota.recordTokenRate = tokenSetting.Limits.RateLimit.Enabled && tokenSetting.Limits.RateLimit.TPM > 0
+	}
 
 	return quota
 
 }
 
-func (q *Quota) PreQuotaConsumption() *types.OpenAIErrorWithStatusCode {
-	if q.price.Type == model.TimesPriceType {
-		q.preConsumedQuota = int(1000 * q.inputRatio)
+func (q *Quota) setPrice(c *gin.Context) {
+	q.price = *model.PricingInstance.GetPrice(q.modelName)
+	q.groupRatio = c.GetFloat64("group_ratio") // 这里的倍率已经在 common.go 中正确设置了
+	q.inputRatio = q.price.GetInput() * q.groupRatio
+	q.outputRatio = q.price.GetOutput() * q.groupRatio
+
+	if q.batchId != "" && config.BatchDiscount > 0 {
+		q.inputRatio *= config.BatchDiscount
+		q.outputRatio *= config.BatchDiscount
+	}
+}
+
+func (q *Quota) PreQuotaConsumption() (errWithCode *types.OpenAIErrorWithStatusCode) {
+	if q.price.IsUnitPrice() {
+		q.preConsumedQuota = q.GetUnitQuota()
 	} else if q.price.Input != 0 || q.price.Output != 0 {
 		q.preConsumedQuota = int(float64(q.promptTokens)*q.inputRatio) + config.PreConsumedQuota
 	}
 
+	if q.chatCacheHit {
+		q.preConsumedQuota = int(math.Ceil(float64(q.preConsumedQuota) * config.ChatCacheBillingRatio))
+	}
+
+	if q.budget != nil {
+		// 预算按预扣额度原子地占用，并发的请求不会同时通过检查
+		reservation, err := model.ReserveTokenBudget(q.tokenId, q.budget, q.preConsumedQuota)
+		if err != nil {
+			if !errors.Is(err, model.ErrTokenBudgetExceeded) {
+				return common.ErrorWrapper(err, "get_token_budget_failed", http.StatusInternalServerError)
+			}
+			return common.ErrorWrapperLocal(err, "insufficient_token_budget", http.StatusPaymentRequired)
+		}
+		q.budgetReservation = reservation
+		defer func() {
+			if errWithCode != nil {
+				q.budgetReservation.Release()
+			}
+		}()
+	}
+
 	if q.preConsumedQuota == 0 {
 		return nil
 	}
@@ -107,17 +207,16 @@ func (q *Quota) PreQuotaConsumption() *types.OpenAIErrorWithStatusCode {
 }
 
 // 更新用户实时配额
+// 按会话累计用量重新计算已用额度，与结算时的计算方式一致，音频等额外 token 按倍率计入
+// 用户所有进行中会话的额度超过余额，或本会话额度超过令牌剩余额度时返回错误
 func (q *Quota) UpdateUserRealtimeQuota(usage *types.UsageEvent, nowUsage *types.UsageEvent) error {
 	usage.Merge(nowUsage)
 
-	// 不开启Redis，则不更新实时配额
-	if !config.RedisEnabled {
+	increaseQuota := q.GetTotalQuotaByUsage(usage.ToChatUsage()) - q.cacheQuota
+	if increaseQuota <= 0 {
 		return nil
 	}
 
-	promptTokens, completionTokens := q.getComputeTokensByUsageEvent(nowUsage)
-	increaseQuota := q.GetTotalQuota(promptTokens, completionTokens, nil)
-
 	cacheQuota, err := model.CacheIncreaseUserRealtimeQuota(q.userId, increaseQuota)
 	if err != nil {
 		return errors.New("error update user realtime quota cache: " + err.Error())
@@ -130,7 +229,24 @@ func (q *Quota) UpdateUserRealtimeQuota(usage *types.UsageEvent, nowUsage *types
 	}
 
 	if cacheQuota >= int64(userQuota) {
-		return errors.New("user quota is not enough")
+		return ErrRealtimeQuotaNotEnough
+	}
+
+	if q.unlimitedQuota {
+		return nil
+	}
+
+	// 令牌剩余额度只在会话开始后查询一次
+	if q.tokenRemainQuota == nil {
+		token, err := model.GetTokenById(q.tokenId)
+		if err != nil {
+			return errors.New("error get token: " + err.Error())
+		}
+		q.tokenRemainQuota = &token.RemainQuota
+	}
+
+	if q.cacheQuota >= *q.tokenRemainQuota {
+		return ErrRealtimeQuotaNotEnough
 	}
 
 	return nil
@@ -145,6 +261,12 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 
 	quota := q.GetTotalQuotaByUsage(usage)
 
+	if q.recordTokenRate {
+		limit.RecordTokenUsage(q.tokenId, usage.PromptTokens+usage.CompletionTokens)
+	}
+
+	q.budgetReservation.Settle(quota)
+
 	if quota > 0 {
 		quotaDelta := quota - q.preConsumedQuota
 		err := model.PostConsumeTokenQuotaWithInfo(q.tokenId, q.userId, q.unlimitedQuota, quotaDelta)
@@ -179,6 +301,9 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 }
 
 func (q *Quota) Undo(c *gin.Context) {
+	if q.budgetReservation != nil {
+		go q.budgetReservation.Release()
+	}
 	if q.HandelStatus {
 		go func(ctx context.Context) {
 			// return pre-consumed quota
@@ -206,6 +331,16 @@ func (q *Quota) GetInputRatio() float64 {
 	return q.inputRatio
 }
 
+// SetUnits 设置按次或按秒计费的单位数，需要在预扣费之前调用
+func (q *Quota) SetUnits(units int) {
+	q.units = units
+}
+
+// GetUnitQuota 按次或按秒计费的额度，异步任务失败时按此退还
+func (q *Quota) GetUnitQuota() int {
+	return int(1000 * q.inputRatio * float64(max(q.units, 1)))
+}
+
 func (q *Quota) GetLogMeta(usage *types.Usage) map[string]any {
 	meta := map[string]any{
 		"group_name":        q.groupName,
@@ -236,6 +371,57 @@ func (q *Quota) GetLogMeta(usage *types.Usage) map[string]any {
 		meta["extra_billing"] = q.extraBillingData
 	}
 
+	if q.chatCacheHit {
+		meta["chat_cache"] = true
+		meta["chat_cache_ratio"] = config.ChatCacheBillingRatio
+		meta["chat_cache_channel_id"] = q.chatCacheChannelId
+	}
+
+	if q.hedge != nil {
+		meta["hedge"] = true
+		meta["hedge_primary_channel_id"] = q.hedge.PrimaryChannelId
+		meta["hedge_channel_id"] = q.hedge.HedgeChannelId
+		meta["hedge_won"] = q.hedge.HedgeWon
+	}
+
+	if q.archiveId != "" {
+		meta["archive_id"] = q.archiveId
+	}
+
+	if q.batchId != "" {
+		meta["batch_id"] = q.batchId
+		meta["batch_discount"] = config.BatchDiscount
+	}
+
+	if q.embeddingCacheHits > 0 {
+		meta["embedding_cache_hits"] = q.embeddingCacheHits
+	}
+
+	if len(q.promptTemplates) > 0 {
+		meta["prompt_templates"] = q.promptTemplates
+	}
+
+	if q.fallbackFrom != "" {
+		meta["fallback_from"] = q.fallbackFrom
+		meta["fallback_model"] = q.modelName
+	}
+
+	if q.contextTrim != nil {
+		meta["context_trim_messages"] = q.contextTrim.DroppedMessages
+		meta["context_trim_tokens"] = q.contextTrim.DroppedTokens
+		meta["context_summarized"] = q.contextTrim.Summarized
+	}
+
+	if q.units > 1 {
+		meta["billing_units"] = q.units
+	}
+
+	if q.realtime != nil {
+		meta["realtime_session_id"] = q.realtime.SessionId
+		meta["realtime_duration"] = int(q.realtime.Duration.Seconds())
+		meta["realtime_close_reason"] = q.realtime.CloseReason
+	}
+
 	return meta
 }
 
@@ -245,8 +431,8 @@ func (q *Quota) getRequestTime() int {
 
 // 通过 token 数获取消费配额
 func (q *Quota) GetTotalQuota(promptTokens, completionTokens int, extraBilling map[string]types.ExtraBilling) (quota int) {
-	if q.price.Type == model.TimesPriceType {
-		quota = int(1000 * q.inputRatio)
+	if q.price.IsUnitPrice() {
+		quota = q.GetUnitQuota()
 	} else {
 		quota = int(math.Ceil((float64(promptTokens) * q.inputRatio) + (float64(completionTokens) * q.outputRatio)))
 	}
@@ -277,6 +463,10 @@ func (q *Quota) GetTotalQuota(promptTokens, completionTokens int, extraBilling m
 		quota = 0
 	}
 
+	if q.chatCacheHit {
+		quota = int(math.Ceil(float64(quota) * config.ChatCacheBillingRatio))
+	}
+
 	return quota
 }
 
@@ -299,23 +489,6 @@ func (q *Quota) getComputeTokensByUsage(usage *types.Usage) (promptTokens, compl
 	return
 }
 
-func (q *Quota) getComputeTokensByUsageEvent(usage *types.UsageEvent) (promptTokens, completionTokens int) {
-	promptTokens = usage.InputTokens
-	completionTokens = usage.OutputTokens
-	extraTokens := usage.GetExtraTokens()
-
-	for key, value := range extraTokens {
-		extraRatio := q.price.GetExtraRatio(key)
-		if model.GetExtraPriceIsPrompt(key) {
-			promptTokens += model.GetIncreaseTokens(value, extraRatio)
-		} else {
-			completionTokens += model.GetIncreaseTokens(value, extraRatio)
-		}
-	}
-
-	return
-}
-
 // 通过 usage 获取消费配额
 func (q *Quota) GetTotalQuotaByUsage(usage *types.Usage) (quota int) {
 	promptTokens, completionTokens := q.getComputeTokensByUsage(usage)
@@ -335,6 +508,31 @@ func (q *Quota) SetFirstResponseTime(firstResponseTime time.Time) {
 	q.firstResponseTime = firstResponseTime
 }
 
+// SetChatCacheHit 标记本次请求命中对话缓存，按 ChatCacheBillingRatio 计费
+func (q *Quota) SetChatCacheHit(channelId int) {
+	q.chatCacheHit = true
+	q.chatCacheChannelId = channelId
+}
+
+// SetHedge 标记本次请求触发了对冲，对冲渠道胜出时按胜出渠道重新计算价格
+// 预扣的额度不变，结算时按差额补扣或退还
+func (q *Quota) SetHedge(c *gin.Context, modelName string, hedge *HedgeInfo) {
+	q.hedge = hedge
+	if !hedge.HedgeWon {
+		return
+	}
+
+	q.modelName = modelName
+	q.channelId = c.GetInt("channel_id")
+	q.isBackupGroup = c.GetBool("is_backupGroup")
+	q.setPrice(c)
+}
+
+// SetRealtimeSession 记录实时会话的汇总信息，写入消费日志
+func (q *Quota) SetRealtimeSession(info *RealtimeSessionInfo) {
+	q.realtime = info
+}
+
 type ExtraBillingData struct {
 	Type      string  `json:"type"`
 	CallCount int     `json:"call_count"`
@@ -59,18 +59,29 @@ func (converter *OpenAIResponsesStreamConverter) initializeResponse(request *typ
 				Type: "text",
 			},
 		},
-		MaxOutputTokens:   request.MaxOutputTokens,
-		ParallelToolCalls: request.ParallelToolCalls,
-		Temperature:       request.Temperature,
-		ToolChoice:        request.ToolChoice,
-		TopP:              request.TopP,
-		Truncation:        request.Truncation,
-		Tools:             request.Tools,
-		Output:            make([]types.ResponsesOutput, 0),
-		Status:            "in_progress",
+		MaxOutputTokens:    request.MaxOutputTokens,
+		ParallelToolCalls:  request.ParallelToolCalls,
+		Temperature:        request.Temperature,
+		ToolChoice:         request.ToolChoice,
+		TopP:               request.TopP,
+		Truncation:         request.Truncation,
+		Tools:              request.Tools,
+		PreviousResponseID: request.PreviousResponseID,
+		Output:             make([]types.ResponsesOutput, 0),
+		Status:             "in_progress",
 	}
 }
 
+// SetResponseID 使用网关生成的响应 ID，不使用上游 chat 的 ID
+func (converter *OpenAIResponsesStreamConverter) SetResponseID(id string) {
+	converter.responses.ID = id
+}
+
+// GetResponse 流结束后获取完整的响应
+func (converter *OpenAIResponsesStreamConverter) GetResponse() *types.OpenAIResponsesResponses {
+	return converter.responses
+}
+
 func (converter *OpenAIResponsesStreamConverter) ProcessStreamData(jsonStr string) {
 	if jsonStr == "[DONE]" {
 		converter.finalizeStream()
@@ -85,7 +96,9 @@ func (converter *OpenAIResponsesStreamConverter) ProcessStreamData(jsonStr strin
 
 	// 第一次响应创建response.created
 	if converter.isFirstResponse {
-		converter.responses.ID = response.ID
+		if converter.responses.ID == "" {
+			converter.responses.ID = response.ID
+		}
 		converter.responses.CreatedAt = response.Created
 		converter.responses.Model = response.Model
 		converter.sendStreamResponse("response.created", converter.populateResponseData)
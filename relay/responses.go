@@ -7,6 +7,7 @@ import (
 	"one-api/common"
 	"one-api/common/logger"
 	"one-api/common/requester"
+	"one-api/common/utils"
 	providersBase "one-api/providers/base"
 	"one-api/relay/relay_util"
 	"one-api/types"
@@ -19,6 +20,10 @@ import (
 type relayResponses struct {
 	relayBase
 	responsesRequest types.OpenAIResponsesRequest
+	// 本次请求的输入，不包含还原的历史对话
+	currentInput any
+	// previous_response_id 是否已经由网关还原
+	previousResolved bool
 }
 
 func NewRelayResponses(c *gin.Context) *relayResponses {
@@ -34,7 +39,7 @@ func (r *relayResponses) setRequest() error {
 
 	r.setOriginalModel(r.responsesRequest.Model)
 
-	return nil
+	return r.loadPreviousResponse()
 }
 
 func (r *relayResponses) getRequest() interface{} {
@@ -66,6 +71,15 @@ func (r *relayResponses) send() (err *types.OpenAIErrorWithStatusCode, done bool
 		return r.compatibleSend(chatProvider)
 	}
 
+	// 已经在网关还原了历史对话，上游没有这个响应
+	previousResponseID := r.responsesRequest.PreviousResponseID
+	if r.previousResolved {
+		r.responsesRequest.PreviousResponseID = ""
+		defer func() {
+			r.responsesRequest.PreviousResponseID = previousResponseID
+		}()
+	}
+
 	if r.responsesRequest.Stream {
 		var response requester.StreamReaderInterface[string]
 		response, err = responsesProvider.CreateResponsesStream(&r.responsesRequest)
@@ -77,7 +91,7 @@ func (r *relayResponses) send() (err *types.OpenAIErrorWithStatusCode, done bool
 			return ""
 		}
 
-		firstResponseTime := responseGeneralStreamClient(r.c, response, doneStr)
+		firstResponseTime := responseGeneralStreamClient(r.c, response, nil, doneStr)
 		r.SetFirstResponseTime(firstResponseTime)
 	} else {
 		var response *types.OpenAIResponsesResponses
@@ -85,6 +99,9 @@ func (r *relayResponses) send() (err *types.OpenAIErrorWithStatusCode, done bool
 		if err != nil {
 			return
 		}
+		if r.previousResolved {
+			response.PreviousResponseID = previousResponseID
+		}
 		openErr := responseJsonClient(r.c, response)
 
 		if openErr != nil {
@@ -100,6 +117,12 @@ func (r *relayResponses) send() (err *types.OpenAIErrorWithStatusCode, done bool
 }
 
 func (r *relayResponses) compatibleSend(chatProvider providersBase.ChatInterface) (errWithCode *types.OpenAIErrorWithStatusCode, done bool) {
+	if r.responsesRequest.PreviousResponseID != "" && !r.previousResolved {
+		return common.StringErrorWrapperLocal("previous response "+r.responsesRequest.PreviousResponseID+" not found", "invalid_request_error", http.StatusNotFound), true
+	}
+
+	responseID := "resp_" + utils.GetUUID()
+
 	chatReq, err := r.responsesRequest.ToChatCompletionRequest()
 	if err != nil {
 		return common.ErrorWrapperLocal(err, "invalid_claude_config", http.StatusInternalServerError), true
@@ -111,7 +134,7 @@ func (r *relayResponses) compatibleSend(chatProvider providersBase.ChatInterface
 		if errWithCode != nil {
 			return
 		}
-		firstResponseTime := r.chatToResponseStreamClient(response)
+		firstResponseTime := r.chatToResponseStreamClient(response, responseID)
 		r.SetFirstResponseTime(firstResponseTime)
 	} else {
 		var response *types.ChatCompletionResponse
@@ -121,7 +144,9 @@ func (r *relayResponses) compatibleSend(chatProvider providersBase.ChatInterface
 		}
 
 		responseResp := response.ToResponses(&r.responsesRequest)
+		responseResp.ID = responseID
 		responseJsonClient(r.c, responseResp)
+		r.saveResponse(responseResp)
 	}
 
 	if errWithCode != nil {
@@ -132,7 +157,7 @@ func (r *relayResponses) compatibleSend(chatProvider providersBase.ChatInterface
 }
 
 // 将chat转换成兼容的responses流处理
-func (r *relayResponses) chatToResponseStreamClient(stream requester.StreamReaderInterface[string]) (firstResponseTime time.Time) {
+func (r *relayResponses) chatToResponseStreamClient(stream requester.StreamReaderInterface[string], responseID string) (firstResponseTime time.Time) {
 	requester.SetEventStreamHeaders(r.c)
 	dataChan, errChan := stream.Recv()
 
@@ -143,6 +168,7 @@ func (r *relayResponses) chatToResponseStreamClient(stream requester.StreamReade
 	var isFirstResponse bool
 
 	converter := relay_util.NewOpenAIResponsesStreamConverter(r.c, &r.responsesRequest, r.provider.GetUsage())
+	converter.SetResponseID(responseID)
 
 	// 在新的goroutine中处理stream数据
 	gopool.Go(func() {
@@ -192,5 +218,7 @@ func (r *relayResponses) chatToResponseStreamClient(stream requester.StreamReade
 
 	// 等待处理完成
 	<-done
+	r.saveResponse(converter.GetResponse())
+
 	return firstResponseTime
 }
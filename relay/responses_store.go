@@ -0,0 +1,236 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultInputItemsLimit = 20
+	maxInputItemsLimit     = 100
+)
+
+type responseInputItemList struct {
+	Object  string                 `json:"object"`
+	Data    []types.InputResponses `json:"data"`
+	FirstId string                 `json:"first_id,omitempty"`
+	LastId  string                 `json:"last_id,omitempty"`
+	HasMore bool                   `json:"has_more"`
+}
+
+// loadPreviousResponse 从网关保存的响应还原 previous_response_id 对应的对话
+// 网关没有保存时保留 previous_response_id，交给原生支持的上游处理
+func (r *relayResponses) loadPreviousResponse() error {
+	r.currentInput = r.responsesRequest.Input
+	if r.responsesRequest.PreviousResponseID == "" {
+		return nil
+	}
+
+	history, err := getResponseHistory(r.c.GetInt("id"), r.responsesRequest.PreviousResponseID)
+	if errors.Is(err, model.ErrStoredResponseNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	inputs, err := r.responsesRequest.ParseInput()
+	if err != nil {
+		return err
+	}
+
+	r.responsesRequest.Input = append(history, inputs...)
+	r.previousResolved = true
+
+	return nil
+}
+
+// getResponseHistory 按时间顺序返回历史响应的输入项和输出项
+func getResponseHistory(userId int, responseId string) ([]types.InputResponses, error) {
+	chain := make([]*model.StoredResponse, 0)
+	for id := responseId; id != ""; {
+		// 超过追溯上限时丢弃更早的对话
+		if config.ResponsesMaxHistory > 0 && len(chain) >= config.ResponsesMaxHistory {
+			break
+		}
+
+		stored, err := model.GetStoredResponse(userId, id)
+		if err != nil {
+			if len(chain) > 0 && errors.Is(err, model.ErrStoredResponseNotFound) {
+				return nil, fmt.Errorf("previous response %s not found", id)
+			}
+			return nil, err
+		}
+
+		chain = append(chain, stored)
+		id = stored.PreviousResponseId
+	}
+
+	items := make([]types.InputResponses, 0)
+	for i := len(chain) - 1; i >= 0; i-- {
+		var inputs []types.InputResponses
+		if err := json.Unmarshal(chain[i].Input, &inputs); err != nil {
+			return nil, err
+		}
+
+		var response types.OpenAIResponsesResponses
+		if err := json.Unmarshal(chain[i].Response, &response); err != nil {
+			return nil, err
+		}
+
+		for _, output := range response.Output {
+			if input, ok := output.ToInput(); ok {
+				inputs = append(inputs, input)
+			}
+		}
+
+		// 网关生成的 ID 上游不认识，不发送
+		for _, input := range inputs {
+			input.ID = ""
+			items = append(items, input)
+		}
+	}
+
+	return items, nil
+}
+
+func (r *relayResponses) shouldStore() bool {
+	return r.responsesRequest.Store == nil || *r.responsesRequest.Store
+}
+
+// saveResponse 保存兼容模式下生成的响应，失败时只记录日志
+func (r *relayResponses) saveResponse(response *types.OpenAIResponsesResponses) {
+	if !r.shouldStore() || response == nil || response.ID == "" {
+		return
+	}
+	if response.Status != types.ResponseStatusCompleted && response.Status != types.ResponseStatusIncomplete {
+		return
+	}
+
+	current := &types.OpenAIResponsesRequest{Input: r.currentInput}
+	inputs, err := current.ParseInput()
+	if err != nil {
+		logger.LogError(r.c.Request.Context(), "failed to parse responses input: "+err.Error())
+		return
+	}
+	for i := range inputs {
+		if inputs[i].ID == "" {
+			inputs[i].ID = fmt.Sprintf("msg_%s", utils.GetRandomString(48))
+		}
+	}
+
+	inputData, _ := json.Marshal(inputs)
+	responseData, _ := json.Marshal(response)
+
+	stored := &model.StoredResponse{
+		Id:                 response.ID,
+		UserId:             r.c.GetInt("id"),
+		TokenId:            r.c.GetInt("token_id"),
+		PreviousResponseId: r.responsesRequest.PreviousResponseID,
+		Model:              r.getOriginalModel(),
+		Input:              inputData,
+		Response:           responseData,
+	}
+	if err := stored.Insert(); err != nil {
+		logger.LogError(r.c.Request.Context(), "failed to save response: "+err.Error())
+	}
+}
+
+func abortStoredResponse(c *gin.Context, err error) {
+	if errors.Is(err, model.ErrStoredResponseNotFound) {
+		common.AbortWithMessage(c, http.StatusNotFound, err.Error())
+		return
+	}
+	common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+}
+
+// RetrieveResponse 获取网关保存的响应，上游原生保存的响应不在这里
+func RetrieveResponse(c *gin.Context) {
+	stored, err := model.GetStoredResponse(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		abortStoredResponse(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", stored.Response)
+}
+
+func DeleteResponse(c *gin.Context) {
+	id := c.Param("id")
+	if err := model.DeleteStoredResponse(c.GetInt("id"), id); err != nil {
+		abortStoredResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":      id,
+		"object":  "response",
+		"deleted": true,
+	})
+}
+
+// ListResponseInputItems 列出响应本次请求的输入项，支持 limit、order 和 after
+func ListResponseInputItems(c *gin.Context) {
+	stored, err := model.GetStoredResponse(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		abortStoredResponse(c, err)
+		return
+	}
+
+	var items []types.InputResponses
+	if err := json.Unmarshal(stored.Input, &items); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if items == nil {
+		items = make([]types.InputResponses, 0)
+	}
+
+	// 默认倒序
+	if c.DefaultQuery("order", "desc") == "desc" {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if after := c.Query("after"); after != "" {
+		for i, item := range items {
+			if item.ID == after {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+
+	limit := utils.String2Int(c.Query("limit"))
+	if limit <= 0 {
+		limit = defaultInputItemsLimit
+	} else if limit > maxInputItemsLimit {
+		limit = maxInputItemsLimit
+	}
+
+	list := &responseInputItemList{
+		Object: "list",
+		Data:   items,
+	}
+	if len(items) > limit {
+		list.Data = items[:limit]
+		list.HasMore = true
+	}
+	if len(list.Data) > 0 {
+		list.FirstId = list.Data[0].ID
+		list.LastId = list.Data[len(list.Data)-1].ID
+	}
+
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,179 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"one-api/common/config"
+	"one-api/model"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storeResponse 保存一轮对话，输入为 input，输出为 output
+func storeResponse(t *testing.T, userId int, id, previousId string, input []types.InputResponses, output string) {
+	inputData, err := json.Marshal(input)
+	require.NoError(t, err)
+	responseData, err := json.Marshal(&types.OpenAIResponsesResponses{
+		ID: id,
+		Output: []types.ResponsesOutput{
+			{Type: types.InputTypeMessage, ID: "msg_" + id, Status: "completed", Role: types.ChatMessageRoleAssistant, Content: output},
+		},
+	})
+	require.NoError(t, err)
+
+	stored := &model.StoredResponse{
+		Id:                 id,
+		UserId:             userId,
+		PreviousResponseId: previousId,
+		Input:              inputData,
+		Response:           responseData,
+	}
+	require.NoError(t, stored.Insert())
+}
+
+func userInput(id, content string) types.InputResponses {
+	return types.InputResponses{Type: types.InputTypeMessage, ID: id, Role: types.ChatMessageRoleUser, Content: content}
+}
+
+func setupResponsesStoreTest(t *testing.T) {
+	setupTestDB(t)
+	require.NoError(t, model.DB.Where("1 = 1").Delete(&model.StoredResponse{}).Error)
+
+	originalMaxHistory := config.ResponsesMaxHistory
+	t.Cleanup(func() { config.ResponsesMaxHistory = originalMaxHistory })
+
+	// resp_1 <- resp_2 <- resp_3
+	storeResponse(t, 1, "resp_1", "", []types.InputResponses{userInput("in_1", "q1")}, "a1")
+	storeResponse(t, 1, "resp_2", "resp_1", []types.InputResponses{userInput("in_2", "q2")}, "a2")
+	storeResponse(t, 1, "resp_3", "resp_2", []types.InputResponses{userInput("in_3", "q3")}, "a3")
+}
+
+func historyContents(items []types.InputResponses) []string {
+	contents := make([]string, 0, len(items))
+	for _, item := range items {
+		contents = append(contents, fmt.Sprintf("%s:%v", item.Role, item.Content))
+	}
+	return contents
+}
+
+func TestGetResponseHistory(t *testing.T) {
+	setupResponsesStoreTest(t)
+
+	history, err := getResponseHistory(1, "resp_3")
+	require.NoError(t, err)
+	// 按时间顺序还原，每轮输入在前输出在后
+	assert.Equal(t, []string{"user:q1", "assistant:a1", "user:q2", "assistant:a2", "user:q3", "assistant:a3"}, historyContents(history))
+	// 网关生成的 ID 不发送给上游
+	for _, item := range history {
+		assert.Empty(t, item.ID)
+	}
+
+	// 超过追溯上限时丢弃更早的对话
+	config.ResponsesMaxHistory = 2
+	history, err = getResponseHistory(1, "resp_3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:q2", "assistant:a2", "user:q3", "assistant:a3"}, historyContents(history))
+
+	// 其他用户的响应不可追溯
+	_, err = getResponseHistory(2, "resp_3")
+	assert.ErrorIs(t, err, model.ErrStoredResponseNotFound)
+}
+
+func TestGetResponseHistoryMissingLink(t *testing.T) {
+	setupResponsesStoreTest(t)
+
+	require.NoError(t, model.DeleteStoredResponse(1, "resp_2"))
+
+	// 链路中间的响应缺失时返回错误，不能当作网关没有保存交给上游
+	_, err := getResponseHistory(1, "resp_3")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, model.ErrStoredResponseNotFound)
+	assert.Contains(t, err.Error(), "resp_2")
+}
+
+func requestStoredResponse(handler gin.HandlerFunc, userId int, id, query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/responses/"+id+"/input_items?"+query, nil)
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	c.Set("id", userId)
+
+	handler(c)
+	return recorder
+}
+
+func TestStoredResponseHandlersUserScope(t *testing.T) {
+	setupResponsesStoreTest(t)
+
+	recorder := requestStoredResponse(RetrieveResponse, 1, "resp_1", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"id":"resp_1"`)
+
+	assert.Equal(t, http.StatusNotFound, requestStoredResponse(RetrieveResponse, 2, "resp_1", "").Code)
+	assert.Equal(t, http.StatusNotFound, requestStoredResponse(ListResponseInputItems, 2, "resp_1", "").Code)
+	assert.Equal(t, http.StatusNotFound, requestStoredResponse(DeleteResponse, 2, "resp_1", "").Code)
+
+	// 其他用户删除失败后响应仍然存在
+	assert.Equal(t, http.StatusOK, requestStoredResponse(RetrieveResponse, 1, "resp_1", "").Code)
+	assert.Equal(t, http.StatusOK, requestStoredResponse(DeleteResponse, 1, "resp_1", "").Code)
+	assert.Equal(t, http.StatusNotFound, requestStoredResponse(RetrieveResponse, 1, "resp_1", "").Code)
+}
+
+func TestListResponseInputItems(t *testing.T) {
+	setupResponsesStoreTest(t)
+
+	inputs := make([]types.InputResponses, 0)
+	for i := 1; i <= 5; i++ {
+		inputs = append(inputs, userInput(fmt.Sprintf("item_%d", i), fmt.Sprintf("q%d", i)))
+	}
+	storeResponse(t, 1, "resp_items", "", inputs, "a")
+
+	tests := []struct {
+		name    string
+		query   string
+		ids     []string
+		hasMore bool
+	}{
+		{name: "default desc", query: "", ids: []string{"item_5", "item_4", "item_3", "item_2", "item_1"}},
+		{name: "asc", query: "order=asc", ids: []string{"item_1", "item_2", "item_3", "item_4", "item_5"}},
+		{name: "limit", query: "limit=2", ids: []string{"item_5", "item_4"}, hasMore: true},
+		{name: "after desc", query: "after=item_4&limit=2", ids: []string{"item_3", "item_2"}, hasMore: true},
+		{name: "after asc", query: "order=asc&after=item_3", ids: []string{"item_4", "item_5"}},
+		{name: "after last", query: "order=asc&after=item_5", ids: []string{}},
+		{name: "unknown after", query: "order=asc&after=item_x&limit=5", ids: []string{"item_1", "item_2", "item_3", "item_4", "item_5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := requestStoredResponse(ListResponseInputItems, 1, "resp_items", tt.query)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			var list responseInputItemList
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &list))
+			assert.Equal(t, "list", list.Object)
+			assert.Equal(t, tt.hasMore, list.HasMore)
+
+			ids := make([]string, 0, len(list.Data))
+			for _, item := range list.Data {
+				ids = append(ids, item.ID)
+			}
+			assert.Equal(t, tt.ids, ids)
+
+			if len(tt.ids) > 0 {
+				assert.Equal(t, tt.ids[0], list.FirstId)
+				assert.Equal(t, tt.ids[len(tt.ids)-1], list.LastId)
+			} else {
+				assert.Empty(t, list.FirstId)
+				assert.Empty(t, list.LastId)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package relay
+
+import (
+	"encoding/json"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/requester"
+	"one-api/safty"
+	"one-api/types"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 默认窗口大小
+const defaultStreamWindowSize = 200
+
+// 审查时带上上一个窗口末尾的字符，避免违规内容被窗口切断
+const streamWindowOverlap = 32
+
+// streamTextExtractor 从一个流式数据块中提取输出的文本
+type streamTextExtractor func(data string) string
+
+// streamModerator 将流式输出按窗口缓冲，窗口审查通过后才发送给客户端
+// 发现违规时丢弃未发送的数据，输出错误事件并终止流，只按已发送的内容计费
+type streamModerator struct {
+	c           *gin.Context
+	usage       *types.Usage
+	modelName   string
+	extract     streamTextExtractor
+	onViolation func(err *types.OpenAIErrorWithStatusCode)
+
+	windowSize int
+	pending    []string
+	window     strings.Builder
+	overlap    string
+	delivered  strings.Builder
+	violated   bool
+}
+
+// newStreamModerator 未开启输出审查时返回 nil，nil 的审查器直接放行所有数据
+func newStreamModerator(c *gin.Context, usage *types.Usage, modelName string, extract streamTextExtractor, onViolation func(err *types.OpenAIErrorWithStatusCode)) *streamModerator {
+	if !safty.OutputCheckEnabled(c) {
+		return nil
+	}
+
+	windowSize := config.SafeStreamWindowSize
+	if windowSize <= 0 {
+		windowSize = defaultStreamWindowSize
+	}
+
+	return &streamModerator{
+		c:           c,
+		usage:       usage,
+		modelName:   modelName,
+		extract:     extract,
+		onViolation: onViolation,
+		windowSize:  windowSize,
+	}
+}
+
+// Push 缓冲一个数据块，返回审查通过可以发送的数据块
+func (m *streamModerator) Push(data string) []string {
+	if m == nil {
+		return []string{data}
+	}
+
+	if m.violated {
+		return nil
+	}
+
+	m.pending = append(m.pending, data)
+	m.window.WriteString(m.extract(data))
+	if utf8.RuneCountInString(m.window.String()) < m.windowSize {
+		return nil
+	}
+
+	return m.flush()
+}
+
+// Finish 流结束时审查剩余的内容
+func (m *streamModerator) Finish() []string {
+	if m == nil || m.violated {
+		return nil
+	}
+
+	return m.flush()
+}
+
+func (m *streamModerator) Violated() bool {
+	return m != nil && m.violated
+}
+
+func (m *streamModerator) flush() []string {
+	text := m.window.String()
+	m.window.Reset()
+
+	if text != "" {
+		result := safty.CheckOutput(m.c, m.overlap+text)
+		if !result.IsSafe {
+			m.violated = true
+			m.pending = nil
+			m.onViolation(common.StringErrorWrapperLocal(result.Reason, result.Code, http.StatusBadRequest))
+			return nil
+		}
+
+		m.delivered.WriteString(text)
+		m.overlap = lastRunes(m.overlap+text, streamWindowOverlap)
+	}
+
+	released := m.pending
+	m.pending = nil
+
+	return released
+}
+
+// Settle 流被终止时，用量改为只计算已发送给客户端的内容
+// 需要在上游读取协程退出后调用，避免与其并发写入用量
+func (m *streamModerator) Settle() {
+	if !m.Violated() || m.usage == nil {
+		return
+	}
+
+	m.usage.TextBuilder.Reset()
+	m.usage.CompletionTokens = 0
+	if m.delivered.Len() > 0 {
+		m.usage.CompletionTokens = common.CountTokenText(m.delivered.String(), m.modelName)
+	}
+	m.usage.TotalTokens = m.usage.PromptTokens + m.usage.CompletionTokens
+}
+
+func lastRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+
+	return string(runes[len(runes)-n:])
+}
+
+// drainStream 关闭上游连接，并读完剩余数据直到读取协程退出
+// 关闭连接后读取协程会通过 errChan 返回错误，不能提前返回，否则 Settle 会与其并发写入用量
+func drainStream(stream requester.StreamReaderInterface[string], dataChan <-chan string, errChan <-chan error) {
+	stream.Close()
+
+	for {
+		select {
+		case _, ok := <-dataChan:
+			if !ok {
+				return
+			}
+		case <-errChan:
+			return
+		}
+	}
+}
+
+// 各响应格式中每个数据块的 SSE data 内容
+func eachSSEData(data string, fn func(payload []byte)) {
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "" || payload == "[DONE]" {
+			continue
+		}
+		fn([]byte(payload))
+	}
+}
+
+// extractOpenAIStreamText OpenAI 格式，data 为去掉 "data: " 前缀的 JSON
+func extractOpenAIStreamText(data string) string {
+	var chunk struct {
+		Choices []struct {
+			Text  string `json:"text"`
+			Delta struct {
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"`
+			} `json:"delta"`
+		} `json:"choices"`
+	}
+	if json.Unmarshal([]byte(data), &chunk) != nil {
+		return ""
+	}
+
+	var text strings.Builder
+	for _, choice := range chunk.Choices {
+		text.WriteString(choice.Delta.ReasoningContent)
+		text.WriteString(choice.Delta.Content)
+		text.WriteString(choice.Text)
+	}
+
+	return text.String()
+}
+
+// extractClaudeStreamText Claude 格式，只有 content_block_delta 事件包含输出文本
+func extractClaudeStreamText(data string) string {
+	var text strings.Builder
+	eachSSEData(data, func(payload []byte) {
+		var event struct {
+			Type  string `json:"type"`
+			Delta struct {
+				Text     string `json:"text"`
+				Thinking string `json:"thinking"`
+			} `json:"delta"`
+		}
+		if json.Unmarshal(payload, &event) != nil || event.Type != "content_block_delta" {
+			return
+		}
+		text.WriteString(event.Delta.Thinking)
+		text.WriteString(event.Delta.Text)
+	})
+
+	return text.String()
+}
+
+// extractGeminiStreamText Gemini 格式，文本位于 candidates[].content.parts[].text
+func extractGeminiStreamText(data string) string {
+	var text strings.Builder
+	eachSSEData(data, func(payload []byte) {
+		var chunk struct {
+			Candidates []struct {
+				Content struct {
+					Parts []struct {
+						Text string `json:"text"`
+					} `json:"parts"`
+				} `json:"content"`
+			} `json:"candidates"`
+		}
+		if json.Unmarshal(payload, &chunk) != nil {
+			return
+		}
+		for _, candidate := range chunk.Candidates {
+			for _, part := range candidate.Content.Parts {
+				text.WriteString(part.Text)
+			}
+		}
+	})
+
+	return text.String()
+}
@@ -0,0 +1,241 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"one-api/common/config"
+	"one-api/safty"
+	saftyTypes "one-api/safty/types"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeSaftyToolName = "StreamModerationFake"
+
+// fakeSaftyTool 内容包含 BADWORD 时判定为违规
+type fakeSaftyTool struct{}
+
+func (fakeSaftyTool) Name() string { return fakeSaftyToolName }
+
+func (fakeSaftyTool) Init() error { return nil }
+
+func (fakeSaftyTool) Check(data string) (saftyTypes.CheckResult, error) {
+	if strings.Contains(data, "BADWORD") {
+		return saftyTypes.CheckResult{IsSafe: false, RiskLevel: 1, Code: "content_violation", Reason: "violation"}, nil
+	}
+	return saftyTypes.CheckResult{IsSafe: true}, nil
+}
+
+// fakeModerationStream 逐个发送数据块，关闭后读取协程返回错误，结束时返回 io.EOF
+type fakeModerationStream struct {
+	chunks []string
+	closed chan struct{}
+	closes atomic.Int32
+	exited atomic.Bool
+}
+
+func newFakeModerationStream(chunks ...string) *fakeModerationStream {
+	return &fakeModerationStream{chunks: chunks, closed: make(chan struct{})}
+}
+
+func (s *fakeModerationStream) Recv() (<-chan string, <-chan error) {
+	dataChan := make(chan string)
+	errChan := make(chan error)
+
+	// 发送错误后读取协程不再访问任何数据
+	go func() {
+		for _, chunk := range s.chunks {
+			select {
+			case dataChan <- chunk:
+			case <-s.closed:
+				s.exited.Store(true)
+				errChan <- errors.New("stream closed")
+				return
+			}
+		}
+		s.exited.Store(true)
+		errChan <- io.EOF
+	}()
+
+	return dataChan, errChan
+}
+
+func (s *fakeModerationStream) Close() {
+	if s.closes.Add(1) == 1 {
+		close(s.closed)
+	}
+}
+
+func openAIChunk(text string) string {
+	chunk, _ := json.Marshal(map[string]any{
+		"choices": []map[string]any{{"delta": map[string]any{"content": text}}},
+	})
+	return string(chunk)
+}
+
+func setupStreamModerationTest(t *testing.T, moderate bool) (*gin.Context, *httptest.ResponseRecorder) {
+	setupTestDB(t)
+
+	safty.Tools[fakeSaftyToolName] = fakeSaftyTool{}
+	originalEnableSafe, originalCheckOutput, originalToolName := config.EnableSafe, config.SafeCheckOutput, config.SafeToolName
+	originalWindowSize, originalDisableEncoders := config.SafeStreamWindowSize, config.DisableTokenEncoders
+	t.Cleanup(func() {
+		config.EnableSafe, config.SafeCheckOutput, config.SafeToolName = originalEnableSafe, originalCheckOutput, originalToolName
+		config.SafeStreamWindowSize, config.DisableTokenEncoders = originalWindowSize, originalDisableEncoders
+		delete(safty.Tools, fakeSaftyToolName)
+	})
+	config.EnableSafe = moderate
+	config.SafeCheckOutput = moderate
+	config.SafeToolName = fakeSaftyToolName
+	config.SafeStreamWindowSize = 10
+	config.DisableTokenEncoders = true
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	return c, recorder
+}
+
+func TestResponseStreamClientModeration(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunks    []string
+		delivered []string
+		dropped   []string
+		violated  bool
+	}{
+		{
+			name:      "violation mid window drops pending chunks",
+			chunks:    []string{"hello", "world", "clean", "BADWORD", "later"},
+			delivered: []string{"hello", "world"},
+			dropped:   []string{"clean", "BADWORD", "later"},
+			violated:  true,
+		},
+		{
+			name:      "violation across window boundary caught by overlap",
+			chunks:    []string{"aaaaaaaBAD", "WORDbbbbbb", "cccccccccc"},
+			delivered: []string{"aaaaaaaBAD"},
+			dropped:   []string{"WORDbbbbbb", "cccccccccc"},
+			violated:  true,
+		},
+		{
+			name:      "violation in tail window on EOF",
+			chunks:    []string{"helloworld", "BADWORD"},
+			delivered: []string{"helloworld"},
+			dropped:   []string{"BADWORD"},
+			violated:  true,
+		},
+		{
+			name:      "clean tail window released on EOF",
+			chunks:    []string{"helloworld", "tail"},
+			delivered: []string{"helloworld", "tail"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, recorder := setupStreamModerationTest(t, true)
+			relay := &relayBase{c: c}
+
+			// 模拟上游读取时记录的全部输出
+			usage := &types.Usage{PromptTokens: 5}
+			usage.TextBuilder.WriteString(strings.Join(tt.chunks, ""))
+			usage.CompletionTokens = 100
+
+			chunks := make([]string, len(tt.chunks))
+			for i, text := range tt.chunks {
+				chunks[i] = openAIChunk(text)
+			}
+			stream := newFakeModerationStream(chunks...)
+
+			moderator := newStreamModerator(c, usage, "gpt-4o", extractOpenAIStreamText, relay.HandleStreamError)
+			require.NotNil(t, moderator)
+
+			_, err := responseStreamClient(c, stream, nil, moderator, func() string { return "" })
+			assert.Nil(t, err)
+
+			body := recorder.Body.String()
+			for _, text := range tt.delivered {
+				assert.Contains(t, body, openAIChunk(text))
+			}
+			for _, text := range tt.dropped {
+				assert.NotContains(t, body, openAIChunk(text))
+			}
+			// 读取协程退出后才返回
+			assert.True(t, stream.exited.Load())
+
+			if !tt.violated {
+				assert.False(t, moderator.Violated())
+				assert.Contains(t, body, "data: [DONE]")
+				assert.Equal(t, 100, usage.CompletionTokens)
+				return
+			}
+
+			assert.True(t, moderator.Violated())
+			assert.Contains(t, body, "content_violation")
+			assert.NotContains(t, body, "[DONE]")
+
+			// 只按已发送的内容计费
+			delivered := strings.Join(tt.delivered, "")
+			assert.Equal(t, int(float64(len(delivered))*0.38), usage.CompletionTokens)
+			assert.Equal(t, usage.PromptTokens+usage.CompletionTokens, usage.TotalTokens)
+			assert.Zero(t, usage.TextBuilder.Len())
+		})
+	}
+}
+
+func TestResponseGeneralStreamClientModeration(t *testing.T) {
+	c, recorder := setupStreamModerationTest(t, true)
+	relay := &relayBase{c: c}
+
+	claudeEvent := func(text string) string {
+		delta, _ := json.Marshal(map[string]any{"type": "content_block_delta", "delta": map[string]any{"type": "text_delta", "text": text}})
+		return "event: content_block_delta\ndata: " + string(delta) + "\n\n"
+	}
+
+	usage := &types.Usage{}
+	stream := newFakeModerationStream(claudeEvent("helloworld"), claudeEvent("BADWORD"), claudeEvent("later"))
+	moderator := newStreamModerator(c, usage, "claude-3-5-sonnet", extractClaudeStreamText, relay.HandleStreamError)
+
+	responseGeneralStreamClient(c, stream, moderator, func() string { return "event: message_stop\n\n" })
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, claudeEvent("helloworld"))
+	assert.NotContains(t, body, "BADWORD")
+	assert.NotContains(t, body, "message_stop")
+	assert.Contains(t, body, "content_violation")
+	assert.True(t, stream.exited.Load())
+	assert.Equal(t, 3, usage.CompletionTokens)
+}
+
+func TestStreamModeratorDisabled(t *testing.T) {
+	c, recorder := setupStreamModerationTest(t, false)
+
+	moderator := newStreamModerator(c, &types.Usage{}, "gpt-4o", extractOpenAIStreamText, func(*types.OpenAIErrorWithStatusCode) {})
+	require.Nil(t, moderator)
+
+	// nil 的审查器原样放行
+	assert.Equal(t, []string{"chunk"}, moderator.Push("chunk"))
+	assert.Nil(t, moderator.Finish())
+	assert.False(t, moderator.Violated())
+	moderator.Settle()
+
+	stream := newFakeModerationStream(openAIChunk("BADWORD"), openAIChunk("hello"))
+	_, err := responseStreamClient(c, stream, nil, moderator, func() string { return "" })
+	assert.Nil(t, err)
+
+	body := recorder.Body.String()
+	assert.Equal(t, "data: "+openAIChunk("BADWORD")+"\n\ndata: "+openAIChunk("hello")+"\n\ndata: [DONE]\n\n", body)
+}
@@ -21,6 +21,10 @@ type TaskBase struct {
 	OriginTaskID  string
 	BaseProvider  base.ProviderInterface
 	Response      any
+	// 客户端的回调地址，任务结束时由网关签名后回调
+	NotifyHook string
+	// 计费单位数，如视频的条数或总秒数，0 视为 1
+	BillingUnits int
 }
 
 type TaskInterface interface {
@@ -29,6 +33,7 @@ type TaskInterface interface {
 	HandleError(err *TaskError)
 	ShouldRetry(c *gin.Context, err *TaskError) bool
 	GetModelName() string
+	GetBillingUnits() int
 	GetTask() *model.Task
 	SetProvider() *TaskError
 	GetProvider() base.ProviderInterface
@@ -47,9 +52,24 @@ func (t *TaskBase) InitTask() {
 		SubmitTime: time.Now().Unix(),
 		Status:     model.TaskStatusNotStart,
 		Progress:   0,
+		NotifyHook: t.NotifyHook,
 	}
 }
 
+// SetNotifyHook 校验客户端的回调地址，只支持 http 和 https 的公网地址
+func (t *TaskBase) SetNotifyHook(hook string) error {
+	if hook == "" {
+		return nil
+	}
+
+	if err := ValidateNotifyHook(hook); err != nil {
+		return err
+	}
+
+	t.NotifyHook = hook
+	return nil
+}
+
 func (t *TaskBase) GetModelName() string {
 	billingOriginalModel := t.C.GetBool("billing_original_model")
 	if billingOriginalModel {
@@ -58,6 +78,10 @@ func (t *TaskBase) GetModelName() string {
 	return t.ModelName
 }
 
+func (t *TaskBase) GetBillingUnits() int {
+	return max(t.BillingUnits, 1)
+}
+
 func (t *TaskBase) GetTask() *model.Task {
 	return t.Task
 }
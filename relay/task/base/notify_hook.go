@@ -0,0 +1,133 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// 回调地址解析和连接时都会拒绝的内网地址，避免通过回调访问网关所在的内网
+var blockedNotifyHookNets = mustParseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10", // 运营商级 NAT
+	"192.0.0.0/24",
+	"198.18.0.0/15",
+)
+
+var errNotifyHookAddress = errors.New("notify_hook must resolve to a public address")
+
+// NotifyHookClient 投递回调专用的客户端，连接时再次校验解析出的地址，防止 DNS 重绑定，且不跟随重定向
+var NotifyHookClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext:         dialNotifyHook,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// IsPublicIP 回环、私有、链路本地、组播和未指定地址都不允许作为回调地址
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, ipNet := range blockedNotifyHookNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveNotifyHookIPs 解析主机名，任意一个地址不是公网地址时拒绝
+func resolveNotifyHookIPs(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return nil, errNotifyHookAddress
+		}
+		return []net.IP{ip}, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no address found for %s", host)
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return nil, errNotifyHookAddress
+		}
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+func dialNotifyHook(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := resolveNotifyHookIPs(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// ValidateNotifyHook 只支持 http 和 https，且主机必须解析为公网地址
+func ValidateNotifyHook(hook string) error {
+	if len(hook) > 500 {
+		return errors.New("notify_hook is too long")
+	}
+
+	u, err := url.Parse(hook)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("invalid notify_hook")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := resolveNotifyHookIPs(ctx, u.Hostname()); err != nil {
+		if errors.Is(err, errNotifyHookAddress) {
+			return err
+		}
+		return errors.New("notify_hook host cannot be resolved")
+	}
+
+	return nil
+}
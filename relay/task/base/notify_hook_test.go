@@ -0,0 +1,40 @@
+package base_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"one-api/relay/task/base"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "0.0.0.0", "100.64.0.1", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1"} {
+		assert.False(t, base.IsPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		assert.True(t, base.IsPublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestValidateNotifyHook(t *testing.T) {
+	assert.Error(t, base.ValidateNotifyHook("ftp://8.8.8.8/hook"))
+	assert.Error(t, base.ValidateNotifyHook("http://127.0.0.1:3000/hook"))
+	assert.Error(t, base.ValidateNotifyHook("http://169.254.169.254/latest/meta-data"))
+	assert.Error(t, base.ValidateNotifyHook("http://[::1]/hook"))
+	assert.Error(t, base.ValidateNotifyHook("http://localhost/hook"))
+	assert.NoError(t, base.ValidateNotifyHook("https://8.8.8.8/hook"))
+}
+
+func TestNotifyHookClientRejectsPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	_, err := base.NotifyHookClient.Post(server.URL, "application/json", nil)
+	assert.Error(t, err)
+}
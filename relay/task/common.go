@@ -7,6 +7,7 @@ import (
 	"one-api/relay/task/base"
 	"one-api/relay/task/kling"
 	"one-api/relay/task/suno"
+	"one-api/relay/task/video"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,6 +22,10 @@ func GetTaskAdaptor(relayType int, c *gin.Context) (base.TaskInterface, error) {
 		return &kling.KlingTask{
 			TaskBase: getTaskBase(c, model.TaskPlatformKling),
 		}, nil
+	case config.RelayModeVideo:
+		return &video.VideoTask{
+			TaskBase: getTaskBase(c, model.TaskPlatformVideo),
+		}, nil
 	default:
 		return nil, errors.New("adaptor not found")
 	}
@@ -34,6 +39,8 @@ func GetTaskAdaptorByPlatform(platform string) (base.TaskInterface, error) {
 		relayType = config.RelayModeSuno
 	case model.TaskPlatformKling:
 		relayType = config.RelayModeKling
+	case model.TaskPlatformVideo:
+		relayType = config.RelayModeVideo
 	}
 
 	return GetTaskAdaptor(relayType, nil)
@@ -43,6 +43,14 @@ func (t *KlingTask) Init() *base.TaskError {
 		return base.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
 	}
 
+	// 回调由网关发送，不转发给上游
+	if callbackURL, ok := t.Request.CallbackURL.(string); ok {
+		if err = t.SetNotifyHook(callbackURL); err != nil {
+			return base.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
+		}
+	}
+	t.Request.CallbackURL = nil
+
 	err = t.HandleOriginTaskID()
 	if err != nil {
 		return base.StringTaskError(http.StatusInternalServerError, "get_origin_task_failed", err.Error(), true)
@@ -204,14 +212,8 @@ func updateKlingTaskAll(ctx context.Context, channelId int, taskIds []string, ta
 		if responseItem.FailReason != "" || task.Status == model.TaskStatusFailure {
 			logger.LogError(ctx, task.TaskID+" 构建失败，"+task.FailReason)
 			task.Progress = 100
-			quota := task.Quota
-			if quota > 0 {
-				err := model.IncreaseUserQuota(task.UserId, quota)
-				if err != nil {
-					logger.LogError(ctx, "fail to increase user quota: "+err.Error())
-				}
-				logContent := fmt.Sprintf("异步任务执行失败 %s，补偿 %s", task.TaskID, common.LogQuota(quota))
-				model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
+			if _, err := task.RefundQuota(model.TaskRefundReasonFailure); err != nil {
+				logger.LogError(ctx, "fail to refund task quota: "+err.Error())
 			}
 		}
 
@@ -9,6 +9,7 @@ import (
 	"one-api/model"
 	"one-api/relay/relay_util"
 	"one-api/relay/task/base"
+	"one-api/relay/task/video"
 	"one-api/types"
 	"strings"
 
@@ -37,6 +38,7 @@ func RelayTaskSubmit(c *gin.Context) {
 	}
 
 	quotaInstance := relay_util.NewQuota(c, taskAdaptor.GetModelName(), 1000)
+	quotaInstance.SetUnits(taskAdaptor.GetBillingUnits())
 	if errWithOA := quotaInstance.PreQuotaConsumption(); errWithOA != nil {
 		taskAdaptor.HandleError(base.OpenAIErrToTaskErr(errWithOA))
 		return
@@ -90,11 +92,32 @@ func RelayTaskSubmit(c *gin.Context) {
 
 }
 
+// RelayTaskCancel 取消统一接口提交的任务，取消成功后和任务失败一样回调
+func RelayTaskCancel(c *gin.Context) {
+	task, err := model.GetTaskByTaskId(model.TaskPlatformVideo, c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		video.StringError(c, http.StatusInternalServerError, "get_task_failed", err.Error())
+		return
+	}
+	if task == nil {
+		video.StringError(c, http.StatusNotFound, "task_not_exist", "task not found")
+		return
+	}
+
+	if errWithCode := video.CancelTask(c.Request.Context(), task); errWithCode != nil {
+		video.StringError(c, errWithCode.StatusCode, fmt.Sprintf("%v", errWithCode.Code), errWithCode.Message)
+		return
+	}
+	NotifyTaskFinished(c.Request.Context(), task)
+
+	c.JSON(http.StatusOK, video.TaskModel2Dto(task))
+}
+
 func CompletedTask(quotaInstance *relay_util.Quota, taskAdaptor base.TaskInterface, c *gin.Context) {
 	quotaInstance.Consume(c, &types.Usage{CompletionTokens: 0, PromptTokens: 1, TotalTokens: 1}, false)
 
 	task := taskAdaptor.GetTask()
-	task.Quota = int(quotaInstance.GetInputRatio() * 1000)
+	task.Quota = quotaInstance.GetUnitQuota()
 
 	err := task.Insert()
 	if err != nil {
@@ -112,6 +135,8 @@ func GetRelayMode(c *gin.Context) int {
 		relayMode = config.RelayModeSuno
 	} else if strings.HasPrefix(path, "/kling") {
 		relayMode = config.RelayModeKling
+	} else if strings.HasPrefix(path, "/v1/tasks") {
+		relayMode = config.RelayModeVideo
 	}
 
 	return relayMode
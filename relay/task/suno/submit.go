@@ -43,6 +43,12 @@ func (t *SunoTask) Init() *base.TaskError {
 		return base.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
 	}
 
+	// 回调由网关发送，不转发给上游
+	if err = t.SetNotifyHook(t.Request.NotifyHook); err != nil {
+		return base.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
+	}
+	t.Request.NotifyHook = ""
+
 	err = t.HandleOriginTaskID()
 	if err != nil {
 		return base.StringTaskError(http.StatusInternalServerError, "get_origin_task_failed", err.Error(), true)
@@ -221,14 +227,8 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 		if responseItem.FailReason != "" || task.Status == model.TaskStatusFailure {
 			logger.LogError(ctx, task.TaskID+" 构建失败，"+task.FailReason)
 			task.Progress = 100
-			quota := task.Quota
-			if quota > 0 {
-				err := model.IncreaseUserQuota(task.UserId, quota)
-				if err != nil {
-					logger.LogError(ctx, "fail to increase user quota: "+err.Error())
-				}
-				logContent := fmt.Sprintf("异步任务执行失败 %s，补偿 %s", task.TaskID, common.LogQuota(quota))
-				model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
+			if _, err := task.RefundQuota(model.TaskRefundReasonFailure); err != nil {
+				logger.LogError(ctx, "fail to refund task quota: "+err.Error())
 			}
 		}
 
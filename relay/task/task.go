@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"one-api/common"
+	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/model"
 	"sync"
@@ -79,13 +80,19 @@ func UpdateTaskBulk() {
 			}
 			taskChannelM := make(map[int][]string)
 			taskM := make(map[string]*model.Task)
+			nullTasks := make([]*model.Task, 0)
 			nullTaskIds := make([]int64, 0)
 			for _, task := range tasks {
 				if task.TaskID == "" {
 					// 统计失败的未完成任务
+					nullTasks = append(nullTasks, task)
 					nullTaskIds = append(nullTaskIds, task.ID)
 					continue
 				}
+				if model.IsTaskTimeout(task.CreatedAt) {
+					timeoutTask(ctx, task)
+					continue
+				}
 				taskM[task.TaskID] = task
 				taskChannelM[task.ChannelId] = append(taskChannelM[task.ChannelId], task.TaskID)
 			}
@@ -99,17 +106,52 @@ func UpdateTaskBulk() {
 					logger.LogError(ctx, fmt.Sprintf("Fix null task_id task error: %v", err))
 				} else {
 					logger.LogInfo(ctx, fmt.Sprintf("Fix null task_id task success: %v", nullTaskIds))
+					for _, task := range nullTasks {
+						refundTask(ctx, task, model.TaskRefundReasonFailure)
+					}
 				}
 			}
 			if len(taskChannelM) == 0 {
 				continue
 			}
 			UpdateTaskByPlatform(ctx, platform, taskChannelM, taskM)
+
+			// 这里只加载了未完成的任务，更新后已结束的任务都是本轮结束的
+			for _, task := range taskM {
+				NotifyTaskFinished(ctx, task)
+			}
 		}
 		time.Sleep(time.Duration(15) * time.Second)
 	}
 }
 
+// timeoutTask 超时未完成的任务按失败结算，按超时的退款比例退还
+func timeoutTask(ctx context.Context, task *model.Task) {
+	task.Status = model.TaskStatusFailure
+	task.FailReason = fmt.Sprintf("上游任务超时（超过%d分钟）", config.TaskTimeoutMinutes)
+	task.Progress = 100
+	task.FinishTime = time.Now().Unix()
+
+	// 其他节点可能已经结算了该任务
+	updated, err := model.UpdateUnfinishedTask(task)
+	if err != nil {
+		logger.LogError(ctx, "UpdateTask task error: "+err.Error())
+		return
+	}
+	if !updated {
+		return
+	}
+
+	refundTask(ctx, task, model.TaskRefundReasonTimeout)
+	NotifyTaskFinished(ctx, task)
+}
+
+func refundTask(ctx context.Context, task *model.Task, reason string) {
+	if _, err := task.RefundQuota(reason); err != nil {
+		logger.LogError(ctx, "fail to refund task quota: "+err.Error())
+	}
+}
+
 func UpdateTaskByPlatform(ctx context.Context,
 	platform string, taskChannelM map[int][]string, taskM map[string]*model.Task) {
 	taskAdaptor, err := GetTaskAdaptorByPlatform(platform)
@@ -0,0 +1,72 @@
+package video
+
+import (
+	"encoding/json"
+	"net/http"
+	"one-api/common/config"
+	"one-api/model"
+	"one-api/providers/base"
+	"one-api/types"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Adaptor 视频平台适配器，按渠道类型注册，负责请求转换和状态映射
+type Adaptor interface {
+	Submit(provider base.ProviderInterface, request *VideoRequest) (*AdaptorResult, *types.OpenAIErrorWithStatusCode)
+	Fetch(provider base.ProviderInterface, task *model.Task) (*AdaptorResult, *types.OpenAIErrorWithStatusCode)
+	Cancel(provider base.ProviderInterface, task *model.Task) *types.OpenAIErrorWithStatusCode
+}
+
+// ContentDownloader 结果需要鉴权才能下载的平台实现，用于转存和 /content 接口
+type ContentDownloader interface {
+	Download(provider base.ProviderInterface, task *model.Task, index int) (*http.Response, *types.OpenAIErrorWithStatusCode)
+}
+
+// AdaptorResult 上游任务状态转换后的结果
+type AdaptorResult struct {
+	TaskId     string
+	Action     string
+	Status     model.TaskStatus
+	Progress   int
+	FailReason string
+	// 可以直接下载的结果链接
+	Videos []string
+	Raw    json.RawMessage
+}
+
+var (
+	adaptorsLock sync.RWMutex
+	adaptors     = map[int]Adaptor{
+		config.ChannelTypeOpenAI: &OpenAIAdaptor{},
+		config.ChannelTypeKling:  &KlingAdaptor{},
+	}
+)
+
+// RegisterAdaptor 注册渠道类型对应的适配器，已存在时覆盖
+func RegisterAdaptor(channelType int, adaptor Adaptor) {
+	adaptorsLock.Lock()
+	defer adaptorsLock.Unlock()
+	adaptors[channelType] = adaptor
+}
+
+func GetAdaptor(channelType int) (Adaptor, bool) {
+	adaptorsLock.RLock()
+	defer adaptorsLock.RUnlock()
+	adaptor, ok := adaptors[channelType]
+	return adaptor, ok
+}
+
+// parseProgress 解析 "50%" 或 "50" 形式的进度
+func parseProgress(progress string) int {
+	value, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(progress), "%"))
+	if err != nil {
+		return 0
+	}
+	return min(max(value, 0), 100)
+}
+
+func isFinishedStatus(status model.TaskStatus) bool {
+	return status == model.TaskStatusSuccess || status == model.TaskStatusFailure
+}
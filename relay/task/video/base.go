@@ -0,0 +1,112 @@
+package video
+
+import (
+	"encoding/json"
+	"one-api/model"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultVideoSeconds = 5
+	maxVideoN           = 4
+)
+
+// VideoRequest 统一的异步视频任务请求，各平台的差异由适配器转换
+type VideoRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt,omitempty"`
+	// 图生视频的首帧，图片链接或 base64
+	Image   string `json:"image,omitempty"`
+	Seconds int    `json:"seconds,omitempty"`
+	Size    string `json:"size,omitempty"`
+	N       int    `json:"n,omitempty"`
+	// 平台特有的参数，由适配器合并到上游请求中
+	Params     map[string]any `json:"params,omitempty"`
+	NotifyHook string         `json:"notify_hook,omitempty"`
+}
+
+// VideoTaskProperties 保存在 task.Properties 中的请求信息
+type VideoTaskProperties struct {
+	Model   string `json:"model"`
+	Seconds int    `json:"seconds"`
+	Size    string `json:"size,omitempty"`
+	N       int    `json:"n"`
+	Units   int    `json:"units"`
+}
+
+// VideoTaskData 保存在 task.Data 中的任务结果
+type VideoTaskData struct {
+	Videos []string `json:"videos,omitempty"`
+	// 结果已经全部转存到 storage
+	Rehosted bool            `json:"rehosted,omitempty"`
+	Upstream json.RawMessage `json:"upstream,omitempty"`
+}
+
+// VideoTaskResponse 统一接口返回的任务信息
+type VideoTaskResponse struct {
+	Id         string           `json:"id"`
+	Object     string           `json:"object"`
+	Model      string           `json:"model"`
+	Status     model.TaskStatus `json:"status"`
+	Progress   int              `json:"progress"`
+	FailReason string           `json:"fail_reason,omitempty"`
+	Seconds    int              `json:"seconds,omitempty"`
+	Size       string           `json:"size,omitempty"`
+	N          int              `json:"n,omitempty"`
+	Videos     []string         `json:"videos,omitempty"`
+	CreatedAt  int64            `json:"created_at"`
+	FinishedAt int64            `json:"finished_at,omitempty"`
+}
+
+func StringError(c *gin.Context, httpCode int, code, message string) {
+	c.JSON(httpCode, &types.OpenAIErrorResponse{
+		Error: types.OpenAIError{
+			Code:    code,
+			Message: message,
+			Type:    "one_hub_error",
+		},
+	})
+}
+
+func getTaskProperties(task *model.Task) *VideoTaskProperties {
+	properties := &VideoTaskProperties{}
+	json.Unmarshal(task.Properties, properties)
+	return properties
+}
+
+func getTaskData(task *model.Task) *VideoTaskData {
+	data := &VideoTaskData{}
+	json.Unmarshal(task.Data, data)
+	return data
+}
+
+func TaskModel2Dto(task *model.Task) *VideoTaskResponse {
+	properties := getTaskProperties(task)
+	data := getTaskData(task)
+
+	return &VideoTaskResponse{
+		Id:         task.TaskID,
+		Object:     "task",
+		Model:      properties.Model,
+		Status:     task.Status,
+		Progress:   task.Progress,
+		FailReason: task.FailReason,
+		Seconds:    properties.Seconds,
+		Size:       properties.Size,
+		N:          properties.N,
+		Videos:     data.Videos,
+		CreatedAt:  task.SubmitTime,
+		FinishedAt: task.FinishTime,
+	}
+}
+
+// GetBillingUnits 按秒计费时为总秒数，否则按条数计费
+func GetBillingUnits(priceType string, seconds, n int) int {
+	n = max(n, 1)
+	if priceType == model.SecondsPriceType {
+		return max(seconds, 1) * n
+	}
+	return n
+}
@@ -0,0 +1,30 @@
+package video_test
+
+import (
+	"testing"
+
+	"one-api/model"
+	"one-api/relay/task/video"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBillingUnits(t *testing.T) {
+	assert.Equal(t, 1, video.GetBillingUnits(model.TimesPriceType, 8, 0))
+	assert.Equal(t, 2, video.GetBillingUnits(model.TimesPriceType, 8, 2))
+	assert.Equal(t, 8, video.GetBillingUnits(model.SecondsPriceType, 8, 1))
+	assert.Equal(t, 20, video.GetBillingUnits(model.SecondsPriceType, 10, 2))
+	assert.Equal(t, 1, video.GetBillingUnits(model.SecondsPriceType, 0, 0))
+}
+
+func TestRegisterAdaptor(t *testing.T) {
+	_, ok := video.GetAdaptor(-1)
+	assert.False(t, ok)
+
+	adaptor := &video.OpenAIAdaptor{}
+	video.RegisterAdaptor(-1, adaptor)
+
+	registered, ok := video.GetAdaptor(-1)
+	assert.True(t, ok)
+	assert.Same(t, adaptor, registered)
+}
@@ -0,0 +1,152 @@
+package video
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/providers"
+	"one-api/types"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetFetchByID(c *gin.Context) {
+	task, ok := getUserTask(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, TaskModel2Dto(task))
+}
+
+func GetFetchList(c *gin.Context) {
+	var params model.TaskQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		StringError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	params.Platform = model.TaskPlatformVideo
+	// 只返回当前令牌提交的任务
+	params.TokenID = c.GetInt("token_id")
+
+	tasks, err := model.GetAllUserTasks(c.GetInt("id"), &params)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "get_task_failed", err.Error())
+		return
+	}
+
+	data := make([]*VideoTaskResponse, 0)
+	if tasks.Data != nil {
+		for _, task := range *tasks.Data {
+			data = append(data, TaskModel2Dto(task))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"object":      "list",
+		"data":        data,
+		"page":        tasks.Page,
+		"size":        tasks.Size,
+		"total_count": tasks.TotalCount,
+	})
+}
+
+// GetContent 下载结果视频，已有链接时重定向，需要鉴权的平台由网关代为下载
+func GetContent(c *gin.Context) {
+	task, ok := getUserTask(c)
+	if !ok {
+		return
+	}
+
+	if task.Status != model.TaskStatusSuccess {
+		StringError(c, http.StatusBadRequest, "task_not_finished", "task is not completed")
+		return
+	}
+
+	index, _ := strconv.Atoi(c.DefaultQuery("index", "0"))
+	data := getTaskData(task)
+	if index >= 0 && index < len(data.Videos) {
+		c.Redirect(http.StatusFound, data.Videos[index])
+		return
+	}
+
+	channel := model.ChannelGroup.GetChannel(task.ChannelId)
+	if channel == nil {
+		StringError(c, http.StatusNotFound, "video_not_found", "video not found")
+		return
+	}
+	adaptor, _ := GetAdaptor(channel.Type)
+	downloader, ok := adaptor.(ContentDownloader)
+	if !ok {
+		StringError(c, http.StatusNotFound, "video_not_found", "video not found")
+		return
+	}
+
+	resp, errWithCode := downloader.Download(providers.GetProvider(channel, c), task, index)
+	if errWithCode != nil {
+		StringError(c, errWithCode.StatusCode, "download_failed", errWithCode.Message)
+		return
+	}
+	defer resp.Body.Close()
+
+	c.Header("Content-Type", resp.Header.Get("Content-Type"))
+	if contentLength := resp.Header.Get("Content-Length"); contentLength != "" {
+		c.Header("Content-Length", contentLength)
+	}
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, resp.Body)
+}
+
+// CancelTask 取消未结束的任务并退还额度，上游不支持取消时返回错误
+func CancelTask(ctx context.Context, task *model.Task) *types.OpenAIErrorWithStatusCode {
+	if task.Progress == 100 || isFinishedStatus(task.Status) {
+		return common.StringErrorWrapperLocal("task is already finished", "task_finished", http.StatusBadRequest)
+	}
+
+	channel := model.ChannelGroup.GetChannel(task.ChannelId)
+	if channel == nil {
+		return common.StringErrorWrapperLocal("channel not found", "cancel_failed", http.StatusServiceUnavailable)
+	}
+	adaptor, ok := GetAdaptor(channel.Type)
+	if !ok {
+		return common.StringErrorWrapperLocal("channel does not support video tasks", "cancel_failed", http.StatusServiceUnavailable)
+	}
+
+	if errWithCode := adaptor.Cancel(providers.GetProvider(channel, nil), task); errWithCode != nil {
+		return errWithCode
+	}
+
+	task.Status = model.TaskStatusFailure
+	task.FailReason = "cancelled"
+	task.Progress = 100
+	task.FinishTime = time.Now().Unix()
+	// 轮询可能已经结算了该任务，此时以数据库中的结果为准
+	updated, err := model.UpdateUnfinishedTask(task)
+	if err != nil {
+		return common.ErrorWrapperLocal(err, "cancel_failed", http.StatusInternalServerError)
+	}
+	if !updated {
+		return common.StringErrorWrapperLocal("task is already finished", "task_finished", http.StatusBadRequest)
+	}
+	refundTask(ctx, task, model.TaskRefundReasonCancel)
+
+	return nil
+}
+
+func getUserTask(c *gin.Context) (*model.Task, bool) {
+	task, err := model.GetTaskByTaskId(model.TaskPlatformVideo, c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "get_task_failed", err.Error())
+		return nil, false
+	}
+	if task == nil {
+		StringError(c, http.StatusNotFound, "task_not_exist", "task not found")
+		return nil, false
+	}
+
+	return task, true
+}
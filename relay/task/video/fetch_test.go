@@ -0,0 +1,72 @@
+package video_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"one-api/common/config"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"one-api/relay/task/video"
+	"one-api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// cancelAdaptor 上游取消总是成功
+type cancelAdaptor struct{}
+
+func (cancelAdaptor) Submit(provider providersBase.ProviderInterface, request *video.VideoRequest) (*video.AdaptorResult, *types.OpenAIErrorWithStatusCode) {
+	return nil, nil
+}
+
+func (cancelAdaptor) Fetch(provider providersBase.ProviderInterface, task *model.Task) (*video.AdaptorResult, *types.OpenAIErrorWithStatusCode) {
+	return nil, nil
+}
+
+func (cancelAdaptor) Cancel(provider providersBase.ProviderInterface, task *model.Task) *types.OpenAIErrorWithStatusCode {
+	return nil
+}
+
+func TestCancelTaskAlreadySettled(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Task{}))
+	model.DB = db
+	config.RedisEnabled = false
+
+	const channelType, channelId = -2, 9001
+	video.RegisterAdaptor(channelType, cancelAdaptor{})
+	model.ChannelGroup.Lock()
+	if model.ChannelGroup.Channels == nil {
+		model.ChannelGroup.Channels = map[int]*model.ChannelChoice{}
+	}
+	model.ChannelGroup.Channels[channelId] = &model.ChannelChoice{Channel: &model.Channel{Id: channelId, Type: channelType}}
+	model.ChannelGroup.Unlock()
+	t.Cleanup(func() {
+		model.ChannelGroup.Lock()
+		delete(model.ChannelGroup.Channels, channelId)
+		model.ChannelGroup.Unlock()
+	})
+
+	task := &model.Task{TaskID: "video_1", Platform: model.TaskPlatformVideo, UserId: 1, ChannelId: channelId, Status: model.TaskStatusInProgress, Progress: 50}
+	require.NoError(t, db.Create(task).Error)
+
+	// 用户读取任务后，轮询先一步将任务结算为成功
+	require.NoError(t, db.Model(&model.Task{}).Where("id = ?", task.ID).Updates(map[string]any{"status": model.TaskStatusSuccess, "progress": 100}).Error)
+
+	errWithCode := video.CancelTask(context.Background(), task)
+	require.NotNil(t, errWithCode)
+	assert.Equal(t, http.StatusBadRequest, errWithCode.StatusCode)
+	assert.Equal(t, "task_finished", errWithCode.Code)
+
+	stored, err := model.GetTaskByTaskId(model.TaskPlatformVideo, 1, "video_1")
+	require.NoError(t, err)
+	assert.EqualValues(t, model.TaskStatusSuccess, stored.Status)
+	assert.Empty(t, stored.FailReason)
+}
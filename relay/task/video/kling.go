@@ -0,0 +1,114 @@
+package video
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/providers/base"
+	KlingProvider "one-api/providers/kling"
+	"one-api/types"
+	"strconv"
+)
+
+// KlingAdaptor 复用可灵渠道的提交和查询接口
+type KlingAdaptor struct{}
+
+func getKlingProvider(provider base.ProviderInterface) (*KlingProvider.KlingProvider, *types.OpenAIErrorWithStatusCode) {
+	klingProvider, ok := provider.(*KlingProvider.KlingProvider)
+	if !ok {
+		return nil, common.StringErrorWrapperLocal("provider not found", "provider_not_found", http.StatusServiceUnavailable)
+	}
+	return klingProvider, nil
+}
+
+func (a *KlingAdaptor) Submit(provider base.ProviderInterface, request *VideoRequest) (*AdaptorResult, *types.OpenAIErrorWithStatusCode) {
+	p, errWithCode := getKlingProvider(provider)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	if request.Seconds != 5 && request.Seconds != 10 {
+		return nil, common.StringErrorWrapperLocal("seconds must be 5 or 10", "invalid_request", http.StatusBadRequest)
+	}
+	if request.N > 1 {
+		return nil, common.StringErrorWrapperLocal("n > 1 is not supported by this channel", "invalid_request", http.StatusBadRequest)
+	}
+
+	klingRequest := &KlingProvider.KlingTask{}
+	if len(request.Params) > 0 {
+		params, _ := json.Marshal(request.Params)
+		if err := json.Unmarshal(params, klingRequest); err != nil {
+			return nil, common.ErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+		}
+	}
+
+	klingRequest.Prompt = request.Prompt
+	klingRequest.ModelName = request.Model
+	klingRequest.Image = request.Image
+	klingRequest.Duration = strconv.Itoa(request.Seconds)
+	klingRequest.CallbackURL = nil
+	if klingRequest.Mode == "" {
+		klingRequest.Mode = "std"
+	}
+
+	action := "text2video"
+	if request.Image != "" {
+		action = "image2video"
+	}
+
+	resp, errWithCode := p.Submit("videos", action, klingRequest)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	if resp.Data.TaskID == "" {
+		return nil, common.ErrorWrapper(errors.New(resp.Message), "submit_failed", http.StatusInternalServerError)
+	}
+
+	result := &AdaptorResult{
+		TaskId: resp.Data.TaskID,
+		Action: action,
+		Status: model.TaskStatusSubmitted,
+	}
+	result.Raw, _ = json.Marshal(resp)
+
+	return result, nil
+}
+
+func (a *KlingAdaptor) Fetch(provider base.ProviderInterface, task *model.Task) (*AdaptorResult, *types.OpenAIErrorWithStatusCode) {
+	p, errWithCode := getKlingProvider(provider)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	resp, errWithCode := p.GetFetch("videos", task.Action, task.TaskID)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	if !resp.IsSuccess() || resp.Data == nil {
+		return nil, common.StringErrorWrapper(resp.Message, "fetch_failed", http.StatusInternalServerError)
+	}
+
+	result := &AdaptorResult{
+		TaskId:     task.TaskID,
+		Action:     task.Action,
+		Status:     model.TaskStatus(resp.Data.Status),
+		Progress:   parseProgress(resp.Data.Progress),
+		FailReason: resp.Data.FailReason,
+		Raw:        json.RawMessage(resp.Data.Data),
+	}
+
+	klingResponse := &KlingProvider.KlingResponse[*KlingProvider.KlingTaskData]{}
+	if err := json.Unmarshal(resp.Data.Data, klingResponse); err == nil && klingResponse.Data != nil && klingResponse.Data.TaskResult != nil {
+		for _, video := range klingResponse.Data.TaskResult.Videos {
+			result.Videos = append(result.Videos, video.URL)
+		}
+	}
+
+	return result, nil
+}
+
+func (a *KlingAdaptor) Cancel(provider base.ProviderInterface, task *model.Task) *types.OpenAIErrorWithStatusCode {
+	return common.StringErrorWrapperLocal("cancel is not supported by this channel", "cancel_not_supported", http.StatusBadRequest)
+}
@@ -0,0 +1,174 @@
+package video
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/providers/base"
+	"one-api/providers/openai"
+	"one-api/types"
+	"strconv"
+)
+
+// OpenAIAdaptor OpenAI 兼容的 /v1/videos 接口
+type OpenAIAdaptor struct{}
+
+type OpenAIVideo struct {
+	Id          string `json:"id"`
+	Object      string `json:"object"`
+	Model       string `json:"model"`
+	Status      string `json:"status"`
+	Progress    int    `json:"progress"`
+	Seconds     string `json:"seconds,omitempty"`
+	Size        string `json:"size,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+	CompletedAt int64  `json:"completed_at,omitempty"`
+	Error       *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func getOpenAIProvider(provider base.ProviderInterface) (*openai.OpenAIProvider, *types.OpenAIErrorWithStatusCode) {
+	openaiProvider, ok := provider.(*openai.OpenAIProvider)
+	if !ok {
+		return nil, common.StringErrorWrapperLocal("provider not found", "provider_not_found", http.StatusServiceUnavailable)
+	}
+	return openaiProvider, nil
+}
+
+func (a *OpenAIAdaptor) Submit(provider base.ProviderInterface, request *VideoRequest) (*AdaptorResult, *types.OpenAIErrorWithStatusCode) {
+	p, errWithCode := getOpenAIProvider(provider)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	// 参考图需要以文件上传，暂不支持
+	if request.Image != "" {
+		return nil, common.StringErrorWrapperLocal("image is not supported by this channel", "invalid_request", http.StatusBadRequest)
+	}
+	if request.N > 1 {
+		return nil, common.StringErrorWrapperLocal("n > 1 is not supported by this channel", "invalid_request", http.StatusBadRequest)
+	}
+
+	body := make(map[string]any, len(request.Params)+4)
+	maps.Copy(body, request.Params)
+	body["model"] = request.Model
+	body["prompt"] = request.Prompt
+	body["seconds"] = strconv.Itoa(request.Seconds)
+	if request.Size != "" {
+		body["size"] = request.Size
+	}
+
+	fullRequestURL := p.GetFullRequestURL("/v1/videos", request.Model)
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithHeader(p.GetRequestHeaders()), p.Requester.WithBody(body))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	response := &OpenAIVideo{}
+	if _, errWithCode = p.Requester.SendRequest(req, response, false); errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	if response.Id == "" {
+		return nil, common.ErrorWrapper(errors.New("task id is empty"), "submit_failed", http.StatusInternalServerError)
+	}
+
+	return response.toResult(), nil
+}
+
+func (a *OpenAIAdaptor) Fetch(provider base.ProviderInterface, task *model.Task) (*AdaptorResult, *types.OpenAIErrorWithStatusCode) {
+	p, errWithCode := getOpenAIProvider(provider)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	fullRequestURL := p.GetFullRequestURL("/v1/videos/"+task.TaskID, "")
+	req, err := p.Requester.NewRequest(http.MethodGet, fullRequestURL, p.Requester.WithHeader(p.GetRequestHeaders()))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	response := &OpenAIVideo{}
+	if _, errWithCode = p.Requester.SendRequest(req, response, false); errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	return response.toResult(), nil
+}
+
+// Cancel OpenAI 没有单独的取消接口，删除任务即可停止生成
+func (a *OpenAIAdaptor) Cancel(provider base.ProviderInterface, task *model.Task) *types.OpenAIErrorWithStatusCode {
+	p, errWithCode := getOpenAIProvider(provider)
+	if errWithCode != nil {
+		return errWithCode
+	}
+
+	fullRequestURL := p.GetFullRequestURL("/v1/videos/"+task.TaskID, "")
+	req, err := p.Requester.NewRequest(http.MethodDelete, fullRequestURL, p.Requester.WithHeader(p.GetRequestHeaders()))
+	if err != nil {
+		return common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	response := make(map[string]any)
+	_, errWithCode = p.Requester.SendRequest(req, &response, false)
+	return errWithCode
+}
+
+// Download 结果需要渠道的密钥才能下载，每个任务只有一个视频
+func (a *OpenAIAdaptor) Download(provider base.ProviderInterface, task *model.Task, index int) (*http.Response, *types.OpenAIErrorWithStatusCode) {
+	p, errWithCode := getOpenAIProvider(provider)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	if index != 0 {
+		return nil, common.StringErrorWrapperLocal("video not found", "not_found", http.StatusNotFound)
+	}
+
+	fullRequestURL := p.GetFullRequestURL(fmt.Sprintf("/v1/videos/%s/content", task.TaskID), "")
+	req, err := p.Requester.NewRequest(http.MethodGet, fullRequestURL, p.Requester.WithHeader(p.GetRequestHeaders()))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	return p.Requester.SendRequestRaw(req)
+}
+
+func (v *OpenAIVideo) toResult() *AdaptorResult {
+	result := &AdaptorResult{
+		TaskId:   v.Id,
+		Action:   "generate",
+		Status:   convertOpenAIStatus(v.Status),
+		Progress: min(v.Progress, 99),
+	}
+
+	if isFinishedStatus(result.Status) {
+		result.Progress = 100
+	}
+	if v.Error != nil {
+		result.FailReason = v.Error.Message
+	}
+
+	result.Raw, _ = json.Marshal(v)
+	return result
+}
+
+func convertOpenAIStatus(status string) model.TaskStatus {
+	switch status {
+	case "queued":
+		return model.TaskStatusQueued
+	case "in_progress":
+		return model.TaskStatusInProgress
+	case "completed":
+		return model.TaskStatusSuccess
+	case "failed":
+		return model.TaskStatusFailure
+	default:
+		return model.TaskStatusUnknown
+	}
+}
@@ -0,0 +1,98 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/common/storage"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/providers/base"
+)
+
+// rehostVideos 开启转存时下载结果并上传到 storage，失败的保留上游链接
+// 需要鉴权才能下载的平台没有上游链接，转存失败时只能通过 /content 接口获取
+// 只有全部结果都上传成功时才标记为已转存
+func rehostVideos(ctx context.Context, provider base.ProviderInterface, adaptor Adaptor, task *model.Task, videos []string) ([]string, bool) {
+	if !config.VideoTaskRehost {
+		return videos, false
+	}
+
+	downloader, isDownloader := adaptor.(ContentDownloader)
+	count := len(videos)
+	if isDownloader {
+		count = max(count, getTaskProperties(task).N, 1)
+	}
+
+	rehosted := make([]string, 0, count)
+	uploaded := 0
+	for i := 0; i < count; i++ {
+		var data []byte
+		var err error
+		if isDownloader {
+			data, err = downloadContent(downloader, provider, task, i)
+		} else {
+			data, err = downloadURL(videos[i])
+		}
+
+		url := ""
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("download task %s video %d error: %s", task.TaskID, i, err.Error()))
+		} else {
+			url = storage.Upload(data, utils.GetUUID()+".mp4")
+		}
+
+		if url == "" {
+			// 转存失败时保留上游链接，需要鉴权的平台不返回上游链接
+			if i < len(videos) && !isDownloader {
+				rehosted = append(rehosted, videos[i])
+			}
+			continue
+		}
+		rehosted = append(rehosted, url)
+		uploaded++
+	}
+
+	return rehosted, uploaded > 0 && uploaded == count
+}
+
+func downloadContent(downloader ContentDownloader, provider base.ProviderInterface, task *model.Task, index int) ([]byte, error) {
+	resp, errWithCode := downloader.Download(provider, task, index)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer resp.Body.Close()
+
+	return readVideo(resp.Body)
+}
+
+func downloadURL(url string) ([]byte, error) {
+	resp, err := requester.HTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return readVideo(resp.Body)
+}
+
+func readVideo(reader io.Reader) ([]byte, error) {
+	limit := int64(config.VideoTaskMaxDownloadMB) * 1024 * 1024
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("video is larger than %d MB", config.VideoTaskMaxDownloadMB)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,256 @@
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/providers"
+	providersBase "one-api/providers/base"
+	"one-api/relay/task/base"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VideoTask struct {
+	base.TaskBase
+	Request *VideoRequest
+	Adaptor Adaptor
+}
+
+func (t *VideoTask) HandleError(err *base.TaskError) {
+	StringError(t.C, err.StatusCode, err.Code, err.Message)
+}
+
+func (t *VideoTask) Init() *base.TaskError {
+	if err := common.UnmarshalBodyReusable(t.C, &t.Request); err != nil {
+		return base.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
+	}
+
+	if err := t.validate(); err != nil {
+		return base.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
+	}
+
+	if err := t.SetNotifyHook(t.Request.NotifyHook); err != nil {
+		return base.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
+	}
+
+	t.OriginalModel = t.Request.Model
+	return nil
+}
+
+func (t *VideoTask) validate() error {
+	if t.Request.Model == "" {
+		return errors.New("model is required")
+	}
+	if t.Request.Prompt == "" && t.Request.Image == "" {
+		return errors.New("prompt or image is required")
+	}
+
+	if t.Request.Seconds == 0 {
+		t.Request.Seconds = defaultVideoSeconds
+	}
+	if t.Request.Seconds < 0 || t.Request.Seconds > config.VideoTaskMaxSeconds {
+		return fmt.Errorf("seconds must be between 1 and %d", config.VideoTaskMaxSeconds)
+	}
+
+	if t.Request.N == 0 {
+		t.Request.N = 1
+	}
+	if t.Request.N < 0 || t.Request.N > maxVideoN {
+		return fmt.Errorf("n must be between 1 and %d", maxVideoN)
+	}
+
+	return nil
+}
+
+func (t *VideoTask) SetProvider() *base.TaskError {
+	provider, err := t.GetProviderByModel()
+	if err != nil {
+		return base.StringTaskError(http.StatusServiceUnavailable, "provider_not_found", err.Error(), true)
+	}
+
+	adaptor, ok := GetAdaptor(provider.GetChannel().Type)
+	if !ok {
+		return base.StringTaskError(http.StatusServiceUnavailable, "provider_not_found", "channel does not support video tasks", true)
+	}
+
+	t.Adaptor = adaptor
+	t.BaseProvider = provider
+
+	price := model.PricingInstance.GetPrice(t.GetModelName())
+	t.BillingUnits = GetBillingUnits(price.Type, t.Request.Seconds, t.Request.N)
+
+	return nil
+}
+
+func (t *VideoTask) Relay() *base.TaskError {
+	request := *t.Request
+	request.Model = t.ModelName
+
+	result, errWithCode := t.Adaptor.Submit(t.BaseProvider, &request)
+	if errWithCode != nil {
+		return base.OpenAIErrToTaskErr(errWithCode)
+	}
+
+	t.InitTask()
+	t.Task.TaskID = result.TaskId
+	t.Task.ChannelId = t.BaseProvider.GetChannel().Id
+	t.Task.Action = result.Action
+	if result.Status != "" && result.Status != model.TaskStatusUnknown {
+		t.Task.Status = result.Status
+	} else {
+		t.Task.Status = model.TaskStatusSubmitted
+	}
+
+	t.Task.Properties, _ = json.Marshal(&VideoTaskProperties{
+		Model:   t.OriginalModel,
+		Seconds: t.Request.Seconds,
+		Size:    t.Request.Size,
+		N:       t.Request.N,
+		Units:   t.GetBillingUnits(),
+	})
+	t.Task.Data, _ = json.Marshal(&VideoTaskData{Upstream: result.Raw})
+
+	t.Response = TaskModel2Dto(t.Task)
+
+	return nil
+}
+
+func (t *VideoTask) ShouldRetry(c *gin.Context, err *base.TaskError) bool {
+	return false
+}
+
+func (t *VideoTask) UpdateTaskStatus(ctx context.Context, taskChannelM map[int][]string, taskM map[string]*model.Task) error {
+	for channelId, taskIds := range taskChannelM {
+		err := updateVideoTaskAll(ctx, channelId, taskIds, taskM)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("渠道 #%d 更新异步任务失败: %s", channelId, err.Error()))
+		}
+	}
+	return nil
+}
+
+func updateVideoTaskAll(ctx context.Context, channelId int, taskIds []string, taskM map[string]*model.Task) error {
+	logger.LogWarn(ctx, fmt.Sprintf("渠道 #%d 未完成的任务有: %d", channelId, len(taskIds)))
+	if len(taskIds) == 0 {
+		return nil
+	}
+
+	channel := model.ChannelGroup.GetChannel(channelId)
+	if channel == nil {
+		failTasks(ctx, taskIds, taskM, fmt.Sprintf("获取渠道信息失败，请联系管理员，渠道ID：%d", channelId))
+		return fmt.Errorf("channel not found")
+	}
+
+	provider := providers.GetProvider(channel, nil)
+	adaptor, ok := GetAdaptor(channel.Type)
+	if provider == nil || !ok {
+		failTasks(ctx, taskIds, taskM, "获取供应商失败，请联系管理员")
+		return fmt.Errorf("provider not found")
+	}
+
+	for _, taskId := range taskIds {
+		task := taskM[taskId]
+		result, errWithCode := adaptor.Fetch(provider, task)
+		if errWithCode != nil {
+			logger.SysError(fmt.Sprintf("Get Task %s Fetch error: %s", taskId, errWithCode.Message))
+			continue
+		}
+
+		if !applyResult(ctx, provider, adaptor, task, result) {
+			continue
+		}
+		if !saveTask(ctx, task) {
+			// 已被取消或由其他节点结算，不再由本轮回调
+			delete(taskM, taskId)
+		}
+	}
+
+	return nil
+}
+
+// applyResult 将上游状态写入任务，没有变化时返回 false
+func applyResult(ctx context.Context, provider providersBase.ProviderInterface, adaptor Adaptor, task *model.Task, result *AdaptorResult) bool {
+	status := result.Status
+	if status == "" || status == model.TaskStatusUnknown {
+		status = task.Status
+	}
+
+	if status == task.Status && result.Progress == task.Progress && result.FailReason == task.FailReason {
+		return false
+	}
+
+	now := time.Now().Unix()
+	if task.StartTime == 0 && status != model.TaskStatusSubmitted && status != model.TaskStatusQueued && status != model.TaskStatusNotStart {
+		task.StartTime = now
+	}
+
+	task.Status = status
+	task.Progress = result.Progress
+	if result.FailReason != "" {
+		task.FailReason = result.FailReason
+	}
+
+	data := getTaskData(task)
+	if len(result.Raw) > 0 {
+		data.Upstream = result.Raw
+	}
+
+	switch status {
+	case model.TaskStatusSuccess:
+		task.Progress = 100
+		task.FinishTime = now
+		data.Videos, data.Rehosted = rehostVideos(ctx, provider, adaptor, task, result.Videos)
+	case model.TaskStatusFailure:
+		logger.LogError(ctx, task.TaskID+" 构建失败，"+task.FailReason)
+		task.Progress = 100
+		task.FinishTime = now
+	default:
+		// 未结束的任务进度不能为 100，否则不会再被轮询
+		task.Progress = min(task.Progress, 99)
+	}
+
+	task.Data, _ = json.Marshal(data)
+	return true
+}
+
+func failTasks(ctx context.Context, taskIds []string, taskM map[string]*model.Task, reason string) {
+	now := time.Now().Unix()
+	for _, taskId := range taskIds {
+		task := taskM[taskId]
+		task.Status = model.TaskStatusFailure
+		task.FailReason = reason
+		task.Progress = 100
+		task.FinishTime = now
+		if !saveTask(ctx, task) {
+			delete(taskM, taskId)
+		}
+	}
+}
+
+// saveTask 任务可能已被用户取消或由其他节点结算，只有更新成功时才结算失败退款并返回 true
+func saveTask(ctx context.Context, task *model.Task) bool {
+	updated, err := model.UpdateUnfinishedTask(task)
+	if err != nil {
+		logger.SysError("UpdateTask task error: " + err.Error())
+		return false
+	}
+
+	if updated && task.Status == model.TaskStatusFailure {
+		refundTask(ctx, task, model.TaskRefundReasonFailure)
+	}
+	return updated
+}
+
+func refundTask(ctx context.Context, task *model.Task, reason string) {
+	if _, err := task.RefundQuota(reason); err != nil {
+		logger.LogError(ctx, "fail to refund task quota: "+err.Error())
+	}
+}
@@ -0,0 +1,228 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay/task/base"
+	"strconv"
+	"time"
+)
+
+// 回调响应体最多保存的长度
+const taskWebhookResponseLimit = 1024
+
+// 单次重试间隔的上限
+const taskWebhookMaxBackoff = 6 * time.Hour
+
+// TaskWebhookPayload 回调请求体，data 为任务查询接口返回的原始数据
+type TaskWebhookPayload struct {
+	Event      string           `json:"event"`
+	TaskId     string           `json:"task_id"`
+	Platform   string           `json:"platform"`
+	Action     string           `json:"action"`
+	Status     model.TaskStatus `json:"status"`
+	FailReason string           `json:"fail_reason,omitempty"`
+	Progress   int              `json:"progress"`
+	SubmitTime int64            `json:"submit_time"`
+	StartTime  int64            `json:"start_time"`
+	FinishTime int64            `json:"finish_time"`
+	Data       json.RawMessage  `json:"data,omitempty"`
+}
+
+func isTaskFinished(task *model.Task) bool {
+	return task.Progress == 100 && (task.Status == model.TaskStatusSuccess || task.Status == model.TaskStatusFailure)
+}
+
+// NotifyTaskFinished 任务结束时创建投递记录并立即投递，失败后由定时任务重试
+func NotifyTaskFinished(ctx context.Context, task *model.Task) {
+	if task.NotifyHook == "" || !isTaskFinished(task) {
+		return
+	}
+
+	event := model.TaskWebhookEventSucceeded
+	if task.Status == model.TaskStatusFailure {
+		event = model.TaskWebhookEventFailed
+	}
+
+	payload, err := json.Marshal(&TaskWebhookPayload{
+		Event:      event,
+		TaskId:     task.TaskID,
+		Platform:   task.Platform,
+		Action:     task.Action,
+		Status:     task.Status,
+		FailReason: task.FailReason,
+		Progress:   task.Progress,
+		SubmitTime: task.SubmitTime,
+		StartTime:  task.StartTime,
+		FinishTime: task.FinishTime,
+		Data:       json.RawMessage(task.Data),
+	})
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("marshal task %s webhook payload error: %s", task.TaskID, err.Error()))
+		return
+	}
+
+	delivery := &model.TaskWebhookDelivery{
+		TaskId:  task.ID,
+		UserId:  task.UserId,
+		TokenId: task.TokenID,
+		Url:     task.NotifyHook,
+		Event:   event,
+		Payload: string(payload),
+		Status:  model.TaskWebhookStatusPending,
+		// 投递期间作为租约，避免定时任务同时重试
+		NextRetryAt: taskWebhookLeaseUntil(),
+	}
+	created, err := delivery.Insert()
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("create task %s webhook delivery error: %s", task.TaskID, err.Error()))
+		return
+	}
+	// 其他节点已经创建了投递记录
+	if !created {
+		return
+	}
+
+	common.SafeGoroutine(func() {
+		deliverTaskWebhook(ctx, delivery)
+	})
+}
+
+// RetryTaskWebhooks 重试到期的投递，由定时任务调用
+func RetryTaskWebhooks() {
+	ctx := context.WithValue(context.Background(), logger.RequestIdKey, "TaskWebhook")
+	deliveries, err := model.GetDueTaskWebhookDeliveries(100)
+	if err != nil {
+		logger.LogError(ctx, "get task webhook deliveries error: "+err.Error())
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if !model.ClaimTaskWebhookDelivery(delivery, taskWebhookLeaseUntil()) {
+			continue
+		}
+		deliverTaskWebhook(ctx, delivery)
+	}
+}
+
+// RedeliverTaskWebhook 手动重新投递，重置重试次数后立即投递
+func RedeliverTaskWebhook(ctx context.Context, delivery *model.TaskWebhookDelivery) error {
+	if err := model.ResetTaskWebhookDelivery(delivery); err != nil {
+		return err
+	}
+	if !model.ClaimTaskWebhookDelivery(delivery, taskWebhookLeaseUntil()) {
+		return nil
+	}
+
+	common.SafeGoroutine(func() {
+		deliverTaskWebhook(ctx, delivery)
+	})
+	return nil
+}
+
+func taskWebhookLeaseUntil() int64 {
+	return time.Now().Add(2 * time.Duration(config.TaskWebhookTimeout) * time.Second).Unix()
+}
+
+// TaskWebhookBackoff 第 attempts 次失败后的重试间隔，按指数增长
+func TaskWebhookBackoff(attempts int) time.Duration {
+	backoff := time.Duration(config.TaskWebhookRetryInterval) * time.Second
+	for i := 1; i < attempts && backoff < taskWebhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, taskWebhookMaxBackoff)
+}
+
+func deliverTaskWebhook(ctx context.Context, delivery *model.TaskWebhookDelivery) {
+	delivery.Attempts++
+	code, body, err := sendTaskWebhook(delivery)
+	delivery.ResponseCode = code
+	delivery.ResponseBody = body
+
+	if err == nil && code/100 != 2 {
+		err = fmt.Errorf("unexpected status code %d", code)
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = model.TaskWebhookStatusSuccess
+		delivery.Error = ""
+		delivery.NextRetryAt = 0
+	case delivery.Attempts >= config.TaskWebhookMaxAttempts:
+		delivery.Status = model.TaskWebhookStatusFailed
+		delivery.Error = err.Error()
+		delivery.NextRetryAt = 0
+	default:
+		delivery.Error = err.Error()
+		delivery.NextRetryAt = time.Now().Add(TaskWebhookBackoff(delivery.Attempts)).Unix()
+	}
+
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("task webhook #%d attempt %d failed: %s", delivery.Id, delivery.Attempts, err.Error()))
+	}
+
+	if saveErr := delivery.SaveResult(); saveErr != nil {
+		logger.LogError(ctx, fmt.Sprintf("save task webhook #%d error: %s", delivery.Id, saveErr.Error()))
+	}
+}
+
+// SignTaskWebhook 签名为 HMAC-SHA256(secret, timestamp + "." + payload) 的十六进制
+func SignTaskWebhook(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func sendTaskWebhook(delivery *model.TaskWebhookDelivery) (int, string, error) {
+	payload := []byte(delivery.Payload)
+
+	secret := ""
+	if delivery.TokenId > 0 {
+		var err error
+		secret, err = model.GetTokenWebhookSecret(delivery.TokenId)
+		if err != nil {
+			return 0, "", fmt.Errorf("get token webhook secret error: %s", err.Error())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.TaskWebhookTimeout)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+
+	timestamp := utils.GetTimestamp()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "one-hub-webhook")
+	req.Header.Set("X-Webhook-Id", strconv.Itoa(delivery.Id))
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	if secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+SignTaskWebhook(secret, timestamp, payload))
+	}
+
+	resp, err := base.NotifyHookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, taskWebhookResponseLimit))
+	return resp.StatusCode, string(body), nil
+}
@@ -0,0 +1,73 @@
+package task
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 任务回调投递日志的管理接口，重新投递需要直接发送，因此放在这里而不是 controller
+
+func GetUserTaskWebhooks(c *gin.Context) {
+	getTaskWebhooks(c, c.GetInt("id"))
+}
+
+func GetAllTaskWebhooks(c *gin.Context) {
+	getTaskWebhooks(c, 0)
+}
+
+func RedeliverUserTaskWebhook(c *gin.Context) {
+	redeliverTaskWebhook(c, c.GetInt("id"))
+}
+
+func RedeliverTaskWebhookByAdmin(c *gin.Context) {
+	redeliverTaskWebhook(c, 0)
+}
+
+func getTaskWebhooks(c *gin.Context, userId int) {
+	var params model.TaskWebhookQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	deliveries, err := model.GetTaskWebhookDeliveries(userId, &params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    deliveries,
+	})
+}
+
+func redeliverTaskWebhook(c *gin.Context, userId int) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	delivery, err := model.GetTaskWebhookDelivery(id, userId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("投递记录不存在"))
+		return
+	}
+
+	if err := RedeliverTaskWebhook(c.Request.Context(), delivery); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
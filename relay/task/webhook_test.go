@@ -0,0 +1,34 @@
+package task_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"one-api/common/config"
+	"one-api/relay/task"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignTaskWebhook(t *testing.T) {
+	payload := []byte(`{"event":"task.succeeded"}`)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1700000000." + string(payload)))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	assert.Equal(t, expected, task.SignTaskWebhook("whsec_test", 1700000000, payload))
+	assert.NotEqual(t, expected, task.SignTaskWebhook("whsec_other", 1700000000, payload))
+}
+
+func TestTaskWebhookBackoff(t *testing.T) {
+	config.TaskWebhookRetryInterval = 30
+
+	assert.Equal(t, 30*time.Second, task.TaskWebhookBackoff(1))
+	assert.Equal(t, 60*time.Second, task.TaskWebhookBackoff(2))
+	assert.Equal(t, 240*time.Second, task.TaskWebhookBackoff(4))
+	assert.Equal(t, 6*time.Hour, task.TaskWebhookBackoff(20))
+}
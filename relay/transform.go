@@ -0,0 +1,39 @@
+package relay
+
+import (
+	"fmt"
+	"one-api/common/logger"
+	"one-api/common/transform"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setTransformer 按分组和渠道配置的规则改写发往上游的请求和返回的响应，分组规则先执行
+func setTransformer(c *gin.Context, provider providersBase.ProviderInterface, channel *model.Channel, modelName string) {
+	requester := provider.GetRequester()
+	if requester == nil {
+		return
+	}
+
+	group := c.GetString("token_group")
+	if c.GetBool("is_backupGroup") {
+		group = c.GetString("token_backup_group")
+	}
+
+	pipeline := transform.NewPipeline(&transform.Request{
+		Model:  modelName,
+		Path:   c.Request.URL.Path,
+		Group:  group,
+		Header: c.Request.Header,
+	}, model.GlobalUserGroupRatio.GetTransformRules(group), channel.GetTransformRules())
+	if pipeline == nil {
+		requester.Transformer = nil
+		return
+	}
+
+	requester.Transformer = pipeline
+	logger.LogDebug(c.Request.Context(), fmt.Sprintf("channel #%d transform rules: %s", channel.Id, strings.Join(pipeline.RuleNames(), ", ")))
+}
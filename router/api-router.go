@@ -4,6 +4,7 @@ import (
 	"one-api/controller"
 	"one-api/middleware"
 	"one-api/relay"
+	"one-api/relay/task"
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
@@ -88,6 +89,11 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.GET("/payment", controller.GetUserPaymentList)
 				selfRoute.POST("/order", controller.CreateOrder)
 				selfRoute.GET("/order/status", controller.CheckOrderStatus)
+				selfRoute.GET("/subscription/plans", controller.GetUserSubscriptionPlans)
+				selfRoute.GET("/subscription", controller.GetUserSubscriptions)
+				selfRoute.POST("/subscription", controller.CreateSubscription)
+				selfRoute.POST("/subscription/:id/renew", controller.RenewSubscription)
+				selfRoute.POST("/subscription/:id/cancel", controller.CancelUserSubscription)
 			}
 
 			adminRoute := userRoute.Group("/")
@@ -150,11 +156,26 @@ func SetApiRouter(router *gin.Engine) {
 			userGroup.DELETE("/:id", controller.DeleteUserGroup)
 
 		}
+		promptTemplate := apiRouter.Group("/prompt_template")
+		promptTemplate.Use(middleware.AdminAuth())
+		{
+			promptTemplate.GET("/", controller.GetPromptTemplates)
+			promptTemplate.GET("/:id", controller.GetPromptTemplate)
+			promptTemplate.POST("/", controller.AddPromptTemplate)
+			promptTemplate.PUT("/", controller.UpdatePromptTemplate)
+			promptTemplate.PUT("/:id/rollback/:version", controller.RollbackPromptTemplate)
+			promptTemplate.DELETE("/:id", controller.DeletePromptTemplate)
+		}
 		channelRoute := apiRouter.Group("/channel")
 		channelRoute.Use(middleware.AdminAuth())
 		{
 			channelRoute.GET("/", controller.GetChannelsList)
 			channelRoute.GET("/models", relay.ListModelsForAdmin)
+			channelRoute.GET("/adaptive_weights", controller.GetChannelAdaptiveWeights)
+			channelRoute.GET("/replay", controller.GetChannelReplayList)
+			channelRoute.GET("/replay/:id", controller.GetChannelReplay)
+			channelRoute.POST("/replay", controller.CreateChannelReplay)
+			channelRoute.POST("/transform/preview", controller.PreviewChannelTransform)
 			channelRoute.POST("/provider_models_list", controller.GetModelList)
 			channelRoute.GET("/:id", controller.GetChannel)
 			channelRoute.GET("/test", controller.TestAllChannels)
@@ -193,6 +214,7 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.POST("/", controller.AddToken)
 			tokenRoute.PUT("/", controller.UpdateToken)
 			tokenRoute.DELETE("/:id", controller.DeleteToken)
+			tokenRoute.POST("/:id/webhook_secret", controller.ResetTokenWebhookSecret)
 		}
 		tokenAdminRoute := apiRouter.Group("/token")
 		tokenAdminRoute.Use(middleware.AdminAuth())
@@ -213,6 +235,7 @@ func SetApiRouter(router *gin.Engine) {
 		logRoute.GET("/", middleware.AdminAuth(), controller.GetLogsList)
 		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
 		logRoute.GET("/stat", middleware.AdminAuth(), controller.GetLogsStat)
+		logRoute.GET("/archive/:id", middleware.AdminAuth(), controller.GetLogArchive)
 		logRoute.GET("/self/stat", middleware.UserAuth(), controller.GetLogsSelfStat)
 		// logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogsList)
@@ -249,6 +272,8 @@ func SetApiRouter(router *gin.Engine) {
 		paymentRoute.Use(middleware.AdminAuth())
 		{
 			paymentRoute.GET("/order", controller.GetOrderList)
+			paymentRoute.POST("/order/:id/refund", controller.RefundOrder)
+			paymentRoute.GET("/refund", controller.GetOrderRefundList)
 			paymentRoute.GET("/", controller.GetPaymentList)
 			paymentRoute.GET("/:id", controller.GetPayment)
 			paymentRoute.POST("/", controller.AddPayment)
@@ -256,13 +281,34 @@ func SetApiRouter(router *gin.Engine) {
 			paymentRoute.DELETE("/:id", controller.DeletePayment)
 		}
 
+		subscriptionRoute := apiRouter.Group("/subscription")
+		subscriptionRoute.Use(middleware.AdminAuth())
+		{
+			subscriptionRoute.GET("/", controller.GetSubscriptionList)
+			subscriptionRoute.GET("/plan", controller.GetSubscriptionPlanList)
+			subscriptionRoute.GET("/plan/:id", controller.GetSubscriptionPlan)
+			subscriptionRoute.POST("/plan", controller.AddSubscriptionPlan)
+			subscriptionRoute.PUT("/plan", controller.UpdateSubscriptionPlan)
+			subscriptionRoute.DELETE("/plan/:id", controller.DeleteSubscriptionPlan)
+		}
+
 		mjRoute := apiRouter.Group("/mj")
 		mjRoute.GET("/self", middleware.UserAuth(), controller.GetUserMidjourney)
 		mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
 
+		chatCacheRoute := apiRouter.Group("/chat_cache")
+		chatCacheRoute.Use(middleware.AdminAuth())
+		{
+			chatCacheRoute.DELETE("/", controller.PurgeChatCache)
+		}
+
 		taskRoute := apiRouter.Group("/task")
 		taskRoute.GET("/self", middleware.UserAuth(), controller.GetUserAllTask)
 		taskRoute.GET("/", middleware.AdminAuth(), controller.GetAllTask)
+		taskRoute.GET("/webhook/self", middleware.UserAuth(), task.GetUserTaskWebhooks)
+		taskRoute.POST("/webhook/self/:id/redeliver", middleware.UserAuth(), task.RedeliverUserTaskWebhook)
+		taskRoute.GET("/webhook", middleware.AdminAuth(), task.GetAllTaskWebhooks)
+		taskRoute.POST("/webhook/:id/redeliver", middleware.AdminAuth(), task.RedeliverTaskWebhookByAdmin)
 	}
 
 	sseRouter := router.Group("/api/sse")
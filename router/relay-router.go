@@ -3,10 +3,12 @@ package router
 import (
 	"one-api/middleware"
 	"one-api/relay"
+	"one-api/relay/batch"
 	"one-api/relay/midjourney"
 	"one-api/relay/task"
 	"one-api/relay/task/kling"
 	"one-api/relay/task/suno"
+	"one-api/relay/task/video"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,6 +23,7 @@ func SetRelayRouter(router *gin.Engine) {
 	setGeminiRouter(router)
 	setRecraftRouter(router)
 	setKlingRouter(router)
+	setTaskRouter(router)
 }
 
 func setOpenAIRouter(router *gin.Engine) {
@@ -36,6 +39,9 @@ func setOpenAIRouter(router *gin.Engine) {
 		relayV1Router.POST("/completions", relay.Relay)
 		relayV1Router.POST("/chat/completions", relay.Relay)
 		relayV1Router.POST("/responses", relay.Relay)
+		relayV1Router.GET("/responses/:id", relay.RetrieveResponse)
+		relayV1Router.DELETE("/responses/:id", relay.DeleteResponse)
+		relayV1Router.GET("/responses/:id/input_items", relay.ListResponseInputItems)
 		// relayV1Router.POST("/edits", controller.Relay)
 		relayV1Router.POST("/images/generations", relay.Relay)
 		relayV1Router.POST("/images/edits", relay.Relay)
@@ -48,17 +54,18 @@ func setOpenAIRouter(router *gin.Engine) {
 		relayV1Router.POST("/moderations", relay.Relay)
 		relayV1Router.POST("/rerank", relay.RelayRerank)
 		relayV1Router.GET("/realtime", relay.ChatRealtime)
+		relayV1Router.Any("/files", batch.Relay)
+		relayV1Router.Any("/files/*any", batch.Relay)
+		relayV1Router.Any("/batches", batch.Relay)
+		relayV1Router.Any("/batches/*any", batch.Relay)
 
 		relayV1Router.Use(middleware.SpecifiedChannel())
 		{
-			relayV1Router.Any("/files", relay.RelayOnly)
-			relayV1Router.Any("/files/*any", relay.RelayOnly)
 			relayV1Router.Any("/fine_tuning/*any", relay.RelayOnly)
 			relayV1Router.Any("/assistants", relay.RelayOnly)
 			relayV1Router.Any("/assistants/*any", relay.RelayOnly)
 			relayV1Router.Any("/threads", relay.RelayOnly)
 			relayV1Router.Any("/threads/*any", relay.RelayOnly)
-			relayV1Router.Any("/batches/*any", relay.RelayOnly)
 			relayV1Router.Any("/vector_stores/*any", relay.RelayOnly)
 			relayV1Router.DELETE("/models/:model", relay.RelayOnly)
 		}
@@ -150,3 +157,18 @@ func setKlingRouter(router *gin.Engine) {
 		relayKlingRouter.POST("/v1/:class/:action", task.RelayTaskSubmit)
 	}
 }
+
+// 统一的异步任务接口，按渠道类型选择平台适配器
+func setTaskRouter(router *gin.Engine) {
+	relayTaskRouter := router.Group("/v1/tasks")
+	relayTaskRouter.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute())
+	relayTaskRouter.GET("", video.GetFetchList)
+	relayTaskRouter.GET("/:id", video.GetFetchByID)
+	relayTaskRouter.GET("/:id/content", video.GetContent)
+	relayTaskRouter.POST("/:id/cancel", task.RelayTaskCancel)
+
+	relayTaskRouter.Use(middleware.DynamicRedisRateLimiter())
+	{
+		relayTaskRouter.POST("", task.RelayTaskSubmit)
+	}
+}
@@ -0,0 +1,136 @@
+package safty
+
+import (
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/safty/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StageInput  = "input"
+	StageOutput = "output"
+)
+
+// 违规日志中保留的内容长度
+const violationExcerptLength = 200
+
+// getPolicyTools 返回分组使用的审查工具，分组未设置时使用系统设置
+func getPolicyTools(group string) []string {
+	if tools := model.GlobalUserGroupRatio.GetSafeTools(group); len(tools) > 0 {
+		return tools
+	}
+
+	return []string{config.SafeToolName}
+}
+
+// OutputCheckEnabled 是否需要审查模型输出
+func OutputCheckEnabled(c *gin.Context) bool {
+	if !config.EnableSafe {
+		return false
+	}
+
+	if config.SafeCheckOutput {
+		return true
+	}
+
+	userGroup := model.GlobalUserGroupRatio.GetBySymbol(c.GetString("token_group"))
+	return userGroup != nil && userGroup.SafeCheckOutput
+}
+
+// CheckInput 按请求所在分组的策略审查请求内容
+func CheckInput(c *gin.Context, content interface{}) types.CheckResult {
+	return checkWithPolicy(c, content, StageInput)
+}
+
+// CheckOutput 按请求所在分组的策略审查模型输出，未开启输出审查时直接通过
+func CheckOutput(c *gin.Context, content interface{}) types.CheckResult {
+	if !OutputCheckEnabled(c) {
+		return safeResult()
+	}
+
+	return checkWithPolicy(c, content, StageOutput)
+}
+
+func safeResult() types.CheckResult {
+	return types.CheckResult{
+		IsSafe:    true,
+		RiskLevel: 0,
+		Code:      types.SafeDefaultSuccessCode,
+		Reason:    types.SafeDefaultSuccessMessage,
+		Details:   make([]string, 0),
+	}
+}
+
+// checkWithPolicy 依次使用分组的审查工具检查内容，任意一个不通过即视为违规并记录日志
+// 审查工具本身出错时（如审查服务不可用）放行，避免影响正常请求
+func checkWithPolicy(c *gin.Context, content interface{}, stage string) types.CheckResult {
+	if !config.EnableSafe {
+		return safeResult()
+	}
+
+	contentStr, err := convertToString(content)
+	if err != nil || contentStr == "" {
+		return safeResult()
+	}
+
+	for _, name := range getPolicyTools(c.GetString("token_group")) {
+		tool, err := getTool(name)
+		if err != nil {
+			logger.LogError(c.Request.Context(), fmt.Sprintf("Safety tool %s not found", name))
+			return types.CheckResult{
+				IsSafe:    false,
+				RiskLevel: 1,
+				Code:      types.SafeDefaultErrorCode,
+				Reason:    types.SafeDefaultErrorMessage,
+				Details:   make([]string, 0),
+			}
+		}
+
+		result, err := tool.Check(contentStr)
+		if err != nil {
+			logger.LogError(c.Request.Context(), fmt.Sprintf("Safety tool %s check failed: %s", name, err.Error()))
+			continue
+		}
+
+		if !result.IsSafe {
+			recordViolation(c, name, stage, result, contentStr)
+			return result
+		}
+	}
+
+	return safeResult()
+}
+
+func recordViolation(c *gin.Context, toolName string, stage string, result types.CheckResult, content string) {
+	excerpt := []rune(content)
+	if len(excerpt) > violationExcerptLength {
+		excerpt = excerpt[:violationExcerptLength]
+	}
+
+	stageName := "请求"
+	if stage == StageOutput {
+		stageName = "输出"
+	}
+
+	metadata := map[string]any{
+		"safe_tool":  toolName,
+		"stage":      stage,
+		"code":       result.Code,
+		"reason":     result.Reason,
+		"details":    result.Details,
+		"risk_level": result.RiskLevel,
+		"excerpt":    string(excerpt),
+	}
+
+	userId := c.GetInt("id")
+	tokenName := c.GetString("token_name")
+	modelName := c.GetString("original_model")
+	sourceIp := c.ClientIP()
+	content = fmt.Sprintf("%s内容审查未通过（%s）：%s", stageName, toolName, result.Reason)
+
+	go model.RecordViolationLog(userId, tokenName, modelName, sourceIp, content, metadata)
+}
@@ -0,0 +1,133 @@
+package moderation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/config"
+	"one-api/model"
+	"one-api/providers"
+	providersBase "one-api/providers/base"
+	"one-api/safty/types"
+	apiTypes "one-api/types"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ModerationChecker 通过已有渠道调用 OpenAI 兼容的 /v1/moderations 接口
+type ModerationChecker struct{}
+
+type moderationResult struct {
+	Flagged        bool               `json:"flagged"`
+	Categories     map[string]bool    `json:"categories"`
+	CategoryScores map[string]float64 `json:"category_scores"`
+}
+
+func NewModerationChecker() *ModerationChecker {
+	return &ModerationChecker{}
+}
+
+// Name 返回检查器名称
+func (m *ModerationChecker) Name() string {
+	return "OpenAIModeration"
+}
+
+// Init 渠道在每次检查时读取，这里无需初始化
+func (m *ModerationChecker) Init() error {
+	return nil
+}
+
+// Check 调用审查接口，任意一条结果被标记即视为不安全
+func (m *ModerationChecker) Check(data string) (types.CheckResult, error) {
+	result := types.CheckResult{
+		IsSafe:    true,
+		Code:      types.SafeDefaultSuccessCode,
+		Reason:    types.SafeDefaultSuccessMessage,
+		Details:   make([]string, 0),
+		RiskLevel: 0,
+	}
+
+	provider, err := getModerationProvider()
+	if err != nil {
+		return result, err
+	}
+
+	modelName, err := provider.ModelMappingHandler(config.SafeModerationModel)
+	if err != nil {
+		return result, err
+	}
+
+	response, errWithCode := provider.CreateModeration(&apiTypes.ModerationRequest{
+		Input: data,
+		Model: strings.TrimPrefix(modelName, "+"),
+	})
+	if errWithCode != nil {
+		return result, errors.New(errWithCode.Message)
+	}
+
+	body, err := json.Marshal(response.Results)
+	if err != nil {
+		return result, err
+	}
+
+	var results []moderationResult
+	if err := json.Unmarshal(body, &results); err != nil {
+		return result, err
+	}
+
+	for _, item := range results {
+		if !item.Flagged {
+			continue
+		}
+
+		result.IsSafe = false
+		for category, flagged := range item.Categories {
+			if flagged {
+				result.Details = append(result.Details, category)
+			}
+		}
+	}
+
+	if result.IsSafe {
+		return result, nil
+	}
+
+	sort.Strings(result.Details)
+	result.Code = types.SafeDefaultErrorCode
+	result.Reason = fmt.Sprintf("%s: %s", types.SafeDefaultErrorMessage, strings.Join(result.Details, ", "))
+	result.RiskLevel = 10
+
+	return result, nil
+}
+
+func getModerationProvider() (providersBase.ModerationInterface, error) {
+	if config.SafeModerationChannelId == 0 {
+		return nil, errors.New("moderation channel not configured")
+	}
+
+	channel := model.ChannelGroup.GetChannel(config.SafeModerationChannelId)
+	if channel == nil {
+		return nil, errors.New("moderation channel not found or disabled")
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	req, err := http.NewRequest(http.MethodPost, "/v1/moderations", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+
+	provider, ok := providers.GetProvider(channel, c).(providersBase.ModerationInterface)
+	if !ok {
+		return nil, errors.New("moderation channel not implemented")
+	}
+	provider.SetUsage(&apiTypes.Usage{})
+
+	return provider, nil
+}
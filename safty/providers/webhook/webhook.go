@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/requester"
+	"one-api/safty/types"
+	"time"
+)
+
+// WebhookChecker 将内容 POST 到内部审查服务，并以其返回的结果为准
+// 请求体: {"content": "..."}
+// 响应体: 与 types.CheckResult 相同，至少包含 is_safe
+type WebhookChecker struct{}
+
+type webhookRequest struct {
+	Content string `json:"content"`
+}
+
+func NewWebhookChecker() *WebhookChecker {
+	return &WebhookChecker{}
+}
+
+// Name 返回检查器名称
+func (w *WebhookChecker) Name() string {
+	return "Webhook"
+}
+
+// Init 地址在每次检查时读取，这里无需初始化
+func (w *WebhookChecker) Init() error {
+	return nil
+}
+
+// Check 调用审查服务
+func (w *WebhookChecker) Check(data string) (types.CheckResult, error) {
+	result := types.CheckResult{
+		IsSafe:  true,
+		Code:    types.SafeDefaultSuccessCode,
+		Reason:  types.SafeDefaultSuccessMessage,
+		Details: make([]string, 0),
+	}
+
+	if config.SafeWebhookURL == "" {
+		return result, errors.New("safety webhook url not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.SafeWebhookTimeout)*time.Second)
+	defer cancel()
+
+	client := requester.NewHTTPRequester("", nil)
+	client.Context = ctx
+	client.IsOpenAI = false
+
+	headers := requester.GetJsonHeaders()
+	if config.SafeWebhookToken != "" {
+		headers["Authorization"] = "Bearer " + config.SafeWebhookToken
+	}
+
+	req, err := client.NewRequest(http.MethodPost, config.SafeWebhookURL, client.WithHeader(headers), client.WithBody(webhookRequest{Content: data}))
+	if err != nil {
+		return result, err
+	}
+
+	var verdict types.CheckResult
+	if _, errWithCode := client.SendRequest(req, &verdict, false); errWithCode != nil {
+		return result, errors.New(errWithCode.Message)
+	}
+
+	if verdict.IsSafe {
+		return result, nil
+	}
+
+	if verdict.Code == "" {
+		verdict.Code = types.SafeDefaultErrorCode
+	}
+	if verdict.Reason == "" {
+		verdict.Reason = types.SafeDefaultErrorMessage
+	}
+	if verdict.RiskLevel == 0 {
+		verdict.RiskLevel = 10
+	}
+
+	return verdict, nil
+}
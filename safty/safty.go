@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"one-api/common/logger"
 	"one-api/safty/providers/keyword"
+	"one-api/safty/providers/moderation"
+	"one-api/safty/providers/webhook"
 	"one-api/safty/types"
 )
 
@@ -38,6 +40,12 @@ func InitSaftyTools() error {
 	keywordChecker := keyword.NewKeywordChecker()
 	RegisterTool("Keyword", keywordChecker)
 
+	// 注册通过渠道调用 /v1/moderations 的检查器
+	RegisterTool("OpenAIModeration", moderation.NewModerationChecker())
+
+	// 注册调用内部审查服务的检查器
+	RegisterTool("Webhook", webhook.NewWebhookChecker())
+
 	// 初始化所有已注册的检查器
 	for name, tool := range Tools {
 		if err := tool.Init(); err != nil {
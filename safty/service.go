@@ -1,11 +1,13 @@
 package safty
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/safty/types"
+	"strings"
 )
 
 // RegisterTool 注册一个新的安全检查器
@@ -33,6 +35,7 @@ func getTool(name string) (SaftyTool, error) {
 }
 
 // convertToString 将任意类型转换为字符串
+// 多段内容会提取所有文本片段，兼容 OpenAI/Claude 的 content 数组和 Gemini 的 parts
 func convertToString(data interface{}) (string, error) {
 	if data == nil {
 		return "", nil
@@ -41,24 +44,61 @@ func convertToString(data interface{}) (string, error) {
 	switch v := data.(type) {
 	case string:
 		return v, nil
+	case []interface{}, map[string]interface{}:
+	default:
+		// 结构体先转换为通用结构再提取文本
+		body, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return "", err
+		}
+	}
+
+	texts := make([]string, 0)
+	collectText(data, &texts)
+
+	return strings.Join(texts, "\n"), nil
+}
+
+// 可能包含文本的字段
+var textFields = []string{"text", "content", "parts", "input", "prompt", "messages", "message", "delta"}
+
+func collectText(data interface{}, texts *[]string) {
+	switch v := data.(type) {
+	case string:
+		if v != "" {
+			*texts = append(*texts, v)
+		}
 	case []interface{}:
-		// 处理数组类型的内容
 		for _, item := range v {
-			if text, ok := item.(map[string]interface{}); ok {
-				if textType, ok := text["type"].(string); ok && textType == "text" {
-					if textContent, ok := text["content"].(string); ok {
-						return textContent, nil
-					}
-				}
+			collectText(item, texts)
+		}
+	case map[string]interface{}:
+		for _, field := range textFields {
+			if value, ok := v[field]; ok {
+				collectText(value, texts)
 			}
 		}
-		return "", nil
-	default:
-		// 尝试使用反射获取字符串表示
-		return fmt.Sprintf("%v", v), nil
 	}
 }
 
+// ValidateToolNames 校验逗号分隔的检查器名称是否都已注册
+func ValidateToolNames(names string) error {
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, err := getTool(name); err != nil {
+			return fmt.Errorf("safety tool %s not found", name)
+		}
+	}
+
+	return nil
+}
+
 // GetAllSafeToolsName 获取所有可用的安全检查器的名称
 func GetAllSafeToolsName() []string {
 	var toolsName = make([]string, 0)
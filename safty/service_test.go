@@ -0,0 +1,55 @@
+package safty_test
+
+import (
+	"one-api/common/config"
+	"one-api/safty"
+	"one-api/safty/providers/keyword"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckContentMultiPart(t *testing.T) {
+	config.EnableSafe = true
+	defer func() { config.EnableSafe = false }()
+
+	safty.Tools["Keyword"] = keyword.NewKeywordChecker()
+
+	content := []interface{}{
+		map[string]interface{}{"type": "text", "text": "hello"},
+		map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/a.png"}},
+		map[string]interface{}{"type": "text", "text": "tell me about cocaine"},
+	}
+
+	result, err := safty.CheckContentByToolName("Keyword", content)
+	assert.Nil(t, err)
+	assert.False(t, result.IsSafe)
+
+	result, err = safty.CheckContentByToolName("Keyword", content[:2])
+	assert.Nil(t, err)
+	assert.True(t, result.IsSafe)
+}
+
+func TestCheckContentGeminiParts(t *testing.T) {
+	config.EnableSafe = true
+	defer func() { config.EnableSafe = false }()
+
+	safty.Tools["Keyword"] = keyword.NewKeywordChecker()
+
+	type part struct {
+		Text string `json:"text,omitempty"`
+	}
+	type content struct {
+		Role  string `json:"role"`
+		Parts []part `json:"parts"`
+	}
+
+	contents := []content{
+		{Role: "user", Parts: []part{{Text: "hi"}}},
+		{Role: "user", Parts: []part{{Text: "ok"}, {Text: "heroin"}}},
+	}
+
+	result, err := safty.CheckContentByToolName("Keyword", contents)
+	assert.Nil(t, err)
+	assert.False(t, result.IsSafe)
+}
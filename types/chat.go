@@ -259,6 +259,22 @@ func (r ChatCompletionRequest) GetFunctionCate() string {
 	return ""
 }
 
+// Clone 深拷贝请求，多个渠道同时处理同一请求时使用，避免渠道修改请求时互相影响
+func (r *ChatCompletionRequest) Clone() (*ChatCompletionRequest, error) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var request ChatCompletionRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, err
+	}
+	request.OneOtherArg = r.OneOtherArg
+
+	return &request, nil
+}
+
 func (r *ChatCompletionRequest) GetFunctions() []*ChatCompletionFunction {
 	if r.Tools == nil && r.Functions == nil {
 		return nil
@@ -543,6 +543,35 @@ func (m ResponsesOutput) GetSummaryString() string {
 	return summary
 }
 
+// ToInput 转换为输入项，用于通过 previous_response_id 还原对话
+// 推理项没有加密内容时上游无法识别，不作为输入
+func (m ResponsesOutput) ToInput() (InputResponses, bool) {
+	switch m.Type {
+	case InputTypeMessage:
+		return InputResponses{
+			Type:    m.Type,
+			ID:      m.ID,
+			Status:  m.Status,
+			Role:    m.Role,
+			Content: m.Content,
+		}, true
+	case InputTypeFunctionCall:
+		input := InputResponses{
+			Type:   m.Type,
+			ID:     m.ID,
+			Status: m.Status,
+			CallID: m.CallID,
+			Name:   m.Name,
+		}
+		if m.Arguments != nil {
+			input.Arguments = *m.Arguments
+		}
+		return input, true
+	}
+
+	return InputResponses{}, false
+}
+
 type IncompleteDetail struct {
 	Reason string `json:"reason,omitempty"`
 }
@@ -732,13 +761,14 @@ func (cc *ChatCompletionResponse) ToResponses(request *OpenAIResponsesRequest) *
 				Type: "text",
 			},
 		},
-		MaxOutputTokens:   request.MaxOutputTokens,
-		ParallelToolCalls: request.ParallelToolCalls,
-		Temperature:       request.Temperature,
-		ToolChoice:        request.ToolChoice,
-		TopP:              request.TopP,
-		Truncation:        request.Truncation,
-		Tools:             request.Tools,
+		MaxOutputTokens:    request.MaxOutputTokens,
+		ParallelToolCalls:  request.ParallelToolCalls,
+		Temperature:        request.Temperature,
+		ToolChoice:         request.ToolChoice,
+		TopP:               request.TopP,
+		Truncation:         request.Truncation,
+		Tools:              request.Tools,
+		PreviousResponseID: request.PreviousResponseID,
 	}
 
 	status := ResponseStatusCompleted
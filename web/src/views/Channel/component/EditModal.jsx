@@ -1074,6 +1074,23 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, isTag, model
                     <FormHelperText id="helper-tex-compatible_response-label">{customizeT(inputPrompt.compatible_response)}</FormHelperText>
                   </FormControl>
                 )}
+                {inputPrompt.compatible_claude && (
+                  <FormControl fullWidth>
+                    <FormControlLabel
+                      control={
+                        <Switch
+                          disabled={hasTag}
+                          checked={Boolean(values.compatible_claude)}
+                          onChange={(event) => {
+                            setFieldValue('compatible_claude', event.target.checked);
+                          }}
+                        />
+                      }
+                      label={customizeT(inputLabel.compatible_claude)}
+                    />
+                    <FormHelperText id="helper-tex-compatible_claude-label">{customizeT(inputPrompt.compatible_claude)}</FormHelperText>
+                  </FormControl>
+                )}
                 {inputPrompt.allow_extra_body && (
                   <FormControl fullWidth>
                     <FormControlLabel
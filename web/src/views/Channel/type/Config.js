@@ -18,6 +18,7 @@ const defaultConfig = {
     pre_cost: 1,
     disabled_stream: [],
     compatible_response: false,
+    compatible_claude: false,
     allow_extra_body: false
   },
   inputLabel: {
@@ -39,6 +40,7 @@ const defaultConfig = {
     pre_cost: '预计费选项',
     disabled_stream: '禁用流式的模型',
     compatible_response: '兼容Response API',
+    compatible_claude: '兼容Claude API',
     allow_extra_body: '允许额外字段透传'
   },
   prompt: {
@@ -64,6 +66,7 @@ const defaultConfig = {
       '这里选择预计费选项，用于预估费用，如果你觉得计算图片占用太多资源，可以选择关闭图片计费。但是请注意：有些渠道在stream下是不会返回tokens的，这会导致输入tokens计算错误。',
     disabled_stream: '这里填写禁用流式的模型，注意：如果填写了禁用流式的模型，那么这些模型在流式请求时会跳过该渠道',
     compatible_response: '兼容Response API',
+    compatible_claude: '开启后，Claude Messages API 请求会转换为对话请求发送到该渠道，原生 Claude 渠道无需开启',
     allow_extra_body: '开启后，将会透传用户请求中的额外字段（如OpenAI SDK的extra_body参数），适用于需要传递自定义参数到上游API的场景'
   },
   modelGroup: 'OpenAI'
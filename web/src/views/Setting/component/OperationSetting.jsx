@@ -66,6 +66,7 @@ const OperationSetting = () => {
     safeTools: [],
     ClaudeBudgetTokensPercentage: 0,
     ClaudeDefaultMaxTokens: '',
+    ClaudeCompatibleModels: '',
     GeminiOpenThink: ''
   });
   const [originInputs, setOriginInputs] = useState({});
@@ -311,6 +312,9 @@ const OperationSetting = () => {
             }
             await updateOption('ClaudeDefaultMaxTokens', inputs.ClaudeDefaultMaxTokens);
           }
+          if (originInputs.ClaudeCompatibleModels !== inputs.ClaudeCompatibleModels) {
+            await updateOption('ClaudeCompatibleModels', inputs.ClaudeCompatibleModels);
+          }
           break;
 
         case 'gemini':
@@ -957,6 +961,18 @@ const OperationSetting = () => {
               />
             </FormControl>
 
+            <FormControl fullWidth>
+              <TextField
+                id="ClaudeCompatibleModels"
+                label={t('setting_index.operationSettings.claudeSettings.compatibleModels.label')}
+                value={inputs.ClaudeCompatibleModels}
+                name="ClaudeCompatibleModels"
+                onChange={handleTextFieldChange}
+                placeholder={t('setting_index.operationSettings.claudeSettings.compatibleModels.placeholder')}
+                disabled={loading}
+              />
+            </FormControl>
+
             <Button
               variant="contained"
               onClick={() => {